@@ -0,0 +1,279 @@
+// Package algo executes the large order by slicing it into small limit orders over a schedule,
+// to reduce the slippage on thin markets.
+package algo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs/book"
+	"github.com/xyths/hs/exchange"
+	"go.uber.org/zap"
+	"sync"
+)
+
+var (
+	ErrBadOrder  = errors.New("algo order needs buy or sell side and positive amount")
+	ErrBadParams = errors.New("bad algo params")
+	ErrNoQuote   = errors.New("no bid or ask")
+)
+
+// the schedule gives up after so many step errors in a row
+const maxFailures = 5
+
+// QuoteFunc returns the best bid and ask of symbol, the child orders are priced against them.
+type QuoteFunc func(symbol string) (bid, ask decimal.Decimal, err error)
+
+// BookQuote quotes from the local order books, see book.Books.Handler
+func BookQuote(books *book.Books) QuoteFunc {
+	return func(symbol string) (bid, ask decimal.Decimal, err error) {
+		b, ok := books.Get(symbol)
+		if !ok {
+			return bid, ask, ErrNoQuote
+		}
+		bestBid, ok1 := b.BestBid()
+		bestAsk, ok2 := b.BestAsk()
+		if !ok1 || !ok2 {
+			return bid, ask, ErrNoQuote
+		}
+		return bestBid.Price, bestAsk.Price, nil
+	}
+}
+
+// LastPriceQuote uses the last price as both bid and ask, for the exchange without order book
+func LastPriceQuote(ex exchange.RestAPIExchange) QuoteFunc {
+	return func(symbol string) (bid, ask decimal.Decimal, err error) {
+		price, err := ex.LastPrice(symbol)
+		return price, price, err
+	}
+}
+
+// Order is the parent order
+type Order struct {
+	Symbol exchange.Symbol
+	Side   exchange.OrderType
+	Amount decimal.Decimal // in base currency
+	// Limit is the highest price to buy or the lowest price to sell, zero means no limit
+	Limit decimal.Decimal
+	// ClientOrderId is the prefix of the child client order ids, the child's is ClientOrderId-N
+	ClientOrderId string
+}
+
+// Progress is the fill progress of the parent order
+type Progress struct {
+	Amount   decimal.Decimal
+	Filled   decimal.Decimal
+	Total    decimal.Decimal // filled in quote currency
+	Children int             // the number of child orders placed
+	Done     bool            // filled, or the remaining is less than the minimal order
+}
+
+// AvgPrice returns the average fill price, zero if nothing filled
+func (p Progress) AvgPrice() decimal.Decimal {
+	if !p.Filled.IsPositive() {
+		return decimal.Zero
+	}
+	return p.Total.Div(p.Filled)
+}
+
+// Remaining returns the unfilled amount
+func (p Progress) Remaining() decimal.Decimal {
+	return p.Amount.Sub(p.Filled)
+}
+
+// ProgressHandler is called after every step of the schedule
+type ProgressHandler func(p Progress)
+
+// executor places one child order at a time, the open child is cancelled and re-priced at the next step.
+type executor struct {
+	ex      exchange.RestAPIExchange
+	quote   QuoteFunc
+	order   Order
+	handler ProgressHandler
+	Sugar   *zap.SugaredLogger
+
+	mu       sync.Mutex
+	progress Progress
+	// the fills of finished children
+	filled decimal.Decimal
+	total  decimal.Decimal
+	// the open child, Id is 0 if none
+	child exchange.Order
+	// the step errors in a row, only used by Run
+	failures int
+}
+
+func newExecutor(ex exchange.RestAPIExchange, quote QuoteFunc, order Order, handler ProgressHandler, logger *zap.SugaredLogger) executor {
+	return executor{
+		ex:       ex,
+		quote:    quote,
+		order:    order,
+		handler:  handler,
+		Sugar:    logger,
+		progress: Progress{Amount: order.Amount},
+	}
+}
+
+func (e *executor) check() error {
+	if e.order.Side != exchange.Buy && e.order.Side != exchange.Sell || !e.order.Amount.IsPositive() {
+		return ErrBadOrder
+	}
+	return nil
+}
+
+// Progress returns the fill progress
+func (e *executor) Progress() Progress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.progress
+}
+
+// step cancels the open child, and places a new one to fill up to target.
+// aggressive crosses the spread, otherwise the child waits at the best price of own side.
+func (e *executor) step(target decimal.Decimal, aggressive bool) (done bool, err error) {
+	if err = e.sync(); err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer func() {
+		p := e.progress
+		e.mu.Unlock()
+		if e.handler != nil {
+			e.handler(p)
+		}
+	}()
+	if e.progress.Done || e.child.Id != 0 {
+		// the cancel is not finished yet
+		return e.progress.Done, nil
+	}
+	s := e.order.Symbol
+	remaining := e.progress.Remaining().Truncate(s.AmountPrecision)
+	if target.GreaterThan(e.order.Amount) {
+		target = e.order.Amount
+	}
+	need := target.Sub(e.progress.Filled).Truncate(s.AmountPrecision)
+
+	bid, ask, err := e.quote(s.Symbol)
+	if err != nil {
+		return
+	}
+	price := e.price(bid, ask, aggressive)
+	if !price.IsPositive() {
+		return false, ErrNoQuote
+	}
+	if e.dust(remaining, price) {
+		e.progress.Done = true
+		return true, nil
+	}
+	// don't leave the dust which can't be placed
+	if e.dust(remaining.Sub(need), price) {
+		need = remaining
+	}
+	if e.dust(need, price) {
+		// wait for the next step
+		return
+	}
+
+	e.progress.Children++
+	clientOrderId := ""
+	if e.order.ClientOrderId != "" {
+		clientOrderId = fmt.Sprintf("%s-%d", e.order.ClientOrderId, e.progress.Children)
+	}
+	var id uint64
+	if e.order.Side == exchange.Buy {
+		id, err = e.ex.BuyLimit(s.Symbol, clientOrderId, price, need)
+	} else {
+		id, err = e.ex.SellLimit(s.Symbol, clientOrderId, price, need)
+	}
+	if err != nil {
+		e.progress.Children--
+		return
+	}
+	e.child = exchange.Order{Id: id, Symbol: s.Symbol, Price: price, Amount: need}
+	e.Sugar.Debugf("algo child %d placed, price %s, amount %s, filled %s/%s", id, price, need, e.progress.Filled, e.order.Amount)
+	return
+}
+
+// sync cancels the open child and updates the fills, the child is kept if the cancel is not finished yet
+func (e *executor) sync() error {
+	e.mu.Lock()
+	child := e.child
+	e.mu.Unlock()
+	if child.Id == 0 {
+		return nil
+	}
+	if err := e.ex.CancelOrder(child.Symbol, child.Id); err != nil {
+		// maybe finished just now
+		e.Sugar.Infof("cancel algo child %d error: %s", child.Id, err)
+	}
+	o, err := e.ex.GetOrderById(child.Id, child.Symbol)
+	if err != nil {
+		return err
+	}
+
+	price := o.FilledPrice
+	if price.IsZero() {
+		price = child.Price
+	}
+	total := o.FilledAmount.Mul(price)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.progress.Filled = e.filled.Add(o.FilledAmount)
+	e.progress.Total = e.total.Add(total)
+	if o.Finished() {
+		e.filled = e.progress.Filled
+		e.total = e.progress.Total
+		e.child = exchange.Order{}
+	}
+	if !e.progress.Remaining().IsPositive() {
+		e.progress.Done = true
+	}
+	return nil
+}
+
+// retry returns true if the schedule goes on after the step error,
+// the quote and order errors are retried at the next step, until maxFailures in a row.
+func (e *executor) retry(err error) bool {
+	if err == nil {
+		e.failures = 0
+		return true
+	}
+	e.failures++
+	e.Sugar.Errorf("algo order of %s step error (%d/%d): %s", e.order.Symbol.Symbol, e.failures, maxFailures, err)
+	return e.failures < maxFailures
+}
+
+// finish cancels the open child and reports the last progress
+func (e *executor) finish(err error) (Progress, error) {
+	if err1 := e.sync(); err1 != nil {
+		e.Sugar.Errorf("cancel algo child error: %s", err1)
+	}
+	p := e.Progress()
+	if e.handler != nil {
+		e.handler(p)
+	}
+	e.Sugar.Infof("algo order of %s finished, filled %s/%s, average price %s", e.order.Symbol.Symbol, p.Filled, p.Amount, p.AvgPrice())
+	return p, err
+}
+
+func (e *executor) price(bid, ask decimal.Decimal, aggressive bool) decimal.Decimal {
+	// buy at bid or sell at ask to wait, buy at ask or sell at bid to take
+	price := bid
+	if e.order.Side == exchange.Buy && aggressive || e.order.Side == exchange.Sell && !aggressive {
+		price = ask
+	}
+	limit := e.order.Limit
+	if limit.IsPositive() {
+		if e.order.Side == exchange.Buy && price.GreaterThan(limit) ||
+			e.order.Side == exchange.Sell && price.LessThan(limit) {
+			price = limit
+		}
+	}
+	return price.Round(e.order.Symbol.PricePrecision)
+}
+
+// dust returns true if the amount is less than the minimal order
+func (e *executor) dust(amount, price decimal.Decimal) bool {
+	s := e.order.Symbol
+	return !amount.IsPositive() || amount.LessThan(s.LimitOrderMinAmount) || amount.Mul(price).LessThan(s.MinTotal)
+}
@@ -0,0 +1,149 @@
+package algo
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/paper"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+var btcUsdt = exchange.Symbol{
+	Symbol:              "btc_usdt",
+	BaseCurrency:        "btc",
+	QuoteCurrency:       "usdt",
+	PricePrecision:      2,
+	AmountPrecision:     4,
+	LimitOrderMinAmount: decimal.RequireFromString("0.0001"),
+	MinTotal:            decimal.NewFromInt(1),
+}
+
+func newPaper(symbol exchange.Symbol, price float64) *paper.Exchange {
+	ex := paper.New([]exchange.Symbol{symbol}, exchange.Fee{})
+	ex.Deposit("usdt", decimal.NewFromInt(10000))
+	ex.UpdateTicker(symbol.Symbol, hs.Ticker{Timestamp: 60, Open: price, High: price, Low: price, Close: price})
+	return ex
+}
+
+func dec(f float64) decimal.Decimal {
+	return decimal.NewFromFloat(f)
+}
+
+func fixedQuote(bid, ask float64) QuoteFunc {
+	return func(symbol string) (decimal.Decimal, decimal.Decimal, error) {
+		return dec(bid), dec(ask), nil
+	}
+}
+
+func TestTWAP_Run(t *testing.T) {
+	ex := newPaper(btcUsdt, 100)
+	var reports []Progress
+	twap := NewTWAP(ex, LastPriceQuote(ex), Order{Symbol: btcUsdt, Side: exchange.Buy, Amount: dec(1)},
+		50*time.Millisecond, 5, func(p Progress) { reports = append(reports, p) }, zap.NewNop().Sugar())
+	p, err := twap.Run(context.Background())
+	require.NoError(t, err)
+	require.True(t, p.Done)
+	require.Equal(t, 5, p.Children)
+	require.True(t, dec(1).Equal(p.Filled))
+	require.True(t, dec(100).Equal(p.AvgPrice()))
+	require.True(t, len(reports) > 5)
+	require.True(t, dec(0.2).Equal(reports[1].Filled), reports[1].Filled.String())
+
+	_, err = NewTWAP(ex, LastPriceQuote(ex), Order{Symbol: btcUsdt, Amount: dec(1)}, time.Second, 5, nil, zap.NewNop().Sugar()).Run(context.Background())
+	require.Equal(t, ErrBadOrder, err)
+}
+
+func TestTWAP_Reprice(t *testing.T) {
+	ex := newPaper(btcUsdt, 100)
+	// the children wait at 99, and the last takes at 101
+	twap := NewTWAP(ex, fixedQuote(99, 101), Order{Symbol: btcUsdt, Side: exchange.Buy, Amount: dec(1)},
+		50*time.Millisecond, 5, nil, zap.NewNop().Sugar())
+	p, err := twap.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 6, p.Children)
+	require.True(t, dec(1).Equal(p.Filled))
+	require.Empty(t, ex.OpenOrders("btc_usdt"))
+
+	// the limit price is never crossed
+	twap = NewTWAP(ex, fixedQuote(99, 101), Order{Symbol: btcUsdt, Side: exchange.Buy, Amount: dec(1), Limit: dec(99.5)},
+		20*time.Millisecond, 2, nil, zap.NewNop().Sugar())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	p, err = twap.Run(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.False(t, p.Done)
+	require.True(t, p.Filled.IsZero())
+	require.Empty(t, ex.OpenOrders("btc_usdt"))
+}
+
+func TestTWAP_MinAmount(t *testing.T) {
+	symbol := btcUsdt
+	symbol.LimitOrderMinAmount = dec(0.3)
+	ex := newPaper(symbol, 100)
+	twap := NewTWAP(ex, LastPriceQuote(ex), Order{Symbol: symbol, Side: exchange.Buy, Amount: dec(1)},
+		50*time.Millisecond, 5, nil, zap.NewNop().Sugar())
+	p, err := twap.Run(context.Background())
+	require.NoError(t, err)
+	// 0.2 per slice is too small, the 2nd slice places 0.4 and the 4th places the rest
+	require.Equal(t, 2, p.Children)
+	require.True(t, dec(1).Equal(p.Filled))
+}
+
+func TestTWAP_Retry(t *testing.T) {
+	ex := newPaper(btcUsdt, 100)
+	// the quote fails twice, then the schedule goes on
+	n := 0
+	quote := func(symbol string) (decimal.Decimal, decimal.Decimal, error) {
+		n++
+		if n <= 2 {
+			return decimal.Zero, decimal.Zero, ErrNoQuote
+		}
+		return dec(100), dec(100), nil
+	}
+	twap := NewTWAP(ex, quote, Order{Symbol: btcUsdt, Side: exchange.Buy, Amount: dec(1)},
+		50*time.Millisecond, 5, nil, zap.NewNop().Sugar())
+	p, err := twap.Run(context.Background())
+	require.NoError(t, err)
+	require.True(t, p.Done)
+	require.True(t, dec(1).Equal(p.Filled))
+
+	// gives up after too many errors in a row
+	twap = NewTWAP(ex, fixedQuote(0, 0), Order{Symbol: btcUsdt, Side: exchange.Buy, Amount: dec(1)},
+		10*time.Millisecond, 2, nil, zap.NewNop().Sugar())
+	p, err = twap.Run(context.Background())
+	require.Equal(t, ErrNoQuote, err)
+	require.False(t, p.Done)
+	require.Equal(t, 0, p.Children)
+}
+
+func TestPOV_Run(t *testing.T) {
+	ex := newPaper(btcUsdt, 100)
+	pov := NewPOV(ex, LastPriceQuote(ex), Order{Symbol: btcUsdt, Side: exchange.Buy, Amount: dec(2)},
+		dec(0.1), 10*time.Millisecond, nil, zap.NewNop().Sugar())
+	pov.OnTrade([]exchange.TradeDetail{{Price: dec(100), Amount: dec(4)}, {Price: dec(100), Amount: dec(6)}})
+
+	type result struct {
+		p   Progress
+		err error
+	}
+	ch := make(chan result)
+	go func() {
+		p, err := pov.Run(context.Background())
+		ch <- result{p, err}
+	}()
+	require.Eventually(t, func() bool { return pov.Progress().Filled.Equal(dec(1)) }, time.Second, 5*time.Millisecond)
+	pov.OnTrade([]exchange.TradeDetail{{Price: dec(100), Amount: dec(10)}})
+	r := <-ch
+	require.NoError(t, r.err)
+	require.True(t, r.p.Done)
+	require.Equal(t, 2, r.p.Children)
+	require.True(t, dec(2).Equal(r.p.Filled))
+
+	_, err := NewPOV(ex, LastPriceQuote(ex), Order{Symbol: btcUsdt, Side: exchange.Sell, Amount: dec(1)},
+		dec(2), time.Second, nil, zap.NewNop().Sugar()).Run(context.Background())
+	require.Equal(t, ErrBadParams, err)
+}
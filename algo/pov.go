@@ -0,0 +1,76 @@
+package algo
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs/exchange"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// POV participates Rate of the market volume since start, so the average price follows the market VWAP.
+// The market volume is fed by OnTrade or Watch, and includes the own fills.
+// It never crosses the spread, use the context deadline to limit the time, the unfilled part is left.
+type POV struct {
+	executor
+	Rate     decimal.Decimal // 0.1 means 10% of the market volume
+	Interval time.Duration
+
+	volumeMu sync.Mutex
+	volume   decimal.Decimal
+}
+
+func NewPOV(ex exchange.RestAPIExchange, quote QuoteFunc, order Order, rate decimal.Decimal, interval time.Duration, handler ProgressHandler, logger *zap.SugaredLogger) *POV {
+	return &POV{
+		executor: newExecutor(ex, quote, order, handler, logger),
+		Rate:     rate,
+		Interval: interval,
+	}
+}
+
+// OnTrade adds the trades to the market volume
+func (p *POV) OnTrade(details []exchange.TradeDetail) {
+	p.volumeMu.Lock()
+	defer p.volumeMu.Unlock()
+	for _, d := range details {
+		p.volume = p.volume.Add(d.Amount)
+	}
+}
+
+// Watch subscribes the trades of the order symbol
+func (p *POV) Watch(ex exchange.StreamAPIExchange, clientId string) *exchange.Subscription {
+	return ex.SubscribeTrade(p.order.Symbol.Symbol, clientId, p.OnTrade)
+}
+
+// Volume returns the market volume since start
+func (p *POV) Volume() decimal.Decimal {
+	p.volumeMu.Lock()
+	defer p.volumeMu.Unlock()
+	return p.volume
+}
+
+// Run executes the order until filled or the context is done, the open child is cancelled before return.
+// The quote and order errors are retried at the next step, it gives up after 5 errors in a row.
+func (p *POV) Run(ctx context.Context) (Progress, error) {
+	if err := p.check(); err != nil {
+		return p.Progress(), err
+	}
+	if !p.Rate.IsPositive() || p.Rate.GreaterThan(decimal.NewFromInt(1)) || p.Interval <= 0 {
+		return p.Progress(), ErrBadParams
+	}
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		done, err := p.step(p.Volume().Mul(p.Rate), false)
+		if done || !p.retry(err) {
+			return p.finish(err)
+		}
+		select {
+		case <-ctx.Done():
+			return p.finish(ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package algo
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs/exchange"
+	"go.uber.org/zap"
+	"time"
+)
+
+// TWAP splits the order into Slices equal parts over Duration.
+// Every slice waits at the best price of own side, and the unfilled part is re-priced at the next slice.
+// After Duration, the remaining is taken at the other side until filled or the context is done.
+type TWAP struct {
+	executor
+	Duration time.Duration
+	Slices   int
+}
+
+func NewTWAP(ex exchange.RestAPIExchange, quote QuoteFunc, order Order, duration time.Duration, slices int, handler ProgressHandler, logger *zap.SugaredLogger) *TWAP {
+	return &TWAP{
+		executor: newExecutor(ex, quote, order, handler, logger),
+		Duration: duration,
+		Slices:   slices,
+	}
+}
+
+// Run executes the order until filled or the context is done, the open child is cancelled before return.
+// The quote and order errors are retried at the next step, it gives up after 5 errors in a row.
+func (t *TWAP) Run(ctx context.Context) (Progress, error) {
+	if err := t.check(); err != nil {
+		return t.Progress(), err
+	}
+	if t.Duration <= 0 || t.Slices <= 0 {
+		return t.Progress(), ErrBadParams
+	}
+	ticker := time.NewTicker(t.Duration / time.Duration(t.Slices))
+	defer ticker.Stop()
+
+	slices := decimal.NewFromInt(int64(t.Slices))
+	for i := 1; ; i++ {
+		n := i
+		if n > t.Slices {
+			n = t.Slices
+		}
+		target := t.order.Amount.Mul(decimal.NewFromInt(int64(n))).Div(slices)
+		done, err := t.step(target, i > t.Slices)
+		if done || !t.retry(err) {
+			return t.finish(err)
+		}
+		select {
+		case <-ctx.Done():
+			return t.finish(ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
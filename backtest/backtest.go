@@ -0,0 +1,91 @@
+// Package backtest replays a hs.Candle bar by bar into a strategy,
+// and simulates the fills of its orders.
+package backtest
+
+import (
+	"errors"
+	"github.com/xyths/hs"
+)
+
+// Strategy is called after the close of every bar,
+// history contains the bars from the first one to the current one, so no future data is visible.
+// Orders placed in Strategy are matched from the next bar.
+type Strategy func(broker *Broker, history hs.Candle)
+
+type Config struct {
+	InitialCash float64 // quote currency
+	InitialBase float64 // base currency
+
+	MakerFee float64 // fee rate of limit order filled by price cross
+	TakerFee float64 // fee rate of market and stop order, or limit order filled at open
+	Slippage float64 // ratio, market and stop order are filled at a worse price by it
+
+	// PeriodsPerYear is used to annualize the Sharpe ratio, eg. 365 for 1d bars.
+	// Zero means not annualized.
+	PeriodsPerYear float64
+}
+
+type EquityPoint struct {
+	Timestamp int64 // unix timestamp in seconds
+	Equity    float64
+}
+
+type Result struct {
+	Equity []EquityPoint
+	Trades []Trade
+	Orders []Order // all orders, include the open ones at the end
+	Stats  Stats
+}
+
+var (
+	ErrEmptyCandle = errors.New("empty candle")
+	ErrBadConfig   = errors.New("bad config")
+)
+
+// Run replays candle into strategy, and returns the result.
+func Run(candle hs.Candle, config Config, strategy Strategy) (Result, error) {
+	if candle.Length() == 0 {
+		return Result{}, ErrEmptyCandle
+	}
+	if config.InitialCash < 0 || config.InitialBase < 0 || config.MakerFee < 0 || config.TakerFee < 0 || config.Slippage < 0 {
+		return Result{}, ErrBadConfig
+	}
+	broker := newBroker(config)
+	// the initial base is valued at the first open
+	broker.cost = candle.Open[0]
+	initial := config.InitialCash + config.InitialBase*candle.Open[0]
+	var result Result
+	for i := 0; i < candle.Length(); i++ {
+		bar := hs.Ticker{
+			Timestamp: candle.Timestamp[i],
+			Open:      candle.Open[i],
+			High:      candle.High[i],
+			Low:       candle.Low[i],
+			Close:     candle.Close[i],
+			Volume:    candle.Volume[i],
+		}
+		broker.match(bar)
+		broker.close = bar.Close
+		broker.timestamp = bar.Timestamp
+		result.Equity = append(result.Equity, EquityPoint{Timestamp: bar.Timestamp, Equity: broker.Equity()})
+
+		strategy(broker, history(candle, i+1))
+	}
+	result.Trades = broker.trades
+	result.Orders = broker.Orders()
+	result.Stats = statistic(initial, result.Equity, result.Trades, config.PeriodsPerYear)
+	return result, nil
+}
+
+// history returns the first n bars of candle, without copy
+func history(candle hs.Candle, n int) hs.Candle {
+	return hs.Candle{
+		Capacity:  n,
+		Timestamp: candle.Timestamp[:n:n],
+		Open:      candle.Open[:n:n],
+		High:      candle.High[:n:n],
+		Low:       candle.Low[:n:n],
+		Close:     candle.Close[:n:n],
+		Volume:    candle.Volume[:n:n],
+	}
+}
@@ -0,0 +1,109 @@
+package backtest
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs"
+	"testing"
+)
+
+func candle(bars ...hs.Ticker) hs.Candle {
+	c := hs.NewCandle(len(bars))
+	for _, b := range bars {
+		c.Append(b)
+	}
+	return c
+}
+
+// bars: open, high, low, close
+var bars = candle(
+	hs.Ticker{Timestamp: 60, Open: 100, High: 102, Low: 98, Close: 100},
+	hs.Ticker{Timestamp: 120, Open: 100, High: 101, Low: 94, Close: 96},
+	hs.Ticker{Timestamp: 180, Open: 96, High: 110, Low: 95, Close: 108},
+	hs.Ticker{Timestamp: 240, Open: 108, High: 109, Low: 90, Close: 91},
+	hs.Ticker{Timestamp: 300, Open: 91, High: 93, Low: 88, Close: 92},
+)
+
+func TestRun_Limit(t *testing.T) {
+	config := Config{InitialCash: 1000, MakerFee: 0.001, TakerFee: 0.002}
+	result, err := Run(bars, config, func(b *Broker, h hs.Candle) {
+		switch h.Length() {
+		case 1:
+			b.BuyLimit(95, 10)
+		case 2:
+			// no future data
+			require.Equal(t, int64(120), h.Timestamp[h.Length()-1])
+			b.SellLimit(105, 10)
+		}
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Trades, 2)
+
+	buy := result.Trades[0]
+	require.Equal(t, int64(120), buy.Timestamp)
+	require.Equal(t, 95.0, buy.Price)
+	require.True(t, buy.Maker)
+	require.InDelta(t, 0.95, buy.Fee, 1e-9)
+
+	sell := result.Trades[1]
+	require.Equal(t, int64(180), sell.Timestamp)
+	require.Equal(t, 105.0, sell.Price)
+	require.InDelta(t, 1050-1.05-950.95, sell.Profit, 1e-9)
+
+	require.InDelta(t, 1000-950.95+1048.95, result.Stats.FinalEquity, 1e-9)
+	require.InDelta(t, 0.098, result.Stats.Return, 1e-9)
+	require.Equal(t, 1, result.Stats.ClosedTrades)
+	require.Equal(t, 1.0, result.Stats.WinRate)
+	require.Len(t, result.Equity, 5)
+}
+
+func TestRun_MarketAndStop(t *testing.T) {
+	config := Config{InitialCash: 1000, TakerFee: 0.001, Slippage: 0.01}
+	var stop int
+	result, err := Run(bars, config, func(b *Broker, h hs.Candle) {
+		switch h.Length() {
+		case 1:
+			b.BuyMarket(5)
+		case 3:
+			stop = b.SellStop(100, 5)
+		}
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Trades, 2)
+	// market buy at next open with slippage
+	require.InDelta(t, 101, result.Trades[0].Price, 1e-9)
+	require.False(t, result.Trades[0].Maker)
+	// stop sell at stop price with slippage, the bar opens above stop
+	require.Equal(t, stop, result.Trades[1].OrderId)
+	require.InDelta(t, 99, result.Trades[1].Price, 1e-9)
+	require.Equal(t, 0.0, result.Stats.WinRate)
+	require.Greater(t, result.Stats.MaxDrawdown, 0.0)
+}
+
+func TestRun_Rejected(t *testing.T) {
+	result, err := Run(bars, Config{InitialCash: 100}, func(b *Broker, h hs.Candle) {
+		if h.Length() == 1 {
+			b.BuyMarket(10)
+			b.SellMarket(1)
+		}
+	})
+	require.NoError(t, err)
+	require.Empty(t, result.Trades)
+	for _, o := range result.Orders {
+		require.Equal(t, OrderStatusRejected, o.Status)
+	}
+	require.Equal(t, 0.0, result.Stats.Return)
+}
+
+func TestRun_Error(t *testing.T) {
+	_, err := Run(hs.NewCandle(0), Config{}, nil)
+	require.Equal(t, ErrEmptyCandle, err)
+	_, err = Run(bars, Config{Slippage: -1}, nil)
+	require.Equal(t, ErrBadConfig, err)
+}
+
+func TestStats(t *testing.T) {
+	equity := []EquityPoint{{1, 100}, {2, 120}, {3, 90}, {4, 130}, {5, 117}}
+	require.InDelta(t, 0.25, maxDrawdown(equity), 1e-9)
+	require.Equal(t, 0.0, sharpe(100, []EquityPoint{{1, 100}, {2, 100}, {3, 100}}, 365))
+	require.Greater(t, sharpe(100, []EquityPoint{{1, 101}, {2, 103}, {3, 104}}, 0), 0.0)
+}
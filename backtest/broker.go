@@ -0,0 +1,238 @@
+package backtest
+
+import (
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"math"
+)
+
+type OrderKind int
+
+const (
+	Limit  OrderKind = iota
+	Market           // filled at the open of next bar
+	Stop             // stop market, filled when the price touch stop price
+)
+
+const (
+	OrderStatusOpen      = "open"
+	OrderStatusClosed    = "closed" // full filled
+	OrderStatusCancelled = "cancelled"
+	OrderStatusRejected  = "rejected" // no enough cash or base when filling
+)
+
+type Order struct {
+	Id        int
+	Side      exchange.OrderType
+	Kind      OrderKind
+	Price     float64 // limit price, or stop price for stop order
+	Amount    float64 // base currency
+	Timestamp int64   // the bar when placed
+	Status    string
+}
+
+type Trade struct {
+	OrderId   int
+	Side      exchange.OrderType
+	Price     float64
+	Amount    float64
+	Fee       float64 // in quote currency
+	Maker     bool
+	Timestamp int64
+	// Profit is the realized profit of sell trade, fees of both sides included, zero for buy trade.
+	Profit float64
+}
+
+// Broker holds the account in backtest, strategy places orders by it.
+type Broker struct {
+	config Config
+
+	cash float64
+	base float64
+	cost float64 // average cost of base, include buy fee
+
+	close     float64 // close price of current bar
+	timestamp int64
+
+	nextId int
+	orders []*Order
+	trades []Trade
+}
+
+func newBroker(config Config) *Broker {
+	return &Broker{
+		config: config,
+		cash:   config.InitialCash,
+		base:   config.InitialBase,
+	}
+}
+
+func (b *Broker) Cash() float64 {
+	return b.cash
+}
+
+func (b *Broker) Position() float64 {
+	return b.base
+}
+
+// Equity is the value of account at the close of current bar
+func (b *Broker) Equity() float64 {
+	return b.cash + b.base*b.close
+}
+
+// Orders returns all orders placed
+func (b *Broker) Orders() []Order {
+	var orders []Order
+	for _, o := range b.orders {
+		orders = append(orders, *o)
+	}
+	return orders
+}
+
+// OpenOrders returns orders not filled or cancelled
+func (b *Broker) OpenOrders() []Order {
+	var orders []Order
+	for _, o := range b.orders {
+		if o.Status == OrderStatusOpen {
+			orders = append(orders, *o)
+		}
+	}
+	return orders
+}
+
+func (b *Broker) BuyLimit(price, amount float64) int {
+	return b.place(exchange.Buy, Limit, price, amount)
+}
+
+func (b *Broker) SellLimit(price, amount float64) int {
+	return b.place(exchange.Sell, Limit, price, amount)
+}
+
+func (b *Broker) BuyMarket(amount float64) int {
+	return b.place(exchange.Buy, Market, 0, amount)
+}
+
+func (b *Broker) SellMarket(amount float64) int {
+	return b.place(exchange.Sell, Market, 0, amount)
+}
+
+// BuyStop buys when price >= stopPrice
+func (b *Broker) BuyStop(stopPrice, amount float64) int {
+	return b.place(exchange.Buy, Stop, stopPrice, amount)
+}
+
+// SellStop sells when price <= stopPrice
+func (b *Broker) SellStop(stopPrice, amount float64) int {
+	return b.place(exchange.Sell, Stop, stopPrice, amount)
+}
+
+// Cancel cancels the open order, returns false if the order is not open
+func (b *Broker) Cancel(id int) bool {
+	for _, o := range b.orders {
+		if o.Id == id && o.Status == OrderStatusOpen {
+			o.Status = OrderStatusCancelled
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Broker) CancelAll() {
+	for _, o := range b.orders {
+		if o.Status == OrderStatusOpen {
+			o.Status = OrderStatusCancelled
+		}
+	}
+}
+
+func (b *Broker) place(side exchange.OrderType, kind OrderKind, price, amount float64) int {
+	b.nextId++
+	o := &Order{
+		Id:        b.nextId,
+		Side:      side,
+		Kind:      kind,
+		Price:     price,
+		Amount:    amount,
+		Timestamp: b.timestamp,
+		Status:    OrderStatusOpen,
+	}
+	if amount <= 0 || kind != Market && price <= 0 {
+		o.Status = OrderStatusRejected
+	}
+	b.orders = append(b.orders, o)
+	return o.Id
+}
+
+// match open orders with bar, in placing sequence
+func (b *Broker) match(bar hs.Ticker) {
+	for _, o := range b.orders {
+		if o.Status != OrderStatusOpen {
+			continue
+		}
+		switch o.Kind {
+		case Market:
+			b.fill(o, b.slip(o.Side, bar.Open), false, bar.Timestamp)
+		case Limit:
+			if o.Side == exchange.Buy {
+				if bar.Open <= o.Price {
+					b.fill(o, bar.Open, false, bar.Timestamp)
+				} else if bar.Low <= o.Price {
+					b.fill(o, o.Price, true, bar.Timestamp)
+				}
+			} else {
+				if bar.Open >= o.Price {
+					b.fill(o, bar.Open, false, bar.Timestamp)
+				} else if bar.High >= o.Price {
+					b.fill(o, o.Price, true, bar.Timestamp)
+				}
+			}
+		case Stop:
+			if o.Side == exchange.Buy && bar.High >= o.Price {
+				b.fill(o, b.slip(o.Side, math.Max(bar.Open, o.Price)), false, bar.Timestamp)
+			} else if o.Side == exchange.Sell && bar.Low <= o.Price {
+				b.fill(o, b.slip(o.Side, math.Min(bar.Open, o.Price)), false, bar.Timestamp)
+			}
+		}
+	}
+}
+
+func (b *Broker) slip(side exchange.OrderType, price float64) float64 {
+	return price * (1 + float64(side)*b.config.Slippage)
+}
+
+func (b *Broker) fill(o *Order, price float64, maker bool, timestamp int64) {
+	rate := b.config.TakerFee
+	if maker {
+		rate = b.config.MakerFee
+	}
+	turnover := price * o.Amount
+	fee := turnover * rate
+	trade := Trade{
+		OrderId:   o.Id,
+		Side:      o.Side,
+		Price:     price,
+		Amount:    o.Amount,
+		Fee:       fee,
+		Maker:     maker,
+		Timestamp: timestamp,
+	}
+	if o.Side == exchange.Buy {
+		if turnover+fee > b.cash {
+			o.Status = OrderStatusRejected
+			return
+		}
+		b.cost = (b.cost*b.base + turnover + fee) / (b.base + o.Amount)
+		b.cash -= turnover + fee
+		b.base += o.Amount
+	} else {
+		if o.Amount > b.base {
+			o.Status = OrderStatusRejected
+			return
+		}
+		trade.Profit = turnover - fee - b.cost*o.Amount
+		b.cash += turnover - fee
+		b.base -= o.Amount
+	}
+	o.Status = OrderStatusClosed
+	b.trades = append(b.trades, trade)
+}
@@ -0,0 +1,96 @@
+package backtest
+
+import (
+	"github.com/xyths/hs/exchange"
+	"math"
+)
+
+type Stats struct {
+	InitialEquity float64
+	FinalEquity   float64
+	Return        float64 // total return, 0.1 means 10%
+	MaxDrawdown   float64 // max drop from a peak of equity, 0.1 means 10%
+	Sharpe        float64 // Sharpe ratio of bar returns, risk free rate is 0
+	Trades        int     // number of trades
+	ClosedTrades  int     // number of sell trades, win rate is based on them
+	WinRate       float64
+}
+
+func statistic(initial float64, equity []EquityPoint, trades []Trade, periodsPerYear float64) Stats {
+	s := Stats{
+		InitialEquity: initial,
+		FinalEquity:   initial,
+		Trades:        len(trades),
+	}
+	if len(equity) > 0 {
+		s.FinalEquity = equity[len(equity)-1].Equity
+	}
+	if initial != 0 {
+		s.Return = s.FinalEquity/initial - 1
+	}
+	s.MaxDrawdown = maxDrawdown(equity)
+	s.Sharpe = sharpe(initial, equity, periodsPerYear)
+
+	wins := 0
+	for _, t := range trades {
+		if t.Side != exchange.Sell {
+			continue
+		}
+		s.ClosedTrades++
+		if t.Profit > 0 {
+			wins++
+		}
+	}
+	if s.ClosedTrades > 0 {
+		s.WinRate = float64(wins) / float64(s.ClosedTrades)
+	}
+	return s
+}
+
+func maxDrawdown(equity []EquityPoint) float64 {
+	peak, drawdown := 0.0, 0.0
+	for _, e := range equity {
+		if e.Equity > peak {
+			peak = e.Equity
+		}
+		if peak > 0 {
+			drawdown = math.Max(drawdown, (peak-e.Equity)/peak)
+		}
+	}
+	return drawdown
+}
+
+func sharpe(initial float64, equity []EquityPoint, periodsPerYear float64) float64 {
+	if len(equity) < 2 {
+		return 0
+	}
+	returns := make([]float64, 0, len(equity))
+	prev := initial
+	for _, e := range equity {
+		if prev != 0 {
+			returns = append(returns, e.Equity/prev-1)
+		}
+		prev = e.Equity
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+	if std == 0 {
+		return 0
+	}
+	ratio := mean / std
+	if periodsPerYear > 0 {
+		ratio *= math.Sqrt(periodsPerYear)
+	}
+	return ratio
+}
@@ -0,0 +1,326 @@
+// Package book maintains local L2 order book from websocket depth streams.
+// The book applies snapshot and incremental exchange.DepthUpdate, detects sequence gap,
+// and resyncs from the snapshot given by Snapshotter automatically.
+package book
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs/exchange"
+	"sync"
+	"time"
+)
+
+// Snapshotter gets the full depth of symbol, usually by rest api, for resync
+type Snapshotter func(symbol string) (exchange.DepthUpdate, error)
+
+// max buffered updates when not synced, the oldest is dropped when full
+const maxBuffer = 1000
+
+// the wait after failed resync doubles from minBackoff to maxBackoff, and resets after synced
+const (
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+var (
+	ErrGap               = errors.New("depth sequence gap")
+	ErrSymbol            = errors.New("depth symbol mismatch")
+	ErrInsufficientDepth = errors.New("insufficient depth")
+)
+
+// Book is the local order book of one symbol, safe for concurrent use.
+type Book struct {
+	symbol      string
+	snapshotter Snapshotter
+
+	mu        sync.RWMutex
+	bids      levels
+	asks      levels
+	seq       uint64 // last applied sequence
+	time      time.Time
+	synced    bool
+	resyncing bool
+	backoff   time.Duration          // wait before next resync
+	buffer    []exchange.DepthUpdate // updates before snapshot
+	err       error                  // last resync error
+}
+
+// New creates an empty book, it's synced after the first snapshot.
+// snapshotter can be nil if the stream sends snapshot itself, eg. gate v2 depth.update with clean flag.
+func New(symbol string, snapshotter Snapshotter) *Book {
+	return &Book{
+		symbol:      symbol,
+		snapshotter: snapshotter,
+		bids:        levels{desc: true},
+	}
+}
+
+func (b *Book) Symbol() string {
+	return b.symbol
+}
+
+// Synced returns true if the book is consistent with the exchange
+func (b *Book) Synced() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.synced
+}
+
+// Seq returns the sequence of last applied update
+func (b *Book) Seq() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.seq
+}
+
+// Time returns the time of last applied update
+func (b *Book) Time() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.time
+}
+
+// Err returns the error of last resync, ErrGap if the snapshot is older than the buffered updates
+func (b *Book) Err() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.err
+}
+
+// Apply applies the update.
+// The incremental update before snapshot is buffered, and replayed after snapshot.
+// It returns ErrGap if the sequence breaks, then the book is unsynced and resyncs in background.
+func (b *Book) Apply(u exchange.DepthUpdate) error {
+	if u.Symbol != "" && u.Symbol != b.symbol {
+		return fmt.Errorf("%w: %s, want %s", ErrSymbol, u.Symbol, b.symbol)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if u.Snapshot {
+		b.bids.reset()
+		b.asks.reset()
+		b.seq = 0
+		b.synced = true
+		b.apply(u)
+		b.replay()
+		return nil
+	}
+	if !b.synced {
+		b.push(u)
+		b.startResync()
+		return nil
+	}
+	if err := b.check(u); errors.Is(err, errStale) {
+		return nil
+	} else if err != nil {
+		b.unsync(u)
+		return err
+	}
+	b.apply(u)
+	return nil
+}
+
+// Resync gets snapshot and applies it, in the caller goroutine
+func (b *Book) Resync() error {
+	if b.snapshotter == nil {
+		return errors.New("no snapshotter")
+	}
+	s, err := b.snapshotter(b.symbol)
+	b.mu.Lock()
+	b.err = err
+	b.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.Snapshot = true
+	return b.Apply(s)
+}
+
+var errStale = errors.New("stale depth update")
+
+// check returns errStale for the old update, and ErrGap if the sequence breaks
+func (b *Book) check(u exchange.DepthUpdate) error {
+	if u.LastSeq == 0 || b.seq == 0 {
+		// sequence not supported
+		return nil
+	}
+	if u.LastSeq <= b.seq {
+		return errStale
+	}
+	if u.PrevSeq != 0 && u.PrevSeq != b.seq {
+		return fmt.Errorf("%w: prev %d, last %d", ErrGap, u.PrevSeq, b.seq)
+	}
+	if u.PrevSeq == 0 && u.FirstSeq > b.seq+1 {
+		return fmt.Errorf("%w: first %d, last %d", ErrGap, u.FirstSeq, b.seq)
+	}
+	return nil
+}
+
+func (b *Book) apply(u exchange.DepthUpdate) {
+	for _, l := range u.Bids {
+		b.bids.set(l.Price, l.Amount)
+	}
+	for _, l := range u.Asks {
+		b.asks.set(l.Price, l.Amount)
+	}
+	if u.LastSeq != 0 {
+		b.seq = u.LastSeq
+	}
+	if !u.Time.IsZero() {
+		b.time = u.Time
+	}
+}
+
+// replay applies the buffered updates after snapshot
+func (b *Book) replay() {
+	buffer := b.buffer
+	b.buffer = nil
+	for i, u := range buffer {
+		err := b.check(u)
+		if errors.Is(err, errStale) {
+			continue
+		}
+		if err != nil {
+			b.unsync(buffer[i:]...)
+			return
+		}
+		b.apply(u)
+	}
+}
+
+func (b *Book) unsync(updates ...exchange.DepthUpdate) {
+	b.synced = false
+	b.push(updates...)
+	b.startResync()
+}
+
+func (b *Book) push(updates ...exchange.DepthUpdate) {
+	b.buffer = append(b.buffer, updates...)
+	if n := len(b.buffer); n > maxBuffer {
+		b.buffer = append(b.buffer[:0], b.buffer[n-maxBuffer:]...)
+	}
+}
+
+// startResync gets snapshot in background, if it's failed, the next update after backoff will start again.
+// The error is kept for Err.
+func (b *Book) startResync() {
+	if b.snapshotter == nil || b.resyncing {
+		return
+	}
+	b.resyncing = true
+	go func() {
+		err := b.Resync()
+		b.mu.Lock()
+		if err == nil && b.synced {
+			b.backoff = 0
+			b.resyncing = false
+			b.mu.Unlock()
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("%w: snapshot %d is older than the buffered updates", ErrGap, b.seq)
+			b.err = err
+		}
+		b.backoff *= 2
+		if b.backoff < minBackoff {
+			b.backoff = minBackoff
+		} else if b.backoff > maxBackoff {
+			b.backoff = maxBackoff
+		}
+		backoff := b.backoff
+		b.mu.Unlock()
+
+		time.Sleep(backoff)
+		b.mu.Lock()
+		b.resyncing = false
+		b.mu.Unlock()
+	}()
+}
+
+// BestBid returns the highest buy level
+func (b *Book) BestBid() (exchange.DepthLevel, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.bids.levels) == 0 {
+		return exchange.DepthLevel{}, false
+	}
+	return b.bids.levels[0], true
+}
+
+// BestAsk returns the lowest sell level
+func (b *Book) BestAsk() (exchange.DepthLevel, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.asks.levels) == 0 {
+		return exchange.DepthLevel{}, false
+	}
+	return b.asks.levels[0], true
+}
+
+// Bids returns the best n buy levels, all if n <= 0
+func (b *Book) Bids(n int) []exchange.DepthLevel {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.bids.top(n)
+}
+
+// Asks returns the best n sell levels, all if n <= 0
+func (b *Book) Asks(n int) []exchange.DepthLevel {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.asks.top(n)
+}
+
+// DepthAt returns the amount at price, of the bid or ask level
+func (b *Book) DepthAt(price decimal.Decimal) decimal.Decimal {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if amount := b.bids.get(price); !amount.IsZero() {
+		return amount
+	}
+	return b.asks.get(price)
+}
+
+// VWAP returns the average price to buy (take asks) or sell (take bids) amount at once.
+// It returns ErrInsufficientDepth with the average price of whole side if the book is not deep enough.
+func (b *Book) VWAP(side exchange.OrderType, amount decimal.Decimal) (decimal.Decimal, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	l := b.bids.levels
+	if side == exchange.Buy {
+		l = b.asks.levels
+	}
+	left, total := amount, decimal.Zero
+	for _, level := range l {
+		if !left.IsPositive() {
+			break
+		}
+		taken := decimal.Min(left, level.Amount)
+		total = total.Add(taken.Mul(level.Price))
+		left = left.Sub(taken)
+	}
+	filled := amount.Sub(left)
+	if filled.IsZero() {
+		return decimal.Zero, ErrInsufficientDepth
+	}
+	if left.IsPositive() {
+		return total.Div(filled), ErrInsufficientDepth
+	}
+	return total.Div(filled), nil
+}
+
+// OrderBook returns the best n levels as exchange.OrderBook, all if n <= 0
+func (b *Book) OrderBook(n int) exchange.OrderBook {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ob := exchange.OrderBook{Id: int(b.seq)}
+	for _, l := range b.bids.top(n) {
+		ob.Bids = append(ob.Bids, exchange.Quote{l.Price.InexactFloat64(), l.Amount.InexactFloat64()})
+	}
+	for _, l := range b.asks.top(n) {
+		ob.Asks = append(ob.Asks, exchange.Quote{l.Price.InexactFloat64(), l.Amount.InexactFloat64()})
+	}
+	return ob
+}
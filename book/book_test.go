@@ -0,0 +1,149 @@
+package book
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs/exchange"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func level(price, amount string) exchange.DepthLevel {
+	return exchange.DepthLevel{Price: decimal.RequireFromString(price), Amount: decimal.RequireFromString(amount)}
+}
+
+func TestBook_Query(t *testing.T) {
+	b := New("btc_usdt", nil)
+	require.NoError(t, b.Apply(exchange.DepthUpdate{
+		Snapshot: true,
+		// unsorted on purpose
+		Bids: []exchange.DepthLevel{level("99", "2"), level("100", "1"), level("98", "3")},
+		Asks: []exchange.DepthLevel{level("102", "2"), level("101", "1"), level("103", "3")},
+	}))
+	require.True(t, b.Synced())
+
+	bid, ok := b.BestBid()
+	require.True(t, ok)
+	require.Equal(t, "100", bid.Price.String())
+	ask, ok := b.BestAsk()
+	require.True(t, ok)
+	require.Equal(t, "101", ask.Price.String())
+	require.Equal(t, "3", b.DepthAt(decimal.NewFromInt(98)).String())
+	require.Equal(t, "2", b.DepthAt(decimal.NewFromInt(102)).String())
+	require.True(t, b.DepthAt(decimal.NewFromInt(50)).IsZero())
+
+	// buy 2: 1@101 + 1@102
+	vwap, err := b.VWAP(exchange.Buy, decimal.NewFromInt(2))
+	require.NoError(t, err)
+	require.Equal(t, "101.5", vwap.String())
+	// sell 4: 1@100 + 2@99 + 1@98
+	vwap, err = b.VWAP(exchange.Sell, decimal.NewFromInt(4))
+	require.NoError(t, err)
+	require.Equal(t, "99", vwap.String())
+	_, err = b.VWAP(exchange.Buy, decimal.NewFromInt(10))
+	require.Equal(t, ErrInsufficientDepth, err)
+
+	// remove and update level
+	require.NoError(t, b.Apply(exchange.DepthUpdate{
+		Bids: []exchange.DepthLevel{level("100", "0"), level("99.5", "5")},
+		Asks: []exchange.DepthLevel{level("101", "4")},
+	}))
+	require.Equal(t, []exchange.DepthLevel{level("99.5", "5"), level("99", "2")}, b.Bids(2))
+	require.Equal(t, "4", b.Asks(1)[0].Amount.String())
+	ob := b.OrderBook(1)
+	require.Equal(t, []exchange.Quote{{99.5, 5}}, ob.Bids)
+	require.Equal(t, []exchange.Quote{{101, 4}}, ob.Asks)
+}
+
+// range style, like gate v4 spot.order_book_update
+func TestBook_RangeSequence(t *testing.T) {
+	snapshots := make(chan exchange.DepthUpdate, 1)
+	b := New("BTC_USDT", func(symbol string) (exchange.DepthUpdate, error) {
+		return <-snapshots, nil
+	})
+
+	// buffered before snapshot
+	require.NoError(t, b.Apply(exchange.DepthUpdate{FirstSeq: 8, LastSeq: 10, Bids: []exchange.DepthLevel{level("100", "1")}}))
+	require.NoError(t, b.Apply(exchange.DepthUpdate{FirstSeq: 11, LastSeq: 12, Bids: []exchange.DepthLevel{level("100", "2")}}))
+	require.False(t, b.Synced())
+	snapshots <- exchange.DepthUpdate{LastSeq: 9, Bids: []exchange.DepthLevel{level("100", "5"), level("99", "1")}}
+	require.Eventually(t, b.Synced, time.Second, time.Millisecond*10)
+	require.Equal(t, uint64(12), b.Seq())
+	require.Equal(t, "2", b.DepthAt(decimal.NewFromInt(100)).String())
+
+	// stale is ignored
+	require.NoError(t, b.Apply(exchange.DepthUpdate{FirstSeq: 11, LastSeq: 12, Bids: []exchange.DepthLevel{level("100", "7")}}))
+	require.Equal(t, "2", b.DepthAt(decimal.NewFromInt(100)).String())
+
+	// gap
+	err := b.Apply(exchange.DepthUpdate{FirstSeq: 15, LastSeq: 16, Bids: []exchange.DepthLevel{level("99", "3")}})
+	require.True(t, errors.Is(err, ErrGap))
+	require.False(t, b.Synced())
+	snapshots <- exchange.DepthUpdate{LastSeq: 15, Bids: []exchange.DepthLevel{level("99", "2")}}
+	require.Eventually(t, b.Synced, time.Second, time.Millisecond*10)
+	require.Equal(t, uint64(16), b.Seq())
+	require.Equal(t, []exchange.DepthLevel{level("99", "3")}, b.Bids(0))
+}
+
+// chain style, like huobi mbp
+func TestBook_ChainSequence(t *testing.T) {
+	b := New("btcusdt", func(symbol string) (exchange.DepthUpdate, error) {
+		return exchange.DepthUpdate{Symbol: symbol, LastSeq: 100, Asks: []exchange.DepthLevel{level("10", "1")}}, nil
+	})
+	require.NoError(t, b.Resync())
+	require.NoError(t, b.Apply(exchange.DepthUpdate{PrevSeq: 100, LastSeq: 105, Asks: []exchange.DepthLevel{level("11", "1")}}))
+	require.Len(t, b.Asks(0), 2)
+
+	err := b.Apply(exchange.DepthUpdate{PrevSeq: 106, LastSeq: 110})
+	require.True(t, errors.Is(err, ErrGap))
+	// resync gets the snapshot at 100, which is before the buffered 106, so it's still a gap
+	require.Eventually(t, func() bool { return b.Seq() == 100 }, time.Second, time.Millisecond*10)
+	require.False(t, b.Synced())
+
+	err = b.Apply(exchange.DepthUpdate{Symbol: "ethusdt"})
+	require.True(t, errors.Is(err, ErrSymbol))
+}
+
+func TestBook_ResyncBackoff(t *testing.T) {
+	errDown := errors.New("rest api is down")
+	var calls int32
+	b := New("btc_usdt", func(symbol string) (exchange.DepthUpdate, error) {
+		atomic.AddInt32(&calls, 1)
+		return exchange.DepthUpdate{}, errDown
+	})
+	var seq uint64
+	apply := func() error {
+		seq++
+		return b.Apply(exchange.DepthUpdate{FirstSeq: seq, LastSeq: seq})
+	}
+	require.NoError(t, apply())
+	require.Eventually(t, func() bool { return errors.Is(b.Err(), errDown) }, time.Second, time.Millisecond)
+	// no retry in backoff
+	for i := 0; i < 100; i++ {
+		require.NoError(t, apply())
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	require.False(t, b.Synced())
+	// retried by the update after backoff
+	require.Eventually(t, func() bool {
+		_ = apply()
+		return atomic.LoadInt32(&calls) == 2
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestBooks(t *testing.T) {
+	bs := NewBooks(nil)
+	handler := bs.Handler()
+	handler(exchange.DepthUpdate{Symbol: "btc_usdt", Snapshot: true, Bids: []exchange.DepthLevel{level("1", "1")}})
+	handler(exchange.DepthUpdate{Symbol: "eth_usdt", Snapshot: true, Asks: []exchange.DepthLevel{level("2", "1")}})
+	b, ok := bs.Get("btc_usdt")
+	require.True(t, ok)
+	require.Len(t, b.Bids(0), 1)
+	b, ok = bs.Get("eth_usdt")
+	require.True(t, ok)
+	require.Len(t, b.Asks(0), 1)
+	_, ok = bs.Get("xrp_usdt")
+	require.False(t, ok)
+}
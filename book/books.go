@@ -0,0 +1,45 @@
+package book
+
+import (
+	"github.com/xyths/hs/exchange"
+	"sync"
+)
+
+// Books keeps the books of many symbols, the book is created at the first update of symbol.
+type Books struct {
+	snapshotter Snapshotter
+
+	mu    sync.Mutex
+	books map[string]*Book
+}
+
+func NewBooks(snapshotter Snapshotter) *Books {
+	return &Books{snapshotter: snapshotter, books: make(map[string]*Book)}
+}
+
+// Get returns the book of symbol
+func (bs *Books) Get(symbol string) (*Book, bool) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	b, ok := bs.books[symbol]
+	return b, ok
+}
+
+// Apply applies the update to the book of u.Symbol
+func (bs *Books) Apply(u exchange.DepthUpdate) error {
+	bs.mu.Lock()
+	b, ok := bs.books[u.Symbol]
+	if !ok {
+		b = New(u.Symbol, bs.snapshotter)
+		bs.books[u.Symbol] = b
+	}
+	bs.mu.Unlock()
+	return b.Apply(u)
+}
+
+// Handler returns the handler for depth subscription, the gap is resynced in background so the error is dropped.
+func (bs *Books) Handler() exchange.DepthHandler {
+	return func(u exchange.DepthUpdate) {
+		_ = bs.Apply(u)
+	}
+}
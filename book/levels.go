@@ -0,0 +1,62 @@
+package book
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs/exchange"
+	"sort"
+)
+
+// levels is one side of book, sorted by price, the best price is first.
+type levels struct {
+	desc   bool // bids are descending, asks are ascending
+	levels []exchange.DepthLevel
+}
+
+// search returns the index of price, or where it should be inserted
+func (l *levels) search(price decimal.Decimal) int {
+	return sort.Search(len(l.levels), func(i int) bool {
+		if l.desc {
+			return l.levels[i].Price.LessThanOrEqual(price)
+		}
+		return l.levels[i].Price.GreaterThanOrEqual(price)
+	})
+}
+
+// set updates the amount at price, zero amount removes the level
+func (l *levels) set(price, amount decimal.Decimal) {
+	i := l.search(price)
+	found := i < len(l.levels) && l.levels[i].Price.Equal(price)
+	switch {
+	case amount.IsZero() && found:
+		l.levels = append(l.levels[:i], l.levels[i+1:]...)
+	case amount.IsZero():
+	case found:
+		l.levels[i].Amount = amount
+	default:
+		l.levels = append(l.levels, exchange.DepthLevel{})
+		copy(l.levels[i+1:], l.levels[i:])
+		l.levels[i] = exchange.DepthLevel{Price: price, Amount: amount}
+	}
+}
+
+func (l *levels) get(price decimal.Decimal) decimal.Decimal {
+	i := l.search(price)
+	if i < len(l.levels) && l.levels[i].Price.Equal(price) {
+		return l.levels[i].Amount
+	}
+	return decimal.Zero
+}
+
+func (l *levels) reset() {
+	l.levels = l.levels[:0]
+}
+
+// top returns copy of the best n levels, all if n <= 0
+func (l *levels) top(n int) []exchange.DepthLevel {
+	if n <= 0 || n > len(l.levels) {
+		n = len(l.levels)
+	}
+	result := make([]exchange.DepthLevel, n)
+	copy(result, l.levels)
+	return result
+}
@@ -30,6 +30,8 @@ type ExchangeConf struct {
 	Key     string
 	Secret  string
 	Host    string
+	// Passphrase is the extra credential of okex api key
+	Passphrase string
 }
 
 type BroadcastConf = broadcast.Config
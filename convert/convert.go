@@ -1,6 +1,7 @@
 package convert
 
 import (
+	"github.com/shopspring/decimal"
 	"math/big"
 	"reflect"
 	"strconv"
@@ -24,6 +25,12 @@ func StrToFloat64(s string) float64 {
 	return 0.0
 }
 
+// StrToDecimal returns zero if s is empty or invalid, the api returns "" for price of market order
+func StrToDecimal(s string) decimal.Decimal {
+	d, _ := decimal.NewFromString(s)
+	return d
+}
+
 func ToFloat64(i interface{}) float64 {
 	switch v := reflect.ValueOf(i); v.Kind() {
 	case reflect.String:
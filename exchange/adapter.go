@@ -0,0 +1,194 @@
+package exchange
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"time"
+)
+
+// RestAPIAdapter wraps a legacy RestAPIExchange as RestAPIExchangeV2.
+// The legacy API can't be cancelled in flight, so the context is checked before every call.
+type RestAPIAdapter struct {
+	ex RestAPIExchange
+}
+
+var _ RestAPIExchangeV2 = (*RestAPIAdapter)(nil)
+
+func NewRestAPIAdapter(ex RestAPIExchange) *RestAPIAdapter {
+	return &RestAPIAdapter{ex: ex}
+}
+
+// Unwrap returns the legacy exchange
+func (a *RestAPIAdapter) Unwrap() RestAPIExchange {
+	return a.ex
+}
+
+func (a *RestAPIAdapter) FormatSymbol(base, quote string) string {
+	return a.ex.FormatSymbol(base, quote)
+}
+
+func (a *RestAPIAdapter) AllSymbols(ctx context.Context) ([]Symbol, error) {
+	return a.ex.AllSymbols(ctx)
+}
+
+func (a *RestAPIAdapter) GetSymbol(ctx context.Context, symbol string) (Symbol, error) {
+	return a.ex.GetSymbol(ctx, symbol)
+}
+
+func (a *RestAPIAdapter) GetFee(ctx context.Context, symbol string) (Fee, error) {
+	if err := ctx.Err(); err != nil {
+		return Fee{}, err
+	}
+	return a.ex.GetFee(symbol)
+}
+
+func (a *RestAPIAdapter) SpotBalance(ctx context.Context) (map[string]decimal.Decimal, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.ex.SpotBalance()
+}
+
+func (a *RestAPIAdapter) SpotAvailableBalance(ctx context.Context) (map[string]decimal.Decimal, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.ex.SpotAvailableBalance()
+}
+
+func (a *RestAPIAdapter) LastPrice(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	if err := ctx.Err(); err != nil {
+		return decimal.Zero, err
+	}
+	return a.ex.LastPrice(symbol)
+}
+
+func (a *RestAPIAdapter) Last24hVolume(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	if err := ctx.Err(); err != nil {
+		return decimal.Zero, err
+	}
+	return a.ex.Last24hVolume(symbol)
+}
+
+func (a *RestAPIAdapter) CandleBySize(ctx context.Context, symbol string, period time.Duration, size int) (hs.Candle, error) {
+	if err := ctx.Err(); err != nil {
+		return hs.Candle{}, err
+	}
+	return a.ex.CandleBySize(symbol, period, size)
+}
+
+func (a *RestAPIAdapter) CandleFrom(ctx context.Context, symbol, clientId string, period time.Duration, from, to time.Time) (hs.Candle, error) {
+	if err := ctx.Err(); err != nil {
+		return hs.Candle{}, err
+	}
+	return a.ex.CandleFrom(symbol, clientId, period, from, to)
+}
+
+func (a *RestAPIAdapter) PlaceOrder(ctx context.Context, symbol, clientOrderId string, side OrderType, price, amount decimal.Decimal, options OrderOptions) (Order, error) {
+	if err := ctx.Err(); err != nil {
+		return Order{}, err
+	}
+	orderId, err := a.ex.PlaceOrder(symbol, clientOrderId, side, price, amount, options)
+	typ := "buy-limit"
+	if side == Sell {
+		typ = "sell-limit"
+	}
+	return a.placed(orderId, err, Order{ClientOrderId: clientOrderId, Type: typ, Side: side, Symbol: symbol, Price: price, Amount: amount})
+}
+
+func (a *RestAPIAdapter) BuyLimit(ctx context.Context, symbol, clientOrderId string, price, amount decimal.Decimal) (Order, error) {
+	if err := ctx.Err(); err != nil {
+		return Order{}, err
+	}
+	orderId, err := a.ex.BuyLimit(symbol, clientOrderId, price, amount)
+	return a.placed(orderId, err, Order{ClientOrderId: clientOrderId, Type: "buy-limit", Side: Buy, Symbol: symbol, Price: price, Amount: amount})
+}
+
+func (a *RestAPIAdapter) SellLimit(ctx context.Context, symbol, clientOrderId string, price, amount decimal.Decimal) (Order, error) {
+	if err := ctx.Err(); err != nil {
+		return Order{}, err
+	}
+	orderId, err := a.ex.SellLimit(symbol, clientOrderId, price, amount)
+	return a.placed(orderId, err, Order{ClientOrderId: clientOrderId, Type: "sell-limit", Side: Sell, Symbol: symbol, Price: price, Amount: amount})
+}
+
+// BuyMarket buys by the total of quote currency, which is not the Amount of base currency,
+// so Amount is zero if the placed order can't be queried.
+func (a *RestAPIAdapter) BuyMarket(ctx context.Context, symbol Symbol, clientOrderId string, total decimal.Decimal) (Order, error) {
+	if err := ctx.Err(); err != nil {
+		return Order{}, err
+	}
+	orderId, err := a.ex.BuyMarket(symbol, clientOrderId, total)
+	return a.placed(orderId, err, Order{ClientOrderId: clientOrderId, Type: "buy-market", Side: Buy, Symbol: symbol.Symbol})
+}
+
+func (a *RestAPIAdapter) SellMarket(ctx context.Context, symbol Symbol, clientOrderId string, amount decimal.Decimal) (Order, error) {
+	if err := ctx.Err(); err != nil {
+		return Order{}, err
+	}
+	orderId, err := a.ex.SellMarket(symbol, clientOrderId, amount)
+	return a.placed(orderId, err, Order{ClientOrderId: clientOrderId, Type: "sell-market", Side: Sell, Symbol: symbol.Symbol, Amount: amount})
+}
+
+func (a *RestAPIAdapter) BuyStopLimit(ctx context.Context, symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (Order, error) {
+	if err := ctx.Err(); err != nil {
+		return Order{}, err
+	}
+	orderId, err := a.ex.BuyStopLimit(symbol, clientOrderId, price, amount, stopPrice)
+	return a.placed(orderId, err, Order{ClientOrderId: clientOrderId, Type: "buy-stop-limit", Side: Buy, Symbol: symbol, Price: price, Amount: amount})
+}
+
+func (a *RestAPIAdapter) SellStopLimit(ctx context.Context, symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (Order, error) {
+	if err := ctx.Err(); err != nil {
+		return Order{}, err
+	}
+	orderId, err := a.ex.SellStopLimit(symbol, clientOrderId, price, amount, stopPrice)
+	return a.placed(orderId, err, Order{ClientOrderId: clientOrderId, Type: "sell-stop-limit", Side: Sell, Symbol: symbol, Price: price, Amount: amount})
+}
+
+func (a *RestAPIAdapter) GetOrder(ctx context.Context, symbol string, orderId uint64) (Order, error) {
+	if err := ctx.Err(); err != nil {
+		return Order{}, err
+	}
+	return a.ex.GetOrderById(orderId, symbol)
+}
+
+// CancelOrder cancels the order and returns its latest state.
+// If the order can't be queried after a successful cancel, only Id and Symbol are filled.
+func (a *RestAPIAdapter) CancelOrder(ctx context.Context, symbol string, orderId uint64) (Order, error) {
+	if err := ctx.Err(); err != nil {
+		return Order{}, err
+	}
+	if err := a.ex.CancelOrder(symbol, orderId); err != nil {
+		return Order{}, err
+	}
+	if o, err := a.ex.GetOrderById(orderId, symbol); err == nil {
+		return o, nil
+	}
+	return Order{Id: orderId, Symbol: symbol}, nil
+}
+
+func (a *RestAPIAdapter) IsFullFilled(ctx context.Context, symbol string, orderId uint64) (Order, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return Order{}, false, err
+	}
+	return a.ex.IsFullFilled(symbol, orderId)
+}
+
+// placed queries the new order, and falls back to the request when the query fails,
+// as the order is already placed.
+func (a *RestAPIAdapter) placed(orderId uint64, err error, request Order) (Order, error) {
+	if err != nil {
+		return Order{}, err
+	}
+	request.Id = orderId
+	request.Time = time.Now()
+	if orderId == 0 {
+		return request, nil
+	}
+	if o, err1 := a.ex.GetOrderById(orderId, request.Symbol); err1 == nil {
+		return o, nil
+	}
+	return request, nil
+}
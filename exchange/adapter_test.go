@@ -0,0 +1,115 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// fakeLegacy records the calls, the methods not overridden panic
+type fakeLegacy struct {
+	RestAPIExchange
+	calls    []string
+	orders   map[uint64]Order
+	queryErr error
+	nextId   uint64
+}
+
+func (f *fakeLegacy) place(name string) (uint64, error) {
+	f.calls = append(f.calls, name)
+	f.nextId++
+	return f.nextId, nil
+}
+
+func (f *fakeLegacy) SpotBalance() (map[string]decimal.Decimal, error) {
+	f.calls = append(f.calls, "SpotBalance")
+	return map[string]decimal.Decimal{"usdt": decimal.NewFromInt(100)}, nil
+}
+
+func (f *fakeLegacy) BuyLimit(symbol, clientOrderId string, price, amount decimal.Decimal) (uint64, error) {
+	return f.place("BuyLimit")
+}
+
+func (f *fakeLegacy) BuyMarket(symbol Symbol, clientOrderId string, total decimal.Decimal) (uint64, error) {
+	return f.place("BuyMarket")
+}
+
+func (f *fakeLegacy) SellStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (uint64, error) {
+	return f.place("SellStopLimit")
+}
+
+func (f *fakeLegacy) GetOrderById(orderId uint64, symbol string) (Order, error) {
+	f.calls = append(f.calls, "GetOrderById")
+	if f.queryErr != nil {
+		return Order{}, f.queryErr
+	}
+	return f.orders[orderId], nil
+}
+
+func (f *fakeLegacy) CancelOrder(symbol string, orderId uint64) error {
+	f.calls = append(f.calls, "CancelOrder")
+	return nil
+}
+
+func TestRestAPIAdapter_Context(t *testing.T) {
+	legacy := &fakeLegacy{}
+	a := NewRestAPIAdapter(legacy)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := a.SpotBalance(ctx)
+	require.True(t, errors.Is(err, context.Canceled))
+	_, err = a.BuyLimit(ctx, "btc_usdt", "b1", decimal.NewFromInt(9000), decimal.NewFromInt(1))
+	require.True(t, errors.Is(err, context.Canceled))
+	_, err = a.CancelOrder(ctx, "btc_usdt", 1)
+	require.True(t, errors.Is(err, context.Canceled))
+	require.Empty(t, legacy.calls)
+
+	balances, err := a.SpotBalance(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "100", balances["usdt"].String())
+	require.Equal(t, []string{"SpotBalance"}, legacy.calls)
+}
+
+func TestRestAPIAdapter_PlaceOrder(t *testing.T) {
+	legacy := &fakeLegacy{queryErr: errors.New("not found")}
+	a := NewRestAPIAdapter(legacy)
+	ctx := context.Background()
+
+	// falls back to the request when the query fails
+	o, err := a.BuyLimit(ctx, "btc_usdt", "b1", decimal.NewFromInt(9000), decimal.NewFromInt(1))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), o.Id)
+	require.Equal(t, "b1", o.ClientOrderId)
+	require.Equal(t, "buy-limit", o.Type)
+	require.Equal(t, Buy, o.Side)
+	require.Equal(t, "9000", o.Price.String())
+	require.Equal(t, "1", o.Amount.String())
+	require.False(t, o.Time.IsZero())
+
+	// the quote total is not the amount
+	o, err = a.BuyMarket(ctx, Symbol{Symbol: "btc_usdt"}, "b2", decimal.NewFromInt(100))
+	require.NoError(t, err)
+	require.Equal(t, "buy-market", o.Type)
+	require.Equal(t, Buy, o.Side)
+	require.True(t, o.Amount.IsZero())
+
+	o, err = a.SellStopLimit(ctx, "btc_usdt", "s1", decimal.NewFromInt(8000), decimal.NewFromInt(1), decimal.NewFromInt(8100))
+	require.NoError(t, err)
+	require.Equal(t, "sell-stop-limit", o.Type)
+	require.Equal(t, Sell, o.Side)
+
+	// the queried order is returned if any
+	legacy.queryErr = nil
+	legacy.orders = map[uint64]Order{4: {Id: 4, Symbol: "btc_usdt", State: Closed, Amount: decimal.RequireFromString("0.01")}}
+	o, err = a.BuyMarket(ctx, Symbol{Symbol: "btc_usdt"}, "b3", decimal.NewFromInt(100))
+	require.NoError(t, err)
+	require.Equal(t, Closed, o.State)
+	require.Equal(t, "0.01", o.Amount.String())
+
+	o, err = a.CancelOrder(ctx, "btc_usdt", 4)
+	require.NoError(t, err)
+	require.Equal(t, Closed, o.State)
+}
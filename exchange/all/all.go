@@ -0,0 +1,13 @@
+// Package all registers all the exchange adapters in this module, import it for exchange.New:
+//
+//	import _ "github.com/xyths/hs/exchange/all"
+package all
+
+import (
+	_ "github.com/xyths/hs/exchange/binance"
+	_ "github.com/xyths/hs/exchange/gateio"
+	_ "github.com/xyths/hs/exchange/huobi"
+	_ "github.com/xyths/hs/exchange/mxc"
+	_ "github.com/xyths/hs/exchange/okex"
+	_ "github.com/xyths/hs/exchange/paper"
+)
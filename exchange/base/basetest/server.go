@@ -0,0 +1,52 @@
+// Package basetest has the fake servers and fixtures to test the exchange clients.
+package basetest
+
+import (
+	"github.com/xyths/hs/exchange/base"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// NewServer starts the server of handler, which is closed when the test finishes
+func NewServer(t testing.TB, handler http.Handler) *httptest.Server {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// WsURL returns the websocket url of the http url of test server
+func WsURL(url string) string {
+	return strings.Replace(url, "http://", "ws://", 1)
+}
+
+// WriteFixture responses testdata/<name>.json.
+// It is called in the server goroutine, so reports error by t.Errorf.
+func WriteFixture(t testing.TB, w http.ResponseWriter, name string) {
+	data, err := ioutil.ReadFile("testdata/" + name + ".json")
+	if err != nil {
+		t.Errorf("read fixture %s: %s", name, err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_, _ = w.Write(data)
+}
+
+// CheckQuerySign checks the api key header and the signature at the end of query,
+// which is sent by base.QueryClient. It returns false if the request is not signed.
+func CheckQuerySign(t testing.TB, r *http.Request, keyHeader, key, secret string) bool {
+	raw := r.URL.RawQuery
+	pos := strings.LastIndex(raw, "&signature=")
+	if pos < 0 {
+		return false
+	}
+	if got := r.Header.Get(keyHeader); got != key {
+		t.Errorf("%s is %q, want %q", keyHeader, got, key)
+	}
+	if got, want := raw[pos+len("&signature="):], base.SignHex(secret, raw[:pos]); got != want {
+		t.Errorf("signature is %s, want %s", got, want)
+	}
+	return true
+}
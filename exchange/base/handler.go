@@ -13,3 +13,6 @@ type ResponseHandler func(response interface{})
 
 // It will be invoked after websocket v2 authentication response received
 type AuthenticationV2ResponseHandler func(resp *auth.WebSocketV2AuthenticationResponse)
+
+// It will be invoked when the connection state changes, err is the reason of disconnected or failed
+type StateHandler func(state ConnectionState, err error)
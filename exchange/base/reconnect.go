@@ -0,0 +1,73 @@
+package base
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrCircuitOpen means reconnect is stopped after MaxAttempts failures
+var ErrCircuitOpen = errors.New("websocket reconnect circuit open")
+
+// ConnectionState is the state of websocket connection
+type ConnectionState int
+
+const (
+	StateDisconnected ConnectionState = iota
+	StateConnecting
+	StateConnected
+	// StateFailed means the circuit is open, no more reconnect until CoolDown or Connect again
+	StateFailed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// ReconnectPolicy is the exponential backoff with jitter used when reconnecting.
+// The nth retry waits MinBackoff * Multiplier^n, at most MaxBackoff, randomized by +/- Jitter.
+type ReconnectPolicy struct {
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	Multiplier float64
+	Jitter     float64 // 0 ~ 1, the fraction of randomized delay
+	// MaxAttempts is the consecutive failures before the circuit opens, 0 means retry forever
+	MaxAttempts int
+	// CoolDown is the wait before a half-open retry after the circuit opens, 0 means stop until Connect again
+	CoolDown time.Duration
+}
+
+// DefaultReconnectPolicy retries forever, from 1 second to 1 minute
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MinBackoff: time.Second,
+	MaxBackoff: time.Minute,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// backoff returns the delay before retry n (from 0), r is random number in [0, 1)
+func (p ReconnectPolicy) backoff(n int, r float64) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	d := float64(p.MinBackoff) * math.Pow(multiplier, float64(n))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d *= 1 - p.Jitter + 2*p.Jitter*r
+	}
+	return time.Duration(d)
+}
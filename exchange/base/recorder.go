@@ -0,0 +1,170 @@
+package base
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Frame is one websocket message in the recording file, one JSON object per line.
+// Text message is kept in Text for reading and grep, binary message (eg. gzip) is base64 encoded in Data.
+type Frame struct {
+	Time time.Time `json:"time"`
+	Type int       `json:"type"`
+	Text string    `json:"text,omitempty"`
+	Data []byte    `json:"data,omitempty"`
+}
+
+// Payload returns the raw message
+func (f Frame) Payload() []byte {
+	if f.Type == websocket.TextMessage {
+		return []byte(f.Text)
+	}
+	return f.Data
+}
+
+// Recorder writes the received messages to file, it's safe for concurrent use,
+// so one recorder can be shared by many connections.
+type Recorder struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w, enc: json.NewEncoder(w)}
+}
+
+// CreateRecorder creates or truncates the recording file
+func CreateRecorder(filename string) (*Recorder, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecorder(f), nil
+}
+
+// Record writes the message with the current time, nil recorder does nothing
+func (r *Recorder) Record(messageType int, payload []byte) error {
+	if r == nil {
+		return nil
+	}
+	f := Frame{Time: time.Now(), Type: messageType}
+	if messageType == websocket.TextMessage {
+		f.Text = string(payload)
+	} else {
+		f.Data = payload
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(f)
+}
+
+// Close closes the underlying writer if it's a io.Closer
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// ReadFrames reads all the frames of recording
+func ReadFrames(r io.Reader) ([]Frame, error) {
+	var frames []Frame
+	scanner := bufio.NewScanner(r)
+	// the depth snapshot may be large
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var f Frame
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			return frames, err
+		}
+		frames = append(frames, f)
+	}
+	return frames, scanner.Err()
+}
+
+// LoadFrames reads the recording file
+func LoadFrames(filename string) ([]Frame, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadFrames(f)
+}
+
+// Replay feeds frames to handler in the same goroutine.
+// The interval between frames is the recorded one divided by speed, 1 is real speed, speed <= 0 means no wait.
+func Replay(ctx context.Context, frames []Frame, speed float64, handler MessageHandler) error {
+	for i, f := range frames {
+		if i > 0 && speed > 0 {
+			wait := time.Duration(float64(f.Time.Sub(frames[i-1].Time)) / speed)
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		handler(f.Type, f.Payload())
+	}
+	return nil
+}
+
+// Replay feeds frames through the message handler of b without connection, see Replay
+func (b *WebsocketBase) Replay(ctx context.Context, frames []Frame, speed float64) error {
+	return Replay(ctx, frames, speed, b.messageHandler)
+}
+
+// NewReplayHandler is the websocket server which sends frames to every connection, see Replay for speed.
+// The messages from client are discarded. Serve it with httptest.NewServer,
+// and connect with host "ws://127.0.0.1:port".
+func NewReplayHandler(frames []Frame, speed float64) http.Handler {
+	upgrader := websocket.Upgrader{}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			// discard the requests, stop replay when client closed
+			defer cancel()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		err = Replay(ctx, frames, speed, func(messageType int, payload []byte) {
+			if err := conn.WriteMessage(messageType, payload); err != nil {
+				cancel()
+			}
+		})
+		if err != nil {
+			return
+		}
+		// keep the connection until client closed
+		<-ctx.Done()
+	})
+}
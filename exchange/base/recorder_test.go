@@ -0,0 +1,94 @@
+package base
+
+import (
+	"bytes"
+	"context"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf)
+	require.NoError(t, r.Record(websocket.TextMessage, []byte(`{"method":"trades.update"}`)))
+	require.NoError(t, r.Record(websocket.BinaryMessage, []byte{0x1f, 0x8b, 0}))
+	var nilRecorder *Recorder
+	require.NoError(t, nilRecorder.Record(websocket.TextMessage, []byte("ignored")))
+
+	// text is readable in file
+	require.Contains(t, buf.String(), `"text":"{\"method\":\"trades.update\"}"`)
+	frames, err := ReadFrames(&buf)
+	require.NoError(t, err)
+	require.Len(t, frames, 2)
+	require.Equal(t, `{"method":"trades.update"}`, string(frames[0].Payload()))
+	require.Equal(t, websocket.BinaryMessage, frames[1].Type)
+	require.Equal(t, []byte{0x1f, 0x8b, 0}, frames[1].Payload())
+}
+
+func TestReplay(t *testing.T) {
+	start := time.Now()
+	frames := []Frame{
+		{Time: start, Type: websocket.TextMessage, Text: "1"},
+		{Time: start.Add(time.Second), Type: websocket.TextMessage, Text: "2"},
+		{Time: start.Add(2 * time.Second), Type: websocket.TextMessage, Text: "3"},
+	}
+	var got []string
+	handler := func(_ int, payload []byte) { got = append(got, string(payload)) }
+
+	// 20x faster, 2s in 100ms
+	begin := time.Now()
+	require.NoError(t, Replay(context.Background(), frames, 20, handler))
+	require.True(t, time.Since(begin) >= 90*time.Millisecond)
+	require.Equal(t, []string{"1", "2", "3"}, got)
+
+	got = nil
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, Replay(ctx, frames, 1, handler))
+	require.Equal(t, []string{"1"}, got)
+
+	// through the same message handler
+	got = nil
+	b := new(WebsocketBase).Init("", "", zap.NewNop().Sugar(), 1, 60, false)
+	b.SetHandler(nil, handler)
+	require.NoError(t, b.Replay(context.Background(), frames, 0))
+	require.Equal(t, []string{"1", "2", "3"}, got)
+}
+
+func TestReplayHandler_RecordAgain(t *testing.T) {
+	frames := []Frame{
+		{Time: time.Now(), Type: websocket.TextMessage, Text: "a"},
+		{Time: time.Now(), Type: websocket.TextMessage, Text: "b"},
+	}
+	server := httptest.NewServer(NewReplayHandler(frames, 0))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var got []string
+	var buf bytes.Buffer
+	b := new(WebsocketBase).Init("ws"+strings.TrimPrefix(server.URL, "http"), "/", zap.NewNop().Sugar(), 1, 60, false)
+	b.SetHandler(nil, func(_ int, payload []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, string(payload))
+	})
+	b.SetRecorder(NewRecorder(&buf))
+	b.Connect(false)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 2
+	}, time.Second, 10*time.Millisecond)
+	b.Close()
+
+	recorded, err := ReadFrames(&buf)
+	require.NoError(t, err)
+	require.Len(t, recorded, 2)
+	require.Equal(t, "b", recorded[1].Text)
+}
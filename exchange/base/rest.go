@@ -0,0 +1,239 @@
+package base
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/convert"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithScheme adds scheme to host if it has no one
+func WithScheme(host, scheme string) string {
+	if strings.Contains(host, "://") {
+		return host
+	}
+	return scheme + "://" + host
+}
+
+func hmacSHA256(secret, payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// SignHex returns hex of HMAC SHA256
+func SignHex(secret, payload string) string {
+	return hex.EncodeToString(hmacSHA256(secret, payload))
+}
+
+// SignBase64 returns base64 of HMAC SHA256
+func SignBase64(secret, payload string) string {
+	return base64.StdEncoding.EncodeToString(hmacSHA256(secret, payload))
+}
+
+// Send sends the request, returns the status code and body
+func Send(client *http.Client, req *http.Request) (int, []byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, data, err
+}
+
+// ApiError is the error returned by the binance style api
+type ApiError struct {
+	Exchange   string `json:"-"`
+	StatusCode int    `json:"-"`
+	Code       int    `json:"code"`
+	Msg        string `json:"msg"`
+}
+
+func (e ApiError) Error() string {
+	return fmt.Sprintf("%s api error %d, code %d: %s", e.Exchange, e.StatusCode, e.Code, e.Msg)
+}
+
+// QueryClient is the RESTful client of the binance style api, which mxc and others copy.
+// All params are in query string. The signed request has timestamp and signature,
+// which is HMAC SHA256 of the query string, and the api key is in KeyHeader.
+// The failed response is returned as ApiError.
+type QueryClient struct {
+	Exchange string
+	// Host is the RESTful api host, with optional scheme, https by default
+	Host      string
+	Key       string
+	Secret    string
+	KeyHeader string
+	// Header is the extra headers of every request
+	Header http.Header
+	HTTP   *http.Client
+}
+
+func (c *QueryClient) Get(ctx context.Context, path string, params url.Values, signed bool, result interface{}) error {
+	return c.Do(ctx, http.MethodGet, path, params, signed, result)
+}
+
+// Do sends the request, and unmarshal the response to result if not nil
+func (c *QueryClient) Do(ctx context.Context, method, path string, params url.Values, signed bool, result interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	if signed {
+		params.Set("timestamp", strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10))
+	}
+	query := params.Encode()
+	if signed {
+		query += "&signature=" + SignHex(c.Secret, query)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, WithScheme(c.Host, "https")+path+"?"+query, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	if c.Key != "" {
+		req.Header.Set(c.KeyHeader, c.Key)
+	}
+	status, data, err := Send(c.HTTP, req)
+	if err != nil {
+		return err
+	}
+	if status >= 300 {
+		apiErr := ApiError{Exchange: c.Exchange, StatusCode: status}
+		_ = json.Unmarshal(data, &apiErr)
+		return apiErr
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+// Balance is the balance in /api/v3/account
+type Balance struct {
+	Asset  string `json:"asset"`
+	Free   string `json:"free"`
+	Locked string `json:"locked"`
+}
+
+// Balances returns the non-zero total and available balances, asset converts the currency name if not nil
+func (c *QueryClient) Balances(asset func(string) string) (total, available map[string]decimal.Decimal, err error) {
+	var account struct {
+		Balances []Balance `json:"balances"`
+	}
+	if err = c.Get(context.Background(), "/api/v3/account", nil, true, &account); err != nil {
+		return
+	}
+	total, available = make(map[string]decimal.Decimal), make(map[string]decimal.Decimal)
+	for _, b := range account.Balances {
+		currency := b.Asset
+		if asset != nil {
+			currency = asset(currency)
+		}
+		free := convert.StrToDecimal(b.Free)
+		if all := free.Add(convert.StrToDecimal(b.Locked)); !all.IsZero() {
+			total[currency] = all
+		}
+		if !free.IsZero() {
+			available[currency] = free
+		}
+	}
+	return
+}
+
+// Klines gets /api/v3/klines, params has symbol, interval and the range
+func (c *QueryClient) Klines(params url.Values) (hs.Candle, error) {
+	var raw [][]interface{}
+	if err := c.Get(context.Background(), "/api/v3/klines", params, false, &raw); err != nil {
+		return hs.Candle{}, err
+	}
+	candle := hs.NewCandle(len(raw))
+	for _, k := range raw {
+		if len(k) < 6 {
+			continue
+		}
+		candle.Append(hs.Ticker{
+			Timestamp: int64(convert.ToFloat64(k[0])) / 1000,
+			Open:      convert.ToFloat64(k[1]),
+			High:      convert.ToFloat64(k[2]),
+			Low:       convert.ToFloat64(k[3]),
+			Close:     convert.ToFloat64(k[4]),
+			Volume:    convert.ToFloat64(k[5]),
+		})
+	}
+	return candle, nil
+}
+
+// CandleBySize gets the latest size klines backward by endTime, maxLength is the max klines of one request
+func (c *QueryClient) CandleBySize(symbol, interval string, size, maxLength int) (hs.Candle, error) {
+	candle := hs.NewCandle(size)
+	for end := int64(0); candle.Length() < size; {
+		limit := size - candle.Length()
+		if limit > maxLength {
+			limit = maxLength
+		}
+		params := url.Values{"symbol": {symbol}, "interval": {interval}, "limit": {strconv.Itoa(limit)}}
+		if end > 0 {
+			params.Set("endTime", strconv.FormatInt(end, 10))
+		}
+		batch, err := c.Klines(params)
+		if err != nil {
+			return candle, err
+		}
+		n := batch.Length()
+		if n == 0 {
+			break
+		}
+		// batch is before candle
+		batch.Capacity = size
+		batch.Add(candle)
+		candle = batch
+		end = candle.Timestamp[0]*1000 - 1
+		if n < limit {
+			break
+		}
+	}
+	return candle, nil
+}
+
+// CandleFrom gets the klines in [from, to] forward by startTime, see CandleBySize
+func (c *QueryClient) CandleFrom(symbol, interval string, period time.Duration, from, to time.Time, maxLength int) (hs.Candle, error) {
+	if !from.Before(to) {
+		return hs.Candle{}, errors.New("'from' need before 'to'")
+	}
+	candle := hs.NewCandle(int(to.Sub(from)/period) + 1)
+	for start := from.Unix() * 1000; start <= to.Unix()*1000; {
+		params := url.Values{
+			"symbol":    {symbol},
+			"interval":  {interval},
+			"limit":     {strconv.Itoa(maxLength)},
+			"startTime": {strconv.FormatInt(start, 10)},
+			"endTime":   {strconv.FormatInt(to.Unix()*1000, 10)},
+		}
+		batch, err := c.Klines(params)
+		if err != nil {
+			return candle, err
+		}
+		candle.Add(batch)
+		if batch.Length() < maxLength {
+			break
+		}
+		start = batch.Timestamp[batch.Length()-1]*1000 + 1
+	}
+	return candle, nil
+}
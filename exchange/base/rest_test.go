@@ -0,0 +1,39 @@
+package base
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithScheme(t *testing.T) {
+	require.Equal(t, "https://api.binance.com", WithScheme("api.binance.com", "https"))
+	require.Equal(t, "http://127.0.0.1:8080", WithScheme("http://127.0.0.1:8080", "https"))
+}
+
+func TestQueryClient_Do(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-KEY") != "key" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":-1121,"msg":"Invalid symbol."}`))
+	}))
+	defer server.Close()
+
+	c := &QueryClient{
+		Exchange:  "test",
+		Host:      server.URL,
+		Key:       "key",
+		Secret:    "secret",
+		KeyHeader: "X-KEY",
+		Header:    http.Header{"Content-Type": {"application/json"}},
+		HTTP:      server.Client(),
+	}
+	err := c.Get(context.Background(), "/api/v3/account", nil, true, nil)
+	require.Equal(t, ApiError{Exchange: "test", StatusCode: http.StatusBadRequest, Code: -1121, Msg: "Invalid symbol."}, err)
+	require.Equal(t, "test api error 400, code -1121: Invalid symbol.", err.Error())
+}
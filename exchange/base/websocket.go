@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
+	"math/rand"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,16 +17,30 @@ type WebsocketBase struct {
 	TimerIntervalSecond int
 	ReconnectWaitSecond int
 	Logger              *zap.SugaredLogger
-	verbose             bool
+	// ManualReplay stops replaying subscriptions after connected,
+	// for the client which subscribes after authentication, and calls ReplaySubscriptions itself.
+	ManualReplay bool
+	verbose      bool
 
-	conn              *websocket.Conn
-	connectedHandler  ConnectedHandler
-	messageHandler    MessageHandler
-	stopReadChannel   chan int
-	stopTickerChannel chan int
-	ticker            *time.Ticker
-	lastReceivedTime  time.Time
-	sendMutex         *sync.Mutex
+	connectedHandler ConnectedHandler
+	messageHandler   MessageHandler
+	stateHandler     StateHandler
+	policy           ReconnectPolicy
+	recorder         *Recorder
+
+	mu               sync.Mutex // guards the fields below
+	conn             *websocket.Conn
+	state            ConnectionState
+	autoConnect      bool
+	running          bool          // supervisor is running
+	done             chan struct{} // closed by Close
+	subscriptionKeys []string      // keep the order of subscribe
+	subscriptions    map[string]string
+
+	wg               sync.WaitGroup // the read loop and supervisor goroutines
+	reconnectChannel chan struct{}
+	lastReceivedTime int64 // unix nano, atomic
+	sendMutex        *sync.Mutex
 }
 
 // Initializer
@@ -34,8 +51,10 @@ func (b *WebsocketBase) Init(host, path string, logger *zap.SugaredLogger, inter
 	b.TimerIntervalSecond = intervalSecond
 	b.ReconnectWaitSecond = reconnectSecond
 	b.verbose = verbose
-	b.stopReadChannel = make(chan int, 1)
-	b.stopTickerChannel = make(chan int, 1)
+	b.policy = DefaultReconnectPolicy
+	b.done = make(chan struct{})
+	b.subscriptions = make(map[string]string)
+	b.reconnectChannel = make(chan struct{}, 1)
 	b.sendMutex = &sync.Mutex{}
 
 	return b
@@ -47,19 +66,58 @@ func (b *WebsocketBase) SetHandler(connHandler ConnectedHandler, msgHandler Mess
 	b.messageHandler = msgHandler
 }
 
+// SetStateHandler sets the callback of connection state change, call it before Connect
+func (b *WebsocketBase) SetStateHandler(handler StateHandler) {
+	b.stateHandler = handler
+}
+
+// SetReconnectPolicy replaces DefaultReconnectPolicy, call it before Connect
+func (b *WebsocketBase) SetReconnectPolicy(policy ReconnectPolicy) {
+	b.policy = policy
+}
+
+// SetRecorder records every received message before handling, call it before Connect
+func (b *WebsocketBase) SetRecorder(recorder *Recorder) {
+	b.recorder = recorder
+}
+
+// State returns the current connection state
+func (b *WebsocketBase) State() ConnectionState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
 // Connect to websocket server
-// if autoConnect is true, then the connection can be re-connect if no data received after the pre-defined timeout
+// if autoConnect is true, then the connection can be re-connect if no data received after the pre-defined timeout,
+// or the connection is broken. It retries with backoff of the ReconnectPolicy, even the first dial failed.
 func (b *WebsocketBase) Connect(autoConnect bool) {
-	b.connectWebSocket()
+	b.mu.Lock()
+	select {
+	case <-b.done:
+		// connect again after Close
+		b.done = make(chan struct{})
+	default:
+	}
+	b.autoConnect = autoConnect
+	b.mu.Unlock()
+
+	err := b.connectWebSocket()
 
 	if autoConnect {
-		b.startTicker()
+		b.startSupervisor()
+		if err != nil {
+			b.requestReconnect()
+		}
 	}
 }
 
 // Send data to websocket server
 func (b *WebsocketBase) Send(data string) {
-	if b.conn == nil {
+	b.mu.Lock()
+	conn := b.conn
+	b.mu.Unlock()
+	if conn == nil {
 		if b.verbose {
 			b.Logger.Error("WebSocket sent error: no connection available")
 		}
@@ -67,181 +125,312 @@ func (b *WebsocketBase) Send(data string) {
 	}
 
 	b.sendMutex.Lock()
-	err := b.conn.WriteMessage(websocket.TextMessage, []byte(data))
+	err := conn.WriteMessage(websocket.TextMessage, []byte(data))
 	b.sendMutex.Unlock()
 
 	if err != nil {
 		if b.verbose {
-			b.Logger.Error("WebSocket sent error: data=%s, error=%s", data, err)
+			b.Logger.Errorf("WebSocket sent error: data=%s, error=%s", data, err)
 		}
 	}
 }
 
-// Close the connection to server
+// Subscribe sends the message and registers it with key, it will be sent again after reconnect.
+// The message is sent after connected if there is no connection now.
+func (b *WebsocketBase) Subscribe(key, message string) {
+	b.mu.Lock()
+	if _, ok := b.subscriptions[key]; !ok {
+		b.subscriptionKeys = append(b.subscriptionKeys, key)
+	}
+	b.subscriptions[key] = message
+	connected := b.conn != nil
+	b.mu.Unlock()
+
+	if connected {
+		b.Send(message)
+	}
+}
+
+// Unsubscribe removes the subscription of key, and sends the unsubscribe message if it's not empty
+func (b *WebsocketBase) Unsubscribe(key, message string) {
+	b.mu.Lock()
+	if _, ok := b.subscriptions[key]; ok {
+		delete(b.subscriptions, key)
+		for i, k := range b.subscriptionKeys {
+			if k == key {
+				b.subscriptionKeys = append(b.subscriptionKeys[:i], b.subscriptionKeys[i+1:]...)
+				break
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	if message != "" {
+		b.Send(message)
+	}
+}
+
+// Close the connection to server, stop reconnecting, and wait for the goroutines to exit.
+// Don't call it in the handlers, it will wait for itself.
 func (b *WebsocketBase) Close() {
-	b.stopTicker()
-	b.disconnectWebSocket()
+	b.mu.Lock()
+	select {
+	case <-b.done:
+	default:
+		close(b.done)
+	}
+	b.mu.Unlock()
+	b.disconnectWebSocket(nil)
+	b.wg.Wait()
 }
 
-// connect to server
-func (b *WebsocketBase) connectWebSocket() {
-	var err error
+// connect to server, the connected handler is called and subscriptions are replayed if success,
+// see ManualReplay
+func (b *WebsocketBase) connectWebSocket() error {
 	url := fmt.Sprintf("wss://%s%s", b.host, b.path)
+	if strings.Contains(b.host, "://") {
+		// host with scheme, eg. ws://127.0.0.1:8080 in test
+		url = b.host + b.path
+	}
 	if b.verbose {
 		b.Logger.Debug("WebSocket connecting...")
 	}
-	b.conn, _, err = websocket.DefaultDialer.Dial(url, nil)
+	b.setState(StateConnecting, nil)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
 	if err != nil {
 		if b.verbose {
 			b.Logger.Errorf("WebSocket connected error: %s", err)
 		}
-		return
+		b.setState(StateDisconnected, err)
+		return err
 	}
+	b.mu.Lock()
+	select {
+	case <-b.done:
+		// closed when dialing
+		b.mu.Unlock()
+		_ = conn.Close()
+		b.setState(StateDisconnected, nil)
+		return nil
+	default:
+	}
+	b.conn = conn
+	atomic.StoreInt64(&b.lastReceivedTime, time.Now().UnixNano())
+	b.wg.Add(1)
+	go b.readLoop(conn)
+	b.mu.Unlock()
 	if b.verbose {
 		b.Logger.Info("WebSocket connected")
 	}
 
-	b.startReadLoop()
-
+	b.setState(StateConnected, nil)
 	if b.connectedHandler != nil {
 		b.connectedHandler()
 	}
+	if !b.ManualReplay {
+		b.ReplaySubscriptions()
+	}
+	return nil
 }
 
-// disconnect with server
-func (b *WebsocketBase) disconnectWebSocket() {
-	if b.conn == nil {
+// disconnect with server, err is the reason passed to state handler
+func (b *WebsocketBase) disconnectWebSocket(reason error) {
+	b.mu.Lock()
+	conn := b.conn
+	b.conn = nil
+	b.mu.Unlock()
+	if conn == nil {
 		return
 	}
 
-	b.stopReadLoop()
-
 	if b.verbose {
 		b.Logger.Debug("WebSocket disconnecting...")
 	}
-	err := b.conn.Close()
-	if err != nil {
+	// the read loop exits when the connection is closed
+	if err := conn.Close(); err != nil {
 		if b.verbose {
-			b.Logger.Error("WebSocket disconnect error: %s", err)
+			b.Logger.Errorf("WebSocket disconnect error: %s", err)
 		}
-		return
 	}
+	b.setState(StateDisconnected, reason)
 
 	if b.verbose {
 		b.Logger.Info("WebSocket disconnected")
 	}
 }
 
-// initialize a ticker and start a goroutine tickerLoop()
-func (b *WebsocketBase) startTicker() {
-	b.ticker = time.NewTicker(time.Duration(b.TimerIntervalSecond) * time.Second)
-	b.lastReceivedTime = time.Now()
+// ReplaySubscriptions sends all the registered subscriptions
+func (b *WebsocketBase) ReplaySubscriptions() {
+	b.mu.Lock()
+	messages := make([]string, 0, len(b.subscriptionKeys))
+	for _, key := range b.subscriptionKeys {
+		messages = append(messages, b.subscriptions[key])
+	}
+	b.mu.Unlock()
+	for _, message := range messages {
+		b.Send(message)
+	}
+}
+
+func (b *WebsocketBase) setState(state ConnectionState, err error) {
+	b.mu.Lock()
+	changed := b.state != state
+	b.state = state
+	b.mu.Unlock()
+	if (changed || err != nil) && b.stateHandler != nil {
+		b.stateHandler(state, err)
+	}
+}
 
-	go b.tickerLoop()
+// startSupervisor starts a goroutine supervisorLoop() if not running
+func (b *WebsocketBase) startSupervisor() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.running {
+		return
+	}
+	select {
+	case <-b.done:
+		// closed
+		return
+	default:
+	}
+	b.running = true
+	b.wg.Add(1)
+	go b.supervisorLoop(b.done)
 }
 
-// stop ticker and stop the goroutine
-func (b *WebsocketBase) stopTicker() {
-	b.ticker.Stop()
-	b.stopTickerChannel <- 1
+// requestReconnect asks the supervisor to reconnect, it doesn't block
+func (b *WebsocketBase) requestReconnect() {
+	select {
+	case b.reconnectChannel <- struct{}{}:
+	default:
+	}
 }
 
-// defines a for loop that will run based on ticker's frequency
-// It checks the last data that received from server, if it is longer than the threshold,
-// it will force disconnect server and connect again.
-func (b *WebsocketBase) tickerLoop() {
+// defines a for loop that checks the last data that received from server by ticker,
+// if it is longer than the threshold, or the read loop failed, it will reconnect with backoff.
+func (b *WebsocketBase) supervisorLoop(done chan struct{}) {
 	if b.verbose {
-		b.Logger.Debug("tickerLoop started")
+		b.Logger.Debug("supervisorLoop started")
+	}
+	interval := time.Duration(b.TimerIntervalSecond) * time.Second
+	if interval <= 0 {
+		interval = time.Second
 	}
+	ticker := time.NewTicker(interval)
+	defer func() {
+		defer b.wg.Done()
+		ticker.Stop()
+		b.mu.Lock()
+		b.running = false
+		b.mu.Unlock()
+		if b.verbose {
+			b.Logger.Debug("supervisorLoop stopped")
+		}
+	}()
 	for {
 		select {
-		// Receive data from stopChannel
-		case <-b.stopTickerChannel:
-			if b.verbose {
-				b.Logger.Debug("tickerLoop stopped")
-			}
+		case <-done:
 			return
-
-		// Receive tick from tickChannel
-		case <-b.ticker.C:
-			elapsedSecond := time.Now().Sub(b.lastReceivedTime).Seconds()
+		case <-b.reconnectChannel:
+			if !b.reconnect(done) {
+				return
+			}
+		case <-ticker.C:
+			elapsed := time.Since(time.Unix(0, atomic.LoadInt64(&b.lastReceivedTime)))
 			if b.verbose {
-				b.Logger.Debugf("WebSocket received data %f sec ago", elapsedSecond)
+				b.Logger.Debugf("WebSocket received data %f sec ago", elapsed.Seconds())
 			}
-
-			if elapsedSecond > float64(b.ReconnectWaitSecond) {
+			if elapsed > time.Duration(b.ReconnectWaitSecond)*time.Second {
 				if b.verbose {
 					b.Logger.Info("WebSocket reconnect...")
 				}
-				b.disconnectWebSocket()
-				b.connectWebSocket()
+				if !b.reconnect(done) {
+					return
+				}
 			}
 		}
 	}
 }
 
-// start a goroutine readLoop()
-func (b *WebsocketBase) startReadLoop() {
-	go b.readLoop()
+// reconnect until success, it returns false if closed or the circuit is open without CoolDown
+func (b *WebsocketBase) reconnect(done chan struct{}) bool {
+	b.disconnectWebSocket(nil)
+	failures := 0
+	for {
+		if b.policy.MaxAttempts > 0 && failures >= b.policy.MaxAttempts {
+			err := fmt.Errorf("%w: %d attempts failed", ErrCircuitOpen, failures)
+			if b.verbose {
+				b.Logger.Error(err)
+			}
+			b.setState(StateFailed, err)
+			if b.policy.CoolDown <= 0 || !b.sleep(done, b.policy.CoolDown) {
+				return false
+			}
+			// half open, try once more
+			failures = b.policy.MaxAttempts - 1
+		} else if !b.sleep(done, b.policy.backoff(failures, rand.Float64())) {
+			return false
+		}
+		if err := b.connectWebSocket(); err == nil {
+			// drain the request during reconnecting
+			select {
+			case <-b.reconnectChannel:
+			default:
+			}
+			return true
+		}
+		failures++
+	}
 }
 
-// stop the goroutine readLoop()
-func (b *WebsocketBase) stopReadLoop() {
-	b.stopReadChannel <- 1
+// sleep returns false if closed
+func (b *WebsocketBase) sleep(done chan struct{}, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return false
+	case <-timer.C:
+		return true
+	}
 }
 
 // defines a for loop to read data from server
-// it will stop once it receives the signal from stopReadChannel
-func (b *WebsocketBase) readLoop() {
+// it will stop once the connection is closed, and asks for reconnect if the connection is broken
+func (b *WebsocketBase) readLoop(conn *websocket.Conn) {
+	defer b.wg.Done()
 	if b.verbose {
 		b.Logger.Debug("readLoop started")
 	}
 	for {
-		select {
-		// Receive data from stopChannel
-		case <-b.stopReadChannel:
-			if b.verbose {
-				b.Logger.Debug("readLoop stopped")
-			}
-			return
-		//case <-time.After(time.Duration(b.TimerIntervalSecond) * time.Second):
-		//	if b.conn == nil {
-		//		if b.verbose {
-		//			b.Logger.Error("Read error: no connection available")
-		//		}
-		//		continue
-		//	}
-		//	msgType, buf, err := b.conn.ReadMessage()
-		//	if err != nil {
-		//		if b.verbose {
-		//			b.Logger.Errorf("Read error: %s", err)
-		//		}
-		//		continue
-		//	}
-		//
-		//	b.lastReceivedTime = time.Now()
-		//	b.messageHandler(msgType, buf)
-
-		default:
-			if b.conn == nil {
-				if b.verbose {
-					b.Logger.Error("Read error: no connection available")
-				}
-				time.Sleep(time.Duration(b.TimerIntervalSecond) * time.Second)
-				continue
-			}
-
-			msgType, buf, err := b.conn.ReadMessage()
-			if err != nil {
+		msgType, buf, err := conn.ReadMessage()
+		if err != nil {
+			b.mu.Lock()
+			current := b.conn == conn
+			autoConnect := b.autoConnect
+			b.mu.Unlock()
+			if current {
+				// broken by server or network, not closed by us
 				if b.verbose {
 					b.Logger.Errorf("Read error: %s", err)
 				}
-				time.Sleep(time.Duration(b.TimerIntervalSecond) * time.Second)
-				continue
+				b.disconnectWebSocket(err)
+				if autoConnect {
+					b.requestReconnect()
+				}
 			}
+			if b.verbose {
+				b.Logger.Debug("readLoop stopped")
+			}
+			return
+		}
 
-			b.lastReceivedTime = time.Now()
-			b.messageHandler(msgType, buf)
+		atomic.StoreInt64(&b.lastReceivedTime, time.Now().UnixNano())
+		if err := b.recorder.Record(msgType, buf); err != nil && b.verbose {
+			b.Logger.Errorf("Record error: %s", err)
 		}
+		b.messageHandler(msgType, buf)
 	}
 }
@@ -0,0 +1,266 @@
+package base
+
+import (
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testPolicy = ReconnectPolicy{
+	MinBackoff: 10 * time.Millisecond,
+	MaxBackoff: 50 * time.Millisecond,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+func TestReconnectPolicy_Backoff(t *testing.T) {
+	p := ReconnectPolicy{MinBackoff: time.Second, MaxBackoff: 10 * time.Second, Multiplier: 2}
+	require.Equal(t, time.Second, p.backoff(0, 0))
+	require.Equal(t, 2*time.Second, p.backoff(1, 0))
+	require.Equal(t, 8*time.Second, p.backoff(3, 0))
+	require.Equal(t, 10*time.Second, p.backoff(4, 0))
+	require.Equal(t, 10*time.Second, p.backoff(100, 0))
+
+	p.Jitter = 0.5
+	require.Equal(t, 2*time.Second, p.backoff(1, 0.5))
+	require.Equal(t, time.Second, p.backoff(1, 0))
+	require.InDelta(t, float64(3*time.Second), float64(p.backoff(1, 0.999999)), float64(time.Millisecond))
+}
+
+// wsServer echoes nothing, it records the messages and counts the connections.
+// The connection is closed by server after receiving closeAfter messages.
+type wsServer struct {
+	*httptest.Server
+	connections int32
+	reject      int32 // reject the first n connections
+	closeAfter  int
+
+	mu       sync.Mutex
+	messages []string
+}
+
+func newWsServer(t *testing.T, reject int32, closeAfter int) *wsServer {
+	s := &wsServer{reject: reject, closeAfter: closeAfter}
+	upgrader := websocket.Upgrader{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&s.reject, -1) >= 0 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		n := atomic.AddInt32(&s.connections, 1)
+		for i := 0; ; i++ {
+			if n == 1 && s.closeAfter > 0 && i >= s.closeAfter {
+				return
+			}
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, string(data))
+			s.mu.Unlock()
+		}
+	}))
+	return s
+}
+
+func (s *wsServer) host() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+func (s *wsServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+type stateRecorder struct {
+	mu     sync.Mutex
+	states []ConnectionState
+	errs   []error
+}
+
+func (r *stateRecorder) handle(state ConnectionState, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, state)
+	r.errs = append(r.errs, err)
+}
+
+func (r *stateRecorder) last() (ConnectionState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.states) == 0 {
+		return StateDisconnected, nil
+	}
+	return r.states[len(r.states)-1], r.errs[len(r.errs)-1]
+}
+
+func (r *stateRecorder) count(state ConnectionState) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, s := range r.states {
+		if s == state {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWebsocketBase_ReplayAfterReconnect(t *testing.T) {
+	// the first connection is closed by server after the first message
+	server := newWsServer(t, 0, 1)
+	defer server.Close()
+
+	var connected int32
+	var recorder stateRecorder
+	b := new(WebsocketBase).Init(server.host(), "/ws", zap.NewNop().Sugar(), 1, 60, true)
+	b.SetHandler(func() {
+		atomic.AddInt32(&connected, 1)
+	}, func(int, []byte) {})
+	b.SetStateHandler(recorder.handle)
+	b.SetReconnectPolicy(testPolicy)
+	b.Subscribe("ticker", "sub ticker")
+	b.Connect(true)
+	defer b.Close()
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&connected) == 2 && len(server.received()) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"sub ticker", "sub ticker"}, server.received())
+	require.Equal(t, StateConnected, b.State())
+	require.Equal(t, 2, recorder.count(StateConnected))
+	require.Equal(t, 1, recorder.count(StateDisconnected))
+
+	// unsubscribed is not replayed
+	b.Unsubscribe("ticker", "unsub ticker")
+	b.Subscribe("depth", "sub depth")
+	require.Eventually(t, func() bool { return len(server.received()) == 4 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"unsub ticker", "sub depth"}, server.received()[2:])
+}
+
+func TestWebsocketBase_ManualReplay(t *testing.T) {
+	server := newWsServer(t, 0, 0)
+	defer server.Close()
+
+	b := new(WebsocketBase).Init(server.host(), "/ws", zap.NewNop().Sugar(), 1, 60, false)
+	b.ManualReplay = true
+	b.SetHandler(func() {
+		b.Send("auth")
+		b.ReplaySubscriptions()
+	}, func(int, []byte) {})
+	b.Subscribe("order", "sub order")
+	b.Connect(true)
+	defer b.Close()
+
+	require.Eventually(t, func() bool { return len(server.received()) == 2 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"auth", "sub order"}, server.received())
+}
+
+func TestWebsocketBase_RetryDialFailure(t *testing.T) {
+	server := newWsServer(t, 2, 0)
+	defer server.Close()
+
+	var recorder stateRecorder
+	b := new(WebsocketBase).Init(server.host(), "/ws", zap.NewNop().Sugar(), 1, 60, false)
+	b.SetHandler(nil, func(int, []byte) {})
+	b.SetStateHandler(recorder.handle)
+	b.SetReconnectPolicy(testPolicy)
+	b.Connect(true)
+	defer b.Close()
+
+	require.Eventually(t, func() bool { return b.State() == StateConnected }, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, 3, recorder.count(StateConnecting))
+	require.Equal(t, int32(1), atomic.LoadInt32(&server.connections))
+}
+
+func TestWebsocketBase_CircuitBreaker(t *testing.T) {
+	server := newWsServer(t, 100, 0)
+	defer server.Close()
+
+	var recorder stateRecorder
+	policy := testPolicy
+	policy.MaxAttempts = 2
+	b := new(WebsocketBase).Init(server.host(), "/ws", zap.NewNop().Sugar(), 1, 60, false)
+	b.SetHandler(nil, func(int, []byte) {})
+	b.SetStateHandler(recorder.handle)
+	b.SetReconnectPolicy(policy)
+	b.Connect(true)
+	defer b.Close()
+
+	require.Eventually(t, func() bool { return b.State() == StateFailed }, 2*time.Second, 10*time.Millisecond)
+	state, err := recorder.last()
+	require.Equal(t, StateFailed, state)
+	require.True(t, errors.Is(err, ErrCircuitOpen))
+	// the first dial and 2 retries
+	require.Equal(t, 3, recorder.count(StateConnecting))
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, 3, recorder.count(StateConnecting))
+}
+
+func TestWebsocketBase_Close(t *testing.T) {
+	server := newWsServer(t, 0, 0)
+	defer server.Close()
+
+	var recorder stateRecorder
+	b := new(WebsocketBase).Init(server.host(), "/ws", zap.NewNop().Sugar(), 1, 60, false)
+	b.SetHandler(nil, func(int, []byte) {})
+	b.SetStateHandler(recorder.handle)
+	b.SetReconnectPolicy(testPolicy)
+	b.Connect(true)
+	require.Equal(t, StateConnected, b.State())
+	b.Close()
+	require.Equal(t, StateDisconnected, b.State())
+	time.Sleep(100 * time.Millisecond)
+	// no reconnect after close
+	require.Equal(t, 1, recorder.count(StateConnecting))
+	require.Equal(t, int32(1), atomic.LoadInt32(&server.connections))
+}
+
+func TestWebsocketBase_CloseWaitsHandler(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.WriteMessage(websocket.TextMessage, []byte("tick"))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	started := make(chan struct{})
+	var finished int32
+	b := new(WebsocketBase).Init("ws"+strings.TrimPrefix(server.URL, "http"), "/ws", zap.NewNop().Sugar(), 1, 60, false)
+	b.SetHandler(nil, func(int, []byte) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+	})
+	b.SetReconnectPolicy(testPolicy)
+	b.Connect(true)
+	<-started
+	b.Close()
+	// the in-flight message is handled before Close returns
+	require.Equal(t, int32(1), atomic.LoadInt32(&finished))
+}
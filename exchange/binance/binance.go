@@ -1 +1,296 @@
+// Package binance is the spot client of binance.com, by plain RESTful and websocket api.
 package binance
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/convert"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHost   = "api.binance.com"
+	DefaultWsHost = "stream.binance.com:9443"
+
+	// max klines of one request
+	maxCandleLength = 1000
+)
+
+type Client struct {
+	Key    string
+	Secret string
+	// Host is the RESTful api host, with optional scheme, https by default
+	Host string
+	// WsHost is the websocket stream host, with optional scheme, wss by default
+	WsHost string
+	Logger *zap.SugaredLogger
+
+	httpClient *http.Client
+
+	mu   sync.Mutex
+	subs map[string]*subscription // clientId -> subscription
+}
+
+var (
+	_ exchange.RestAPIExchange = (*Client)(nil)
+	_ exchange.WsAPIExchange   = (*Client)(nil)
+)
+
+func New(key, secret, host string, logger *zap.SugaredLogger) *Client {
+	if host == "" {
+		host = DefaultHost
+	}
+	return &Client{
+		Key:        key,
+		Secret:     secret,
+		Host:       host,
+		WsHost:     DefaultWsHost,
+		Logger:     logger,
+		httpClient: &http.Client{Timeout: time.Second * 10},
+		subs:       make(map[string]*subscription),
+	}
+}
+
+func init() {
+	exchange.Register(hs.Binance, func(conf hs.ExchangeConf, logger *zap.SugaredLogger) (exchange.Exchange, error) {
+		return New(conf.Key, conf.Secret, conf.Host, logger), nil
+	})
+}
+
+func (c *Client) Name() string {
+	return hs.Binance
+}
+
+// FormatSymbol returns symbol like BTCUSDT
+func (c *Client) FormatSymbol(base, quote string) string {
+	return strings.ToUpper(base + quote)
+}
+
+func (c *Client) AllSymbols(ctx context.Context) (s []exchange.Symbol, err error) {
+	var info rawExchangeInfo
+	if err = c.rest().Get(ctx, "/api/v3/exchangeInfo", nil, false, &info); err != nil {
+		return
+	}
+	for _, r := range info.Symbols {
+		s = append(s, convertSymbol(r))
+	}
+	return
+}
+
+func (c *Client) GetSymbol(ctx context.Context, symbol string) (exchange.Symbol, error) {
+	var info rawExchangeInfo
+	if err := c.rest().Get(ctx, "/api/v3/exchangeInfo", url.Values{"symbol": {symbol}}, false, &info); err != nil {
+		return exchange.Symbol{}, err
+	}
+	for _, r := range info.Symbols {
+		if r.Symbol == symbol {
+			return convertSymbol(r), nil
+		}
+	}
+	return exchange.Symbol{}, ErrSymbolNotFound
+}
+
+func (c *Client) GetFee(symbol string) (fee exchange.Fee, err error) {
+	var fees []struct {
+		Symbol          string `json:"symbol"`
+		MakerCommission string `json:"makerCommission"`
+		TakerCommission string `json:"takerCommission"`
+	}
+	if err = c.rest().Get(context.Background(), "/sapi/v1/asset/tradeFee", url.Values{"symbol": {symbol}}, true, &fees); err != nil {
+		return
+	}
+	if len(fees) == 0 {
+		return fee, ErrSymbolNotFound
+	}
+	fee.Symbol = fees[0].Symbol
+	fee.BaseMaker = convert.StrToDecimal(fees[0].MakerCommission)
+	fee.BaseTaker = convert.StrToDecimal(fees[0].TakerCommission)
+	fee.ActualMaker = fee.BaseMaker
+	fee.ActualTaker = fee.BaseTaker
+	return
+}
+
+func (c *Client) SpotBalance() (map[string]decimal.Decimal, error) {
+	total, _, err := c.rest().Balances(nil)
+	return total, err
+}
+
+func (c *Client) SpotAvailableBalance() (map[string]decimal.Decimal, error) {
+	_, available, err := c.rest().Balances(nil)
+	return available, err
+}
+
+func (c *Client) LastPrice(symbol string) (decimal.Decimal, error) {
+	var ticker struct {
+		Price string `json:"price"`
+	}
+	if err := c.rest().Get(context.Background(), "/api/v3/ticker/price", url.Values{"symbol": {symbol}}, false, &ticker); err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromString(ticker.Price)
+}
+
+// Last24hVolume returns the volume of base currency in 24 hours
+func (c *Client) Last24hVolume(symbol string) (decimal.Decimal, error) {
+	var ticker struct {
+		Volume string `json:"volume"`
+	}
+	if err := c.rest().Get(context.Background(), "/api/v3/ticker/24hr", url.Values{"symbol": {symbol}}, false, &ticker); err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromString(ticker.Volume)
+}
+
+func (c *Client) CandleBySize(symbol string, period time.Duration, size int) (hs.Candle, error) {
+	return c.rest().CandleBySize(symbol, getInterval(period), size, maxCandleLength)
+}
+
+func (c *Client) CandleFrom(symbol, clientId string, period time.Duration, from, to time.Time) (hs.Candle, error) {
+	return c.rest().CandleFrom(symbol, getInterval(period), period, from, to, maxCandleLength)
+}
+
+func (c *Client) BuyLimit(symbol, clientOrderId string, price, amount decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(symbol, clientOrderId, SideBuy, OrderTypeLimit, TimeInForceGTC, price, amount, decimal.Zero, decimal.Zero, decimal.Zero)
+}
+
+func (c *Client) SellLimit(symbol, clientOrderId string, price, amount decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(symbol, clientOrderId, SideSell, OrderTypeLimit, TimeInForceGTC, price, amount, decimal.Zero, decimal.Zero, decimal.Zero)
+}
+
+// BuyMarket spends total quote currency
+func (c *Client) BuyMarket(symbol exchange.Symbol, clientOrderId string, total decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(symbol.Symbol, clientOrderId, SideBuy, OrderTypeMarket, "", decimal.Zero, decimal.Zero, total, decimal.Zero, decimal.Zero)
+}
+
+func (c *Client) SellMarket(symbol exchange.Symbol, clientOrderId string, amount decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(symbol.Symbol, clientOrderId, SideSell, OrderTypeMarket, "", decimal.Zero, amount, decimal.Zero, decimal.Zero, decimal.Zero)
+}
+
+// BuyStopLimit places a limit order when price >= stopPrice
+func (c *Client) BuyStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(symbol, clientOrderId, SideBuy, OrderTypeStopLossLimit, TimeInForceGTC, price, amount, decimal.Zero, stopPrice, decimal.Zero)
+}
+
+// SellStopLimit places a limit order when price <= stopPrice
+func (c *Client) SellStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(symbol, clientOrderId, SideSell, OrderTypeStopLossLimit, TimeInForceGTC, price, amount, decimal.Zero, stopPrice, decimal.Zero)
+}
+
+// orderOptions lists the spot order options, reduce-only is for futures only
+var orderOptions = exchange.OptionSupport{
+	Name:        "binance",
+	TimeInForce: []exchange.TimeInForce{exchange.GTC, exchange.IOC, exchange.FOK, exchange.PostOnly},
+	Iceberg:     true,
+}
+
+// PlaceOrder places limit order, the post-only order is LIMIT_MAKER.
+// Binance accepts iceberg for GTC and post-only order only.
+func (c *Client) PlaceOrder(symbol, clientOrderId string, side exchange.OrderType, price, amount decimal.Decimal, options exchange.OrderOptions) (orderId uint64, err error) {
+	if err = orderOptions.Check(options, amount); err != nil {
+		return
+	}
+	s := SideBuy
+	switch side {
+	case exchange.Buy:
+	case exchange.Sell:
+		s = SideSell
+	default:
+		return 0, exchange.ErrBadSide
+	}
+	orderType, timeInForce := OrderTypeLimit, TimeInForceGTC
+	switch options.TIF() {
+	case exchange.IOC:
+		timeInForce = TimeInForceIOC
+	case exchange.FOK:
+		timeInForce = TimeInForceFOK
+	case exchange.PostOnly:
+		orderType, timeInForce = OrderTypeLimitMaker, ""
+	}
+	return c.placeOrder(symbol, clientOrderId, s, orderType, timeInForce, price, amount, decimal.Zero, decimal.Zero, options.Iceberg)
+}
+
+func (c *Client) placeOrder(symbol, clientOrderId, side, orderType, timeInForce string, price, amount, total, stopPrice, iceberg decimal.Decimal) (uint64, error) {
+	params := url.Values{
+		"symbol":           {symbol},
+		"side":             {side},
+		"type":             {orderType},
+		"newOrderRespType": {"ACK"},
+	}
+	if clientOrderId != "" {
+		params.Set("newClientOrderId", clientOrderId)
+	}
+	if timeInForce != "" {
+		params.Set("timeInForce", timeInForce)
+	}
+	if orderType != OrderTypeMarket {
+		params.Set("price", price.String())
+	}
+	if !amount.IsZero() {
+		params.Set("quantity", amount.String())
+	}
+	if !total.IsZero() {
+		params.Set("quoteOrderQty", total.String())
+	}
+	if !stopPrice.IsZero() {
+		params.Set("stopPrice", stopPrice.String())
+	}
+	if !iceberg.IsZero() {
+		params.Set("icebergQty", iceberg.String())
+	}
+	var r struct {
+		OrderId uint64 `json:"orderId"`
+	}
+	if err := c.rest().Do(context.Background(), http.MethodPost, "/api/v3/order", params, true, &r); err != nil {
+		return 0, err
+	}
+	return r.OrderId, nil
+}
+
+func (c *Client) GetOrderById(orderId uint64, symbol string) (exchange.Order, error) {
+	var r rawOrder
+	params := url.Values{"symbol": {symbol}, "orderId": {strconv.FormatUint(orderId, 10)}}
+	if err := c.rest().Get(context.Background(), "/api/v3/order", params, true, &r); err != nil {
+		return exchange.Order{}, err
+	}
+	return convertOrder(r), nil
+}
+
+func (c *Client) CancelOrder(symbol string, orderId uint64) error {
+	params := url.Values{"symbol": {symbol}, "orderId": {strconv.FormatUint(orderId, 10)}}
+	return c.rest().Do(context.Background(), http.MethodDelete, "/api/v3/order", params, true, nil)
+}
+
+func (c *Client) IsFullFilled(symbol string, orderId uint64) (exchange.Order, bool, error) {
+	o, err := c.GetOrderById(orderId, symbol)
+	if err != nil {
+		return o, false, err
+	}
+	return o, o.FullFilled(), nil
+}
+
+// ApiError is the error returned by binance api
+type ApiError = base.ApiError
+
+var ErrSymbolNotFound = errors.New("symbol not found")
+
+// rest returns the RESTful client of current Host and keys
+func (c *Client) rest() *base.QueryClient {
+	return &base.QueryClient{
+		Exchange:  hs.Binance,
+		Host:      c.Host,
+		Key:       c.Key,
+		Secret:    c.Secret,
+		KeyHeader: "X-MBX-APIKEY",
+		HTTP:      c.httpClient,
+	}
+}
@@ -0,0 +1,206 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
+	"github.com/xyths/hs/exchange/base/basetest"
+	"go.uber.org/zap"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := basetest.NewServer(t, handler)
+	return New("key", "secret", server.URL, zap.NewNop().Sugar())
+}
+
+func TestSign(t *testing.T) {
+	// example from binance api document
+	query := "symbol=LTCBTC&side=BUY&type=LIMIT&timeInForce=GTC&quantity=1&price=0.1&recvWindow=5000&timestamp=1499827319559"
+	secret := "NhqPtmdSJYdKjVHjA7PZj4Mge3R5YNiP1e3UZjInClVN65XAbvqqM6A7H5fATj0j"
+	require.Equal(t, "c8db56825ae71d6d79447849e617115f4a920fa2acdcab2b053c4b2838bd6b71", base.SignHex(secret, query))
+}
+
+func TestClient_GetSymbol(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v3/exchangeInfo", r.URL.Path)
+		require.Equal(t, "BTCUSDT", r.URL.Query().Get("symbol"))
+		_, _ = w.Write([]byte(`{"symbols":[{"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT","filters":[
+{"filterType":"PRICE_FILTER","minPrice":"0.01000000","maxPrice":"1000000.00000000","tickSize":"0.01000000"},
+{"filterType":"LOT_SIZE","minQty":"0.00001000","maxQty":"9000.00000000","stepSize":"0.00001000"},
+{"filterType":"NOTIONAL","minNotional":"5.00000000"}]}]}`))
+	})
+	s, err := c.GetSymbol(context.Background(), c.FormatSymbol("btc", "usdt"))
+	require.NoError(t, err)
+	require.Equal(t, "BTCUSDT", s.Symbol)
+	require.Equal(t, "btc", s.BaseCurrency)
+	require.Equal(t, "usdt", s.QuoteCurrency)
+	require.Equal(t, int32(2), s.PricePrecision)
+	require.Equal(t, int32(5), s.AmountPrecision)
+	require.True(t, decimal.RequireFromString("0.00001").Equal(s.LimitOrderMinAmount))
+	require.True(t, decimal.NewFromInt(5).Equal(s.MinTotal))
+	require.False(t, s.Disabled)
+}
+
+func TestClient_Orders(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v3/order", r.URL.Path)
+		require.True(t, basetest.CheckQuerySign(t, r, "X-MBX-APIKEY", "key", "secret"))
+		q := r.URL.Query()
+		switch r.Method {
+		case http.MethodPost:
+			require.Equal(t, "BUY", q.Get("side"))
+			require.Equal(t, "STOP_LOSS_LIMIT", q.Get("type"))
+			require.Equal(t, "GTC", q.Get("timeInForce"))
+			require.Equal(t, "30000", q.Get("price"))
+			require.Equal(t, "0.1", q.Get("quantity"))
+			require.Equal(t, "29900", q.Get("stopPrice"))
+			require.Equal(t, "my-order", q.Get("newClientOrderId"))
+			_, _ = w.Write([]byte(`{"symbol":"BTCUSDT","orderId":28,"clientOrderId":"my-order","transactTime":1507725176595}`))
+		case http.MethodGet:
+			require.Equal(t, "28", q.Get("orderId"))
+			_, _ = w.Write([]byte(`{"symbol":"BTCUSDT","orderId":28,"clientOrderId":"my-order","price":"30000.00","origQty":"0.10000000",
+"executedQty":"0.10000000","cummulativeQuoteQty":"2990.00000000","status":"FILLED","timeInForce":"GTC","type":"STOP_LOSS_LIMIT",
+"side":"BUY","stopPrice":"29900.00","time":1499827319559}`))
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":-2011,"msg":"Unknown order sent."}`))
+		}
+	})
+	id, err := c.BuyStopLimit("BTCUSDT", "my-order", decimal.NewFromInt(30000), decimal.RequireFromString("0.1"), decimal.NewFromInt(29900))
+	require.NoError(t, err)
+	require.Equal(t, uint64(28), id)
+
+	o, filled, err := c.IsFullFilled("BTCUSDT", id)
+	require.NoError(t, err)
+	require.True(t, filled)
+	require.Equal(t, "buy-stop-limit", o.Type)
+	require.Equal(t, "my-order", o.ClientOrderId)
+	require.True(t, decimal.NewFromInt(29900).Equal(o.FilledPrice))
+	require.Equal(t, int64(1499827319), o.Time.Unix())
+
+	err = c.CancelOrder("BTCUSDT", id)
+	require.Error(t, err)
+	apiErr, ok := err.(ApiError)
+	require.True(t, ok)
+	require.Equal(t, -2011, apiErr.Code)
+}
+
+func TestClient_CandleBySize(t *testing.T) {
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v3/klines", r.URL.Path)
+		require.Equal(t, "5m", r.URL.Query().Get("interval"))
+		calls++
+		_, _ = w.Write([]byte(`[
+[1499040000000,"0.01634790","0.80000000","0.01575800","0.01577100","148976.11427815",1499644799999,"2434.19055334",308,"1756.87402397","28.46694368","0"],
+[1499040300000,"0.01577100","0.01600000","0.01570000","0.01590000","100.0",1499644799999,"0",1,"0","0","0"]]`))
+	})
+	candle, err := c.CandleBySize("BTCUSDT", time.Minute*5, 2)
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+	require.Equal(t, 2, candle.Length())
+	require.Equal(t, int64(1499040000), candle.Timestamp[0])
+	require.Equal(t, 0.0159, candle.Close[1])
+}
+
+func TestClient_SubscribeOrder(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/userDataStream":
+			require.Equal(t, "key", r.Header.Get("X-MBX-APIKEY"))
+			if r.Method == http.MethodPost {
+				_, _ = w.Write([]byte(`{"listenKey":"listen-key"}`))
+			} else {
+				_, _ = w.Write([]byte(`{}`))
+			}
+		case "/ws/listen-key":
+			conn, err := upgrader.Upgrade(w, r, nil)
+			require.NoError(t, err)
+			defer conn.Close()
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"outboundAccountPosition","E":1564034571105}`)))
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"executionReport","E":1499405658658,"s":"ETHBTC",
+"c":"mUvoqJxFIILMdfAW5iGSOW","S":"BUY","o":"LIMIT","f":"GTC","q":"1.00000000","p":"0.10264410","P":"0.00000000","C":"",
+"x":"TRADE","X":"FILLED","r":"NONE","i":4293153,"l":"1.00000000","z":"1.00000000","L":"0.10264410","n":"0.001","N":"BNB",
+"T":1499405658657,"t":11,"I":8641984,"w":false,"m":true,"M":false,"O":1499405658657,"Z":"0.10264410"}`)))
+			_, _, _ = conn.ReadMessage()
+		}
+	})
+	c.WsHost = basetest.WsURL(c.Host)
+
+	orders := make(chan exchange.Order, 1)
+	c.SubscribeOrder("ETHBTC", "test", func(response interface{}) {
+		orders <- response.(exchange.Order)
+	})
+	defer c.UnsubscribeOrder("ETHBTC", "test")
+
+	select {
+	case o := <-orders:
+		require.Equal(t, uint64(4293153), o.Id)
+		require.Equal(t, "buy-limit", o.Type)
+		require.Equal(t, OrderStatusFilled, o.Status)
+		require.Equal(t, exchange.Closed, o.State)
+		require.True(t, decimal.RequireFromString("0.1026441").Equal(o.FilledPrice))
+		require.Len(t, o.Trades, 1)
+		require.Equal(t, "maker", o.Trades[0].Role)
+		require.Equal(t, "bnb", o.Trades[0].FeeCurrency)
+	case <-time.After(time.Second * 5):
+		t.Fatal("no order update")
+	}
+}
+
+func TestClient_SubscribeCandlestick(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/ws/btcusdt@kline_1m", r.URL.Path)
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"kline","E":123456789,"s":"BTCUSDT","k":{
+"t":123400000,"T":123460000,"s":"BTCUSDT","i":"1m","o":"0.0010","c":"0.0020","h":"0.0025","l":"0.0015","v":"1000","x":false}}`)))
+		_, _, _ = conn.ReadMessage()
+	})
+	c.WsHost = basetest.WsURL(c.Host)
+
+	tickers := make(chan hs.Ticker, 1)
+	c.SubscribeCandlestick("BTCUSDT", "test", time.Minute, func(response interface{}) {
+		tickers <- response.(hs.Ticker)
+	})
+	defer c.UnsubscribeCandlestick("BTCUSDT", "test", time.Minute)
+
+	select {
+	case ticker := <-tickers:
+		require.Equal(t, int64(123400), ticker.Timestamp)
+		require.Equal(t, 0.0025, ticker.High)
+		require.Equal(t, 1000.0, ticker.Volume)
+	case <-time.After(time.Second * 5):
+		t.Fatal("no candlestick update")
+	}
+}
+
+func TestClient_PlaceOrder(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		q := r.URL.Query()
+		require.Equal(t, "SELL", q.Get("side"))
+		require.Equal(t, "LIMIT_MAKER", q.Get("type"))
+		require.Equal(t, "", q.Get("timeInForce"))
+		require.Equal(t, "0.1", q.Get("icebergQty"))
+		_, _ = w.Write([]byte(`{"symbol":"BTCUSDT","orderId":29,"clientOrderId":"pm","transactTime":1507725176595}`))
+	})
+	id, err := c.PlaceOrder("BTCUSDT", "pm", exchange.Sell, decimal.NewFromInt(30000), decimal.NewFromInt(1),
+		exchange.OrderOptions{TimeInForce: exchange.PostOnly, Iceberg: decimal.RequireFromString("0.1")})
+	require.NoError(t, err)
+	require.Equal(t, uint64(29), id)
+
+	_, err = c.PlaceOrder("BTCUSDT", "ro", exchange.Sell, decimal.NewFromInt(30000), decimal.NewFromInt(1), exchange.OrderOptions{ReduceOnly: true})
+	require.True(t, errors.Is(err, exchange.ErrUnsupportedOption))
+}
@@ -0,0 +1,106 @@
+package binance
+
+const (
+	SideBuy  = "BUY"
+	SideSell = "SELL"
+
+	OrderTypeLimit         = "LIMIT"
+	OrderTypeMarket        = "MARKET"
+	OrderTypeStopLossLimit = "STOP_LOSS_LIMIT"
+	OrderTypeLimitMaker    = "LIMIT_MAKER" // post-only
+
+	TimeInForceGTC = "GTC"
+	TimeInForceIOC = "IOC"
+	TimeInForceFOK = "FOK"
+
+	OrderStatusNew             = "NEW"
+	OrderStatusPartiallyFilled = "PARTIALLY_FILLED"
+	OrderStatusFilled          = "FILLED"
+	OrderStatusCanceled        = "CANCELED"
+	OrderStatusRejected        = "REJECTED"
+	OrderStatusExpired         = "EXPIRED"
+)
+
+type rawExchangeInfo struct {
+	Symbols []rawSymbol `json:"symbols"`
+}
+
+type rawSymbol struct {
+	Symbol     string      `json:"symbol"`
+	Status     string      `json:"status"`
+	BaseAsset  string      `json:"baseAsset"`
+	QuoteAsset string      `json:"quoteAsset"`
+	Filters    []rawFilter `json:"filters"`
+}
+
+type rawFilter struct {
+	FilterType  string `json:"filterType"`
+	TickSize    string `json:"tickSize"`
+	StepSize    string `json:"stepSize"`
+	MinQty      string `json:"minQty"`
+	MinNotional string `json:"minNotional"`
+}
+
+type rawOrder struct {
+	Symbol              string `json:"symbol"`
+	OrderId             uint64 `json:"orderId"`
+	ClientOrderId       string `json:"clientOrderId"`
+	Price               string `json:"price"`
+	OrigQty             string `json:"origQty"`
+	ExecutedQty         string `json:"executedQty"`
+	CummulativeQuoteQty string `json:"cummulativeQuoteQty"`
+	Status              string `json:"status"`
+	Type                string `json:"type"`
+	Side                string `json:"side"`
+	StopPrice           string `json:"stopPrice"`
+	Time                int64  `json:"time"`
+}
+
+// rawKlineEvent is the message of <symbol>@kline_<interval> stream.
+// Fields differ only in case ("e" and "E") are both declared, because json matches keys case-insensitively.
+type rawKlineEvent struct {
+	EventType string `json:"e"`
+	EventTime int64  `json:"E"`
+	Symbol    string `json:"s"`
+	Kline     struct {
+		StartTime int64  `json:"t"`
+		CloseTime int64  `json:"T"`
+		Interval  string `json:"i"`
+		Open      string `json:"o"`
+		High      string `json:"h"`
+		Low       string `json:"l"`
+		Close     string `json:"c"`
+		Volume    string `json:"v"`
+		Closed    bool   `json:"x"`
+	} `json:"k"`
+}
+
+// rawExecutionReport is the order update of user data stream, fields differ only in case are both declared
+type rawExecutionReport struct {
+	EventType          string  `json:"e"`
+	EventTime          int64   `json:"E"`
+	Symbol             string  `json:"s"`
+	ClientOrderId      string  `json:"c"`
+	Side               string  `json:"S"`
+	OrderType          string  `json:"o"`
+	Quantity           string  `json:"q"`
+	Price              string  `json:"p"`
+	StopPrice          string  `json:"P"`
+	OrigClientOrderId  string  `json:"C"`
+	ExecutionType      string  `json:"x"`
+	Status             string  `json:"X"`
+	OrderId            uint64  `json:"i"`
+	LastQuantity       string  `json:"l"`
+	CumulativeQuantity string  `json:"z"`
+	LastPrice          string  `json:"L"`
+	Commission         string  `json:"n"`
+	CommissionAsset    *string `json:"N"`
+	TransactionTime    int64   `json:"T"`
+	TradeId            int64   `json:"t"`
+	Maker              bool    `json:"m"`
+	CreateTime         int64   `json:"O"`
+	CumulativeQuote    string  `json:"Z"`
+	QuoteOrderQty      string  `json:"Q"`
+	Ignore             int64   `json:"I"`
+	IgnoreM            bool    `json:"M"`
+}
@@ -0,0 +1,174 @@
+package binance
+
+import (
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/convert"
+	"github.com/xyths/hs/exchange"
+	"strings"
+	"time"
+)
+
+// getInterval convert duration to binance kline interval
+func getInterval(period time.Duration) string {
+	switch period {
+	case time.Minute:
+		return "1m"
+	case time.Minute * 3:
+		return "3m"
+	case time.Minute * 5:
+		return "5m"
+	case time.Minute * 15:
+		return "15m"
+	case time.Minute * 30:
+		return "30m"
+	case time.Hour:
+		return "1h"
+	case time.Hour * 2:
+		return "2h"
+	case time.Hour * 4:
+		return "4h"
+	case time.Hour * 6:
+		return "6h"
+	case time.Hour * 8:
+		return "8h"
+	case time.Hour * 12:
+		return "12h"
+	case time.Hour * 24:
+		return "1d"
+	case time.Hour * 24 * 3:
+		return "3d"
+	case time.Hour * 24 * 7:
+		return "1w"
+	default:
+		return "1d"
+	}
+}
+
+// precision returns the decimal places of step, eg. "0.01000000" is 2, "1.00000000" is 0
+func precision(step string) int32 {
+	step = strings.TrimRight(step, "0")
+	pos := strings.Index(step, ".")
+	if pos < 0 {
+		return 0
+	}
+	return int32(len(step) - pos - 1)
+}
+
+func convertSymbol(r rawSymbol) exchange.Symbol {
+	s := exchange.Symbol{
+		Symbol:        r.Symbol,
+		Disabled:      r.Status != "TRADING",
+		BaseCurrency:  strings.ToLower(r.BaseAsset),
+		QuoteCurrency: strings.ToLower(r.QuoteAsset),
+	}
+	for _, f := range r.Filters {
+		switch f.FilterType {
+		case "PRICE_FILTER":
+			s.PricePrecision = precision(f.TickSize)
+		case "LOT_SIZE":
+			s.AmountPrecision = precision(f.StepSize)
+			s.LimitOrderMinAmount = convert.StrToDecimal(f.MinQty)
+		case "MIN_NOTIONAL", "NOTIONAL":
+			s.MinTotal = convert.StrToDecimal(f.MinNotional)
+		}
+	}
+	return s
+}
+
+// orderType returns the type like buy-limit, sell-market, buy-stop-limit
+func orderType(side, typ string) string {
+	t := "limit"
+	switch typ {
+	case OrderTypeMarket:
+		t = "market"
+	case OrderTypeStopLossLimit:
+		t = "stop-limit"
+	}
+	return strings.ToLower(side) + "-" + t
+}
+
+// EXPIRED is cancelled by exchange, eg. IOC and FOK
+var orderStatus = exchange.StatusTable{
+	OrderStatusNew:             exchange.Open,
+	OrderStatusPartiallyFilled: exchange.Filled,
+	OrderStatusFilled:          exchange.Closed,
+	OrderStatusCanceled:        exchange.Cancelled,
+	OrderStatusRejected:        exchange.Rejected,
+	OrderStatusExpired:         exchange.Cancelled,
+}
+
+func convertOrder(r rawOrder) exchange.Order {
+	o := exchange.Order{
+		Id:            r.OrderId,
+		ClientOrderId: r.ClientOrderId,
+		Type:          orderType(r.Side, r.Type),
+		Side:          exchange.ParseSide(r.Side),
+		Symbol:        r.Symbol,
+		Price:         convert.StrToDecimal(r.Price),
+		Amount:        convert.StrToDecimal(r.OrigQty),
+		Time:          time.Unix(0, r.Time*int64(time.Millisecond)),
+		Status:        r.Status,
+		FilledAmount:  convert.StrToDecimal(r.ExecutedQty),
+	}
+	o.State = orderStatus.Normalize(o.Status, o.FilledAmount)
+	if !o.FilledAmount.IsZero() {
+		o.FilledPrice = convert.StrToDecimal(r.CummulativeQuoteQty).Div(o.FilledAmount)
+	}
+	return o
+}
+
+func convertExecutionReport(r rawExecutionReport) exchange.Order {
+	o := exchange.Order{
+		Id:            r.OrderId,
+		ClientOrderId: r.ClientOrderId,
+		Type:          orderType(r.Side, r.OrderType),
+		Side:          exchange.ParseSide(r.Side),
+		Symbol:        r.Symbol,
+		Price:         convert.StrToDecimal(r.Price),
+		Amount:        convert.StrToDecimal(r.Quantity),
+		Time:          time.Unix(0, r.CreateTime*int64(time.Millisecond)),
+		Status:        r.Status,
+		FilledAmount:  convert.StrToDecimal(r.CumulativeQuantity),
+	}
+	if r.Status == OrderStatusCanceled && r.OrigClientOrderId != "" {
+		// the client order id of cancel request is in "c"
+		o.ClientOrderId = r.OrigClientOrderId
+	}
+	o.State = orderStatus.Normalize(o.Status, o.FilledAmount)
+	if !o.FilledAmount.IsZero() {
+		o.FilledPrice = convert.StrToDecimal(r.CumulativeQuote).Div(o.FilledAmount)
+	}
+	if r.ExecutionType == "TRADE" {
+		t := exchange.Trade{
+			Id:        uint64(r.TradeId),
+			OrderId:   r.OrderId,
+			Symbol:    r.Symbol,
+			Type:      o.Type,
+			Side:      strings.ToLower(r.Side),
+			Role:      "taker",
+			Price:     convert.StrToDecimal(r.LastPrice),
+			Amount:    convert.StrToDecimal(r.LastQuantity),
+			FeeAmount: convert.StrToDecimal(r.Commission),
+			Time:      time.Unix(0, r.TransactionTime*int64(time.Millisecond)),
+		}
+		if r.Maker {
+			t.Role = "maker"
+		}
+		if r.CommissionAsset != nil {
+			t.FeeCurrency = strings.ToLower(*r.CommissionAsset)
+		}
+		o.Trades = append(o.Trades, t)
+	}
+	return o
+}
+
+func convertKline(r rawKlineEvent) hs.Ticker {
+	return hs.Ticker{
+		Timestamp: r.Kline.StartTime / 1000,
+		Open:      convert.StrToFloat64(r.Kline.Open),
+		High:      convert.StrToFloat64(r.Kline.High),
+		Low:       convert.StrToFloat64(r.Kline.Low),
+		Close:     convert.StrToFloat64(r.Kline.Close),
+		Volume:    convert.StrToFloat64(r.Kline.Volume),
+	}
+}
@@ -0,0 +1,157 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// CandlestickReqMaxLength is the history length sent before the candlestick updates
+	CandlestickReqMaxLength = 300
+
+	// listen key expires after 60 minutes, keep it alive every 30 minutes
+	listenKeyKeepAlive = time.Minute * 30
+)
+
+// subscription is one websocket stream
+type subscription struct {
+	ws        *base.WebsocketBase
+	listenKey string
+	stop      chan struct{}
+}
+
+func (c *Client) SubscribeOrder(symbol, clientId string, responseHandler exchange.ResponseHandler) {
+	listenKey, err := c.newListenKey()
+	if err != nil {
+		c.Logger.Errorf("create listen key error: %s", err)
+		return
+	}
+	ws := new(base.WebsocketBase).Init(base.WithScheme(c.WsHost, "wss"), "/ws/"+listenKey, c.Logger, 60, 600, false)
+	ws.SetHandler(nil, func(messageType int, payload []byte) {
+		var report rawExecutionReport
+		if err := json.Unmarshal(payload, &report); err != nil {
+			c.Logger.Errorf("unmarshal user data error: %s", err)
+			return
+		}
+		if report.EventType != "executionReport" || (symbol != "" && report.Symbol != symbol) {
+			return
+		}
+		responseHandler(convertExecutionReport(report))
+	})
+	sub := &subscription{ws: ws, listenKey: listenKey, stop: make(chan struct{})}
+	c.addSubscription(orderKey(symbol, clientId), sub)
+	go c.keepAlive(sub)
+	ws.Connect(true)
+}
+
+func (c *Client) UnsubscribeOrder(symbol, clientId string) {
+	sub := c.removeSubscription(orderKey(symbol, clientId))
+	if sub == nil {
+		return
+	}
+	close(sub.stop)
+	sub.ws.Close()
+	if err := c.rest().Do(context.Background(), http.MethodDelete, "/api/v3/userDataStream", url.Values{"listenKey": {sub.listenKey}}, false, nil); err != nil {
+		c.Logger.Errorf("delete listen key error: %s", err)
+	}
+}
+
+// SubscribeCandlestick sends hs.Ticker to responseHandler
+func (c *Client) SubscribeCandlestick(symbol, clientId string, period time.Duration, responseHandler exchange.ResponseHandler) {
+	interval := getInterval(period)
+	path := fmt.Sprintf("/ws/%s@kline_%s", strings.ToLower(symbol), interval)
+	ws := new(base.WebsocketBase).Init(base.WithScheme(c.WsHost, "wss"), path, c.Logger, 10, 60, false)
+	ws.SetHandler(nil, func(messageType int, payload []byte) {
+		var event rawKlineEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			c.Logger.Errorf("unmarshal kline error: %s", err)
+			return
+		}
+		if event.EventType != "kline" {
+			return
+		}
+		responseHandler(convertKline(event))
+	})
+	c.addSubscription(candleKey(symbol, clientId, period), &subscription{ws: ws})
+	ws.Connect(true)
+}
+
+func (c *Client) UnsubscribeCandlestick(symbol, clientId string, period time.Duration) {
+	if sub := c.removeSubscription(candleKey(symbol, clientId, period)); sub != nil {
+		sub.ws.Close()
+	}
+}
+
+// SubscribeCandlestickWithReq sends the latest history as hs.Candle first, then hs.Ticker as SubscribeCandlestick
+func (c *Client) SubscribeCandlestickWithReq(symbol, clientId string, period time.Duration, responseHandler exchange.ResponseHandler) {
+	candle, err := c.CandleBySize(symbol, period, CandlestickReqMaxLength)
+	if err != nil {
+		c.Logger.Errorf("get candle error: %s", err)
+	} else {
+		responseHandler(candle)
+	}
+	c.SubscribeCandlestick(symbol, clientId, period, responseHandler)
+}
+
+func (c *Client) UnsubscribeCandlestickWithReq(symbol, clientId string, period time.Duration) {
+	c.UnsubscribeCandlestick(symbol, clientId, period)
+}
+
+func (c *Client) newListenKey() (string, error) {
+	var r struct {
+		ListenKey string `json:"listenKey"`
+	}
+	err := c.rest().Do(context.Background(), http.MethodPost, "/api/v3/userDataStream", nil, false, &r)
+	return r.ListenKey, err
+}
+
+func (c *Client) keepAlive(sub *subscription) {
+	ticker := time.NewTicker(listenKeyKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sub.stop:
+			return
+		case <-ticker.C:
+			if err := c.rest().Do(context.Background(), http.MethodPut, "/api/v3/userDataStream", url.Values{"listenKey": {sub.listenKey}}, false, nil); err != nil {
+				c.Logger.Errorf("keep alive listen key error: %s", err)
+			}
+		}
+	}
+}
+
+func (c *Client) addSubscription(key string, sub *subscription) {
+	c.mu.Lock()
+	old := c.subs[key]
+	c.subs[key] = sub
+	c.mu.Unlock()
+	if old != nil {
+		if old.stop != nil {
+			close(old.stop)
+		}
+		old.ws.Close()
+	}
+}
+
+func (c *Client) removeSubscription(key string) *subscription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sub := c.subs[key]
+	delete(c.subs, key)
+	return sub
+}
+
+func orderKey(symbol, clientId string) string {
+	return fmt.Sprintf("order#%s#%s", symbol, clientId)
+}
+
+func candleKey(symbol, clientId string, period time.Duration) string {
+	return fmt.Sprintf("kline#%s#%s#%s", symbol, getInterval(period), clientId)
+}
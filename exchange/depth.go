@@ -0,0 +1,33 @@
+package exchange
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// DepthLevel is one price level of order book, zero amount in incremental update means remove the level.
+type DepthLevel struct {
+	Price  decimal.Decimal `json:"price"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// DepthUpdate is the normalized depth message of all exchanges.
+// The sequence fields are zero if the exchange doesn't support, there are two styles:
+//   - range: gate v4 spot.order_book_update, FirstSeq is U and LastSeq is u,
+//     the update follows when FirstSeq <= last + 1 <= LastSeq.
+//   - chain: huobi mbp, PrevSeq is prevSeqNum and LastSeq is seqNum,
+//     the update follows when PrevSeq == last.
+type DepthUpdate struct {
+	Symbol string
+	// Snapshot is the full depth, which replaces the whole book
+	Snapshot bool
+	FirstSeq uint64
+	LastSeq  uint64
+	PrevSeq  uint64
+	Time     time.Time
+	Bids     []DepthLevel // buy, price descending
+	Asks     []DepthLevel // sell, price ascending
+}
+
+// DepthHandler is the handler of depth subscription
+type DepthHandler func(update DepthUpdate)
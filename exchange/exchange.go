@@ -20,7 +20,8 @@ type RestAPIExchange interface {
 	CandleBySize(symbol string, period time.Duration, size int) (hs.Candle, error)
 	CandleFrom(symbol, clientId string, period time.Duration, from, to time.Time) (hs.Candle, error)
 
-	//PlaceOrder(orderType, symbol, clientOrderId string, price, amount decimal.Decimal) (uint64, error)
+	// PlaceOrder places limit order with options, returns ErrUnsupportedOption if any option is not supported
+	PlaceOrder(symbol, clientOrderId string, side OrderType, price, amount decimal.Decimal, options OrderOptions) (orderId uint64, err error)
 	BuyLimit(symbol, clientOrderId string, price, amount decimal.Decimal) (orderId uint64, err error)
 	SellLimit(symbol, clientOrderId string, price, amount decimal.Decimal) (orderId uint64, err error)
 	BuyMarket(symbol Symbol, clientOrderId string, total decimal.Decimal) (orderId uint64, err error)
@@ -33,6 +34,35 @@ type RestAPIExchange interface {
 	IsFullFilled(symbol string, orderId uint64) (Order, bool, error)
 }
 
+// RestAPIExchangeV2 is the context-aware version of RestAPIExchange.
+// Every call takes a context, and order placement returns the full Order instead of the id.
+// Legacy implementations can be wrapped by NewRestAPIAdapter.
+type RestAPIExchangeV2 interface {
+	FormatSymbol(base, quote string) string
+	AllSymbols(ctx context.Context) ([]Symbol, error)
+	GetSymbol(ctx context.Context, symbol string) (Symbol, error)
+	GetFee(ctx context.Context, symbol string) (Fee, error)
+	SpotBalance(ctx context.Context) (map[string]decimal.Decimal, error)
+	SpotAvailableBalance(ctx context.Context) (map[string]decimal.Decimal, error)
+	LastPrice(ctx context.Context, symbol string) (decimal.Decimal, error)
+	Last24hVolume(ctx context.Context, symbol string) (decimal.Decimal, error)
+	CandleBySize(ctx context.Context, symbol string, period time.Duration, size int) (hs.Candle, error)
+	CandleFrom(ctx context.Context, symbol, clientId string, period time.Duration, from, to time.Time) (hs.Candle, error)
+
+	// PlaceOrder places limit order with options, returns ErrUnsupportedOption if any option is not supported
+	PlaceOrder(ctx context.Context, symbol, clientOrderId string, side OrderType, price, amount decimal.Decimal, options OrderOptions) (Order, error)
+	BuyLimit(ctx context.Context, symbol, clientOrderId string, price, amount decimal.Decimal) (Order, error)
+	SellLimit(ctx context.Context, symbol, clientOrderId string, price, amount decimal.Decimal) (Order, error)
+	BuyMarket(ctx context.Context, symbol Symbol, clientOrderId string, total decimal.Decimal) (Order, error)
+	SellMarket(ctx context.Context, symbol Symbol, clientOrderId string, amount decimal.Decimal) (Order, error)
+	BuyStopLimit(ctx context.Context, symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (Order, error)
+	SellStopLimit(ctx context.Context, symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (Order, error)
+
+	GetOrder(ctx context.Context, symbol string, orderId uint64) (Order, error)
+	CancelOrder(ctx context.Context, symbol string, orderId uint64) (Order, error)
+	IsFullFilled(ctx context.Context, symbol string, orderId uint64) (Order, bool, error)
+}
+
 type ResponseHandler func(response interface{})
 
 type WsAPIExchange interface {
@@ -50,6 +80,33 @@ type Exchange interface {
 	WsAPIExchange
 }
 
+// FuturesExchange is the common interface of perpetual futures, settled in one currency.
+type FuturesExchange interface {
+	Contracts(ctx context.Context) ([]Contract, error)
+	Contract(ctx context.Context, contract string) (Contract, error)
+	FuturesDepth(ctx context.Context, contract string, limit int) (FuturesOrderbook, error)
+	// FuturesTrades returns the latest public trades
+	FuturesTrades(ctx context.Context, contract string, limit int) ([]FuturesTrade, error)
+	FuturesCandles(ctx context.Context, contract string, period time.Duration, size int) (hs.Candle, error)
+
+	FuturesAccount(ctx context.Context) (FuturesBalance, error)
+	Positions(ctx context.Context) ([]Position, error)
+	Position(ctx context.Context, contract string) (Position, error)
+	// SetLeverage, 0 means cross margin
+	SetLeverage(ctx context.Context, contract string, leverage int) (Position, error)
+	// AdjustMargin adds change to position margin, negative to remove
+	AdjustMargin(ctx context.Context, contract string, change decimal.Decimal) (Position, error)
+	SwitchDualMode(ctx context.Context, dual bool) (FuturesBalance, error)
+
+	// PlaceFuturesOrder places order, size > 0 to buy, size < 0 to sell, price 0 with tif "ioc" for market order
+	PlaceFuturesOrder(ctx context.Context, order FuturesOrder) (FuturesOrder, error)
+	CancelFuturesOrder(ctx context.Context, contract string, orderId uint64) (FuturesOrder, error)
+	// CancelFuturesOrders cancels all open orders of contract
+	CancelFuturesOrders(ctx context.Context, contract string) ([]FuturesOrder, error)
+	GetFuturesOrder(ctx context.Context, contract string, orderId uint64) (FuturesOrder, error)
+	OpenFuturesOrders(ctx context.Context, contract string) ([]FuturesOrder, error)
+}
+
 type Balance struct {
 	Currency  string
 	Available decimal.Decimal
@@ -275,3 +275,56 @@ type FuturesOrder struct {
 	// Reference user ID
 	Reference uint64 `json:"reference,omitempty"`
 }
+
+// 价格触发条件
+const (
+	// StrategyType
+	TriggerStrategyPrice    = 0 // 价格触发
+	TriggerStrategyPriceGap = 1 // 价差触发
+
+	// PriceType
+	TriggerPriceLast  = 0 // 最新成交价
+	TriggerPriceMark  = 1 // 标记价格
+	TriggerPriceIndex = 2 // 指数价格
+
+	// Rule
+	TriggerRuleGTE = 1 // price >= trigger price
+	TriggerRuleLTE = 2 // price <= trigger price
+)
+
+// FuturesPriceTrigger is the condition of price-triggered order
+type FuturesPriceTrigger struct {
+	// How the order will be triggered, 0 - by price, 1 - by price gap
+	StrategyType int `json:"strategy_type"`
+	// Price type, 0 - latest deal price, 1 - mark price, 2 - index price
+	PriceType int `json:"price_type"`
+	// Trigger price, or price gap when StrategyType is 1
+	Price decimal.Decimal `json:"price"`
+	// 1 - price >= trigger price, 2 - price <= trigger price
+	Rule int `json:"rule"`
+	// How many seconds to wait for the condition, cancelled if not triggered. 0 means never expire
+	Expiration int `json:"expiration,omitempty"`
+}
+
+// FuturesPriceTriggeredOrder, 条件单，触发后按 Initial 下单。
+type FuturesPriceTriggeredOrder struct {
+	// Order placed when triggered, only Contract, Size, Price, Close, Tif, Text and ReduceOnly are used
+	Initial FuturesOrder        `json:"initial"`
+	Trigger FuturesPriceTrigger `json:"trigger"`
+	// Auto order ID
+	Id uint64 `json:"id,omitempty"`
+	// User ID
+	User uint64 `json:"user,omitempty"`
+	// Creation time
+	CreateTime time.Time `json:"create_time,omitempty"`
+	// Finished time
+	FinishTime time.Time `json:"finish_time,omitempty"`
+	// ID of the newly created order on condition triggered
+	TradeId uint64 `json:"trade_id,omitempty"`
+	// Order status, open, finished, inactive or invalid
+	Status string `json:"status,omitempty"`
+	// How order is finished, cancelled, succeeded or failed
+	FinishAs string `json:"finish_as,omitempty"`
+	// Additional remarks on how the order was finished
+	Reason string `json:"reason,omitempty"`
+}
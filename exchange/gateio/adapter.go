@@ -0,0 +1,21 @@
+package gateio
+
+import "github.com/xyths/hs/exchange"
+
+// the legacy clients are exchange.RestAPIExchange, and used as exchange.RestAPIExchangeV2 through adapters
+var (
+	_ exchange.RestAPIExchange   = (*GateIO)(nil)
+	_ exchange.RestAPIExchange   = (*V2)(nil)
+	_ exchange.RestAPIExchangeV2 = NewGateIOAdapter(nil)
+	_ exchange.RestAPIExchangeV2 = NewV2Adapter(nil)
+)
+
+// NewGateIOAdapter wraps GateIO as exchange.RestAPIExchangeV2
+func NewGateIOAdapter(g *GateIO) *exchange.RestAPIAdapter {
+	return exchange.NewRestAPIAdapter(g)
+}
+
+// NewV2Adapter wraps V2 as exchange.RestAPIExchangeV2
+func NewV2Adapter(g *V2) *exchange.RestAPIAdapter {
+	return exchange.NewRestAPIAdapter(g)
+}
@@ -14,6 +14,7 @@ const (
 	OrderTypeGTC    = "gtc"
 	OrderTypeIOC    = "ioc"
 	OrderTypePOC    = "poc"
+	OrderTypeFOK    = "fok" // api v4 only
 )
 
 const (
@@ -25,6 +26,14 @@ const (
 	OrderTypeSell = "sell"
 )
 
+// futures
+const (
+	DefaultSettle = "usdt"
+
+	FuturesOrderStatusOpen     = "open"
+	FuturesOrderStatusFinished = "finished"
+)
+
 const (
 	WsIntervalSecond  = 5
 	WsReconnectSecond = 60
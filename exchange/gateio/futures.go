@@ -12,6 +12,7 @@ import (
 	"github.com/xyths/hs/convert"
 	"github.com/xyths/hs/exchange"
 	"go.uber.org/zap"
+	"net/http"
 	"strconv"
 	"time"
 )
@@ -19,6 +20,8 @@ import (
 type Futures struct {
 	Key    string
 	Secret string
+	// Settle is the settle currency used by exchange.FuturesExchange methods, "usdt" or "btc"
+	Settle string
 	client *gateapi.APIClient
 	wsHost string
 	wsPath string
@@ -26,9 +29,16 @@ type Futures struct {
 	Logger *zap.SugaredLogger
 }
 
+var _ exchange.FuturesExchange = (*Futures)(nil)
+
+// max orders of one BatchOrders request
+const maxBatchOrders = 10
+
+var ErrTooManyOrders = fmt.Errorf("batch orders are at most %d", maxBatchOrders)
+
 func NewFutures(key, secret, host string, logger *zap.SugaredLogger) *Futures {
 	client := gateapi.NewAPIClient(gateapi.NewConfiguration())
-	return &Futures{Key: key, Secret: secret, client: client, wsHost: host, wsPath: "/v4", Logger: logger}
+	return &Futures{Key: key, Secret: secret, Settle: DefaultSettle, client: client, wsHost: host, wsPath: "/v4", Logger: logger}
 }
 
 // class function layout
@@ -156,7 +166,11 @@ func (f *Futures) CommonLiquidation(ctx context.Context, settle, contract string
 }
 
 func (f *Futures) ListFuturesAccounts(ctx context.Context, settle string) (exchange.FuturesBalance, error) {
-	raw, _, err := f.client.FuturesApi.ListFuturesAccounts(ctx, settle)
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.ListFuturesAccounts(ctx2, settle)
 	if err != nil {
 		return exchange.FuturesBalance{}, err
 	}
@@ -165,7 +179,11 @@ func (f *Futures) ListFuturesAccounts(ctx context.Context, settle string) (excha
 
 // 设置持仓模式
 func (f *Futures) SetDualMode(ctx context.Context, settle string, newDualMode bool) (exchange.FuturesBalance, error) {
-	raw, _, err := f.client.FuturesApi.SetDualMode(ctx, settle, newDualMode)
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.SetDualMode(ctx2, settle, newDualMode)
 	if err != nil {
 		return exchange.FuturesBalance{}, err
 	}
@@ -173,7 +191,11 @@ func (f *Futures) SetDualMode(ctx context.Context, settle string, newDualMode bo
 }
 
 func (f *Futures) ListPositions(ctx context.Context, settle string) ([]exchange.Position, error) {
-	rawList, _, err := f.client.FuturesApi.ListPositions(ctx, settle)
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	rawList, _, err := f.client.FuturesApi.ListPositions(ctx2, settle)
 	if err != nil {
 		return nil, err
 	}
@@ -185,7 +207,11 @@ func (f *Futures) ListPositions(ctx context.Context, settle string) ([]exchange.
 }
 
 func (f *Futures) GetPosition(ctx context.Context, settle, contract string) (exchange.Position, error) {
-	raw, _, err := f.client.FuturesApi.GetPosition(ctx, settle, contract)
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.GetPosition(ctx2, settle, contract)
 	if err != nil {
 		return exchange.Position{}, err
 	}
@@ -193,7 +219,11 @@ func (f *Futures) GetPosition(ctx context.Context, settle, contract string) (exc
 }
 
 func (f *Futures) AddMargin(ctx context.Context, settle, contract string, margin decimal.Decimal) (exchange.Position, error) {
-	raw, _, err := f.client.FuturesApi.UpdatePositionMargin(ctx, settle, contract, margin.String())
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.UpdatePositionMargin(ctx2, settle, contract, margin.String())
 	if err != nil {
 		return exchange.Position{}, err
 	}
@@ -202,7 +232,11 @@ func (f *Futures) AddMargin(ctx context.Context, settle, contract string, margin
 
 // 更新头寸杠杆
 func (f *Futures) UpdateLeverage(ctx context.Context, settle, contract string, newLeverage int) (exchange.Position, error) {
-	raw, _, err := f.client.FuturesApi.UpdatePositionLeverage(ctx, settle, contract, strconv.Itoa(newLeverage))
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.UpdatePositionLeverage(ctx2, settle, contract, strconv.Itoa(newLeverage))
 	if err != nil {
 		return exchange.Position{}, err
 	}
@@ -211,7 +245,11 @@ func (f *Futures) UpdateLeverage(ctx context.Context, settle, contract string, n
 
 // 更新头寸风险限额
 func (f *Futures) UpdateRiskLimit(ctx context.Context, settle, contract string, newRiskLimit int) (exchange.Position, error) {
-	raw, _, err := f.client.FuturesApi.UpdatePositionRiskLimit(ctx, settle, contract, strconv.Itoa(newRiskLimit))
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.UpdatePositionRiskLimit(ctx2, settle, contract, strconv.Itoa(newRiskLimit))
 	if err != nil {
 		return exchange.Position{}, err
 	}
@@ -219,7 +257,11 @@ func (f *Futures) UpdateRiskLimit(ctx context.Context, settle, contract string,
 }
 
 func (f *Futures) GetDualPosition(ctx context.Context, settle string, contract string) ([]exchange.Position, error) {
-	raw, _, err := f.client.FuturesApi.GetDualModePosition(ctx, settle, contract)
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.GetDualModePosition(ctx2, settle, contract)
 	if err != nil {
 		return nil, err
 	}
@@ -227,7 +269,11 @@ func (f *Futures) GetDualPosition(ctx context.Context, settle string, contract s
 }
 
 func (f *Futures) AddDualMargin(ctx context.Context, settle, contract string, margin decimal.Decimal) ([]exchange.Position, error) {
-	raw, _, err := f.client.FuturesApi.UpdateDualModePositionMargin(ctx, settle, contract, margin.String())
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.UpdateDualModePositionMargin(ctx2, settle, contract, margin.String())
 	if err != nil {
 		return nil, err
 	}
@@ -236,7 +282,11 @@ func (f *Futures) AddDualMargin(ctx context.Context, settle, contract string, ma
 
 // 更新双仓模式下的头寸杠杆
 func (f *Futures) UpdateDualLeverage(ctx context.Context, settle, contract string, newLeverage int) ([]exchange.Position, error) {
-	raw, _, err := f.client.FuturesApi.UpdateDualModePositionLeverage(ctx, settle, contract, strconv.Itoa(newLeverage))
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.UpdateDualModePositionLeverage(ctx2, settle, contract, strconv.Itoa(newLeverage))
 	if err != nil {
 		return nil, err
 	}
@@ -245,9 +295,300 @@ func (f *Futures) UpdateDualLeverage(ctx context.Context, settle, contract strin
 
 // 更新双仓模式下的头寸风险限额
 func (f *Futures) UpdateDualRiskLimit(ctx context.Context, settle, contract string, newRiskLimit int) ([]exchange.Position, error) {
-	raw, _, err := f.client.FuturesApi.UpdateDualModePositionRiskLimit(ctx, settle, contract, strconv.Itoa(newRiskLimit))
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.UpdateDualModePositionRiskLimit(ctx2, settle, contract, strconv.Itoa(newRiskLimit))
 	if err != nil {
 		return nil, err
 	}
 	return convertDualPosition(raw), err
-}
\ No newline at end of file
+}
+
+// CreateOrder places a futures order, size > 0 to buy, size < 0 to sell, price 0 with tif "ioc" for market order
+func (f *Futures) CreateOrder(ctx context.Context, settle string, order exchange.FuturesOrder) (exchange.FuturesOrder, error) {
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.CreateFuturesOrder(ctx2, settle, toFuturesOrder(order))
+	if err != nil {
+		return exchange.FuturesOrder{}, err
+	}
+	return convertFuturesOrder(raw), nil
+}
+
+// ListOrders lists futures orders, status is "open" or "finished"
+func (f *Futures) ListOrders(ctx context.Context, settle, contract, status string, limit, offset int) ([]exchange.FuturesOrder, error) {
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	opts := gateapi.ListFuturesOrdersOpts{}
+	if limit > 0 {
+		opts.Limit = optional.NewInt32(int32(limit))
+	}
+	if offset > 0 {
+		opts.Offset = optional.NewInt32(int32(offset))
+	}
+	rawList, _, err := f.client.FuturesApi.ListFuturesOrders(ctx2, settle, contract, status, &opts)
+	if err != nil {
+		return nil, err
+	}
+	var orders []exchange.FuturesOrder
+	for _, o := range rawList {
+		orders = append(orders, convertFuturesOrder(o))
+	}
+	return orders, nil
+}
+
+func (f *Futures) GetOrder(ctx context.Context, settle string, orderId uint64) (exchange.FuturesOrder, error) {
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.GetFuturesOrder(ctx2, settle, strconv.FormatUint(orderId, 10))
+	if err != nil {
+		return exchange.FuturesOrder{}, err
+	}
+	return convertFuturesOrder(raw), nil
+}
+
+func (f *Futures) CancelOrder(ctx context.Context, settle string, orderId uint64) (exchange.FuturesOrder, error) {
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.CancelFuturesOrder(ctx2, settle, strconv.FormatUint(orderId, 10))
+	if err != nil {
+		return exchange.FuturesOrder{}, err
+	}
+	return convertFuturesOrder(raw), nil
+}
+
+// CancelOrders cancels all open orders of contract, side is "ask", "bid" or "" for both
+func (f *Futures) CancelOrders(ctx context.Context, settle, contract, side string) ([]exchange.FuturesOrder, error) {
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	opts := gateapi.CancelFuturesOrdersOpts{}
+	if side != "" {
+		opts.Side = optional.NewString(side)
+	}
+	rawList, _, err := f.client.FuturesApi.CancelFuturesOrders(ctx2, settle, contract, &opts)
+	if err != nil {
+		return nil, err
+	}
+	var orders []exchange.FuturesOrder
+	for _, o := range rawList {
+		orders = append(orders, convertFuturesOrder(o))
+	}
+	return orders, nil
+}
+
+// AmendOrder changes the size or price of an open order, zero size or price means no change.
+// Size is the new total size, include the filled part.
+func (f *Futures) AmendOrder(ctx context.Context, settle string, orderId uint64, size int64, price decimal.Decimal) (exchange.FuturesOrder, error) {
+	body := struct {
+		Size  int64  `json:"size,omitempty"`
+		Price string `json:"price,omitempty"`
+	}{Size: size}
+	if !price.IsZero() {
+		body.Price = price.String()
+	}
+	var raw gateapi.FuturesOrder
+	path := fmt.Sprintf("/futures/%s/orders/%d", settle, orderId)
+	if err := signedRequest(ctx, f.client, f.Key, f.Secret, http.MethodPut, path, nil, body, &raw); err != nil {
+		return exchange.FuturesOrder{}, err
+	}
+	return convertFuturesOrder(raw), nil
+}
+
+// BatchOrderResult is the result of each order in BatchOrders
+type BatchOrderResult struct {
+	exchange.FuturesOrder
+	Succeeded bool
+	Label     string // error label if failed
+	Message   string // error message if failed
+}
+
+// BatchOrders places at most 10 orders in one request,
+// the order is placed or failed independently, see the results in the same sequence of orders.
+func (f *Futures) BatchOrders(ctx context.Context, settle string, orders []exchange.FuturesOrder) ([]BatchOrderResult, error) {
+	if len(orders) > maxBatchOrders {
+		return nil, ErrTooManyOrders
+	}
+	var body []gateapi.FuturesOrder
+	for _, o := range orders {
+		body = append(body, toFuturesOrder(o))
+	}
+	var rawList []struct {
+		gateapi.FuturesOrder
+		Succeeded bool   `json:"succeeded"`
+		Label     string `json:"label"`
+		Message   string `json:"message"`
+	}
+	path := fmt.Sprintf("/futures/%s/batch_orders", settle)
+	if err := signedRequest(ctx, f.client, f.Key, f.Secret, http.MethodPost, path, nil, body, &rawList); err != nil {
+		return nil, err
+	}
+	var results []BatchOrderResult
+	for _, r := range rawList {
+		results = append(results, BatchOrderResult{
+			FuturesOrder: convertFuturesOrder(r.FuturesOrder),
+			Succeeded:    r.Succeeded,
+			Label:        r.Label,
+			Message:      r.Message,
+		})
+	}
+	return results, nil
+}
+
+// CreatePriceTriggeredOrder places a conditional order, returns the auto order id
+func (f *Futures) CreatePriceTriggeredOrder(ctx context.Context, settle string, order exchange.FuturesPriceTriggeredOrder) (uint64, error) {
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	resp, _, err := f.client.FuturesApi.CreatePriceTriggeredOrder(ctx2, settle, toPriceTriggeredOrder(order))
+	if err != nil {
+		return 0, err
+	}
+	return uint64(resp.Id), nil
+}
+
+// ListPriceTriggeredOrders lists conditional orders, status is "open" or "finished", contract "" for all
+func (f *Futures) ListPriceTriggeredOrders(ctx context.Context, settle, contract, status string, limit, offset int) ([]exchange.FuturesPriceTriggeredOrder, error) {
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	opts := gateapi.ListPriceTriggeredOrdersOpts{}
+	if contract != "" {
+		opts.Contract = optional.NewString(contract)
+	}
+	if limit > 0 {
+		opts.Limit = optional.NewInt32(int32(limit))
+	}
+	if offset > 0 {
+		opts.Offset = optional.NewInt32(int32(offset))
+	}
+	rawList, _, err := f.client.FuturesApi.ListPriceTriggeredOrders(ctx2, settle, status, &opts)
+	if err != nil {
+		return nil, err
+	}
+	var orders []exchange.FuturesPriceTriggeredOrder
+	for _, o := range rawList {
+		orders = append(orders, convertPriceTriggeredOrder(o))
+	}
+	return orders, nil
+}
+
+func (f *Futures) GetPriceTriggeredOrder(ctx context.Context, settle string, orderId uint64) (exchange.FuturesPriceTriggeredOrder, error) {
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.GetPriceTriggeredOrder(ctx2, settle, strconv.FormatUint(orderId, 10))
+	if err != nil {
+		return exchange.FuturesPriceTriggeredOrder{}, err
+	}
+	return convertPriceTriggeredOrder(raw), nil
+}
+
+func (f *Futures) CancelPriceTriggeredOrder(ctx context.Context, settle string, orderId uint64) (exchange.FuturesPriceTriggeredOrder, error) {
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	raw, _, err := f.client.FuturesApi.CancelPriceTriggeredOrder(ctx2, settle, strconv.FormatUint(orderId, 10))
+	if err != nil {
+		return exchange.FuturesPriceTriggeredOrder{}, err
+	}
+	return convertPriceTriggeredOrder(raw), nil
+}
+
+// CancelPriceTriggeredOrders cancels all open conditional orders of contract
+func (f *Futures) CancelPriceTriggeredOrders(ctx context.Context, settle, contract string) ([]exchange.FuturesPriceTriggeredOrder, error) {
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    f.Key,
+		Secret: f.Secret,
+	})
+	rawList, _, err := f.client.FuturesApi.CancelPriceTriggeredOrderList(ctx2, settle, contract)
+	if err != nil {
+		return nil, err
+	}
+	var orders []exchange.FuturesPriceTriggeredOrder
+	for _, o := range rawList {
+		orders = append(orders, convertPriceTriggeredOrder(o))
+	}
+	return orders, nil
+}
+
+// exchange.FuturesExchange, all in Settle currency
+
+func (f *Futures) Contracts(ctx context.Context) ([]exchange.Contract, error) {
+	return f.ListContracts(ctx, f.Settle)
+}
+
+func (f *Futures) Contract(ctx context.Context, contract string) (exchange.Contract, error) {
+	return f.GetContract(ctx, f.Settle, contract)
+}
+
+func (f *Futures) FuturesDepth(ctx context.Context, contract string, limit int) (exchange.FuturesOrderbook, error) {
+	return f.Orderbook(ctx, f.Settle, contract, limit, 0)
+}
+
+func (f *Futures) FuturesTrades(ctx context.Context, contract string, limit int) ([]exchange.FuturesTrade, error) {
+	return f.ListTrades(ctx, f.Settle, contract, limit, 0, 0)
+}
+
+func (f *Futures) FuturesCandles(ctx context.Context, contract string, period time.Duration, size int) (hs.Candle, error) {
+	return f.Candle(ctx, f.Settle, contract, 0, 0, size, period)
+}
+
+func (f *Futures) FuturesAccount(ctx context.Context) (exchange.FuturesBalance, error) {
+	return f.ListFuturesAccounts(ctx, f.Settle)
+}
+
+func (f *Futures) Positions(ctx context.Context) ([]exchange.Position, error) {
+	return f.ListPositions(ctx, f.Settle)
+}
+
+func (f *Futures) Position(ctx context.Context, contract string) (exchange.Position, error) {
+	return f.GetPosition(ctx, f.Settle, contract)
+}
+
+func (f *Futures) SetLeverage(ctx context.Context, contract string, leverage int) (exchange.Position, error) {
+	return f.UpdateLeverage(ctx, f.Settle, contract, leverage)
+}
+
+func (f *Futures) AdjustMargin(ctx context.Context, contract string, change decimal.Decimal) (exchange.Position, error) {
+	return f.AddMargin(ctx, f.Settle, contract, change)
+}
+
+func (f *Futures) SwitchDualMode(ctx context.Context, dual bool) (exchange.FuturesBalance, error) {
+	return f.SetDualMode(ctx, f.Settle, dual)
+}
+
+func (f *Futures) PlaceFuturesOrder(ctx context.Context, order exchange.FuturesOrder) (exchange.FuturesOrder, error) {
+	return f.CreateOrder(ctx, f.Settle, order)
+}
+
+func (f *Futures) CancelFuturesOrder(ctx context.Context, contract string, orderId uint64) (exchange.FuturesOrder, error) {
+	return f.CancelOrder(ctx, f.Settle, orderId)
+}
+
+func (f *Futures) CancelFuturesOrders(ctx context.Context, contract string) ([]exchange.FuturesOrder, error) {
+	return f.CancelOrders(ctx, f.Settle, contract, "")
+}
+
+func (f *Futures) GetFuturesOrder(ctx context.Context, contract string, orderId uint64) (exchange.FuturesOrder, error) {
+	return f.GetOrder(ctx, f.Settle, orderId)
+}
+
+func (f *Futures) OpenFuturesOrders(ctx context.Context, contract string) ([]exchange.FuturesOrder, error) {
+	return f.ListOrders(ctx, f.Settle, contract, FuturesOrderStatusOpen, 0, 0)
+}
@@ -4,8 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -123,3 +128,74 @@ func TestFutures_CommonLiquidation(t *testing.T) {
 		t.Logf("[%d]\n%s", i, string(b))
 	}
 }
+
+func TestFutures_BatchOrders(t *testing.T) {
+	type request struct {
+		path, key, sign, wantSign string
+		body                      []byte
+	}
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- request{
+			path:     r.URL.Path,
+			key:      r.Header.Get("KEY"),
+			sign:     r.Header.Get("SIGN"),
+			wantSign: sign("secret", r.Method, r.URL.Path, r.URL.RawQuery, body, r.Header.Get("Timestamp")),
+			body:     body,
+		}
+		_, _ = w.Write([]byte(`[
+{"succeeded":true,"id":15675394,"contract":"BTC_USDT","size":1,"price":"9000","status":"open","create_time":1546569968.514,"tif":"gtc","left":1,"tkfr":"0.0005","mkfr":"-0.00025"},
+{"succeeded":false,"label":"INSUFFICIENT_AVAILABLE","message":"balance not enough"}]`))
+	}))
+	defer server.Close()
+
+	f := NewFutures("key", "secret", "", nil)
+	f.client.GetConfig().BasePath = server.URL + "/api/v4"
+	results, err := f.BatchOrders(context.Background(), "usdt", []exchange.FuturesOrder{
+		{Contract: "BTC_USDT", Size: 1, Price: decimal.NewFromInt(9000), Tif: "gtc"},
+		{Contract: "BTC_USDT", Size: -1, Price: decimal.NewFromInt(20000), Tif: "gtc"},
+	})
+	require.NoError(t, err)
+
+	r := <-requests
+	require.Equal(t, "/api/v4/futures/usdt/batch_orders", r.path)
+	require.Equal(t, "key", r.key)
+	require.Equal(t, r.wantSign, r.sign)
+	var orders []map[string]interface{}
+	require.NoError(t, json.Unmarshal(r.body, &orders))
+	require.Len(t, orders, 2)
+	require.Equal(t, "BTC_USDT", orders[0]["contract"])
+
+	require.Len(t, results, 2)
+	require.True(t, results[0].Succeeded)
+	require.Equal(t, uint64(15675394), results[0].Id)
+	require.True(t, decimal.NewFromInt(9000).Equal(results[0].Price))
+	require.Equal(t, 0.0005, results[0].TakerFee)
+	require.Equal(t, int64(1546569968), results[0].CreateTime.Unix())
+	require.False(t, results[1].Succeeded)
+	require.Equal(t, "INSUFFICIENT_AVAILABLE", results[1].Label)
+
+	_, err = f.BatchOrders(context.Background(), "usdt", make([]exchange.FuturesOrder, maxBatchOrders+1))
+	require.Equal(t, ErrTooManyOrders, err)
+	require.Empty(t, requests)
+}
+
+func TestFutures_AmendOrderError(t *testing.T) {
+	requests := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Method + " " + r.URL.Path
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"label":"ORDER_NOT_FOUND","message":"order not found"}`))
+	}))
+	defer server.Close()
+
+	f := NewFutures("key", "secret", "", nil)
+	f.client.GetConfig().BasePath = server.URL + "/api/v4"
+	_, err := f.AmendOrder(context.Background(), "usdt", 123, 0, decimal.NewFromInt(100))
+	require.Equal(t, "PUT /api/v4/futures/usdt/orders/123", <-requests)
+	require.Error(t, err)
+	apiErr, ok := err.(ApiError)
+	require.True(t, ok)
+	require.Equal(t, "ORDER_NOT_FOUND", apiErr.Label)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/xyths/hs"
 	"github.com/xyths/hs/convert"
 	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
 	"go.uber.org/zap"
 	"io/ioutil"
 	"log"
@@ -36,10 +37,25 @@ type GateIO struct {
 
 	host   string
 	wsPath string
+	subs   *exchange.Subscriptions
+
+	recorder *base.Recorder
+	triggers *TriggerEngine
 
 	Logger *zap.SugaredLogger
 }
 
+// SetRecorder records the raw messages of all websocket connections created after, nil to stop
+func (g *GateIO) SetRecorder(recorder *base.Recorder) {
+	g.recorder = recorder
+}
+
+// SetTriggerEngine enables BuyStopLimit and SellStopLimit, api v2 has no stop-limit order.
+// Remember to call Watch of the engine to feed the trade price.
+func (g *GateIO) SetTriggerEngine(engine *TriggerEngine) {
+	g.triggers = engine
+}
+
 func (g *GateIO) SubscribeOrder(symbol, clientId string, responseHandler exchange.ResponseHandler) {
 	g.SubOrder(symbol, clientId, responseHandler)
 }
@@ -65,7 +81,7 @@ func (g *GateIO) UnsubscribeCandlestickWithReq(symbol, clientId string, period t
 }
 
 func New(key, secret, host string, logger *zap.SugaredLogger) *GateIO {
-	g := &GateIO{Key: key, Secret: secret, wsPath: WsPathV4, Logger: logger}
+	g := &GateIO{Key: key, Secret: secret, wsPath: WsPathV4, subs: exchange.NewSubscriptions(), Logger: logger}
 	if host == "" {
 		host = DefaultHost
 	}
@@ -75,6 +91,14 @@ func New(key, secret, host string, logger *zap.SugaredLogger) *GateIO {
 	return g
 }
 
+var _ exchange.StreamAPIExchange = (*GateIO)(nil)
+
+func init() {
+	exchange.Register(hs.GateIO, func(conf hs.ExchangeConf, logger *zap.SugaredLogger) (exchange.Exchange, error) {
+		return New(conf.Key, conf.Secret, conf.Host, logger), nil
+	})
+}
+
 const (
 	GET  = "GET"
 	POST = "POST"
@@ -381,6 +405,29 @@ func (g *GateIO) BuyLimit(symbol, text string, price, amount decimal.Decimal) (o
 	return resp.OrderNumber, nil
 }
 
+// PlaceOrder places limit order, api v2 supports gtc, ioc and poc (post-only) only
+func (g *GateIO) PlaceOrder(symbol, clientOrderId string, side exchange.OrderType, price, amount decimal.Decimal, options exchange.OrderOptions) (orderId uint64, err error) {
+	if err = v2OrderOptions.Check(options, amount); err != nil {
+		return
+	}
+	var resp ResponseOrder
+	switch side {
+	case exchange.Buy:
+		resp, err = g.BuyOrder(symbol, price, amount, timeInForce(options.TIF()), clientOrderId)
+	case exchange.Sell:
+		resp, err = g.SellOrder(symbol, price, amount, timeInForce(options.TIF()), clientOrderId)
+	default:
+		return 0, exchange.ErrBadSide
+	}
+	if err != nil {
+		return 0, err
+	}
+	if resp.Result == "false" || resp.OrderNumber == 0 {
+		return 0, errors.New(resp.Message)
+	}
+	return resp.OrderNumber, nil
+}
+
 func (g *GateIO) BuyOrder(symbol string, price, amount decimal.Decimal, orderType, text string) (resp ResponseOrder, err error) {
 	url := "/private/buy"
 	param := fmt.Sprintf("currencyPair=%s&rate=%s&amount=%s&orderType=%s&text=t-%s", symbol, price, amount, orderType, text)
@@ -388,6 +435,13 @@ func (g *GateIO) BuyOrder(symbol string, price, amount decimal.Decimal, orderTyp
 	return
 }
 
+func (g *GateIO) SellOrder(symbol string, price, amount decimal.Decimal, orderType, text string) (resp ResponseOrder, err error) {
+	url := "/private/sell"
+	param := fmt.Sprintf("currencyPair=%s&rate=%s&amount=%s&orderType=%s&text=t-%s", symbol, price, amount, orderType, text)
+	err = g.request(POST, url, param, &resp)
+	return
+}
+
 // Place order sell
 func (g *GateIO) SellLimit(symbol, text string, price, amount decimal.Decimal) (orderId uint64, err error) {
 	url := "/private/sell"
@@ -459,16 +513,23 @@ func (g *GateIO) SellFromOrderBook(symbol exchange.Symbol, clientOrderId string,
 	return g.SellLimit(symbol.Symbol, clientOrderId, price, amount)
 }
 
+// BuyStopLimit adds a trigger to the trigger engine, the returned id is the trigger id with TriggerIdFlag,
+// which can be used by GetOrderById, IsFullFilled and CancelOrder.
+// The limit order is placed when the last price >= stopPrice, see TriggerEngine.
 func (g *GateIO) BuyStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (orderId uint64, err error) {
-	return 0, nil
+	return g.triggers.Add(context.Background(), symbol, clientOrderId, exchange.Buy, price, amount, stopPrice)
 }
 
+// SellStopLimit places the limit order when the last price <= stopPrice, see BuyStopLimit.
 func (g *GateIO) SellStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (orderId uint64, err error) {
-	return 0, nil
+	return g.triggers.Add(context.Background(), symbol, clientOrderId, exchange.Sell, price, amount, stopPrice)
 }
 
 // Cancel order
 func (g *GateIO) CancelOrder(symbol string, orderNumber uint64) error {
+	if IsTriggerId(orderNumber) {
+		return g.triggers.CancelOrder(context.Background(), orderNumber)
+	}
 	url := "/private/cancelOrder"
 	param := fmt.Sprintf("currencyPair=%s&orderNumber=%d", symbol, orderNumber)
 	var res ResponseCancel
@@ -486,6 +547,9 @@ func (g *GateIO) CancelAllOrders(types string, currencyPair string) (res Respons
 }
 
 func (g *GateIO) GetOrderById(orderId uint64, symbol string) (order exchange.Order, err error) {
+	if IsTriggerId(orderId) {
+		return g.triggers.GetOrder(orderId)
+	}
 	return g.GetOrder(orderId, symbol)
 }
 
@@ -519,12 +583,14 @@ func (g *GateIO) GetOrder(orderId uint64, symbol string) (order exchange.Order,
 	order.ClientOrderId = o.Text
 	order.Symbol = o.CurrencyPair
 	order.Type = o.Type
+	order.Side = exchange.ParseSide(o.Type)
 	// 下单价格
 	order.Price = decimal.RequireFromString(o.InitialRate)
 	order.Amount = decimal.RequireFromString(o.InitialAmount)
 	order.Status = o.Status
 	order.FilledPrice = decimal.RequireFromString(o.Rate)
 	order.FilledAmount = decimal.RequireFromString(o.FilledAmount)
+	order.State = orderStatus.Normalize(order.Status, order.FilledAmount)
 	//order.FeePercentage = o.FeePercentage
 	//order.FeeValue = decimal.RequireFromString(o.FeeValue)
 	order.Time = time.Unix(o.Timestamp, 0)
@@ -533,11 +599,11 @@ func (g *GateIO) GetOrder(orderId uint64, symbol string) (order exchange.Order,
 }
 
 func (g *GateIO) IsFullFilled(symbol string, orderId uint64) (order exchange.Order, filled bool, err error) {
-	order, err = g.GetOrder(orderId, symbol)
+	order, err = g.GetOrderById(orderId, symbol)
 	if err != nil {
 		return
 	}
-	filled = order.Status == "closed"
+	filled = order.FullFilled()
 	return
 }
 
@@ -666,6 +732,7 @@ func (g *GateIO) request(method string, url string, param string, result interfa
 // always call ReqPing use context context with a timeout
 func (g *GateIO) ReqPing(ctx context.Context, id int64) (string, error) {
 	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	ch := make(chan string, 1)
 	client.SetHandler(
 		func() {
@@ -676,7 +743,10 @@ func (g *GateIO) ReqPing(ctx context.Context, id int64) (string, error) {
 			pong, ok := resp.(string)
 			if ok {
 				g.Logger.Debugf("handler got response: %s", pong)
-				ch <- pong
+				select {
+				case ch <- pong:
+				default:
+				}
 			} else {
 				g.Logger.Error("wrong response")
 			}
@@ -698,6 +768,7 @@ func (g *GateIO) ReqPing(ctx context.Context, id int64) (string, error) {
 
 func (g *GateIO) ReqTime(ctx context.Context, id int64) (int64, error) {
 	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	ch := make(chan int64, 1)
 	client.SetHandler(
 		func() {
@@ -708,7 +779,10 @@ func (g *GateIO) ReqTime(ctx context.Context, id int64) (int64, error) {
 			t, ok := resp.(int64)
 			if ok {
 				g.Logger.Debugf("handler got response: %d", t)
-				ch <- t
+				select {
+				case ch <- t:
+				default:
+				}
 			} else {
 				g.Logger.Error("wrong response")
 			}
@@ -730,6 +804,7 @@ func (g *GateIO) ReqTime(ctx context.Context, id int64) (int64, error) {
 
 func (g *GateIO) ReqTicker(ctx context.Context, id int64, symbol string, period time.Duration) (hs.Ticker, error) {
 	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	ch := make(chan hs.Ticker, 1)
 	client.SetHandler(
 		func() {
@@ -744,7 +819,10 @@ func (g *GateIO) ReqTicker(ctx context.Context, id int64, symbol string, period
 			}
 			g.Logger.Debugf("handler got raw response: %d", t1)
 			t2, _ := parseTicker(t1)
-			ch <- t2
+			select {
+			case ch <- t2:
+			default:
+			}
 		}),
 	)
 	client.Connect(true)
@@ -761,24 +839,29 @@ func (g *GateIO) ReqTicker(ctx context.Context, id int64, symbol string, period
 	}
 }
 
-func (g *GateIO) SubTicker(id int64, symbol string, responseHandler exchange.ResponseHandler) {
+func (g *GateIO) SubTicker(id int64, symbol string, responseHandler exchange.ResponseHandler) *exchange.Subscription {
 	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	client.SetHandler(
-		func() {
-			client.SubTicker(id, symbol)
-		},
+		nil,
 		client.SubTickerHandler(responseHandler),
 	)
+	client.SubTicker(id, symbol)
 	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubTicker(id)
+	})
+	g.subs.Add(subKey("ticker", symbol, fmt.Sprintf("%d", id)), sub)
+	return sub
 }
 
 func (g *GateIO) UnsubTicker(id int64, symbol string) {
-	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
-	client.UnsubTicker(id)
+	g.subs.Unsubscribe(subKey("ticker", symbol, fmt.Sprintf("%d", id)))
 }
 
 func (g *GateIO) ReqCandlestick(ctx context.Context, symbol, clientId string, period time.Duration, from, to time.Time) (hs.Candle, error) {
 	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	ch := make(chan hs.Candle, 1)
 	id := time.Now().Unix()
 	client.SetHandler(
@@ -790,7 +873,10 @@ func (g *GateIO) ReqCandlestick(ctx context.Context, symbol, clientId string, pe
 			if !ok {
 				return
 			}
-			ch <- r
+			select {
+			case ch <- r:
+			default:
+			}
 		}),
 	)
 	client.Connect(true)
@@ -806,27 +892,41 @@ func (g *GateIO) ReqCandlestick(ctx context.Context, symbol, clientId string, pe
 	}
 }
 
+// SubCandlestick pass hs.Ticker to responseHandler
 func (g *GateIO) SubCandlestick(symbol, clientId string, period time.Duration,
-	responseHandler exchange.ResponseHandler) {
+	responseHandler exchange.ResponseHandler) *exchange.Subscription {
+	return g.SubscribeCandleUpdate(symbol, clientId, period, func(update exchange.CandleUpdate) {
+		responseHandler(update.Ticker)
+	})
+}
+
+func (g *GateIO) SubscribeCandleUpdate(symbol, clientId string, period time.Duration, handler exchange.CandleHandler) *exchange.Subscription {
 	id := time.Now().Unix()
 	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	client.SetHandler(
-		func() {
-			client.SubCandle(id, symbol, int64(period.Seconds()))
-		},
-		client.SubCandleHandler(responseHandler),
+		nil,
+		client.SubCandleUpdateHandler(func(update exchange.CandleUpdate) {
+			update.Period = period
+			handler(update)
+		}),
 	)
+	client.SubCandle(id, symbol, int64(period.Seconds()))
 	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubCandle(id)
+	})
+	g.subs.Add(subKey("kline", symbol, clientId), sub)
+	return sub
 }
 
 func (g *GateIO) UnsubCandlestick(symbol, clientId string) {
-	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
-	id := time.Now().Unix()
-	client.UnsubCandle(id)
+	g.subs.Unsubscribe(subKey("kline", symbol, clientId))
 }
 
 func (g *GateIO) ReqOrder(ctx context.Context, symbol, clientId string) (orders []exchange.Order, err error) {
 	client := new(PrivateWebsocketClient).Init(g.host, g.wsPath, g.Key, g.Secret, g.Logger)
+	client.SetRecorder(g.recorder)
 	id := time.Now().Unix()
 	ch := make(chan ResponseReqOrder, 10)
 	done := make(chan int, 1)
@@ -839,16 +939,25 @@ func (g *GateIO) ReqOrder(ctx context.Context, symbol, clientId string) (orders
 		client.ReqOrderHandler(func(resp interface{}) {
 			if resp == nil {
 				g.Logger.Debug("no open order")
-				done <- 1
+				select {
+				case done <- 1:
+				default:
+				}
 				return
 			}
 			r, ok := resp.(ResponseReqOrder)
 			if !ok {
 				g.Logger.Debug("response not ok")
-				done <- 1
+				select {
+				case done <- 1:
+				default:
+				}
 				return
 			}
-			ch <- r
+			select {
+			case ch <- r:
+			default:
+			}
 		}),
 	)
 	client.Connect(true)
@@ -879,22 +988,75 @@ func (g *GateIO) ReqOrder(ctx context.Context, symbol, clientId string) (orders
 	}
 }
 
-func (g *GateIO) SubOrder(symbol, clientId string, responseHandler exchange.ResponseHandler) {
+// SubOrder pass exchange.Order to responseHandler
+func (g *GateIO) SubOrder(symbol, clientId string, responseHandler exchange.ResponseHandler) *exchange.Subscription {
+	return g.SubscribeOrderUpdate(symbol, clientId, func(order exchange.Order) {
+		responseHandler(order)
+	})
+}
+
+func (g *GateIO) SubscribeOrderUpdate(symbol, clientId string, handler exchange.OrderHandler) *exchange.Subscription {
 	id := time.Now().Unix()
 	client := new(PrivateWebsocketClient).Init(g.host, g.wsPath, g.Key, g.Secret, g.Logger)
+	client.SetRecorder(g.recorder)
 	client.SetHandler(
-		func() {
-			client.SubOrder(id, []string{symbol})
-		},
-		client.SubOrderHandler(responseHandler),
+		nil,
+		client.SubOrderUpdateHandler(handler),
 	)
+	client.SubOrder(id, []string{symbol})
 	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubOrder(id, []string{symbol})
+	})
+	g.subs.Add(subKey("order", symbol, clientId), sub)
+	return sub
 }
 
 func (g *GateIO) UnsubOrder(symbol, clientId string) {
-	client := new(PrivateWebsocketClient).Init(g.host, g.wsPath, g.Key, g.Secret, g.Logger)
+	g.subs.Unsubscribe(subKey("order", symbol, clientId))
+}
+
+func (g *GateIO) SubscribeTrade(symbol, clientId string, responseHandler exchange.TradeHandler) *exchange.Subscription {
+	id := time.Now().Unix()
+	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
+	client.SetHandler(
+		nil,
+		client.SubTradeHandler(responseHandler),
+	)
+	client.SubTrade(id, []string{symbol})
+	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubTrade(id)
+	})
+	g.subs.Add(subKey("trades", symbol, clientId), sub)
+	return sub
+}
+
+func (g *GateIO) UnsubscribeTrade(symbol, clientId string) {
+	g.subs.Unsubscribe(subKey("trades", symbol, clientId))
+}
+
+// SubscribeBalanceUpdate subscribe balance of all currencies
+func (g *GateIO) SubscribeBalanceUpdate(clientId string, handler exchange.BalanceHandler) *exchange.Subscription {
 	id := time.Now().Unix()
-	client.UnsubOrder(id, []string{symbol})
+	client := new(PrivateWebsocketClient).Init(g.host, g.wsPath, g.Key, g.Secret, g.Logger)
+	client.SetRecorder(g.recorder)
+	client.SetHandler(
+		nil,
+		client.SubBalanceUpdateHandler(handler),
+	)
+	client.SubBalance(id, nil)
+	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubBalance(id, nil)
+	})
+	g.subs.Add(subKey("balance", "", clientId), sub)
+	return sub
+}
+
+func (g *GateIO) UnsubscribeBalanceUpdate(clientId string) {
+	g.subs.Unsubscribe(subKey("balance", "", clientId))
 }
 
 func (g *GateIO) ReqBalance(ctx context.Context, currencies []string) {
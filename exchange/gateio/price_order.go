@@ -0,0 +1,230 @@
+package gateio
+
+import (
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs/convert"
+	"github.com/xyths/hs/exchange"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// PriceOrderRuleGTE triggers when the last price >= trigger price, used by buy stop-limit
+	PriceOrderRuleGTE = ">="
+	// PriceOrderRuleLTE triggers when the last price <= trigger price, used by sell stop-limit
+	PriceOrderRuleLTE = "<="
+
+	// PriceOrderExpiration is the seconds to wait for trigger, the order is cancelled after that.
+	PriceOrderExpiration = 30 * 24 * 3600
+
+	PriceOrderStatusOpen      = "open"
+	PriceOrderStatusFinished  = "finished" // used by list only, includes all the status below
+	PriceOrderStatusFinish    = "finish"   // triggered, see FiredOrderId
+	PriceOrderStatusCancelled = "cancelled"
+	PriceOrderStatusFailed    = "failed"
+	PriceOrderStatusExpired   = "expired"
+)
+
+// PriceOrderIdFlag is set in the price-triggered order id, so GetOrder, CancelOrder of SpotV4 can
+// route it to the price order api. It's not TriggerIdFlag, which is used by api v2.
+const PriceOrderIdFlag = uint64(1) << 62
+
+// IsPriceOrderId returns true if the order id is returned by the stop-limit of SpotV4
+func IsPriceOrderId(orderId uint64) bool {
+	return orderId&PriceOrderIdFlag != 0
+}
+
+// priceOrderStatus maps the status of price-triggered order, the triggered one is still Open,
+// the state of the fired limit order is resolved by GetOrder.
+var priceOrderStatus = exchange.StatusTable{
+	PriceOrderStatusOpen:      exchange.Open,
+	PriceOrderStatusFinish:    exchange.Open,
+	PriceOrderStatusCancelled: exchange.Cancelled,
+	PriceOrderStatusExpired:   exchange.Cancelled,
+	PriceOrderStatusFailed:    exchange.Rejected,
+}
+
+// PriceOrder is the spot price-triggered order, the limit order is placed when triggered.
+// The Id is with PriceOrderIdFlag, and not the id of limit order, which is FiredOrderId after triggered.
+type PriceOrder struct {
+	exchange.Order
+	StopPrice    decimal.Decimal
+	Rule         string
+	FiredOrderId uint64
+	Reason       string // the reason of failed
+}
+
+type rawPriceOrder struct {
+	Trigger struct {
+		Price      string `json:"price"`
+		Rule       string `json:"rule"`
+		Expiration int    `json:"expiration"`
+	} `json:"trigger"`
+	Put struct {
+		Type        string `json:"type"`
+		Side        string `json:"side"`
+		Price       string `json:"price"`
+		Amount      string `json:"amount"`
+		Account     string `json:"account"`
+		TimeInForce string `json:"time_in_force,omitempty"`
+	} `json:"put"`
+	Id           int64   `json:"id,omitempty"`
+	Market       string  `json:"market"`
+	Ctime        float64 `json:"ctime,omitempty"`
+	FiredOrderId int64   `json:"fired_order_id,omitempty"`
+	Status       string  `json:"status,omitempty"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+func convertPriceOrder(r rawPriceOrder) PriceOrder {
+	o := PriceOrder{
+		Order: exchange.Order{
+			Id:     uint64(r.Id) | PriceOrderIdFlag,
+			Type:   r.Put.Side + "-stop-limit",
+			Side:   exchange.ParseSide(r.Put.Side),
+			Symbol: r.Market,
+			Price:  convert.StrToDecimal(r.Put.Price),
+			Amount: convert.StrToDecimal(r.Put.Amount),
+			Time:   time.Unix(0, int64(r.Ctime*float64(time.Second))),
+			Status: r.Status,
+		},
+		StopPrice:    convert.StrToDecimal(r.Trigger.Price),
+		Rule:         r.Trigger.Rule,
+		FiredOrderId: uint64(r.FiredOrderId),
+		Reason:       r.Reason,
+	}
+	o.State = priceOrderStatus.Normalize(o.Status, decimal.Zero)
+	return o
+}
+
+// BuyStopLimit places a price-triggered order, which buys at price when the last price >= stopPrice.
+// The returned order id is the id of price-triggered order, see PriceOrder.
+// Gate doesn't keep the client order id for price-triggered order, it's only set in the returned order.
+func (g *SpotV4) BuyStopLimit(ctx context.Context, symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (exchange.Order, error) {
+	o, err := g.CreatePriceOrder(ctx, symbol, OrderTypeBuy, PriceOrderRuleGTE, price, amount, stopPrice)
+	o.ClientOrderId = clientOrderId
+	return o.Order, err
+}
+
+// SellStopLimit places a price-triggered order, which sells at price when the last price <= stopPrice, see BuyStopLimit.
+func (g *SpotV4) SellStopLimit(ctx context.Context, symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (exchange.Order, error) {
+	o, err := g.CreatePriceOrder(ctx, symbol, OrderTypeSell, PriceOrderRuleLTE, price, amount, stopPrice)
+	o.ClientOrderId = clientOrderId
+	return o.Order, err
+}
+
+// CreatePriceOrder places a price-triggered limit order, side is buy or sell, rule is PriceOrderRuleGTE or PriceOrderRuleLTE.
+func (g *SpotV4) CreatePriceOrder(ctx context.Context, symbol, side, rule string, price, amount, stopPrice decimal.Decimal) (PriceOrder, error) {
+	var body rawPriceOrder
+	body.Trigger.Price = stopPrice.String()
+	body.Trigger.Rule = rule
+	body.Trigger.Expiration = PriceOrderExpiration
+	body.Put.Type = "limit"
+	body.Put.Side = side
+	body.Put.Price = price.String()
+	body.Put.Amount = amount.String()
+	body.Put.Account = "normal"
+	body.Market = symbol
+	var resp struct {
+		Id int64 `json:"id"`
+	}
+	if err := signedRequest(ctx, g.client, g.Key, g.Secret, http.MethodPost, "/spot/price_orders", nil, body, &resp); err != nil {
+		return PriceOrder{}, err
+	}
+	body.Id = resp.Id
+	body.Ctime = float64(time.Now().Unix())
+	body.Status = PriceOrderStatusOpen
+	return convertPriceOrder(body), nil
+}
+
+// GetPriceOrder returns the price-triggered order, the PriceOrderIdFlag of orderId is optional
+func (g *SpotV4) GetPriceOrder(ctx context.Context, orderId uint64) (PriceOrder, error) {
+	var raw rawPriceOrder
+	path := fmt.Sprintf("/spot/price_orders/%d", orderId&^PriceOrderIdFlag)
+	if err := signedRequest(ctx, g.client, g.Key, g.Secret, http.MethodGet, path, nil, nil, &raw); err != nil {
+		return PriceOrder{}, err
+	}
+	return convertPriceOrder(raw), nil
+}
+
+// ListPriceOrders lists the price-triggered orders, status is PriceOrderStatusOpen or PriceOrderStatusFinished,
+// symbol "" for all.
+func (g *SpotV4) ListPriceOrders(ctx context.Context, symbol, status string, limit, offset int) ([]PriceOrder, error) {
+	query := url.Values{"status": {status}}
+	if symbol != "" {
+		query.Set("market", symbol)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	var rawList []rawPriceOrder
+	if err := signedRequest(ctx, g.client, g.Key, g.Secret, http.MethodGet, "/spot/price_orders", query, nil, &rawList); err != nil {
+		return nil, err
+	}
+	var orders []PriceOrder
+	for _, r := range rawList {
+		orders = append(orders, convertPriceOrder(r))
+	}
+	return orders, nil
+}
+
+// CancelPriceOrder cancels the open price-triggered order, see GetPriceOrder
+func (g *SpotV4) CancelPriceOrder(ctx context.Context, orderId uint64) (PriceOrder, error) {
+	var raw rawPriceOrder
+	path := fmt.Sprintf("/spot/price_orders/%d", orderId&^PriceOrderIdFlag)
+	if err := signedRequest(ctx, g.client, g.Key, g.Secret, http.MethodDelete, path, nil, nil, &raw); err != nil {
+		return PriceOrder{}, err
+	}
+	return convertPriceOrder(raw), nil
+}
+
+// getPriceOrder returns the price-triggered order as open order, or the limit order fired by it.
+// The returned order id is always the price order id, like TriggerEngine.GetOrder.
+func (g *SpotV4) getPriceOrder(ctx context.Context, orderId uint64) (exchange.Order, error) {
+	p, err := g.GetPriceOrder(ctx, orderId)
+	if err != nil || p.Status != PriceOrderStatusFinish || p.FiredOrderId == 0 {
+		return p.Order, err
+	}
+	o, err := g.GetOrder(ctx, p.Symbol, p.FiredOrderId)
+	o.Id = orderId
+	return o, err
+}
+
+// cancelPriceOrder cancels the open price-triggered order, or the limit order fired by it.
+func (g *SpotV4) cancelPriceOrder(ctx context.Context, orderId uint64) (exchange.Order, error) {
+	p, err := g.GetPriceOrder(ctx, orderId)
+	if err != nil {
+		return exchange.Order{}, err
+	}
+	if p.Status != PriceOrderStatusFinish || p.FiredOrderId == 0 {
+		p, err = g.CancelPriceOrder(ctx, orderId)
+		return p.Order, err
+	}
+	o, err := g.CancelOrder(ctx, p.Symbol, p.FiredOrderId)
+	o.Id = orderId
+	return o, err
+}
+
+// CancelPriceOrders cancels all the open price-triggered orders of symbol, "" for all
+func (g *SpotV4) CancelPriceOrders(ctx context.Context, symbol string) ([]PriceOrder, error) {
+	query := url.Values{}
+	if symbol != "" {
+		query.Set("market", symbol)
+	}
+	var rawList []rawPriceOrder
+	if err := signedRequest(ctx, g.client, g.Key, g.Secret, http.MethodDelete, "/spot/price_orders", query, nil, &rawList); err != nil {
+		return nil, err
+	}
+	var orders []PriceOrder
+	for _, r := range rawList {
+		orders = append(orders, convertPriceOrder(r))
+	}
+	return orders, nil
+}
@@ -0,0 +1,122 @@
+package gateio
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs/exchange"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSpotV4_SellStopLimit(t *testing.T) {
+	type request struct {
+		method, path, sign, wantSign string
+		body                         []byte
+	}
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- request{
+			method:   r.Method,
+			path:     r.URL.Path,
+			sign:     r.Header.Get("SIGN"),
+			wantSign: sign("secret", r.Method, r.URL.Path, r.URL.RawQuery, body, r.Header.Get("Timestamp")),
+			body:     body,
+		}
+		_, _ = w.Write([]byte(`{"id":1432329}`))
+	}))
+	defer server.Close()
+
+	g := NewSpotV4("key", "secret", "", nil)
+	g.client.GetConfig().BasePath = server.URL + "/api/v4"
+	o, err := g.SellStopLimit(context.Background(), "BTC_USDT", "sl", decimal.NewFromInt(8990), decimal.NewFromInt(1), decimal.NewFromInt(9000))
+	require.NoError(t, err)
+
+	r := <-requests
+	require.Equal(t, http.MethodPost, r.method)
+	require.Equal(t, "/api/v4/spot/price_orders", r.path)
+	require.Equal(t, r.wantSign, r.sign)
+	var order rawPriceOrder
+	require.NoError(t, json.Unmarshal(r.body, &order))
+	require.Equal(t, "BTC_USDT", order.Market)
+	require.Equal(t, "9000", order.Trigger.Price)
+	require.Equal(t, PriceOrderRuleLTE, order.Trigger.Rule)
+	require.Equal(t, "sell", order.Put.Side)
+	require.Equal(t, "8990", order.Put.Price)
+
+	require.Equal(t, uint64(1432329)|PriceOrderIdFlag, o.Id)
+	require.True(t, IsPriceOrderId(o.Id))
+	require.Equal(t, "sl", o.ClientOrderId)
+	require.Equal(t, exchange.Sell, o.Side)
+	require.Equal(t, exchange.Open, o.State)
+}
+
+func TestConvertPriceOrder(t *testing.T) {
+	raw := `{"trigger":{"price":"100","rule":">=","expiration":3600},"put":{"type":"limit","side":"buy","price":"2.15","amount":"2.00000000","account":"normal","time_in_force":"gtc"},"id":1283293,"user":1234,"market":"GT_USDT","ctime":1616397800,"ftime":1616397801,"fired_order_id":0,"status":"failed","reason":"balance not enough"}`
+	var r rawPriceOrder
+	require.NoError(t, json.Unmarshal([]byte(raw), &r))
+	o := convertPriceOrder(r)
+	require.Equal(t, uint64(1283293)|PriceOrderIdFlag, o.Id)
+	require.Equal(t, exchange.Buy, o.Side)
+	require.Equal(t, exchange.Rejected, o.State)
+	require.Equal(t, "100", o.StopPrice.String())
+	require.Equal(t, int64(1616397800), o.Time.Unix())
+	require.Equal(t, "balance not enough", o.Reason)
+}
+
+func TestSpotV4_GetPriceOrder(t *testing.T) {
+	type request struct {
+		method, path string
+	}
+	requests := make(chan request, 10)
+	status := PriceOrderStatusOpen
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- request{method: r.Method, path: r.URL.Path}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v4/spot/price_orders/1432329":
+			_, _ = w.Write([]byte(`{"trigger":{"price":"9000","rule":"<=","expiration":3600},"put":{"type":"limit","side":"sell","price":"8990","amount":"1","account":"normal"},"id":1432329,"market":"BTC_USDT","ctime":1616397800,"fired_order_id":12332324,"status":"` + status + `"}`))
+		case "/api/v4/spot/orders/12332324":
+			_, _ = w.Write([]byte(`{"id":"12332324","create_time":"1548000000","status":"closed","currency_pair":"BTC_USDT","type":"limit","account":"spot","side":"sell","amount":"1","price":"8990","left":"0"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	g := NewSpotV4("key", "secret", "", nil)
+	g.client.GetConfig().BasePath = server.URL + "/api/v4"
+	ctx := context.Background()
+	id := uint64(1432329) | PriceOrderIdFlag
+
+	// not triggered
+	o, err := g.GetOrder(ctx, "BTC_USDT", id)
+	require.NoError(t, err)
+	require.Equal(t, id, o.Id)
+	require.Equal(t, exchange.Open, o.State)
+	require.Equal(t, request{http.MethodGet, "/api/v4/spot/price_orders/1432329"}, <-requests)
+
+	o, err = g.CancelOrder(ctx, "BTC_USDT", id)
+	require.NoError(t, err)
+	require.Equal(t, request{http.MethodGet, "/api/v4/spot/price_orders/1432329"}, <-requests)
+	require.Equal(t, request{http.MethodDelete, "/api/v4/spot/price_orders/1432329"}, <-requests)
+
+	// triggered, the state is of the fired order
+	status = PriceOrderStatusFinish
+	o, filled, err := g.IsFullFilled(ctx, "BTC_USDT", id)
+	require.NoError(t, err)
+	require.True(t, filled)
+	require.Equal(t, id, o.Id)
+	require.Equal(t, request{http.MethodGet, "/api/v4/spot/price_orders/1432329"}, <-requests)
+	require.Equal(t, request{http.MethodGet, "/api/v4/spot/orders/12332324"}, <-requests)
+
+	o, err = g.CancelOrder(ctx, "BTC_USDT", id)
+	require.NoError(t, err)
+	require.Equal(t, id, o.Id)
+	require.Equal(t, request{http.MethodGet, "/api/v4/spot/price_orders/1432329"}, <-requests)
+	require.Equal(t, request{http.MethodDelete, "/api/v4/spot/orders/12332324"}, <-requests)
+}
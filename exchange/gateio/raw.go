@@ -74,3 +74,19 @@ type UpdateWsBase struct {
 	Method string      `json:"method"`
 	Params interface{} `json:"params"`
 }
+
+// ResponseWsDepth 是depth.query的结果，也是depth.update的第二个参数
+// 增量更新时，没有变化的一边可能不存在，数量为0表示删除该价格
+type ResponseWsDepth struct {
+	Asks [][2]string `json:"asks"`
+	Bids [][2]string `json:"bids"`
+}
+
+// ResponseWsTrade 在trades.query和trades.update中相同，新数据在前
+type ResponseWsTrade struct {
+	Id     int64   `json:"id"`
+	Time   float64 `json:"time"` // 秒.微秒
+	Price  string  `json:"price"`
+	Amount string  `json:"amount"`
+	Type   string  `json:"type"` // buy, sell
+}
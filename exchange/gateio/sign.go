@@ -0,0 +1,89 @@
+package gateio
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gateio/gateapi-go/v5"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ApiError is the error returned by gate v4 api
+type ApiError struct {
+	StatusCode int
+	Label      string `json:"label"`
+	Message    string `json:"message"`
+}
+
+func (e ApiError) Error() string {
+	return fmt.Sprintf("gate api error %d %s: %s", e.StatusCode, e.Label, e.Message)
+}
+
+// sign the v4 request, see https://www.gateio.pro/docs/apiv4/en/index.html#apiv4-signed-request-requirements
+func sign(secret, method, path, rawQuery string, body []byte, timestamp string) string {
+	hashed := sha512.Sum512(body)
+	payload := fmt.Sprintf("%s\n%s\n%s\n%s\n%s", method, path, rawQuery, hex.EncodeToString(hashed[:]), timestamp)
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signedRequest calls the v4 api which is not in gateapi-go,
+// path is relative to the base path of client, like "/futures/usdt/batch_orders".
+func signedRequest(ctx context.Context, client *gateapi.APIClient, key, secret, method, path string, query url.Values, body, result interface{}) error {
+	config := client.GetConfig()
+	base, err := url.Parse(config.BasePath)
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	u := *base
+	u.Path += path
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("KEY", key)
+	req.Header.Set("Timestamp", timestamp)
+	req.Header.Set("SIGN", sign(secret, method, u.Path, u.RawQuery, payload, timestamp))
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		apiErr := ApiError{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(data, &apiErr)
+		return apiErr
+	}
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
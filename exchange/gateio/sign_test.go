@@ -0,0 +1,12 @@
+package gateio
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	// example from gate v4 api document
+	sign := sign("secret", "GET", "/api/v4/futures/orders", "contract=BTC_USD&status=finished&limit=50", nil, "1541993715")
+	require.Equal(t, "55f84ea195d6fe57ce62464daaa7c3c02fa9d1dde954e4c898289c9a2407a3d6fb3faf24deff16790d726b66ac9f74526668b13bd01029199cc4fcc522418b8a", sign)
+}
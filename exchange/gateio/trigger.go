@@ -0,0 +1,333 @@
+package gateio
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoTriggerEngine = errors.New("stop-limit order needs trigger engine in api v2")
+	ErrTriggerNotFound = errors.New("trigger not found")
+	ErrBadTrigger      = errors.New("price, amount and stop price should be positive")
+)
+
+// TriggerIdFlag is set in the trigger id, so the trigger id and gate order number never collide,
+// and GetOrderById, CancelOrder of api v2 can route the trigger id to TriggerEngine.
+const TriggerIdFlag = uint64(1) << 63
+
+// maxFired is the number of fired triggers kept for query
+const maxFired = 1000
+
+const (
+	TriggerStatusTriggered = "triggered" // fired, the limit order is placing
+	TriggerStatusFailed    = "failed"    // fired, but the limit order is not placed
+)
+
+// IsTriggerId returns true if the order id is returned by the stop-limit of api v2
+func IsTriggerId(orderId uint64) bool {
+	return orderId&TriggerIdFlag != 0
+}
+
+// Trigger is a stop-limit order in TriggerEngine
+type Trigger struct {
+	Id            uint64             `json:"id"` // with TriggerIdFlag
+	ClientOrderId string             `json:"clientOrderId"`
+	Symbol        string             `json:"symbol"`
+	Side          exchange.OrderType `json:"side"`
+	Price         decimal.Decimal    `json:"price"`
+	Amount        decimal.Decimal    `json:"amount"`
+	StopPrice     decimal.Decimal    `json:"stopPrice"`
+	Time          time.Time          `json:"time"`
+
+	// OrderId is the limit order placed after fired
+	OrderId uint64 `json:"orderId,omitempty"`
+	// Error is the reason if the limit order is not placed
+	Error string `json:"error,omitempty"`
+}
+
+// order converts the pending or failed trigger to order
+func (t Trigger) order(status string, state exchange.OrderStatus) exchange.Order {
+	typ := "buy-stop-limit"
+	if t.Side == exchange.Sell {
+		typ = "sell-stop-limit"
+	}
+	return exchange.Order{
+		Id:            t.Id,
+		ClientOrderId: t.ClientOrderId,
+		Type:          typ,
+		Side:          t.Side,
+		Symbol:        t.Symbol,
+		Price:         t.Price,
+		Amount:        t.Amount,
+		Time:          t.Time,
+		Status:        status,
+		State:         state,
+	}
+}
+
+// crossed returns true if buy trigger's price rises to stop price, or sell trigger's price falls to stop price
+func (t Trigger) crossed(price decimal.Decimal) bool {
+	if t.Side == exchange.Buy {
+		return price.GreaterThanOrEqual(t.StopPrice)
+	}
+	return price.LessThanOrEqual(t.StopPrice)
+}
+
+// TriggerState is the persisted state of TriggerEngine
+type TriggerState struct {
+	NextId   uint64    `json:"nextId"`
+	Triggers []Trigger `json:"triggers"`
+	// Fired keeps the last fired triggers, for GetOrder and CancelOrder
+	Fired []Trigger `json:"fired,omitempty"`
+}
+
+// TriggerHandler is called after the limit order placed, orderId is 0 if err is not nil
+type TriggerHandler func(t Trigger, orderId uint64, err error)
+
+// TriggerEngine emulates stop-limit order on client side for api v2, which has no price-triggered order.
+// It watches the trades, and places the limit order when the stop price is crossed.
+// The fired trigger is never fired again even if the limit order failed, the handler gets the error.
+type TriggerEngine struct {
+	ex      exchange.RestAPIExchange
+	store   hs.KeyStore
+	key     string
+	handler TriggerHandler
+	Sugar   *zap.SugaredLogger
+
+	mu    sync.Mutex
+	state TriggerState
+}
+
+// NewTriggerEngine creates the engine which places order by ex and saves the triggers to store by key,
+// call Load to resume the saved triggers.
+func NewTriggerEngine(ex exchange.RestAPIExchange, store hs.KeyStore, key string, handler TriggerHandler, logger *zap.SugaredLogger) *TriggerEngine {
+	return &TriggerEngine{
+		ex:      ex,
+		store:   store,
+		key:     key,
+		handler: handler,
+		Sugar:   logger,
+		state:   TriggerState{NextId: 1},
+	}
+}
+
+// Load resumes the saved triggers
+func (e *TriggerEngine) Load(ctx context.Context) error {
+	var state TriggerState
+	if err := e.store.Load(ctx, e.key, &state); err != nil {
+		return err
+	}
+	if state.NextId == 0 {
+		state.NextId = 1
+	}
+	for i := range state.Fired {
+		if state.Fired[i].OrderId == 0 && state.Fired[i].Error == "" {
+			// stopped while placing, the order may or may not be placed
+			state.Fired[i].Error = "interrupted while placing order"
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.state = state
+	return nil
+}
+
+// Add saves a pending trigger, returns the trigger id with TriggerIdFlag
+func (e *TriggerEngine) Add(ctx context.Context, symbol, clientOrderId string, side exchange.OrderType, price, amount, stopPrice decimal.Decimal) (uint64, error) {
+	if e == nil {
+		return 0, ErrNoTriggerEngine
+	}
+	if !price.IsPositive() || !amount.IsPositive() || !stopPrice.IsPositive() {
+		return 0, ErrBadTrigger
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	t := Trigger{
+		Id:            TriggerIdFlag | e.state.NextId,
+		ClientOrderId: clientOrderId,
+		Symbol:        symbol,
+		Side:          side,
+		Price:         price,
+		Amount:        amount,
+		StopPrice:     stopPrice,
+		Time:          time.Now(),
+	}
+	state := TriggerState{NextId: e.state.NextId + 1, Triggers: append(e.triggers(), t), Fired: e.state.Fired}
+	if err := e.store.Save(ctx, e.key, state); err != nil {
+		return 0, err
+	}
+	e.state = state
+	return t.Id, nil
+}
+
+// Cancel removes the pending trigger
+func (e *TriggerEngine) Cancel(ctx context.Context, id uint64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var left []Trigger
+	for _, t := range e.state.Triggers {
+		if t.Id != id {
+			left = append(left, t)
+		}
+	}
+	if len(left) == len(e.state.Triggers) {
+		return ErrTriggerNotFound
+	}
+	state := TriggerState{NextId: e.state.NextId, Triggers: left, Fired: e.state.Fired}
+	if err := e.store.Save(ctx, e.key, state); err != nil {
+		return err
+	}
+	e.state = state
+	return nil
+}
+
+// GetOrder returns the pending trigger as open order, or the limit order placed by the fired trigger.
+// The returned order id is always the trigger id.
+func (e *TriggerEngine) GetOrder(orderId uint64) (exchange.Order, error) {
+	if e == nil {
+		return exchange.Order{}, ErrNoTriggerEngine
+	}
+	t, pending, ok := e.find(orderId)
+	switch {
+	case !ok:
+		return exchange.Order{}, ErrTriggerNotFound
+	case pending:
+		return t.order(OrderStatusOpen, exchange.Open), nil
+	case t.OrderId != 0:
+		o, err := e.ex.GetOrderById(t.OrderId, t.Symbol)
+		o.Id = orderId
+		return o, err
+	case t.Error != "":
+		return t.order(TriggerStatusFailed, exchange.Rejected), nil
+	}
+	return t.order(TriggerStatusTriggered, exchange.Open), nil
+}
+
+// CancelOrder cancels the pending trigger, or the limit order placed by the fired trigger.
+// It does nothing if the limit order is not placed.
+func (e *TriggerEngine) CancelOrder(ctx context.Context, orderId uint64) error {
+	if e == nil {
+		return ErrNoTriggerEngine
+	}
+	t, pending, ok := e.find(orderId)
+	switch {
+	case !ok:
+		return ErrTriggerNotFound
+	case pending:
+		return e.Cancel(ctx, orderId)
+	case t.OrderId != 0:
+		return e.ex.CancelOrder(t.Symbol, t.OrderId)
+	}
+	return nil
+}
+
+// Pending returns a copy of the pending triggers
+func (e *TriggerEngine) Pending() []Trigger {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.triggers()
+}
+
+// Update fires the triggers of symbol crossed by the last price
+func (e *TriggerEngine) Update(symbol string, price decimal.Decimal) {
+	e.mu.Lock()
+	var fired, left []Trigger
+	for _, t := range e.state.Triggers {
+		if t.Symbol == symbol && t.crossed(price) {
+			fired = append(fired, t)
+		} else {
+			left = append(left, t)
+		}
+	}
+	if len(fired) == 0 {
+		e.mu.Unlock()
+		return
+	}
+	// Fired is persisted before the limit orders go out; Load marks the unplaced ones as interrupted
+	e.state.Triggers = left
+	e.state.Fired = append(e.state.Fired, fired...)
+	if n := len(e.state.Fired); n > maxFired {
+		e.state.Fired = e.state.Fired[n-maxFired:]
+	}
+	e.save()
+	e.mu.Unlock()
+
+	for _, t := range fired {
+		e.fire(t, price)
+	}
+}
+
+// Watch subscribes the trades of symbol, and updates the triggers with every trade price
+func (e *TriggerEngine) Watch(ex exchange.StreamAPIExchange, symbol, clientId string) *exchange.Subscription {
+	return ex.SubscribeTrade(symbol, clientId, func(details []exchange.TradeDetail) {
+		for _, d := range details {
+			e.Update(symbol, d.Price)
+		}
+	})
+}
+
+func (e *TriggerEngine) fire(t Trigger, price decimal.Decimal) {
+	var orderId uint64
+	var err error
+	if t.Side == exchange.Buy {
+		orderId, err = e.ex.BuyLimit(t.Symbol, t.ClientOrderId, t.Price, t.Amount)
+	} else {
+		orderId, err = e.ex.SellLimit(t.Symbol, t.ClientOrderId, t.Price, t.Amount)
+	}
+	if err != nil {
+		e.Sugar.Errorf("trigger %d of %s fired at %s, place order error: %s", t.Id, t.Symbol, price, err)
+	} else {
+		e.Sugar.Infof("trigger %d of %s fired at %s, order %d placed", t.Id, t.Symbol, price, orderId)
+	}
+
+	e.mu.Lock()
+	for i := range e.state.Fired {
+		if e.state.Fired[i].Id == t.Id {
+			e.state.Fired[i].OrderId = orderId
+			if err != nil {
+				e.state.Fired[i].Error = err.Error()
+			} else if orderId == 0 {
+				e.state.Fired[i].Error = "no order id"
+			}
+		}
+	}
+	e.save()
+	e.mu.Unlock()
+
+	if e.handler != nil {
+		e.handler(t, orderId, err)
+	}
+}
+
+// find returns the trigger of id, pending is true if it's not fired
+func (e *TriggerEngine) find(id uint64) (t Trigger, pending, ok bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, t := range e.state.Triggers {
+		if t.Id == id {
+			return t, true, true
+		}
+	}
+	for _, t := range e.state.Fired {
+		if t.Id == id {
+			return t, false, true
+		}
+	}
+	return
+}
+
+func (e *TriggerEngine) save() {
+	if err := e.store.Save(context.Background(), e.key, e.state); err != nil {
+		e.Sugar.Errorf("save triggers error: %s", err)
+	}
+}
+
+func (e *TriggerEngine) triggers() []Trigger {
+	return append([]Trigger(nil), e.state.Triggers...)
+}
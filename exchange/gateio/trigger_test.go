@@ -0,0 +1,98 @@
+package gateio
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/paper"
+	"testing"
+)
+
+func TestTriggerEngine(t *testing.T) {
+	ctx := context.Background()
+	symbol := exchange.Symbol{Symbol: "btc_usdt", BaseCurrency: "btc", QuoteCurrency: "usdt", PricePrecision: 2, AmountPrecision: 4}
+	ex := paper.New([]exchange.Symbol{symbol}, exchange.Fee{})
+	ex.Deposit("btc", decimal.NewFromInt(1))
+	ex.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 1600000000, Open: 100, High: 100, Low: 100, Close: 100, Volume: 1})
+
+	store := hs.NewMemoryKeyStore()
+	var fired []uint64
+	handler := func(t Trigger, orderId uint64, err error) {
+		if err == nil {
+			fired = append(fired, orderId)
+		}
+	}
+	g := &GateIO{}
+	_, err := g.SellStopLimit("btc_usdt", "sl", decimal.NewFromInt(89), decimal.NewFromInt(1), decimal.NewFromInt(90))
+	require.Equal(t, ErrNoTriggerEngine, err)
+
+	engine := NewTriggerEngine(ex, store, "triggers", handler, g4.Logger)
+	g.SetTriggerEngine(engine)
+	id, err := g.SellStopLimit("btc_usdt", "sl", decimal.NewFromInt(89), decimal.NewFromInt(1), decimal.NewFromInt(90))
+	require.NoError(t, err)
+	require.Equal(t, TriggerIdFlag|1, id)
+	o, err := g.GetOrderById(id, "btc_usdt")
+	require.NoError(t, err)
+	require.Equal(t, exchange.Open, o.State)
+	require.Equal(t, "sell-stop-limit", o.Type)
+
+	// resume after restart
+	engine = NewTriggerEngine(ex, store, "triggers", handler, g4.Logger)
+	require.NoError(t, engine.Load(ctx))
+	require.Len(t, engine.Pending(), 1)
+	require.Equal(t, exchange.Sell, engine.Pending()[0].Side)
+
+	engine.Update("btc_usdt", decimal.NewFromInt(95))
+	engine.Update("eth_usdt", decimal.NewFromInt(80))
+	require.Empty(t, fired)
+	engine.Update("btc_usdt", decimal.NewFromInt(90))
+	require.Len(t, fired, 1)
+	require.Empty(t, engine.Pending())
+	o, err = ex.GetOrderById(fired[0], "btc_usdt")
+	require.NoError(t, err)
+	require.Equal(t, "sl", o.ClientOrderId)
+	require.Equal(t, exchange.Sell, o.Side)
+	require.True(t, decimal.NewFromInt(89).Equal(o.Price))
+
+	// the trigger id is routed to the fired order
+	g.SetTriggerEngine(engine)
+	o, filled, err := g.IsFullFilled("btc_usdt", id)
+	require.NoError(t, err)
+	require.True(t, filled)
+	require.Equal(t, id, o.Id)
+
+	// fired trigger is not resumed, and id is not reused
+	engine = NewTriggerEngine(ex, store, "triggers", handler, g4.Logger)
+	require.NoError(t, engine.Load(ctx))
+	require.Empty(t, engine.Pending())
+	id, err = engine.Add(ctx, "btc_usdt", "bs", exchange.Buy, decimal.NewFromInt(111), decimal.NewFromInt(1), decimal.NewFromInt(110))
+	require.NoError(t, err)
+	require.Equal(t, TriggerIdFlag|2, id)
+	g.SetTriggerEngine(engine)
+	require.NoError(t, g.CancelOrder("btc_usdt", id))
+	require.Equal(t, ErrTriggerNotFound, engine.Cancel(ctx, id))
+	_, err = g.GetOrderById(id, "btc_usdt")
+	require.Equal(t, ErrTriggerNotFound, err)
+
+	// the fired order is cancelled by the trigger id
+	id, err = engine.Add(ctx, "btc_usdt", "bs", exchange.Buy, decimal.NewFromInt(95), decimal.NewFromInt(1), decimal.NewFromInt(104))
+	require.NoError(t, err)
+	ex.Deposit("usdt", decimal.NewFromInt(1000))
+	engine.Update("btc_usdt", decimal.NewFromInt(106))
+	require.Len(t, fired, 2)
+	require.NoError(t, g.CancelOrder("btc_usdt", id))
+	o, err = g.GetOrderById(id, "btc_usdt")
+	require.NoError(t, err)
+	require.Equal(t, exchange.Cancelled, o.State)
+
+	// the failed trigger is rejected
+	id, err = engine.Add(ctx, "btc_usdt", "bs", exchange.Buy, decimal.NewFromInt(105), decimal.NewFromInt(100), decimal.NewFromInt(104))
+	require.NoError(t, err)
+	engine.Update("btc_usdt", decimal.NewFromInt(106))
+	o, err = g.GetOrderById(id, "btc_usdt")
+	require.NoError(t, err)
+	require.Equal(t, exchange.Rejected, o.State)
+	require.NoError(t, g.CancelOrder("btc_usdt", id))
+}
@@ -9,11 +9,25 @@ import (
 	"github.com/xyths/hs"
 	"github.com/xyths/hs/convert"
 	"github.com/xyths/hs/exchange"
+	"math"
+	"sort"
 	"strings"
 	"time"
 )
 
 func (g GateIO) FormatSymbol(base, quote string) string {
+	return formatSymbol(base, quote)
+}
+
+func (g V2) FormatSymbol(base, quote string) string {
+	return formatSymbol(base, quote)
+}
+
+func (g SpotV4) FormatSymbol(base, quote string) string {
+	return formatSymbol(base, quote)
+}
+
+func formatSymbol(base, quote string) string {
 	return fmt.Sprintf("%s_%s", strings.ToLower(base), strings.ToLower(quote))
 }
 
@@ -97,24 +111,74 @@ func parseTickers(data []interface{}) ([]hs.Ticker, error) {
 	return tickers, err
 }
 
-// parseOrdersQuery parse the records in response of order.query
+// orderStatus maps the status of rest api and the event of order.update,
+// "finish" of order.update is filled or cancelled, decided by amount left.
+var orderStatus = exchange.StatusTable{
+	OrderStatusOpen:      exchange.Open,
+	OrderStatusClosed:    exchange.Closed,
+	OrderStatusCancelled: exchange.Cancelled,
+	"filled":             exchange.Filled,
+}
+
+// parseOrdersQuery parse the records in response of order.query, all of them are open
 func parseOrdersQuery(records []WsOrderRecord) (orders []exchange.Order, err error) {
 	for _, r := range records {
-		orders = append(orders, exchange.Order{
+		o := exchange.Order{
 			Id:            r.Id,
 			ClientOrderId: r.Text,
 			Type:          parseOrderType(r.Type),
+			Side:          parseWsSide(r.Type),
 			Symbol:        r.Market,
 			Price:         decimal.RequireFromString(r.Price),
 			Amount:        decimal.RequireFromString(r.Amount),
 			Time:          time.Unix(int64(r.CTime), 0),
-			//Status:
-			FilledAmount: decimal.RequireFromString(r.FilledAmount),
-		})
+			Status:        OrderStatusOpen,
+			FilledAmount:  decimal.RequireFromString(r.FilledAmount),
+		}
+		o.State = orderStatus.Normalize(o.Status, o.FilledAmount)
+		orders = append(orders, o)
 	}
 	return
 }
 
+// v2OrderOptions lists the order options of api v2
+var v2OrderOptions = exchange.OptionSupport{
+	Name:        "gate api v2",
+	TimeInForce: []exchange.TimeInForce{exchange.GTC, exchange.IOC, exchange.PostOnly},
+}
+
+// v4OrderOptions lists the spot order options of api v4, reduce-only is for futures only
+var v4OrderOptions = exchange.OptionSupport{
+	Name:        "gate api v4",
+	TimeInForce: []exchange.TimeInForce{exchange.GTC, exchange.IOC, exchange.FOK, exchange.PostOnly},
+	Iceberg:     true,
+}
+
+// timeInForce converts to the orderType of v2 or time_in_force of v4, post-only is "poc"
+func timeInForce(tif exchange.TimeInForce) string {
+	switch tif {
+	case exchange.IOC:
+		return OrderTypeIOC
+	case exchange.FOK:
+		return OrderTypeFOK
+	case exchange.PostOnly:
+		return OrderTypePOC
+	}
+	return OrderTypeGTC
+}
+
+// parseWsSide parse the type of websocket order, 1: sell, 2: buy
+func parseWsSide(gateType int) exchange.OrderType {
+	switch gateType {
+	case 1:
+		return exchange.Sell
+	case 2:
+		return exchange.Buy
+	default:
+		return 0
+	}
+}
+
 func parseOrderType(gateType int) string {
 	switch gateType {
 	case 0:
@@ -131,7 +195,8 @@ func parseOrderUpdate(params []interface{}) (exchange.Order, error) {
 	if len(params) != 2 {
 		return o, errors.New("order.update should have 2 params")
 	}
-	event, ok := params[0].(int)
+	// number in interface{} is float64 after json.Unmarshal
+	event, ok := params[0].(float64)
 	if !ok {
 		return o, errors.New("bad event in order.update message")
 	}
@@ -141,11 +206,12 @@ func parseOrderUpdate(params []interface{}) (exchange.Order, error) {
 		return o, err
 	}
 	if err1 := json.Unmarshal(data, &r); err1 != nil {
-		return o, err
+		return o, err1
 	}
 	o.Id = r.Id
 	o.ClientOrderId = r.Text
 	o.Type = parseOrderType(r.Type)
+	o.Side = parseWsSide(r.Type)
 	o.Symbol = r.Market
 	o.Price = decimal.RequireFromString(r.Price)
 	o.Amount = decimal.RequireFromString(r.Amount)
@@ -160,9 +226,174 @@ func parseOrderUpdate(params []interface{}) (exchange.Order, error) {
 	case 3:
 		o.Status = "finish"
 	}
+	o.State = orderStatus.Normalize(o.Status, o.FilledAmount)
+	if event == 3 {
+		o.State = exchange.Closed
+		if left, err := decimal.NewFromString(r.Left); err == nil && left.IsPositive() {
+			o.State = orderStatus.Normalize(OrderStatusCancelled, o.FilledAmount)
+		}
+	}
 	return o, nil
 }
 
+// parseCandleUpdates parse kline.update message's params, which are
+// [time, open, close, highest, lowest, volume, amount, market_name]
+func parseCandleUpdates(params []interface{}) ([]exchange.CandleUpdate, error) {
+	tickers, err := parseTickers(params)
+	if err != nil {
+		return nil, err
+	}
+	updates := make([]exchange.CandleUpdate, len(tickers))
+	for i, t := range tickers {
+		updates[i].Ticker = t
+		// checked in parseTickers
+		updates[i].Symbol, _ = params[i].([]interface{})[7].(string)
+	}
+	return updates, nil
+}
+
+// parseBalanceUpdate parse balance.update message's params, like
+// [{"EOS": {"available": "96.765323611874", "freeze": "11"}}]
+func parseBalanceUpdate(params []interface{}) ([]exchange.BalanceUpdate, error) {
+	if len(params) != 1 {
+		return nil, errors.New("balance.update should have 1 param")
+	}
+	var raw map[string]struct {
+		Available string `json:"available"`
+		Freeze    string `json:"freeze"`
+	}
+	data, err := json.Marshal(params[0])
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var updates []exchange.BalanceUpdate
+	for currency, b := range raw {
+		available, err := decimal.NewFromString(b.Available)
+		if err != nil {
+			return nil, err
+		}
+		freeze, err := decimal.NewFromString(b.Freeze)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, exchange.BalanceUpdate{
+			Balance: exchange.Balance{Currency: currency, Available: available, Locked: freeze},
+			Time:    now,
+		})
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Currency < updates[j].Currency })
+	return updates, nil
+}
+
+// parseDepth convert the result of depth.query, or depth in depth.update, clean means full depth
+func parseDepth(symbol string, clean bool, raw ResponseWsDepth) (exchange.DepthUpdate, error) {
+	u := exchange.DepthUpdate{Symbol: symbol, Snapshot: clean}
+	var err error
+	if u.Bids, err = parseDepthLevels(raw.Bids); err != nil {
+		return u, err
+	}
+	if u.Asks, err = parseDepthLevels(raw.Asks); err != nil {
+		return u, err
+	}
+	return u, nil
+}
+
+func parseDepthLevels(raw [][2]string) ([]exchange.DepthLevel, error) {
+	levels := make([]exchange.DepthLevel, 0, len(raw))
+	for _, r := range raw {
+		price, err := decimal.NewFromString(r[0])
+		if err != nil {
+			return nil, err
+		}
+		amount, err := decimal.NewFromString(r[1])
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, exchange.DepthLevel{Price: price, Amount: amount})
+	}
+	return levels, nil
+}
+
+// parseDepthUpdate parse depth.update message's params field: [clean, depth, market]
+func parseDepthUpdate(params []interface{}) (exchange.DepthUpdate, error) {
+	if len(params) != 3 {
+		return exchange.DepthUpdate{}, errors.New("depth.update should have 3 params")
+	}
+	clean, ok := params[0].(bool)
+	if !ok {
+		return exchange.DepthUpdate{}, errors.New("bad clean in depth.update message")
+	}
+	symbol, ok := params[2].(string)
+	if !ok {
+		return exchange.DepthUpdate{}, errors.New("bad market in depth.update message")
+	}
+	var raw ResponseWsDepth
+	data, err := json.Marshal(params[1])
+	if err != nil {
+		return exchange.DepthUpdate{}, err
+	}
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return exchange.DepthUpdate{}, err
+	}
+	return parseDepth(symbol, clean, raw)
+}
+
+// parseTrades convert trades to exchange.TradeDetail, sorted by time ascending (gate is descending)
+func parseTrades(raw []ResponseWsTrade) ([]exchange.TradeDetail, error) {
+	details := make([]exchange.TradeDetail, 0, len(raw))
+	for _, r := range raw {
+		price, err := decimal.NewFromString(r.Price)
+		if err != nil {
+			return nil, err
+		}
+		amount, err := decimal.NewFromString(r.Amount)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, exchange.TradeDetail{
+			Id:        r.Id,
+			Price:     price,
+			Amount:    amount,
+			Timestamp: int64(math.Round(r.Time * 1000)),
+			Direction: r.Type,
+		})
+	}
+	sort.SliceStable(details, func(i, j int) bool {
+		if details[i].Timestamp == details[j].Timestamp {
+			return details[i].Id < details[j].Id
+		}
+		return details[i].Timestamp < details[j].Timestamp
+	})
+	return details, nil
+}
+
+// parseTradesUpdate parse trades.update message's params field: [market, trades]
+func parseTradesUpdate(params []interface{}) (symbol string, details []exchange.TradeDetail, err error) {
+	if len(params) != 2 {
+		err = errors.New("trades.update should have 2 params")
+		return
+	}
+	symbol, ok := params[0].(string)
+	if !ok {
+		err = errors.New("bad market in trades.update message")
+		return
+	}
+	var raw []ResponseWsTrade
+	data, err := json.Marshal(params[1])
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return
+	}
+	details, err = parseTrades(raw)
+	return
+}
+
 func cutSymbol(symbol string) (base, quote string) {
 	tokens := strings.Split(symbol, "_")
 	if len(tokens) == 2 {
@@ -238,16 +469,22 @@ func getInterval(period time.Duration) string {
 }
 
 func convertOrder(o gateapi.Order) exchange.Order {
-	return exchange.Order{
+	order := exchange.Order{
 		Id:            convert.StrToUint64(o.Id),
 		ClientOrderId: o.Text,
 		Type:          o.Type, // limit
+		Side:          exchange.ParseSide(o.Side),
 		Symbol:        o.CurrencyPair,
 		Price:         decimal.RequireFromString(o.Price),
 		Amount:        decimal.RequireFromString(o.Amount),
 		Time:          time.Unix(convert.StrToInt64(o.CreateTime), 0),
 		Status:        o.Status,
 	}
+	if o.Left != "" {
+		order.FilledAmount = order.Amount.Sub(convert.StrToDecimal(o.Left))
+	}
+	order.State = orderStatus.Normalize(o.Status, order.FilledAmount)
+	return order
 }
 
 func convertTicker(t gateapi.Ticker) exchange.Ticker {
@@ -416,3 +653,105 @@ func convertDualPosition(positions []gateapi.Position) []exchange.Position {
 	}
 	return rets
 }
+
+// floatToTime converts the unix timestamp in seconds with fraction, zero to zero time
+func floatToTime(t float64) time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(t)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+func convertFuturesOrder(o gateapi.FuturesOrder) exchange.FuturesOrder {
+	return exchange.FuturesOrder{
+		Id:           uint64(o.Id),
+		User:         uint64(o.User),
+		CreateTime:   floatToTime(o.CreateTime),
+		FinishTime:   floatToTime(o.FinishTime),
+		FinishAs:     o.FinishAs,
+		Status:       o.Status,
+		Contract:     o.Contract,
+		Size:         o.Size,
+		Iceberg:      o.Iceberg,
+		Price:        convert.StrToDecimal(o.Price),
+		Close:        o.Close,
+		IsClose:      o.IsClose,
+		ReduceOnly:   o.ReduceOnly,
+		IsReduceOnly: o.IsReduceOnly,
+		IsLiq:        o.IsLiq,
+		Tif:          o.Tif,
+		Left:         o.Left,
+		FillPrice:    convert.StrToDecimal(o.FillPrice),
+		Text:         o.Text,
+		TakerFee:     convert.StrToFloat64(o.Tkfr),
+		MakerFee:     convert.StrToFloat64(o.Mkfr),
+		Reference:    uint64(o.Refu),
+	}
+}
+
+// toFuturesOrder converts the order to place
+func toFuturesOrder(o exchange.FuturesOrder) gateapi.FuturesOrder {
+	return gateapi.FuturesOrder{
+		Contract:   o.Contract,
+		Size:       o.Size,
+		Iceberg:    o.Iceberg,
+		Price:      o.Price.String(),
+		Close:      o.Close,
+		ReduceOnly: o.ReduceOnly,
+		Tif:        o.Tif,
+		Text:       o.Text,
+	}
+}
+
+func convertPriceTriggeredOrder(o gateapi.FuturesPriceTriggeredOrder) exchange.FuturesPriceTriggeredOrder {
+	return exchange.FuturesPriceTriggeredOrder{
+		Initial: exchange.FuturesOrder{
+			Contract:     o.Initial.Contract,
+			Size:         o.Initial.Size,
+			Price:        convert.StrToDecimal(o.Initial.Price),
+			Close:        o.Initial.Close,
+			Tif:          o.Initial.Tif,
+			Text:         o.Initial.Text,
+			ReduceOnly:   o.Initial.ReduceOnly,
+			IsReduceOnly: o.Initial.IsReduceOnly,
+			IsClose:      o.Initial.IsClose,
+		},
+		Trigger: exchange.FuturesPriceTrigger{
+			StrategyType: int(o.Trigger.StrategyType),
+			PriceType:    int(o.Trigger.PriceType),
+			Price:        convert.StrToDecimal(o.Trigger.Price),
+			Rule:         int(o.Trigger.Rule),
+			Expiration:   int(o.Trigger.Expiration),
+		},
+		Id:         uint64(o.Id),
+		User:       uint64(o.User),
+		CreateTime: floatToTime(o.CreateTime),
+		FinishTime: floatToTime(o.FinishTime),
+		TradeId:    uint64(o.TradeId),
+		Status:     o.Status,
+		FinishAs:   o.FinishAs,
+		Reason:     o.Reason,
+	}
+}
+
+func toPriceTriggeredOrder(o exchange.FuturesPriceTriggeredOrder) gateapi.FuturesPriceTriggeredOrder {
+	return gateapi.FuturesPriceTriggeredOrder{
+		Initial: gateapi.FuturesInitialOrder{
+			Contract:   o.Initial.Contract,
+			Size:       o.Initial.Size,
+			Price:      o.Initial.Price.String(),
+			Close:      o.Initial.Close,
+			Tif:        o.Initial.Tif,
+			Text:       o.Initial.Text,
+			ReduceOnly: o.Initial.ReduceOnly,
+		},
+		Trigger: gateapi.FuturesPriceTrigger{
+			StrategyType: int32(o.Trigger.StrategyType),
+			PriceType:    int32(o.Trigger.PriceType),
+			Price:        o.Trigger.Price.String(),
+			Rule:         int32(o.Trigger.Rule),
+			Expiration:   int32(o.Trigger.Expiration),
+		},
+	}
+}
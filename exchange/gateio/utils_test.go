@@ -1,7 +1,10 @@
 package gateio
 
 import (
+	"encoding/json"
 	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs/exchange"
 	"testing"
 )
 
@@ -42,3 +45,99 @@ func Test_TakeBids(t *testing.T) {
 		}
 	}
 }
+
+func Test_ParseDepthUpdate(t *testing.T) {
+	raw := `[false, {"bids": [["8000.00", "0"], ["7999.5", "1.25"]]}, "btc_usdt"]`
+	var params []interface{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &params))
+	u, err := parseDepthUpdate(params)
+	require.NoError(t, err)
+	require.Equal(t, "btc_usdt", u.Symbol)
+	require.False(t, u.Snapshot)
+	require.Len(t, u.Asks, 0)
+	require.Len(t, u.Bids, 2)
+	require.True(t, u.Bids[0].Amount.IsZero())
+	require.Equal(t, "7999.5", u.Bids[1].Price.String())
+	require.Equal(t, "1.25", u.Bids[1].Amount.String())
+
+	_, err = parseDepthUpdate(params[:2])
+	require.Error(t, err)
+}
+
+func Test_ParseTradesUpdate(t *testing.T) {
+	raw := `["btc_usdt", [
+		{"id": 3, "time": 1523339279.761838, "price": "398.59", "amount": "0.027", "type": "buy"},
+		{"id": 2, "time": 1523339279.761838, "price": "398.58", "amount": "0.1", "type": "sell"},
+		{"id": 1, "time": 1523339278.5, "price": "398.5", "amount": "1", "type": "sell"}
+	]]`
+	var params []interface{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &params))
+	symbol, details, err := parseTradesUpdate(params)
+	require.NoError(t, err)
+	require.Equal(t, "btc_usdt", symbol)
+	require.Len(t, details, 3)
+	// ascending
+	for i, d := range details {
+		require.Equal(t, int64(i+1), d.Id)
+	}
+	require.Equal(t, int64(1523339278500), details[0].Timestamp)
+	require.Equal(t, int64(1523339279762), details[2].Timestamp)
+	require.Equal(t, exchange.TradeDirectionBuy, details[2].Direction)
+	require.Equal(t, "398.59", details[2].Price.String())
+}
+
+func Test_ParseCandleUpdates(t *testing.T) {
+	raw := `[[1492358400, "7000.00", "8000.0", "8100.00", "6800.00", "1000.00", "123456.00", "BTC_USDT"]]`
+	var params []interface{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &params))
+	updates, err := parseCandleUpdates(params)
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	require.Equal(t, "BTC_USDT", updates[0].Symbol)
+	require.Equal(t, int64(1492358400), updates[0].Timestamp)
+	require.Equal(t, 7000.0, updates[0].Open)
+	require.Equal(t, 8000.0, updates[0].Close)
+	require.Equal(t, 1000.0, updates[0].Volume)
+}
+
+func Test_ParseOrderUpdate(t *testing.T) {
+	raw := `[2, {"id": 34628963, "market": "EOS_USDT", "orderType": 1, "type": 2, "user": 602123, "ctime": 1523013969.6271579, "mtime": 1523013969.6271579, "price": "0.1", "amount": "1000", "left": "800", "filledAmount": "200", "filledTotal": "20", "dealFee": "0"}]`
+	var params []interface{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &params))
+	o, err := parseOrderUpdate(params)
+	require.NoError(t, err)
+	require.Equal(t, uint64(34628963), o.Id)
+	require.Equal(t, "EOS_USDT", o.Symbol)
+	require.Equal(t, "filled", o.Status)
+	require.Equal(t, exchange.Filled, o.State)
+	require.Equal(t, exchange.Buy, o.Side)
+	require.Equal(t, "200", o.FilledAmount.String())
+
+	// finish with amount left is cancelled
+	raw = `[3, {"id": 34628963, "market": "EOS_USDT", "orderType": 1, "type": 1, "user": 602123, "ctime": 1523013969.6271579, "mtime": 1523013969.6271579, "price": "0.1", "amount": "1000", "left": "800", "filledAmount": "200", "filledTotal": "20", "dealFee": "0"}]`
+	require.NoError(t, json.Unmarshal([]byte(raw), &params))
+	o, err = parseOrderUpdate(params)
+	require.NoError(t, err)
+	require.Equal(t, "finish", o.Status)
+	require.Equal(t, exchange.PartialCancelled, o.State)
+	require.Equal(t, exchange.Sell, o.Side)
+
+	raw = `[3, {"id": 34628963, "market": "EOS_USDT", "orderType": 1, "type": 1, "user": 602123, "ctime": 1523013969.6271579, "mtime": 1523013969.6271579, "price": "0.1", "amount": "1000", "left": "0", "filledAmount": "1000", "filledTotal": "100", "dealFee": "0"}]`
+	require.NoError(t, json.Unmarshal([]byte(raw), &params))
+	o, err = parseOrderUpdate(params)
+	require.NoError(t, err)
+	require.Equal(t, exchange.Closed, o.State)
+}
+
+func Test_ParseBalanceUpdate(t *testing.T) {
+	raw := `[{"EOS": {"available": "96.765323611874", "freeze": "11"}, "BTC": {"available": "1", "freeze": "0"}}]`
+	var params []interface{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &params))
+	updates, err := parseBalanceUpdate(params)
+	require.NoError(t, err)
+	require.Len(t, updates, 2)
+	require.Equal(t, "BTC", updates[0].Currency)
+	require.Equal(t, "EOS", updates[1].Currency)
+	require.Equal(t, "96.765323611874", updates[1].Available.String())
+	require.Equal(t, "11", updates[1].Locked.String())
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/xyths/hs"
 	"github.com/xyths/hs/convert"
 	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
 	"go.uber.org/zap"
 	"io/ioutil"
 	"log"
@@ -32,12 +33,27 @@ type V2 struct {
 
 	host   string
 	wsPath string
+	subs   *exchange.Subscriptions
+
+	recorder *base.Recorder
+	triggers *TriggerEngine
 
 	Logger *zap.SugaredLogger
 }
 
+// SetRecorder records the raw messages of all websocket connections created after, nil to stop
+func (g *V2) SetRecorder(recorder *base.Recorder) {
+	g.recorder = recorder
+}
+
+// SetTriggerEngine enables BuyStopLimit and SellStopLimit, api v2 has no stop-limit order.
+// Remember to call Watch of the engine to feed the trade price.
+func (g *V2) SetTriggerEngine(engine *TriggerEngine) {
+	g.triggers = engine
+}
+
 func NewV2(key, secret, host string, logger *zap.SugaredLogger) *V2 {
-	g := &V2{Key: key, Secret: secret, wsPath: WsPathV4, Logger: logger}
+	g := &V2{Key: key, Secret: secret, wsPath: WsPathV4, subs: exchange.NewSubscriptions(), Logger: logger}
 	if host == "" {
 		host = DefaultHost
 	}
@@ -392,6 +408,29 @@ func (g *V2) BuyLimit(symbol, text string, price, amount decimal.Decimal) (order
 	return resp.OrderNumber, nil
 }
 
+// PlaceOrder places limit order, api v2 supports gtc, ioc and poc (post-only) only
+func (g *V2) PlaceOrder(symbol, clientOrderId string, side exchange.OrderType, price, amount decimal.Decimal, options exchange.OrderOptions) (orderId uint64, err error) {
+	if err = v2OrderOptions.Check(options, amount); err != nil {
+		return
+	}
+	var resp ResponseOrder
+	switch side {
+	case exchange.Buy:
+		resp, err = g.BuyOrder(symbol, price, amount, timeInForce(options.TIF()), clientOrderId)
+	case exchange.Sell:
+		resp, err = g.SellOrder(symbol, price, amount, timeInForce(options.TIF()), clientOrderId)
+	default:
+		return 0, exchange.ErrBadSide
+	}
+	if err != nil {
+		return 0, err
+	}
+	if resp.Result == "false" || resp.OrderNumber == 0 {
+		return 0, errors.New(resp.Message)
+	}
+	return resp.OrderNumber, nil
+}
+
 func (g *V2) BuyOrder(symbol string, price, amount decimal.Decimal, orderType, text string) (resp ResponseOrder, err error) {
 	url := "/private/buy"
 	param := fmt.Sprintf("currencyPair=%s&rate=%s&amount=%s&orderType=%s&text=t-%s", symbol, price, amount, orderType, text)
@@ -399,6 +438,13 @@ func (g *V2) BuyOrder(symbol string, price, amount decimal.Decimal, orderType, t
 	return
 }
 
+func (g *V2) SellOrder(symbol string, price, amount decimal.Decimal, orderType, text string) (resp ResponseOrder, err error) {
+	url := "/private/sell"
+	param := fmt.Sprintf("currencyPair=%s&rate=%s&amount=%s&orderType=%s&text=t-%s", symbol, price, amount, orderType, text)
+	err = g.request(POST, url, param, &resp)
+	return
+}
+
 // Place order sell
 func (g *V2) SellLimit(symbol, text string, price, amount decimal.Decimal) (orderId uint64, err error) {
 	url := "/private/sell"
@@ -434,16 +480,23 @@ func (g *V2) SellMarket(symbol exchange.Symbol, clientOrderId string, amount dec
 	return g.SellLimit(symbol.Symbol, clientOrderId, price, amount)
 }
 
+// BuyStopLimit adds a trigger to the trigger engine, the returned id is the trigger id with TriggerIdFlag,
+// which can be used by GetOrderById, IsFullFilled and CancelOrder.
+// The limit order is placed when the last price >= stopPrice, see TriggerEngine.
 func (g *V2) BuyStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (orderId uint64, err error) {
-	return 0, nil
+	return g.triggers.Add(context.Background(), symbol, clientOrderId, exchange.Buy, price, amount, stopPrice)
 }
 
+// SellStopLimit places the limit order when the last price <= stopPrice, see BuyStopLimit.
 func (g *V2) SellStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (orderId uint64, err error) {
-	return 0, nil
+	return g.triggers.Add(context.Background(), symbol, clientOrderId, exchange.Sell, price, amount, stopPrice)
 }
 
 // Cancel order
 func (g *V2) CancelOrder(symbol string, orderNumber uint64) error {
+	if IsTriggerId(orderNumber) {
+		return g.triggers.CancelOrder(context.Background(), orderNumber)
+	}
 	url := "/private/cancelOrder"
 	param := fmt.Sprintf("currencyPair=%s&orderNumber=%d", symbol, orderNumber)
 	var res ResponseCancel
@@ -461,6 +514,9 @@ func (g *V2) CancelAllOrders(types string, currencyPair string) (res ResponseCan
 }
 
 func (g *V2) GetOrderById(orderId uint64, symbol string) (order exchange.Order, err error) {
+	if IsTriggerId(orderId) {
+		return g.triggers.GetOrder(orderId)
+	}
 	return g.GetOrder(orderId, symbol)
 }
 
@@ -494,12 +550,14 @@ func (g *V2) GetOrder(orderId uint64, symbol string) (order exchange.Order, err
 	order.ClientOrderId = o.Text
 	order.Symbol = o.CurrencyPair
 	order.Type = o.Type
+	order.Side = exchange.ParseSide(o.Type)
 	// 下单价格
 	order.Price = decimal.RequireFromString(o.InitialRate)
 	order.Amount = decimal.RequireFromString(o.InitialAmount)
 	order.Status = o.Status
 	order.FilledPrice = decimal.RequireFromString(o.Rate)
 	order.FilledAmount = decimal.RequireFromString(o.FilledAmount)
+	order.State = orderStatus.Normalize(order.Status, order.FilledAmount)
 	//order.FeePercentage = o.FeePercentage
 	//order.FeeValue = decimal.RequireFromString(o.FeeValue)
 	order.Time = time.Unix(o.Timestamp, 0)
@@ -508,11 +566,11 @@ func (g *V2) GetOrder(orderId uint64, symbol string) (order exchange.Order, err
 }
 
 func (g *V2) IsFullFilled(symbol string, orderId uint64) (order exchange.Order, filled bool, err error) {
-	order, err = g.GetOrder(orderId, symbol)
+	order, err = g.GetOrderById(orderId, symbol)
 	if err != nil {
 		return
 	}
-	filled = order.Status == "closed"
+	filled = order.FullFilled()
 	return
 }
 
@@ -538,6 +596,8 @@ func (g *V2) OpenOrders() ([]exchange.Order, error) {
 			FilledPrice:  decimal.RequireFromString(raw.FilledRate),
 			FilledAmount: decimal.RequireFromString(raw.FilledAmount),
 		}
+		o.Side = exchange.ParseSide(raw.Type)
+		o.State = orderStatus.Normalize(o.Status, o.FilledAmount)
 		orders = append(orders, o)
 	}
 	return orders, nil
@@ -676,6 +736,7 @@ func (g *V2) request(method string, url string, param string, result interface{}
 // always call ReqPing use context context with a timeout
 func (g *V2) ReqPing(ctx context.Context, id int64) (string, error) {
 	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	ch := make(chan string, 1)
 	client.SetHandler(
 		func() {
@@ -686,7 +747,10 @@ func (g *V2) ReqPing(ctx context.Context, id int64) (string, error) {
 			pong, ok := resp.(string)
 			if ok {
 				g.Logger.Debugf("handler got response: %s", pong)
-				ch <- pong
+				select {
+				case ch <- pong:
+				default:
+				}
 			} else {
 				g.Logger.Error("wrong response")
 			}
@@ -708,6 +772,7 @@ func (g *V2) ReqPing(ctx context.Context, id int64) (string, error) {
 
 func (g *V2) ReqTime(ctx context.Context, id int64) (int64, error) {
 	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	ch := make(chan int64, 1)
 	client.SetHandler(
 		func() {
@@ -718,7 +783,10 @@ func (g *V2) ReqTime(ctx context.Context, id int64) (int64, error) {
 			t, ok := resp.(int64)
 			if ok {
 				g.Logger.Debugf("handler got response: %d", t)
-				ch <- t
+				select {
+				case ch <- t:
+				default:
+				}
 			} else {
 				g.Logger.Error("wrong response")
 			}
@@ -740,6 +808,7 @@ func (g *V2) ReqTime(ctx context.Context, id int64) (int64, error) {
 
 func (g *V2) ReqTicker(ctx context.Context, id int64, symbol string, period time.Duration) (hs.Ticker, error) {
 	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	ch := make(chan hs.Ticker, 1)
 	client.SetHandler(
 		func() {
@@ -754,7 +823,10 @@ func (g *V2) ReqTicker(ctx context.Context, id int64, symbol string, period time
 			}
 			g.Logger.Debugf("handler got raw response: %d", t1)
 			t2, _ := parseTicker(t1)
-			ch <- t2
+			select {
+			case ch <- t2:
+			default:
+			}
 		}),
 	)
 	client.Connect(true)
@@ -771,24 +843,29 @@ func (g *V2) ReqTicker(ctx context.Context, id int64, symbol string, period time
 	}
 }
 
-func (g *V2) SubTicker(id int64, symbol string, responseHandler exchange.ResponseHandler) {
+func (g *V2) SubTicker(id int64, symbol string, responseHandler exchange.ResponseHandler) *exchange.Subscription {
 	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	client.SetHandler(
-		func() {
-			client.SubTicker(id, symbol)
-		},
+		nil,
 		client.SubTickerHandler(responseHandler),
 	)
+	client.SubTicker(id, symbol)
 	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubTicker(id)
+	})
+	g.subs.Add(subKey("ticker", symbol, fmt.Sprintf("%d", id)), sub)
+	return sub
 }
 
 func (g *V2) UnsubTicker(id int64, symbol string) {
-	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
-	client.UnsubTicker(id)
+	g.subs.Unsubscribe(subKey("ticker", symbol, fmt.Sprintf("%d", id)))
 }
 
 func (g *V2) ReqCandlestick(ctx context.Context, symbol, clientId string, period time.Duration, from, to time.Time) (hs.Candle, error) {
 	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	ch := make(chan hs.Candle, 1)
 	id := time.Now().Unix()
 	client.SetHandler(
@@ -800,7 +877,10 @@ func (g *V2) ReqCandlestick(ctx context.Context, symbol, clientId string, period
 			if !ok {
 				return
 			}
-			ch <- r
+			select {
+			case ch <- r:
+			default:
+			}
 		}),
 	)
 	client.Connect(true)
@@ -817,26 +897,30 @@ func (g *V2) ReqCandlestick(ctx context.Context, symbol, clientId string, period
 }
 
 func (g *V2) SubCandlestick(symbol, clientId string, period time.Duration,
-	responseHandler exchange.ResponseHandler) {
+	responseHandler exchange.ResponseHandler) *exchange.Subscription {
 	id := time.Now().Unix()
 	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	client.SetHandler(
-		func() {
-			client.SubCandle(id, symbol, int64(period.Seconds()))
-		},
+		nil,
 		client.SubCandleHandler(responseHandler),
 	)
+	client.SubCandle(id, symbol, int64(period.Seconds()))
 	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubCandle(id)
+	})
+	g.subs.Add(subKey("kline", symbol, clientId), sub)
+	return sub
 }
 
 func (g *V2) UnsubCandlestick(symbol, clientId string) {
-	client := new(WebsocketClient).Init(g.host, g.wsPath, g.Logger)
-	id := time.Now().Unix()
-	client.UnsubCandle(id)
+	g.subs.Unsubscribe(subKey("kline", symbol, clientId))
 }
 
 func (g *V2) ReqOrder(ctx context.Context, symbol, clientId string) (orders []exchange.Order, err error) {
 	client := new(PrivateWebsocketClient).Init(g.host, g.wsPath, g.Key, g.Secret, g.Logger)
+	client.SetRecorder(g.recorder)
 	id := time.Now().Unix()
 	ch := make(chan ResponseReqOrder, 10)
 	done := make(chan int, 1)
@@ -849,16 +933,25 @@ func (g *V2) ReqOrder(ctx context.Context, symbol, clientId string) (orders []ex
 		client.ReqOrderHandler(func(resp interface{}) {
 			if resp == nil {
 				g.Logger.Debug("no open order")
-				done <- 1
+				select {
+				case done <- 1:
+				default:
+				}
 				return
 			}
 			r, ok := resp.(ResponseReqOrder)
 			if !ok {
 				g.Logger.Debug("response not ok")
-				done <- 1
+				select {
+				case done <- 1:
+				default:
+				}
 				return
 			}
-			ch <- r
+			select {
+			case ch <- r:
+			default:
+			}
 		}),
 	)
 	client.Connect(true)
@@ -889,22 +982,25 @@ func (g *V2) ReqOrder(ctx context.Context, symbol, clientId string) (orders []ex
 	}
 }
 
-func (g *V2) SubOrder(symbol, clientId string, responseHandler exchange.ResponseHandler) {
+func (g *V2) SubOrder(symbol, clientId string, responseHandler exchange.ResponseHandler) *exchange.Subscription {
 	id := time.Now().Unix()
 	client := new(PrivateWebsocketClient).Init(g.host, g.wsPath, g.Key, g.Secret, g.Logger)
+	client.SetRecorder(g.recorder)
 	client.SetHandler(
-		func() {
-			client.SubOrder(id, []string{symbol})
-		},
+		nil,
 		client.SubOrderHandler(responseHandler),
 	)
+	client.SubOrder(id, []string{symbol})
 	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubOrder(id, []string{symbol})
+	})
+	g.subs.Add(subKey("order", symbol, clientId), sub)
+	return sub
 }
 
 func (g *V2) UnsubOrder(symbol, clientId string) {
-	client := new(PrivateWebsocketClient).Init(g.host, g.wsPath, g.Key, g.Secret, g.Logger)
-	id := time.Now().Unix()
-	client.UnsubOrder(id, []string{symbol})
+	g.subs.Unsubscribe(subKey("order", symbol, clientId))
 }
 
 func (g *V2) ReqBalance(ctx context.Context, currencies []string) {
@@ -50,6 +50,22 @@ func (a *GateAuthentication) Build() (string, error) {
 	return req.String(), nil
 }
 
+// subKey is the key of subscriptions, so it can be unsubscribed by symbol and client id
+func subKey(channel, symbol, clientId string) string {
+	return channel + ":" + symbol + ":" + clientId
+}
+
+// newSubscription returns the handle which sends unsubscribe, then closes ws and waits for its goroutines
+func newSubscription(ws *base.WebsocketBase, unsubscribe func()) *exchange.Subscription {
+	return exchange.NewSubscription(func() {
+		unsubscribe()
+		ws.Close()
+	})
+}
+
+// WebsocketClient is the public client of the v2 websocket api.
+// The Sub* requests are registered by Subscribe and sent again after reconnect,
+// so call them before Connect instead of in the connected handler.
 type WebsocketClient struct {
 	base.WebsocketBase
 	//base.WebsocketBase
@@ -203,7 +219,7 @@ func (c *WebsocketClient) SubTicker(id int64, symbol string) {
 		Params: make([]interface{}, 1),
 	}
 	req.Params[0] = symbol
-	c.WebsocketBase.Send(req.String())
+	c.WebsocketBase.Subscribe("ticker", req.String())
 }
 
 func (c *WebsocketClient) UnsubTicker(id int64) {
@@ -212,7 +228,7 @@ func (c *WebsocketClient) UnsubTicker(id int64) {
 		Method: "ticker.unsubscribe",
 		Params: make([]interface{}, 0),
 	}
-	c.WebsocketBase.Send(req.String())
+	c.WebsocketBase.Unsubscribe("ticker", req.String())
 }
 
 func (c *WebsocketClient) SubTickerHandler(handler exchange.ResponseHandler) base.ResponseHandler {
@@ -263,7 +279,7 @@ func (c *WebsocketClient) SubCandle(id int64, symbol string, interval int64) {
 			symbol, interval,
 		},
 	}
-	c.WebsocketBase.Send(req.String())
+	c.WebsocketBase.Subscribe("kline", req.String())
 }
 
 func (c *WebsocketClient) UnsubCandle(id int64) {
@@ -272,24 +288,32 @@ func (c *WebsocketClient) UnsubCandle(id int64) {
 		Method: "kline.unsubscribe",
 		Params: make([]interface{}, 0),
 	}
-	c.WebsocketBase.Send(req.String())
+	c.WebsocketBase.Unsubscribe("kline", req.String())
 }
 
+// SubCandleHandler pass hs.Ticker to handler
 func (c *WebsocketClient) SubCandleHandler(handler exchange.ResponseHandler) base.ResponseHandler {
+	return c.SubCandleUpdateHandler(func(update exchange.CandleUpdate) {
+		handler(update.Ticker)
+	})
+}
+
+// SubCandleUpdateHandler pass the updates one by one, Period is not set as kline.update doesn't have it
+func (c *WebsocketClient) SubCandleUpdateHandler(handler exchange.CandleHandler) base.ResponseHandler {
 	return func(response interface{}) {
 		r, ok := response.([]interface{})
 		if !ok {
 			return
 		}
-		tickers, err := parseTickers(r)
+		updates, err := parseCandleUpdates(r)
 		if err != nil {
 			c.Logger.Errorf("parse ticker error: %s", err)
 			return
 		}
-		c.Logger.Debugf("receive %d tickers", len(tickers))
+		c.Logger.Debugf("receive %d tickers", len(updates))
 		// pass a ticker to callback
-		for _, t := range tickers {
-			handler(t)
+		for _, u := range updates {
+			handler(u)
 		}
 	}
 }
@@ -305,6 +329,147 @@ func (c *WebsocketClient) ReqCandleHandler(handler base.ResponseHandler) base.Re
 	}
 }
 
+// limit is one of 1, 5, 10, 20, 30, interval is the price merge precision, eg. "0.01", "0" for no merge
+func (c *WebsocketClient) ReqDepth(id int64, symbol string, limit int, interval string) {
+	req := WebsocketRequest{
+		Id:     id,
+		Method: "depth.query",
+		Params: []interface{}{
+			symbol, limit, interval,
+		},
+	}
+	c.WebsocketBase.Send(req.String())
+}
+
+// ReqDepthHandler cast the raw result to exchange.DepthUpdate, which is a snapshot without symbol
+func (c *WebsocketClient) ReqDepthHandler(handler base.ResponseHandler) base.ResponseHandler {
+	return func(response interface{}) {
+		var r ResponseWsDepth
+		data, err := json.Marshal(response)
+		if err != nil {
+			c.Logger.Errorf("parse response error: %s", err)
+			return
+		}
+		if err1 := json.Unmarshal(data, &r); err1 != nil {
+			c.Logger.Errorf("parse response error: %s", err1)
+			return
+		}
+		depth, err := parseDepth("", true, r)
+		if err != nil {
+			c.Logger.Errorf("parse depth error: %s", err)
+			return
+		}
+		handler(depth)
+	}
+}
+
+// the first depth.update is full depth (clean is true), then incremental
+func (c *WebsocketClient) SubDepth(id int64, symbol string, limit int, interval string) {
+	req := WebsocketRequest{
+		Id:     id,
+		Method: "depth.subscribe",
+		Params: []interface{}{
+			symbol, limit, interval,
+		},
+	}
+	c.WebsocketBase.Subscribe("depth", req.String())
+}
+
+func (c *WebsocketClient) UnsubDepth(id int64) {
+	req := WebsocketRequest{
+		Id:     id,
+		Method: "depth.unsubscribe",
+		Params: make([]interface{}, 0),
+	}
+	c.WebsocketBase.Unsubscribe("depth", req.String())
+}
+
+func (c *WebsocketClient) SubDepthHandler(handler exchange.DepthHandler) base.ResponseHandler {
+	return func(response interface{}) {
+		r, ok := response.([]interface{})
+		if !ok {
+			return
+		}
+		depth, err := parseDepthUpdate(r)
+		if err != nil {
+			c.Logger.Errorf("parse depth error: %s", err)
+			return
+		}
+		handler(depth)
+	}
+}
+
+// lastId is the trade id, only trades after it are returned, 0 for the latest
+func (c *WebsocketClient) ReqTrade(id int64, symbol string, limit int, lastId int64) {
+	req := WebsocketRequest{
+		Id:     id,
+		Method: "trades.query",
+		Params: []interface{}{
+			symbol, limit, lastId,
+		},
+	}
+	c.WebsocketBase.Send(req.String())
+}
+
+// ReqTradeHandler cast the raw result to []exchange.TradeDetail
+func (c *WebsocketClient) ReqTradeHandler(handler base.ResponseHandler) base.ResponseHandler {
+	return func(response interface{}) {
+		var r []ResponseWsTrade
+		data, err := json.Marshal(response)
+		if err != nil {
+			c.Logger.Errorf("parse response error: %s", err)
+			return
+		}
+		if err1 := json.Unmarshal(data, &r); err1 != nil {
+			c.Logger.Errorf("parse response error: %s", err1)
+			return
+		}
+		details, err := parseTrades(r)
+		if err != nil {
+			c.Logger.Errorf("parse trades error: %s", err)
+			return
+		}
+		handler(details)
+	}
+}
+
+func (c *WebsocketClient) SubTrade(id int64, symbols []string) {
+	req := WebsocketRequest{
+		Id:     id,
+		Method: "trades.subscribe",
+		Params: make([]interface{}, 0),
+	}
+	for _, s := range symbols {
+		req.Params = append(req.Params, s)
+	}
+	c.WebsocketBase.Subscribe("trades", req.String())
+}
+
+func (c *WebsocketClient) UnsubTrade(id int64) {
+	req := WebsocketRequest{
+		Id:     id,
+		Method: "trades.unsubscribe",
+		Params: make([]interface{}, 0),
+	}
+	c.WebsocketBase.Unsubscribe("trades", req.String())
+}
+
+func (c *WebsocketClient) SubTradeHandler(handler exchange.TradeHandler) base.ResponseHandler {
+	return func(response interface{}) {
+		r, ok := response.([]interface{})
+		if !ok {
+			return
+		}
+		symbol, details, err := parseTradesUpdate(r)
+		if err != nil {
+			c.Logger.Errorf("parse trades error: %s", err)
+			return
+		}
+		c.Logger.Debugf("receive %d trades of %s", len(details), symbol)
+		handler(details)
+	}
+}
+
 func (c *WebsocketClient) Auth(api, secret string) {
 	auth := GateAuthentication{}
 	auth.Init(api, secret)
@@ -318,6 +483,9 @@ func (c *WebsocketClient) AuthHandler(handler base.ResponseHandler) base.Respons
 	}
 }
 
+// PrivateWebsocketClient is the authenticated client of the v2 websocket api.
+// Like WebsocketClient, but the subscriptions are sent after authentication,
+// and the connected handler is called after them.
 type PrivateWebsocketClient struct {
 	base.WebsocketBase
 	auth             *GateAuthentication
@@ -330,6 +498,7 @@ func (c *PrivateWebsocketClient) Init(host, path string, apiKey, secretKey strin
 	c.auth = &GateAuthentication{}
 	c.auth.Init(apiKey, secretKey)
 	c.WebsocketBase.Init(host, path, logger, 5, 60, true)
+	c.ManualReplay = true
 	return c
 }
 
@@ -372,6 +541,7 @@ func (c *PrivateWebsocketClient) handleReqMessage(payload []byte) {
 	}
 	if r.Id == authId {
 		c.Logger.Info("auth success")
+		c.ReplaySubscriptions()
 		if c.connectedHandler != nil {
 			c.connectedHandler()
 		}
@@ -430,7 +600,7 @@ func (c *PrivateWebsocketClient) SubOrder(id int64, symbols []string) {
 		req.Params = append(req.Params, s)
 	}
 
-	c.WebsocketBase.Send(req.String())
+	c.WebsocketBase.Subscribe("order", req.String())
 }
 
 func (c *PrivateWebsocketClient) UnsubOrder(id int64, symbols []string) {
@@ -442,7 +612,7 @@ func (c *PrivateWebsocketClient) UnsubOrder(id int64, symbols []string) {
 	for _, s := range symbols {
 		req.Params = append(req.Params, s)
 	}
-	c.WebsocketBase.Send(req.String())
+	c.WebsocketBase.Unsubscribe("order", req.String())
 }
 
 // ReqOrderHandler cast the raw result to gate order object list
@@ -471,6 +641,12 @@ func (c *PrivateWebsocketClient) ReqOrderHandler(handler base.ResponseHandler) b
 
 // client handler got a exchange.Order as response if everything's ok
 func (c *PrivateWebsocketClient) SubOrderHandler(handler exchange.ResponseHandler) base.ResponseHandler {
+	return c.SubOrderUpdateHandler(func(order exchange.Order) {
+		handler(order)
+	})
+}
+
+func (c *PrivateWebsocketClient) SubOrderUpdateHandler(handler exchange.OrderHandler) base.ResponseHandler {
 	return func(response interface{}) {
 		c.Logger.Debugf("order update: %v", response)
 		r, ok := response.([]interface{})
@@ -509,7 +685,7 @@ func (c *PrivateWebsocketClient) SubBalance(id int64, assets []string) {
 		req.Params = append(req.Params, s)
 	}
 
-	c.WebsocketBase.Send(req.String())
+	c.WebsocketBase.Subscribe("balance", req.String())
 }
 
 func (c *PrivateWebsocketClient) UnsubBalance(id int64, assets []string) {
@@ -521,7 +697,7 @@ func (c *PrivateWebsocketClient) UnsubBalance(id int64, assets []string) {
 	for _, s := range assets {
 		req.Params = append(req.Params, s)
 	}
-	c.WebsocketBase.Send(req.String())
+	c.WebsocketBase.Unsubscribe("balance", req.String())
 }
 
 // ReqBalanceHandler cast the raw result to gate order object list
@@ -548,19 +724,27 @@ func (c *PrivateWebsocketClient) ReqBalanceHandler(handler base.ResponseHandler)
 	}
 }
 
+// client handler got a exchange.BalanceUpdate as response for every currency
 func (c *PrivateWebsocketClient) SubBalanceHandler(handler exchange.ResponseHandler) base.ResponseHandler {
+	return c.SubBalanceUpdateHandler(func(update exchange.BalanceUpdate) {
+		handler(update)
+	})
+}
+
+func (c *PrivateWebsocketClient) SubBalanceUpdateHandler(handler exchange.BalanceHandler) base.ResponseHandler {
 	return func(response interface{}) {
-		c.Logger.Debugf("order update: %v", response)
+		c.Logger.Debugf("balance update: %v", response)
 		r, ok := response.([]interface{})
 		if !ok {
 			return
 		}
-		order, err := parseOrderUpdate(r)
+		updates, err := parseBalanceUpdate(r)
 		if err != nil {
-			c.Logger.Errorf("parse ticker error: %s", err)
+			c.Logger.Errorf("parse balance error: %s", err)
 			return
 		}
-		c.Logger.Debugf("receive order %v update", order)
-		handler(order)
+		for _, u := range updates {
+			handler(u)
+		}
 	}
 }
@@ -1,9 +1,18 @@
 package gateio
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/require"
 	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -70,3 +79,194 @@ func TestWebsocketClient_Auth(t *testing.T) {
 
 	client.Close()
 }
+
+// fake v3 ws server, replies the messages to each request by method
+func newFakeWsServer(t *testing.T, replies map[string][]string) (*httptest.Server, chan WebsocketRequest) {
+	requests := make(chan WebsocketRequest, 10)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req WebsocketRequest
+			if err = json.Unmarshal(data, &req); err != nil {
+				t.Error(err)
+				return
+			}
+			requests <- req
+			for _, reply := range replies[req.Method] {
+				if err = conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	return server, requests
+}
+
+func TestSpotV4_Depth(t *testing.T) {
+	server, requests := newFakeWsServer(t, map[string][]string{
+		"depth.query": {`{"error": null, "result": {"asks": [["8000.00", "9.6250"]], "bids": [["7990.00", "0.0219"]]}, "id": 1}`},
+		"depth.subscribe": {
+			`{"error": null, "result": {"status": "success"}, "id": 2}`,
+			`{"id": null, "method": "depth.update", "params": [true, {"asks": [["8000.00", "9.6250"]], "bids": [["7990.00", "0.0219"]]}, "btc_usdt"]}`,
+			`{"id": null, "method": "depth.update", "params": [false, {"asks": [["8000.00", "0"]]}, "btc_usdt"]}`,
+		},
+	})
+	defer server.Close()
+	g := NewSpotV4("", "", "ws"+strings.TrimPrefix(server.URL, "http"), g4.Logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	snapshot, err := g.ReqDepth(ctx, "btc_usdt", 5, "0")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"btc_usdt", float64(5), "0"}, (<-requests).Params)
+	require.True(t, snapshot.Snapshot)
+	require.Equal(t, "btc_usdt", snapshot.Symbol)
+	require.Equal(t, "8000", snapshot.Asks[0].Price.String())
+
+	updates := make(chan exchange.DepthUpdate, 2)
+	g.SubDepth("btc_usdt", "test", 5, "0", func(update exchange.DepthUpdate) {
+		updates <- update
+	})
+	require.Equal(t, "depth.subscribe", (<-requests).Method)
+	u := <-updates
+	require.True(t, u.Snapshot)
+	require.Len(t, u.Bids, 1)
+	u = <-updates
+	require.False(t, u.Snapshot)
+	require.True(t, u.Asks[0].Amount.IsZero())
+
+	g.UnsubDepth("btc_usdt", "test")
+	require.Equal(t, "depth.unsubscribe", (<-requests).Method)
+}
+
+func TestSpotV4_Trade(t *testing.T) {
+	server, requests := newFakeWsServer(t, map[string][]string{
+		"trades.query": {`{"error": null, "result": [{"id": 2, "time": 1523339279.5, "price": "398.59", "amount": "0.027", "type": "buy"}, {"id": 1, "time": 1523339279.4, "price": "398.5", "amount": "1", "type": "sell"}], "id": 1}`},
+		"trades.subscribe": {
+			`{"error": null, "result": {"status": "success"}, "id": 2}`,
+			`{"id": null, "method": "trades.update", "params": ["btc_usdt", [{"id": 4, "time": 1523339280.1, "price": "399", "amount": "1", "type": "buy"}, {"id": 3, "time": 1523339280.0, "price": "398", "amount": "2", "type": "sell"}]]}`,
+		},
+	})
+	defer server.Close()
+	g := NewSpotV4("", "", "ws"+strings.TrimPrefix(server.URL, "http"), g4.Logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	trades, err := g.ReqTrade(ctx, "btc_usdt", 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, "trades.query", (<-requests).Method)
+	require.Len(t, trades, 2)
+	require.Equal(t, int64(1), trades[0].Id)
+
+	ch := make(chan []exchange.TradeDetail, 1)
+	g.SubscribeTrade("btc_usdt", "test", func(details []exchange.TradeDetail) {
+		ch <- details
+	})
+	require.Equal(t, []interface{}{"btc_usdt"}, (<-requests).Params)
+	trades = <-ch
+	require.Len(t, trades, 2)
+	require.Equal(t, int64(3), trades[0].Id)
+	require.Equal(t, int64(4), trades[1].Id)
+
+	g.UnsubscribeTrade("btc_usdt", "test")
+	require.Equal(t, "trades.unsubscribe", (<-requests).Method)
+}
+
+func TestSpotV4_SubscribeOrderAfterAuth(t *testing.T) {
+	server, requests := newFakeWsServer(t, map[string][]string{
+		"server.sign":     {`{"error": null, "result": {"status": "success"}, "id": 100}`},
+		"order.subscribe": {`{"error": null, "result": {"status": "success"}, "id": 1}`},
+	})
+	defer server.Close()
+	g := NewSpotV4("key", "secret", "ws"+strings.TrimPrefix(server.URL, "http"), g4.Logger)
+
+	sub := g.SubscribeOrderUpdate("btc_usdt", "test", func(exchange.Order) {})
+	defer sub.Unsubscribe()
+	require.Equal(t, "server.sign", (<-requests).Method)
+	req := <-requests
+	require.Equal(t, "order.subscribe", req.Method)
+	require.Equal(t, []interface{}{"btc_usdt"}, req.Params)
+}
+
+func TestSpotV4_SubscriptionContext(t *testing.T) {
+	server, requests := newFakeWsServer(t, map[string][]string{
+		"depth.subscribe": {`{"error": null, "result": {"status": "success"}, "id": 1}`},
+	})
+	defer server.Close()
+	g := NewSpotV4("", "", "ws"+strings.TrimPrefix(server.URL, "http"), g4.Logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := g.SubDepth("btc_usdt", "test", 5, "0", func(exchange.DepthUpdate) {}).WithContext(ctx)
+	require.Equal(t, "depth.subscribe", (<-requests).Method)
+	require.Equal(t, 1, g.subs.Len())
+
+	cancel()
+	select {
+	case <-sub.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("not unsubscribed after cancel")
+	}
+	require.Equal(t, "depth.unsubscribe", (<-requests).Method)
+	require.Eventually(t, func() bool { return g.subs.Len() == 0 }, time.Second, 10*time.Millisecond)
+
+	// already unsubscribed, nothing is sent
+	g.UnsubDepth("btc_usdt", "test")
+	select {
+	case req := <-requests:
+		t.Fatalf("unexpected request %s", req.Method)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSpotV4_RecordReplay(t *testing.T) {
+	server, requests := newFakeWsServer(t, map[string][]string{
+		"trades.subscribe": {
+			`{"error": null, "result": {"status": "success"}, "id": 1}`,
+			`{"id": null, "method": "trades.update", "params": ["btc_usdt", [{"id": 2, "time": 1523339280.1, "price": "399", "amount": "1", "type": "buy"}, {"id": 1, "time": 1523339280.0, "price": "398", "amount": "2", "type": "sell"}]]}`,
+		},
+	})
+	defer server.Close()
+	var buf bytes.Buffer
+	g := NewSpotV4("", "", "ws"+strings.TrimPrefix(server.URL, "http"), g4.Logger)
+	g.SetRecorder(base.NewRecorder(&buf))
+
+	ch := make(chan []exchange.TradeDetail, 1)
+	sub := g.SubscribeTrade("btc_usdt", "test", func(details []exchange.TradeDetail) {
+		ch <- details
+	})
+	<-requests
+	live := <-ch
+	sub.Unsubscribe()
+
+	frames, err := base.ReadFrames(&buf)
+	require.NoError(t, err)
+	require.Len(t, frames, 2)
+
+	// offline, through the same message handler
+	client := new(WebsocketClient).Init("", "", g4.Logger)
+	client.SetHandler(nil, client.SubTradeHandler(func(details []exchange.TradeDetail) {
+		ch <- details
+	}))
+	require.NoError(t, client.Replay(context.Background(), frames, 0))
+	require.Equal(t, live, <-ch)
+
+	// replay server
+	replay := httptest.NewServer(base.NewReplayHandler(frames, 0))
+	defer replay.Close()
+	g = NewSpotV4("", "", "ws"+strings.TrimPrefix(replay.URL, "http"), g4.Logger)
+	sub = g.SubscribeTrade("btc_usdt", "test", func(details []exchange.TradeDetail) {
+		ch <- details
+	})
+	require.Equal(t, live, <-ch)
+	sub.Unsubscribe()
+}
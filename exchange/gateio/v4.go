@@ -2,6 +2,7 @@ package gateio
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/antihax/optional"
 	"github.com/gateio/gateapi-go/v5"
@@ -9,7 +10,9 @@ import (
 	"github.com/xyths/hs"
 	"github.com/xyths/hs/convert"
 	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
 	"go.uber.org/zap"
+	"net/http"
 	"time"
 )
 
@@ -24,12 +27,23 @@ type SpotV4 struct {
 	wsHost string
 	wsPath string
 
+	subs     *exchange.Subscriptions
+	recorder *base.Recorder
+
 	Logger *zap.SugaredLogger
 }
 
+var _ exchange.RestAPIExchangeV2 = (*SpotV4)(nil)
+var _ exchange.StreamAPIExchange = (*SpotV4)(nil)
+
+// SetRecorder records the raw messages of all websocket connections created after, nil to stop
+func (g *SpotV4) SetRecorder(recorder *base.Recorder) {
+	g.recorder = recorder
+}
+
 func NewSpotV4(key, secret, host string, logger *zap.SugaredLogger) *SpotV4 {
 	client := gateapi.NewAPIClient(gateapi.NewConfiguration())
-	return &SpotV4{Key: key, Secret: secret, client: client, wsHost: host, wsPath: "/v4", Logger: logger}
+	return &SpotV4{Key: key, Secret: secret, client: client, wsHost: host, wsPath: "/v4", subs: exchange.NewSubscriptions(), Logger: logger}
 }
 
 // class function layout
@@ -60,10 +74,11 @@ func (g *SpotV4) Currencies(ctx context.Context) ([]gateapi.Currency, error) {
 
 // 获取一定数量的k线
 // 可以用于策略启动时的查询
-func (g *SpotV4) CandleBySize(symbol string, period time.Duration, size int) (hs.Candle, error) {
-	return g.CandleBySizeContext(context.Background(), symbol, period, size)
-}
 func (g *SpotV4) CandleBySizeContext(ctx context.Context, symbol string, period time.Duration, size int) (hs.Candle, error) {
+	return g.CandleBySize(ctx, symbol, period, size)
+}
+
+func (g *SpotV4) CandleBySize(ctx context.Context, symbol string, period time.Duration, size int) (hs.Candle, error) {
 	interval := optional.NewString(getInterval(period))
 	left := size
 	to := time.Now()
@@ -104,6 +119,33 @@ func (g *SpotV4) CandleBySizeContext(ctx context.Context, symbol string, period
 	return candle, nil
 }
 
+// CandleFrom get candles in [from, to] by RESTful API, split into batches of maxCandleLength
+func (g *SpotV4) CandleFrom(ctx context.Context, symbol, clientId string, period time.Duration, from, to time.Time) (hs.Candle, error) {
+	interval := optional.NewString(getInterval(period))
+	size := int(to.Sub(from)/period) + 1
+	if size <= 0 {
+		return hs.Candle{}, errors.New("'from' need before 'to'")
+	}
+	candle := hs.NewCandle(size)
+	for start := from; !start.After(to); start = start.Add(period * maxCandleLength) {
+		end := start.Add(period * (maxCandleLength - 1))
+		if end.After(to) {
+			end = to
+		}
+		options := &gateapi.ListCandlesticksOpts{
+			From:     optional.NewInt64(start.Unix()),
+			To:       optional.NewInt64(end.Unix()),
+			Interval: interval,
+		}
+		c, err := g.listCandlesticks(ctx, symbol, options)
+		if err != nil {
+			return candle, err
+		}
+		candle.Add(c)
+	}
+	return candle, nil
+}
+
 func (g *SpotV4) AllSymbols(ctx context.Context) (symbols []exchange.Symbol, err error) {
 	pairs, _, err := g.client.SpotApi.ListCurrencyPairs(ctx)
 	if err != nil {
@@ -186,6 +228,28 @@ func (g *SpotV4) Balance(ctx context.Context) ([]exchange.Balance, error) {
 	return result, nil
 }
 
+// SpotBalance returns total balances (available + locked)
+func (g *SpotV4) SpotBalance(ctx context.Context) (map[string]decimal.Decimal, error) {
+	all, err := g.Balance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := make(map[string]decimal.Decimal)
+	for _, c := range all {
+		total := c.Available.Add(c.Locked)
+		if total.IsPositive() {
+			balance[c.Currency] = total
+		}
+	}
+	return balance, nil
+}
+
+// SpotAvailableBalance is alias of AvailableBalance
+func (g *SpotV4) SpotAvailableBalance(ctx context.Context) (map[string]decimal.Decimal, error) {
+	return g.AvailableBalance(ctx)
+}
+
 // AvailableBalance returns account available balances
 func (g *SpotV4) AvailableBalance(ctx context.Context) (map[string]decimal.Decimal, error) {
 	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
@@ -270,12 +334,16 @@ func (g *SpotV4) ListOpenOrders(ctx context.Context, symbol string) ([]exchange.
 	return g.ListOrders(ctx2, symbol, "open")
 }
 
+// GetOrder returns the spot order, or the price-triggered order if orderId has PriceOrderIdFlag
 func (g *SpotV4) GetOrder(ctx context.Context, symbol string, orderId uint64) (exchange.Order, error) {
+	if IsPriceOrderId(orderId) {
+		return g.getPriceOrder(ctx, orderId)
+	}
 	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
 		Key:    g.Key,
 		Secret: g.Secret,
 	})
-	raw, _, err := g.client.SpotApi.GetOrder(ctx2, symbol, fmt.Sprintf("%d", orderId))
+	raw, _, err := g.client.SpotApi.GetOrder(ctx2, fmt.Sprintf("%d", orderId), symbol)
 	if err != nil {
 		return exchange.Order{}, err
 	}
@@ -289,12 +357,15 @@ func (g *SpotV4) IsFullFilled(ctx context.Context, symbol string, orderId uint64
 	if err != nil {
 		return
 	}
-	filled = order.Status == "closed"
+	filled = order.FullFilled()
 	return
 }
 
-// Cancel order
+// Cancel order, or the price-triggered order if orderId has PriceOrderIdFlag
 func (g *SpotV4) CancelOrder(ctx context.Context, symbol string, orderId uint64) (exchange.Order, error) {
+	if IsPriceOrderId(orderId) {
+		return g.cancelPriceOrder(ctx, orderId)
+	}
 	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
 		Key:    g.Key,
 		Secret: g.Secret,
@@ -410,10 +481,53 @@ func (g *SpotV4) listCandlesticks(ctx context.Context, symbol string, options *g
 	return candles, nil
 }
 
+// PlaceOrder places limit order, post-only is "poc" of gate.
+// The iceberg order is sent by signed request, for the sdk has no iceberg field.
+func (g *SpotV4) PlaceOrder(ctx context.Context, symbol, clientOrderId string, side exchange.OrderType, price, amount decimal.Decimal, options exchange.OrderOptions) (exchange.Order, error) {
+	if err := v4OrderOptions.Check(options, amount); err != nil {
+		return exchange.Order{}, err
+	}
+	s := OrderTypeBuy
+	switch side {
+	case exchange.Buy:
+	case exchange.Sell:
+		s = OrderTypeSell
+	default:
+		return exchange.Order{}, exchange.ErrBadSide
+	}
+	if options.Iceberg.IsZero() {
+		ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+			Key:    g.Key,
+			Secret: g.Secret,
+		})
+		return g.placeOrder(ctx2, symbol, price, amount, s, timeInForce(options.TIF()), clientOrderId)
+	}
+	body := struct {
+		gateapi.Order
+		Iceberg string `json:"iceberg"`
+	}{
+		Order:   newOrderRequest(symbol, price, amount, s, timeInForce(options.TIF()), clientOrderId),
+		Iceberg: options.Iceberg.String(),
+	}
+	var r gateapi.Order
+	if err := signedRequest(ctx, g.client, g.Key, g.Secret, http.MethodPost, "/spot/orders", nil, body, &r); err != nil {
+		return exchange.Order{}, err
+	}
+	return convertNewOrder(r), nil
+}
+
 // placeOrder is a internal function
 // it convert struct gateio order to standard order type
 func (g *SpotV4) placeOrder(ctx context.Context, symbol string, price, amount decimal.Decimal, side, orderType, text string) (exchange.Order, error) {
-	orderRequest := gateapi.Order{
+	r, _, err := g.client.SpotApi.CreateOrder(ctx, newOrderRequest(symbol, price, amount, side, orderType, text))
+	if err != nil {
+		return exchange.Order{}, err
+	}
+	return convertNewOrder(r), nil
+}
+
+func newOrderRequest(symbol string, price, amount decimal.Decimal, side, orderType, text string) gateapi.Order {
+	return gateapi.Order{
 		Account:      "spot",
 		Type:         "limit",
 		CurrencyPair: symbol,
@@ -423,10 +537,10 @@ func (g *SpotV4) placeOrder(ctx context.Context, symbol string, price, amount de
 		TimeInForce:  orderType,
 		Text:         fmt.Sprintf("t-%s", text),
 	}
-	r, _, err := g.client.SpotApi.CreateOrder(ctx, orderRequest)
-	if err != nil {
-		return exchange.Order{}, err
-	}
+}
+
+// convertNewOrder converts the order returned by create order
+func convertNewOrder(r gateapi.Order) exchange.Order {
 	o := exchange.Order{
 		Id:            convert.StrToUint64(r.Id),
 		ClientOrderId: r.Text,
@@ -437,7 +551,9 @@ func (g *SpotV4) placeOrder(ctx context.Context, symbol string, price, amount de
 		Time:          time.Unix(convert.StrToInt64(r.CreateTime), 0),
 		Status:        r.Status,
 	}
-	return o, err
+	o.Side = exchange.ParseSide(r.Side)
+	o.State = orderStatus.Normalize(o.Status, decimal.Zero)
+	return o
 }
 
 // BuyFromTicker use Ticker's last price to place order.
@@ -507,14 +623,28 @@ func (g *SpotV4) UnsubscribeCandlestickWithReq(symbol, clientId string, period t
 	panic("implement me")
 }
 
-//func (g *SpotV4) GetFee(symbol string) (fee exchange.Fee, err error) {
-//	fee.Symbol = symbol
-//	fee.BaseMaker = decimal.NewFromFloat(DefaultMaker)
-//	fee.BaseTaker = decimal.NewFromFloat(DefaultTaker)
-//	fee.ActualMaker = fee.BaseMaker
-//	fee.ActualTaker = fee.BaseTaker
-//	return
-//}
+func (g *SpotV4) GetFee(ctx context.Context, symbol string) (fee exchange.Fee, err error) {
+	ctx2 := context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
+		Key:    g.Key,
+		Secret: g.Secret,
+	})
+	raw, _, err := g.client.SpotApi.GetFee(ctx2, &gateapi.GetFeeOpts{CurrencyPair: optional.NewString(symbol)})
+	if err != nil {
+		return
+	}
+	fee.Symbol = symbol
+	fee.BaseMaker = decimal.NewFromFloat(DefaultMaker)
+	fee.BaseTaker = decimal.NewFromFloat(DefaultTaker)
+	fee.ActualMaker = fee.BaseMaker
+	fee.ActualTaker = fee.BaseTaker
+	if d, err1 := decimal.NewFromString(raw.MakerFee); err1 == nil {
+		fee.ActualMaker = d
+	}
+	if d, err1 := decimal.NewFromString(raw.TakerFee); err1 == nil {
+		fee.ActualTaker = d
+	}
+	return
+}
 
 //// Depth
 //func (g *SpotV4) orderBooks() string {
@@ -565,14 +695,6 @@ func (g *SpotV4) orderBook(ctx context.Context, symbol string) (exchange.OrderBo
 //}
 //
 
-func (g *SpotV4) BuyStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (orderId uint64, err error) {
-	return 0, nil
-}
-
-func (g *SpotV4) SellStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (orderId uint64, err error) {
-	return 0, nil
-}
-
 // 获取我的24小时内成交记录
 //func (g *SpotV4) MyTradeHistory(currencyPair string) (*MyTradeHistoryResult, error) {
 //	method := "POST"
@@ -598,6 +720,7 @@ func (g *SpotV4) SellStopLimit(symbol, clientOrderId string, price, amount, stop
 // always call ReqPing use context context with a timeout
 func (g *SpotV4) ReqPing(ctx context.Context, id int64) (string, error) {
 	client := new(WebsocketClient).Init(g.wsHost, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	ch := make(chan string, 1)
 	client.SetHandler(
 		func() {
@@ -608,7 +731,10 @@ func (g *SpotV4) ReqPing(ctx context.Context, id int64) (string, error) {
 			pong, ok := resp.(string)
 			if ok {
 				g.Logger.Debugf("handler got response: %s", pong)
-				ch <- pong
+				select {
+				case ch <- pong:
+				default:
+				}
 			} else {
 				g.Logger.Error("wrong response")
 			}
@@ -630,6 +756,7 @@ func (g *SpotV4) ReqPing(ctx context.Context, id int64) (string, error) {
 
 func (g *SpotV4) ReqTime(ctx context.Context, id int64) (int64, error) {
 	client := new(WebsocketClient).Init(g.wsHost, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	ch := make(chan int64, 1)
 	client.SetHandler(
 		func() {
@@ -640,7 +767,10 @@ func (g *SpotV4) ReqTime(ctx context.Context, id int64) (int64, error) {
 			t, ok := resp.(int64)
 			if ok {
 				g.Logger.Debugf("handler got response: %d", t)
-				ch <- t
+				select {
+				case ch <- t:
+				default:
+				}
 			} else {
 				g.Logger.Error("wrong response")
 			}
@@ -662,6 +792,7 @@ func (g *SpotV4) ReqTime(ctx context.Context, id int64) (int64, error) {
 
 func (g *SpotV4) ReqTicker(ctx context.Context, id int64, symbol string, period time.Duration) (hs.Ticker, error) {
 	client := new(WebsocketClient).Init(g.wsHost, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	ch := make(chan hs.Ticker, 1)
 	client.SetHandler(
 		func() {
@@ -676,7 +807,10 @@ func (g *SpotV4) ReqTicker(ctx context.Context, id int64, symbol string, period
 			}
 			g.Logger.Debugf("handler got raw response: %d", t1)
 			t2, _ := parseTicker(t1)
-			ch <- t2
+			select {
+			case ch <- t2:
+			default:
+			}
 		}),
 	)
 	client.Connect(true)
@@ -693,24 +827,29 @@ func (g *SpotV4) ReqTicker(ctx context.Context, id int64, symbol string, period
 	}
 }
 
-func (g *SpotV4) SubTicker(id int64, symbol string, responseHandler exchange.ResponseHandler) {
+func (g *SpotV4) SubTicker(id int64, symbol string, responseHandler exchange.ResponseHandler) *exchange.Subscription {
 	client := new(WebsocketClient).Init(g.wsHost, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	client.SetHandler(
-		func() {
-			client.SubTicker(id, symbol)
-		},
+		nil,
 		client.SubTickerHandler(responseHandler),
 	)
+	client.SubTicker(id, symbol)
 	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubTicker(id)
+	})
+	g.subs.Add(subKey("ticker", symbol, fmt.Sprintf("%d", id)), sub)
+	return sub
 }
 
 func (g *SpotV4) UnsubTicker(id int64, symbol string) {
-	client := new(WebsocketClient).Init(g.wsHost, g.wsPath, g.Logger)
-	client.UnsubTicker(id)
+	g.subs.Unsubscribe(subKey("ticker", symbol, fmt.Sprintf("%d", id)))
 }
 
 func (g *SpotV4) ReqCandlestick(ctx context.Context, symbol, clientId string, period time.Duration, from, to time.Time) (hs.Candle, error) {
 	client := new(WebsocketClient).Init(g.wsHost, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
 	ch := make(chan hs.Candle, 1)
 	id := time.Now().Unix()
 	client.SetHandler(
@@ -722,7 +861,10 @@ func (g *SpotV4) ReqCandlestick(ctx context.Context, symbol, clientId string, pe
 			if !ok {
 				return
 			}
-			ch <- r
+			select {
+			case ch <- r:
+			default:
+			}
 		}),
 	)
 	client.Connect(true)
@@ -738,27 +880,157 @@ func (g *SpotV4) ReqCandlestick(ctx context.Context, symbol, clientId string, pe
 	}
 }
 
+// SubCandlestick pass hs.Ticker to responseHandler
 func (g *SpotV4) SubCandlestick(symbol, clientId string, period time.Duration,
-	responseHandler exchange.ResponseHandler) {
+	responseHandler exchange.ResponseHandler) *exchange.Subscription {
+	return g.SubscribeCandleUpdate(symbol, clientId, period, func(update exchange.CandleUpdate) {
+		responseHandler(update.Ticker)
+	})
+}
+
+func (g *SpotV4) SubscribeCandleUpdate(symbol, clientId string, period time.Duration, handler exchange.CandleHandler) *exchange.Subscription {
 	id := time.Now().Unix()
 	client := new(WebsocketClient).Init(g.wsHost, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
+	client.SetHandler(
+		nil,
+		client.SubCandleUpdateHandler(func(update exchange.CandleUpdate) {
+			update.Period = period
+			handler(update)
+		}),
+	)
+	client.SubCandle(id, symbol, int64(period.Seconds()))
+	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubCandle(id)
+	})
+	g.subs.Add(subKey("kline", symbol, clientId), sub)
+	return sub
+}
+
+func (g *SpotV4) UnsubCandlestick(symbol, clientId string) {
+	g.subs.Unsubscribe(subKey("kline", symbol, clientId))
+}
+
+// ReqDepth query depth snapshot, limit is one of 1, 5, 10, 20, 30, interval is price merge precision, "0" for no merge
+func (g *SpotV4) ReqDepth(ctx context.Context, symbol string, limit int, interval string) (exchange.DepthUpdate, error) {
+	client := new(WebsocketClient).Init(g.wsHost, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
+	ch := make(chan exchange.DepthUpdate, 1)
+	id := time.Now().Unix()
 	client.SetHandler(
 		func() {
-			client.SubCandle(id, symbol, int64(period.Seconds()))
+			client.ReqDepth(id, symbol, limit, interval)
 		},
-		client.SubCandleHandler(responseHandler),
+		client.ReqDepthHandler(func(resp interface{}) {
+			r, ok := resp.(exchange.DepthUpdate)
+			if !ok {
+				return
+			}
+			r.Symbol = symbol
+			select {
+			case ch <- r:
+			default:
+			}
+		}),
+	)
+	client.Connect(true)
+	defer client.Close()
+
+	select {
+	case d := <-ch:
+		return d, nil
+	case <-ctx.Done():
+		return exchange.DepthUpdate{}, ctx.Err()
+	}
+}
+
+// SubDepth subscribe depth, the first update is snapshot, then incremental
+func (g *SpotV4) SubDepth(symbol, clientId string, limit int, interval string, responseHandler exchange.DepthHandler) *exchange.Subscription {
+	id := time.Now().Unix()
+	client := new(WebsocketClient).Init(g.wsHost, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
+	client.SetHandler(
+		nil,
+		client.SubDepthHandler(responseHandler),
 	)
+	client.SubDepth(id, symbol, limit, interval)
 	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubDepth(id)
+	})
+	g.subs.Add(subKey("depth", symbol, clientId), sub)
+	return sub
 }
 
-func (g *SpotV4) UnsubCandlestick(symbol, clientId string) {
+func (g *SpotV4) UnsubDepth(symbol, clientId string) {
+	g.subs.Unsubscribe(subKey("depth", symbol, clientId))
+}
+
+// ReqTrade query the latest trades after lastId, 0 for the latest
+func (g *SpotV4) ReqTrade(ctx context.Context, symbol string, limit int, lastId int64) ([]exchange.TradeDetail, error) {
 	client := new(WebsocketClient).Init(g.wsHost, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
+	ch := make(chan []exchange.TradeDetail, 1)
+	id := time.Now().Unix()
+	client.SetHandler(
+		func() {
+			client.ReqTrade(id, symbol, limit, lastId)
+		},
+		client.ReqTradeHandler(func(resp interface{}) {
+			r, ok := resp.([]exchange.TradeDetail)
+			if !ok {
+				return
+			}
+			select {
+			case ch <- r:
+			default:
+			}
+		}),
+	)
+	client.Connect(true)
+	defer client.Close()
+
+	select {
+	case t := <-ch:
+		return t, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *SpotV4) SubscribeTrade(symbol, clientId string, responseHandler exchange.TradeHandler) *exchange.Subscription {
+	return g.SubTrade(symbol, clientId, responseHandler)
+}
+
+func (g *SpotV4) UnsubscribeTrade(symbol, clientId string) {
+	g.UnsubTrade(symbol, clientId)
+}
+
+func (g *SpotV4) SubTrade(symbol, clientId string, responseHandler exchange.TradeHandler) *exchange.Subscription {
 	id := time.Now().Unix()
-	client.UnsubCandle(id)
+	client := new(WebsocketClient).Init(g.wsHost, g.wsPath, g.Logger)
+	client.SetRecorder(g.recorder)
+	client.SetHandler(
+		nil,
+		client.SubTradeHandler(responseHandler),
+	)
+	client.SubTrade(id, []string{symbol})
+	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubTrade(id)
+	})
+	g.subs.Add(subKey("trades", symbol, clientId), sub)
+	return sub
+}
+
+func (g *SpotV4) UnsubTrade(symbol, clientId string) {
+	g.subs.Unsubscribe(subKey("trades", symbol, clientId))
 }
 
 func (g *SpotV4) ReqOrder(ctx context.Context, symbol, clientId string) (orders []exchange.Order, err error) {
 	client := new(PrivateWebsocketClient).Init(g.wsHost, g.wsPath, g.Key, g.Secret, g.Logger)
+	client.SetRecorder(g.recorder)
 	id := time.Now().Unix()
 	ch := make(chan ResponseReqOrder, 10)
 	done := make(chan int, 1)
@@ -771,16 +1043,25 @@ func (g *SpotV4) ReqOrder(ctx context.Context, symbol, clientId string) (orders
 		client.ReqOrderHandler(func(resp interface{}) {
 			if resp == nil {
 				g.Logger.Debug("no open order")
-				done <- 1
+				select {
+				case done <- 1:
+				default:
+				}
 				return
 			}
 			r, ok := resp.(ResponseReqOrder)
 			if !ok {
 				g.Logger.Debug("response not ok")
-				done <- 1
+				select {
+				case done <- 1:
+				default:
+				}
 				return
 			}
-			ch <- r
+			select {
+			case ch <- r:
+			default:
+			}
 		}),
 	)
 	client.Connect(true)
@@ -811,19 +1092,59 @@ func (g *SpotV4) ReqOrder(ctx context.Context, symbol, clientId string) (orders
 	}
 }
 
-// TODO: 应该改造为使用context，当context取消时，自动退出订阅
+// SubOrder blocks until ctx is done, then unsubscribes and closes the connection
 func (g *SpotV4) SubOrder(ctx context.Context, symbol, clientId string, responseHandler exchange.ResponseHandler) {
+	sub := g.SubscribeOrderUpdate(symbol, clientId, func(order exchange.Order) {
+		responseHandler(order)
+	})
+	select {
+	case <-ctx.Done():
+		sub.Unsubscribe()
+	case <-sub.Done():
+	}
+}
+
+func (g *SpotV4) SubscribeOrderUpdate(symbol, clientId string, handler exchange.OrderHandler) *exchange.Subscription {
 	id := time.Now().Unix()
 	client := new(PrivateWebsocketClient).Init(g.wsHost, g.wsPath, g.Key, g.Secret, g.Logger)
+	client.SetRecorder(g.recorder)
 	client.SetHandler(
-		func() {
-			client.SubOrder(id, []string{symbol})
-		},
-		client.SubOrderHandler(responseHandler),
+		nil,
+		client.SubOrderUpdateHandler(handler),
+	)
+	client.SubOrder(id, []string{symbol})
+	client.Connect(true)
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubOrder(id, []string{symbol})
+	})
+	g.subs.Add(subKey("order", symbol, clientId), sub)
+	return sub
+}
+
+func (g *SpotV4) UnsubscribeOrderUpdate(symbol, clientId string) {
+	g.subs.Unsubscribe(subKey("order", symbol, clientId))
+}
+
+// SubscribeBalanceUpdate subscribe balance of all currencies
+func (g *SpotV4) SubscribeBalanceUpdate(clientId string, handler exchange.BalanceHandler) *exchange.Subscription {
+	id := time.Now().Unix()
+	client := new(PrivateWebsocketClient).Init(g.wsHost, g.wsPath, g.Key, g.Secret, g.Logger)
+	client.SetRecorder(g.recorder)
+	client.SetHandler(
+		nil,
+		client.SubBalanceUpdateHandler(handler),
 	)
+	client.SubBalance(id, nil)
 	client.Connect(true)
-	<-ctx.Done()
-	client.UnsubOrder(id, []string{symbol})
+	sub := newSubscription(&client.WebsocketBase, func() {
+		client.UnsubBalance(id, nil)
+	})
+	g.subs.Add(subKey("balance", "", clientId), sub)
+	return sub
+}
+
+func (g *SpotV4) UnsubscribeBalanceUpdate(clientId string) {
+	g.subs.Unsubscribe(subKey("balance", "", clientId))
 }
 
 //func (g *SpotV4) UnsubOrder(symbol, clientId string) {
@@ -3,7 +3,13 @@ package gateio
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs/exchange"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -66,3 +72,52 @@ func TestSpotV4_AllSymbols(t *testing.T) {
 		t.Logf("[%d] %s", i, string(b))
 	}
 }
+
+func TestSpotV4_PlaceOrder(t *testing.T) {
+	type request struct {
+		method, path, sign, wantSign string
+		body                         []byte
+	}
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- request{
+			method:   r.Method,
+			path:     r.URL.Path,
+			sign:     r.Header.Get("SIGN"),
+			wantSign: sign("secret", r.Method, r.URL.Path, r.URL.RawQuery, body, r.Header.Get("Timestamp")),
+			body:     body,
+		}
+		_, _ = w.Write([]byte(`{"id":"12332324","text":"t-ice","create_time":"1548000000","status":"open","currency_pair":"BTC_USDT","type":"limit","account":"spot","side":"buy","amount":"1","price":"9000","time_in_force":"poc","left":"1"}`))
+	}))
+	defer server.Close()
+
+	g := NewSpotV4("key", "secret", "", nil)
+	g.client.GetConfig().BasePath = server.URL + "/api/v4"
+	o, err := g.PlaceOrder(context.Background(), "BTC_USDT", "ice", exchange.Buy, decimal.NewFromInt(9000), decimal.NewFromInt(1),
+		exchange.OrderOptions{TimeInForce: exchange.PostOnly, Iceberg: decimal.RequireFromString("0.1")})
+	require.NoError(t, err)
+
+	r := <-requests
+	require.Equal(t, http.MethodPost, r.method)
+	require.Equal(t, "/api/v4/spot/orders", r.path)
+	require.Equal(t, r.wantSign, r.sign)
+	var order map[string]interface{}
+	require.NoError(t, json.Unmarshal(r.body, &order))
+	require.Equal(t, "BTC_USDT", order["currency_pair"])
+	require.Equal(t, "buy", order["side"])
+	require.Equal(t, OrderTypePOC, order["time_in_force"])
+	require.Equal(t, "0.1", order["iceberg"])
+	require.Equal(t, "t-ice", order["text"])
+
+	require.Equal(t, uint64(12332324), o.Id)
+	require.Equal(t, exchange.Buy, o.Side)
+	require.Equal(t, exchange.Open, o.State)
+
+	_, err = g.PlaceOrder(context.Background(), "BTC_USDT", "ro", exchange.Sell, decimal.NewFromInt(9000), decimal.NewFromInt(1), exchange.OrderOptions{ReduceOnly: true})
+	require.True(t, errors.Is(err, exchange.ErrUnsupportedOption))
+	_, err = g.PlaceOrder(context.Background(), "BTC_USDT", "ice", exchange.Buy, decimal.NewFromInt(9000), decimal.NewFromInt(1),
+		exchange.OrderOptions{Iceberg: decimal.NewFromInt(1)})
+	require.True(t, errors.Is(err, exchange.ErrBadIceberg))
+	require.Empty(t, requests)
+}
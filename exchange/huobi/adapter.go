@@ -0,0 +1,14 @@
+package huobi
+
+import "github.com/xyths/hs/exchange"
+
+// Client is exchange.RestAPIExchange, and used as exchange.RestAPIExchangeV2 through adapter
+var (
+	_ exchange.RestAPIExchange   = (*Client)(nil)
+	_ exchange.RestAPIExchangeV2 = NewAdapter(nil)
+)
+
+// NewAdapter wraps Client as exchange.RestAPIExchangeV2
+func NewAdapter(c *Client) *exchange.RestAPIAdapter {
+	return exchange.NewRestAPIAdapter(c)
+}
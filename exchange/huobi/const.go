@@ -1,5 +1,7 @@
 package huobi
 
+import "github.com/xyths/hs/exchange"
+
 const (
 	OrderTypeBuyMarket  = "buy-market"
 	OrderTypeSellMarket = "sell-market"
@@ -40,3 +42,27 @@ const (
 	OrderTypeBuyStopLimitFok  = "buy-stop-limit-fok"
 	OrderTypeSellStopLimitFok = "sell-stop-limit-fok"
 )
+
+// 订单状态
+const (
+	OrderStateCreated         = "created" // 止盈止损订单未触发
+	OrderStateSubmitted       = "submitted"
+	OrderStatePartialFilled   = "partial-filled"
+	OrderStateFilled          = "filled"
+	OrderStatePartialCanceled = "partial-canceled"
+	OrderStateCanceling       = "canceling"
+	OrderStateCanceled        = "canceled"
+	OrderStateRejected        = "rejected" // 止盈止损订单触发失败
+)
+
+// orderState 把REST和WebSocket的订单状态转换成exchange.OrderStatus
+var orderState = exchange.StatusTable{
+	OrderStateCreated:         exchange.Open,
+	OrderStateSubmitted:       exchange.Open,
+	OrderStatePartialFilled:   exchange.Filled,
+	OrderStateFilled:          exchange.Closed,
+	OrderStatePartialCanceled: exchange.PartialCancelled,
+	OrderStateCanceling:       exchange.Open,
+	OrderStateCanceled:        exchange.Cancelled,
+	OrderStateRejected:        exchange.Rejected,
+}
@@ -0,0 +1,177 @@
+package huobi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/huobirdcenter/huobi_golang/logging/applogger"
+	"github.com/huobirdcenter/huobi_golang/pkg/client"
+	"github.com/huobirdcenter/huobi_golang/pkg/client/marketwebsocketclient"
+	"github.com/huobirdcenter/huobi_golang/pkg/client/websocketclientbase"
+	"github.com/huobirdcenter/huobi_golang/pkg/model/market"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs/exchange"
+	"strings"
+	"time"
+)
+
+// OrderBook 通过RESTful接口获取深度快照，size可选5，10，20，0表示默认的150档
+func (c *Client) OrderBook(symbol string, size int) (exchange.OrderBook, error) {
+	hb := new(client.MarketClient).Init(c.Host)
+	depth, err := hb.GetDepth(symbol, market.STEP0, market.GetDepthOptionalRequest{Size: size})
+	if err != nil {
+		return exchange.OrderBook{}, err
+	}
+	return convertDepth(depth), nil
+}
+
+// SubscribeDepth 订阅150档MBP增量深度。
+// 连接后会先请求一次全量快照（Snapshot为true），之后是增量（PrevSeq/LastSeq），可以直接交给book.Book维护。
+func (c *Client) SubscribeDepth(symbol, clientId string, responseHandler exchange.DepthHandler) *exchange.Subscription {
+	hb := new(marketwebsocketclient.MarketByPriceWebSocketClient).Init(c.Host)
+	guard := new(handlerGuard)
+	hb.WebSocketClientBase.SetHandler(
+		// Connected handler
+		func() {
+			hb.Subscribe(symbol, clientId)
+			hb.Request(symbol, clientId)
+		},
+		c.recording(parseMarketByPrice),
+		guard.wrap(depthHandler(symbol, responseHandler)),
+	)
+
+	hb.Connect(true)
+	return c.subscribe(subKey("mbp", symbol, clientId), hb, guard, func() {
+		hb.UnSubscribe(symbol, clientId)
+	})
+}
+
+func (c *Client) UnsubscribeDepth(symbol, clientId string) {
+	c.subs.Unsubscribe(subKey("mbp", symbol, clientId))
+}
+
+// SubscribeFullDepth 订阅MBP全量深度，level可选5，10，20，每次推送都是快照
+func (c *Client) SubscribeFullDepth(symbol, clientId string, level int, responseHandler exchange.DepthHandler) *exchange.Subscription {
+	hb := new(marketwebsocketclient.MarketByPriceWebSocketClient).Init(c.Host)
+	guard := new(handlerGuard)
+	hb.WebSocketClientBase.SetHandler(
+		// Connected handler
+		func() {
+			hb.SubscribeFull(symbol, level, clientId)
+		},
+		c.recording(parseMarketByPrice),
+		guard.wrap(depthHandler(symbol, responseHandler)),
+	)
+
+	hb.Connect(true)
+	return c.subscribe(subKey("mbp.refresh", fmt.Sprintf("%s.%d", symbol, level), clientId), hb, guard, func() {
+		hb.UnSubscribeFull(symbol, level, clientId)
+	})
+}
+
+func (c *Client) UnsubscribeFullDepth(symbol, clientId string, level int) {
+	c.subs.Unsubscribe(subKey("mbp.refresh", fmt.Sprintf("%s.%d", symbol, level), clientId))
+}
+
+// ReqDepth 通过websocket请求150档MBP全量快照，序列号和增量推送一致，可以用作book.Snapshotter
+func (c *Client) ReqDepth(ctx context.Context, symbol, clientId string) (exchange.DepthUpdate, error) {
+	hb := new(marketwebsocketclient.MarketByPriceWebSocketClient).Init(c.Host)
+	ch := make(chan exchange.DepthUpdate, 1)
+	hb.SetHandler(
+		func() {
+			hb.Request(symbol, clientId)
+		},
+		depthHandler(symbol, func(update exchange.DepthUpdate) {
+			if update.Snapshot {
+				select {
+				case ch <- update:
+				default:
+				}
+			}
+		}),
+	)
+	hb.Connect(true)
+	defer hb.Close()
+
+	select {
+	case d := <-ch:
+		return d, nil
+	case <-ctx.Done():
+		return exchange.DepthUpdate{}, ctx.Err()
+	}
+}
+
+// depthHandler 转换MBP推送，Symbol是订阅的交易对，req的应答没有ch
+func depthHandler(symbol string, responseHandler exchange.DepthHandler) websocketclientbase.ResponseHandler {
+	return func(response interface{}) {
+		mbpResponse, ok := response.(market.SubscribeMarketByPriceResponse)
+		if !ok {
+			applogger.Warn("Unknown response: %v", response)
+			return
+		}
+		update, err := convertMarketByPrice(symbol, mbpResponse)
+		if err != nil {
+			// subscribe response etc.
+			return
+		}
+		responseHandler(update)
+	}
+}
+
+// convertMarketByPrice 转换MBP推送。
+// Tick是推送：增量的mbp.150有prevSeqNum，全量的mbp.refresh没有，视为快照；Data是req的全量应答，没有ch和ts，用symbol和当前时间。
+func convertMarketByPrice(symbol string, r market.SubscribeMarketByPriceResponse) (exchange.DepthUpdate, error) {
+	update := exchange.DepthUpdate{
+		Symbol: symbol,
+		Time:   time.Unix(0, r.Timestamp*int64(time.Millisecond)),
+	}
+	if r.Timestamp == 0 {
+		update.Time = time.Now()
+	}
+	var mbp *market.MarketByPrice
+	switch {
+	case r.Tick != nil:
+		mbp = r.Tick
+		update.Snapshot = strings.Contains(r.Channel, ".refresh.")
+	case r.Data != nil:
+		mbp = r.Data
+		update.Snapshot = true
+	default:
+		return update, errors.New("no mbp data")
+	}
+	update.LastSeq = uint64(mbp.SeqNum)
+	if !update.Snapshot {
+		update.PrevSeq = uint64(mbp.PrevSeqNum)
+	}
+	update.Bids = convertLevels(mbp.Bids)
+	update.Asks = convertLevels(mbp.Asks)
+	return update, nil
+}
+
+func convertLevels(raw [][]decimal.Decimal) []exchange.DepthLevel {
+	levels := make([]exchange.DepthLevel, 0, len(raw))
+	for _, r := range raw {
+		if len(r) < 2 {
+			continue
+		}
+		levels = append(levels, exchange.DepthLevel{Price: r[0], Amount: r[1]})
+	}
+	return levels
+}
+
+func convertDepth(depth *market.Depth) exchange.OrderBook {
+	ob := exchange.OrderBook{Id: int(depth.Version)}
+	for _, b := range depth.Bids {
+		if len(b) < 2 {
+			continue
+		}
+		ob.Bids = append(ob.Bids, exchange.Quote{b[0].InexactFloat64(), b[1].InexactFloat64()})
+	}
+	for _, a := range depth.Asks {
+		if len(a) < 2 {
+			continue
+		}
+		ob.Asks = append(ob.Asks, exchange.Quote{a[0].InexactFloat64(), a[1].InexactFloat64()})
+	}
+	return ob
+}
@@ -0,0 +1,93 @@
+package huobi
+
+import (
+	"encoding/json"
+	"github.com/huobirdcenter/huobi_golang/pkg/model/market"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs/book"
+	"github.com/xyths/hs/exchange"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestConvertMarketByPrice(t *testing.T) {
+	var tests = []struct {
+		Raw      string
+		Snapshot bool
+		Last     uint64
+		Prev     uint64
+		Bids     int
+		Asks     int
+	}{
+		{`{"ch":"market.btcusdt.mbp.150","ts":1573199608679,"tick":{"seqNum":100020146795,"prevSeqNum":100020146794,"asks":[[645.140000000000000000,26.755973959140651643]]}}`,
+			false, 100020146795, 100020146794, 0, 1},
+		{`{"id":"id1","rep":"market.btcusdt.mbp.150","status":"ok","data":{"seqNum":100020142010,"bids":[[618.37,71.594],[423.33,77.726]],"asks":[[623.19,13.138]]}}`,
+			true, 100020142010, 0, 2, 1},
+		{`{"ch":"market.btcusdt.mbp.refresh.5","ts":1573199608679,"tick":{"seqNum":100020146795,"bids":[[618.37,71.594]],"asks":[[623.19,13.138]]}}`,
+			true, 100020146795, 0, 1, 1},
+	}
+	for i, tt := range tests {
+		var r market.SubscribeMarketByPriceResponse
+		require.NoError(t, json.Unmarshal([]byte(tt.Raw), &r))
+		u, err := convertMarketByPrice("btcusdt", r)
+		require.NoError(t, err, i)
+		require.Equal(t, "btcusdt", u.Symbol, i)
+		require.False(t, u.Time.IsZero(), i)
+		require.Equal(t, tt.Snapshot, u.Snapshot, i)
+		require.Equal(t, tt.Last, u.LastSeq, i)
+		require.Equal(t, tt.Prev, u.PrevSeq, i)
+		require.Len(t, u.Bids, tt.Bids, i)
+		require.Len(t, u.Asks, tt.Asks, i)
+	}
+
+	var r market.SubscribeMarketByPriceResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"id1","status":"ok","subbed":"market.btcusdt.mbp.150","ts":1489474081631}`), &r))
+	_, err := convertMarketByPrice("btcusdt", r)
+	require.Error(t, err)
+}
+
+func TestDepthHandler_Books(t *testing.T) {
+	books := book.NewBooks(nil)
+	handler := depthHandler("btcusdt", books.Handler())
+	for _, raw := range []string{
+		`{"id":"id1","rep":"market.btcusdt.mbp.150","status":"ok","data":{"seqNum":100,"bids":[[618.37,71.594]],"asks":[[623.19,13.138]]}}`,
+		`{"ch":"market.btcusdt.mbp.150","ts":1573199608679,"tick":{"seqNum":101,"prevSeqNum":100,"bids":[[619,1]]}}`,
+	} {
+		var r market.SubscribeMarketByPriceResponse
+		require.NoError(t, json.Unmarshal([]byte(raw), &r))
+		handler(r)
+	}
+	_, ok := books.Get("")
+	require.False(t, ok)
+	b, ok := books.Get("btcusdt")
+	require.True(t, ok)
+	bid, ok := b.BestBid()
+	require.True(t, ok)
+	require.Equal(t, "619", bid.Price.String())
+}
+
+func TestClient_OrderBook(t *testing.T) {
+	ob, err := c.OrderBook("btcusdt", 5)
+	require.NoError(t, err)
+	require.Len(t, ob.Bids, 5)
+	require.Len(t, ob.Asks, 5)
+	t.Logf("version %d, bids %v, asks %v", ob.Id, ob.Bids, ob.Asks)
+}
+
+func TestClient_SubscribeDepth(t *testing.T) {
+	handler := func(update exchange.DepthUpdate) {
+		t.Logf("snapshot: %v, seq: %d -> %d, bids: %d, asks: %d",
+			update.Snapshot, update.PrevSeq, update.LastSeq, len(update.Bids), len(update.Asks))
+	}
+	symbol := "btcusdt"
+	clientId := "depthtest"
+	c.SubscribeDepth(symbol, clientId, handler)
+	log.Println("subscribed")
+	defer func() {
+		c.UnsubscribeDepth(symbol, clientId)
+		log.Println("unsubscribed")
+	}()
+
+	time.Sleep(time.Minute * 1)
+}
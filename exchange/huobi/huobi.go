@@ -19,7 +19,9 @@ import (
 	"github.com/xyths/hs"
 	"github.com/xyths/hs/convert"
 	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
 	"github.com/xyths/hs/logger"
+	"go.uber.org/zap"
 	"log"
 	"sort"
 	"strconv"
@@ -38,6 +40,9 @@ type Client struct {
 	Host      string
 
 	SpotAccountId int64
+
+	subs     *exchange.Subscriptions
+	recorder *base.Recorder
 }
 
 func New(label, accessKey, secretKey, host string) (*Client, error) {
@@ -45,6 +50,7 @@ func New(label, accessKey, secretKey, host string) (*Client, error) {
 		Label:     label,
 		AccessKey: accessKey,
 		SecretKey: secretKey,
+		subs:      exchange.NewSubscriptions(),
 	}
 	if host != "" {
 		c.Host = host
@@ -60,6 +66,16 @@ func New(label, accessKey, secretKey, host string) (*Client, error) {
 	return c, nil
 }
 
+func init() {
+	exchange.Register(hs.Huobi, func(conf hs.ExchangeConf, logger *zap.SugaredLogger) (exchange.Exchange, error) {
+		c, err := New(conf.Label, conf.Key, conf.Secret, conf.Host)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	})
+}
+
 func (c *Client) Name() string {
 	return "huobi"
 }
@@ -395,9 +411,11 @@ func (c *Client) GetOrderById(orderId uint64, _ string) (exchange.Order, error)
 		Amount:        decimal.RequireFromString(d.Amount),
 		// for huobi, CreatedAt is ms
 		Time:         time.Unix(d.CreatedAt/1000, d.CreatedAt%1000),
+		Side:         exchange.ParseSide(d.Type),
 		Status:       d.State,
 		FilledAmount: decimal.RequireFromString(d.FilledAmount),
 	}
+	o.State = orderState.Normalize(o.Status, o.FilledAmount)
 	return o, nil
 }
 
@@ -406,11 +424,14 @@ func (c *Client) IsFullFilled(symbol string, orderId uint64) (order exchange.Ord
 	if err != nil {
 		return
 	}
-	filled = order.Status == "filled"
+	filled = order.FullFilled()
 	return
 }
 
-func (c *Client) PlaceOrder(request *order.PlaceOrderRequest) (uint64, error) {
+// PlaceRequest places the raw request of huobi sdk.
+// It was named PlaceOrder, which is the exchange.RestAPIExchange method with order options now,
+// so the callers with *order.PlaceOrderRequest should be changed to PlaceRequest.
+func (c *Client) PlaceRequest(request *order.PlaceOrderRequest) (uint64, error) {
 	hb := new(client.OrderClient).Init(c.AccessKey, c.SecretKey, c.Host)
 	resp, err := hb.PlaceOrder(request)
 	if err != nil {
@@ -430,6 +451,40 @@ func (c *Client) PlaceOrder(request *order.PlaceOrderRequest) (uint64, error) {
 	return 0, errors.New("unknown status")
 }
 
+// orderOptions lists the spot order options, the time in force is part of the order type of huobi
+var orderOptions = exchange.OptionSupport{
+	Name:        "huobi",
+	TimeInForce: []exchange.TimeInForce{exchange.GTC, exchange.IOC, exchange.FOK, exchange.PostOnly},
+}
+
+// limitOrderTypes maps the side and time in force to order type
+var limitOrderTypes = map[exchange.OrderType]map[exchange.TimeInForce]string{
+	exchange.Buy: {
+		exchange.GTC:      OrderTypeBuyLimit,
+		exchange.IOC:      OrderTypeBuyIoc,
+		exchange.FOK:      OrderTypeBuyLimitFok,
+		exchange.PostOnly: OrderTypeBuyLimitMaker,
+	},
+	exchange.Sell: {
+		exchange.GTC:      OrderTypeSellLimit,
+		exchange.IOC:      OrderTypeSellIoc,
+		exchange.FOK:      OrderTypeSellLimitFok,
+		exchange.PostOnly: OrderTypeSellLimitMaker,
+	},
+}
+
+// PlaceOrder places spot limit order, huobi has no iceberg or reduce-only order
+func (c *Client) PlaceOrder(symbol, clientOrderId string, side exchange.OrderType, price, amount decimal.Decimal, options exchange.OrderOptions) (orderId uint64, err error) {
+	if err = orderOptions.Check(options, amount); err != nil {
+		return
+	}
+	types, ok := limitOrderTypes[side]
+	if !ok {
+		return 0, exchange.ErrBadSide
+	}
+	return c.SpotLimitOrder(types[options.TIF()], symbol, clientOrderId, price, amount)
+}
+
 func (c *Client) SpotLimitOrder(orderType, symbol, clientOrderId string, price, amount decimal.Decimal) (uint64, error) {
 	request := order.PlaceOrderRequest{
 		AccountId:     fmt.Sprintf("%d", c.SpotAccountId),
@@ -440,7 +495,7 @@ func (c *Client) SpotLimitOrder(orderType, symbol, clientOrderId string, price,
 		Amount:        amount.String(),
 		ClientOrderId: clientOrderId,
 	}
-	return c.PlaceOrder(&request)
+	return c.PlaceRequest(&request)
 }
 
 func (c *Client) SpotMarketOrder(orderType, symbol, clientOrderId string, total decimal.Decimal) (uint64, error) {
@@ -452,7 +507,7 @@ func (c *Client) SpotMarketOrder(orderType, symbol, clientOrderId string, total
 		Amount:        total.String(),
 		ClientOrderId: clientOrderId,
 	}
-	return c.PlaceOrder(&request)
+	return c.PlaceRequest(&request)
 }
 
 func (c *Client) SpotStopLimitOrder(orderType, symbol, clientOrderId, operator string, price, amount, stopPrice decimal.Decimal) (uint64, error) {
@@ -467,7 +522,7 @@ func (c *Client) SpotStopLimitOrder(orderType, symbol, clientOrderId, operator s
 		StopPrice:     stopPrice.String(),
 		Operator:      operator,
 	}
-	return c.PlaceOrder(&request)
+	return c.PlaceRequest(&request)
 }
 
 func (c *Client) BuyLimit(symbol, clientOrderId string, price, amount decimal.Decimal) (orderId uint64, err error) {
@@ -529,6 +584,7 @@ func (c *Client) SubscribeLast24hCandlestick(ctx context.Context, symbol, client
 	select {
 	case <-ctx.Done():
 		hb.UnSubscribe(symbol, clientId)
+		hb.Close()
 		log.Printf("UnSubscribed, symbol = %s, clientId = %s", symbol, clientId)
 	}
 	return nil
@@ -536,23 +592,31 @@ func (c *Client) SubscribeLast24hCandlestick(ctx context.Context, symbol, client
 
 func (c *Client) SubscribeCandlestick(symbol, clientId string, period time.Duration,
 	responseHandler exchange.ResponseHandler) {
+	c.subscribeCandlestick(symbol, clientId, period, websocketclientbase.ResponseHandler(responseHandler))
+}
+
+func (c *Client) subscribeCandlestick(symbol, clientId string, period time.Duration,
+	responseHandler websocketclientbase.ResponseHandler) *exchange.Subscription {
 	periodStr := getPeriodString(period)
 	hb := new(marketwebsocketclient.CandlestickWebSocketClient).Init(c.Host)
-	hb.SetHandler(
+	guard := new(handlerGuard)
+	hb.WebSocketClientBase.SetHandler(
 		// Connected handler
 		func() {
 			hb.Subscribe(symbol, periodStr, clientId)
 		},
-		websocketclientbase.ResponseHandler(responseHandler),
+		c.recording(parseCandlestick),
+		guard.wrap(responseHandler),
 	)
 
 	hb.Connect(true)
+	return c.subscribe(subKey("kline", symbol+"."+periodStr, clientId), hb, guard, func() {
+		hb.UnSubscribe(symbol, periodStr, clientId)
+	})
 }
 
 func (c *Client) UnsubscribeCandlestick(symbol, clientId string, period time.Duration) {
-	periodStr := getPeriodString(period)
-	hb := new(marketwebsocketclient.CandlestickWebSocketClient).Init(c.Host)
-	hb.UnSubscribe(symbol, periodStr, clientId)
+	c.subs.Unsubscribe(subKey("kline", symbol+"."+getPeriodString(period), clientId))
 }
 
 const CandlestickReqMaxLength = 300
@@ -563,26 +627,34 @@ func (c *Client) SubscribeCandlestickWithReq(symbol, clientId string, period tim
 	now := time.Now()
 	periodStr := getPeriodString(period)
 	start := now.Add(-CandlestickReqMaxLength * period)
-	hb.SetHandler(
+	guard := new(handlerGuard)
+	hb.WebSocketClientBase.SetHandler(
 		// Connected handler
 		func() {
 			hb.Request(symbol, periodStr, start.Unix(), now.Unix(), clientId)
 			hb.Subscribe(symbol, periodStr, clientId)
 		},
-		websocketclientbase.ResponseHandler(responseHandler))
+		c.recording(parseCandlestick),
+		guard.wrap(websocketclientbase.ResponseHandler(responseHandler)))
 
 	hb.Connect(true)
+	c.subscribe(subKey("klinereq", symbol+"."+periodStr, clientId), hb, guard, func() {
+		hb.UnSubscribe(symbol, periodStr, clientId)
+	})
 }
 func (c *Client) UnsubscribeCandlestickWithReq(symbol, clientId string, period time.Duration) {
-	hb := new(marketwebsocketclient.CandlestickWebSocketClient).Init(c.Host)
-	periodStr := getPeriodString(period)
-	hb.UnSubscribe(symbol, periodStr, clientId)
+	c.subs.Unsubscribe(subKey("klinereq", symbol+"."+getPeriodString(period), clientId))
 }
 
 func (c *Client) SubscribeOrder(symbol, clientId string, responseHandler exchange.ResponseHandler) {
+	c.subscribeOrder(symbol, clientId, websocketclientbase.ResponseHandler(responseHandler))
+}
+
+func (c *Client) subscribeOrder(symbol, clientId string, responseHandler websocketclientbase.ResponseHandler) *exchange.Subscription {
 	hb := new(orderwebsocketclient.SubscribeOrderWebSocketV2Client).Init(c.AccessKey, c.SecretKey, c.Host)
 
-	hb.SetHandler(
+	guard := new(handlerGuard)
+	hb.WebSocketV2ClientBase.SetHandler(
 		// Connected handler
 		func(resp *auth.WebSocketV2AuthenticationResponse) {
 			if resp.IsSuccess() {
@@ -592,19 +664,23 @@ func (c *Client) SubscribeOrder(symbol, clientId string, responseHandler exchang
 				log.Fatalf("Authentication error, code: %d, message:%s", resp.Code, resp.Message)
 			}
 		},
-		websocketclientbase.ResponseHandler(responseHandler))
+		c.recording(parseOrderV2),
+		guard.wrap(responseHandler))
 
 	hb.Connect(true)
+	return c.subscribe(subKey("order", symbol, clientId), hb, guard, func() {
+		hb.UnSubscribe(symbol, clientId)
+	})
 }
 func (c *Client) UnsubscribeOrder(symbol, clientId string) {
-	hb := new(orderwebsocketclient.SubscribeOrderWebSocketV2Client).Init(c.AccessKey, c.SecretKey, c.Host)
-	hb.UnSubscribe(symbol, clientId)
+	c.subs.Unsubscribe(subKey("order", symbol, clientId))
 }
 
-func (c *Client) SubscribeAccountUpdate(clientId string, responseHandler websocketclientbase.ResponseHandler) {
+func (c *Client) SubscribeAccountUpdate(clientId string, responseHandler websocketclientbase.ResponseHandler) *exchange.Subscription {
 	hb := new(accountwebsocketclient.SubscribeAccountWebSocketV2Client).Init(c.AccessKey, c.SecretKey, c.Host)
 
-	hb.SetHandler(
+	guard := new(handlerGuard)
+	hb.WebSocketV2ClientBase.SetHandler(
 		// Connected handler
 		func(resp *auth.WebSocketV2AuthenticationResponse) {
 			if resp.IsSuccess() {
@@ -614,20 +690,24 @@ func (c *Client) SubscribeAccountUpdate(clientId string, responseHandler websock
 				applogger.Error("Authentication error, code: %d, message:%s", resp.Code, resp.Message)
 			}
 		},
-		responseHandler)
+		c.recording(parseAccountV2),
+		guard.wrap(responseHandler))
 
 	hb.Connect(true)
+	return c.subscribe(subKey("account", "", clientId), hb, guard, func() {
+		hb.UnSubscribe("1", clientId)
+	})
 }
 
 func (c *Client) UnsubscribeAccountUpdate(clientId string) {
-	hb := new(accountwebsocketclient.SubscribeAccountWebSocketV2Client).Init(c.AccessKey, c.SecretKey, c.Host)
-	hb.UnSubscribe("1", clientId)
+	c.subs.Unsubscribe(subKey("account", "", clientId))
 }
 
 func (c *Client) SubscribeTradeClear(symbol, clientId string,
-	responseHandler websocketclientbase.ResponseHandler) {
+	responseHandler websocketclientbase.ResponseHandler) *exchange.Subscription {
 	hb := new(orderwebsocketclient.SubscribeTradeClearWebSocketV2Client).Init(c.AccessKey, c.SecretKey, c.Host)
 
+	guard := new(handlerGuard)
 	hb.SetHandler(
 		// Connected handler
 		func(resp *auth.WebSocketV2AuthenticationResponse) {
@@ -638,14 +718,62 @@ func (c *Client) SubscribeTradeClear(symbol, clientId string,
 				applogger.Error("Authentication error, code: %d, message:%s", resp.Code, resp.Message)
 			}
 		},
-		responseHandler)
+		guard.wrap(responseHandler))
 
 	hb.Connect(true)
+	return c.subscribe(subKey("tradeclear", symbol, clientId), hb, guard, func() {
+		hb.UnSubscribe(symbol, clientId)
+	})
 }
 
 func (c *Client) UnsubscribeTradeClear(symbol, clientId string) {
-	hb := new(orderwebsocketclient.SubscribeTradeClearWebSocketV2Client).Init(c.AccessKey, c.SecretKey, c.Host)
-	hb.UnSubscribe(symbol, clientId)
+	c.subs.Unsubscribe(subKey("tradeclear", symbol, clientId))
+}
+
+// closer is the websocket client of huobi SDK
+type closer interface {
+	Close()
+}
+
+// handlerGuard drops the responses after stopped.
+// SDK's Close doesn't wait for its goroutines, and the read loop may still call the handler after Close,
+// so stop waits for the running handler, and the later ones are dropped.
+type handlerGuard struct {
+	mu      sync.RWMutex
+	stopped bool
+}
+
+func (g *handlerGuard) wrap(handler websocketclientbase.ResponseHandler) websocketclientbase.ResponseHandler {
+	return func(response interface{}) {
+		g.mu.RLock()
+		defer g.mu.RUnlock()
+		if !g.stopped {
+			handler(response)
+		}
+	}
+}
+
+func (g *handlerGuard) stop() {
+	g.mu.Lock()
+	g.stopped = true
+	g.mu.Unlock()
+}
+
+// subscribe keeps the handle of SDK client hb, Unsubscribe sends unsubscribe, closes hb and stops guard.
+// SDK doesn't expose its goroutines, they exit by themselves some time after Close,
+// Unsubscribe only makes sure the handler is not called after it returns.
+func (c *Client) subscribe(key string, hb closer, guard *handlerGuard, unsubscribe func()) *exchange.Subscription {
+	sub := exchange.NewSubscription(func() {
+		unsubscribe()
+		hb.Close()
+		guard.stop()
+	})
+	c.subs.Add(key, sub)
+	return sub
+}
+
+func subKey(channel, symbol, clientId string) string {
+	return channel + ":" + symbol + ":" + clientId
 }
 
 func (c Client) splitTimestamp(period time.Duration, from, to time.Time) (timestamps []int64) {
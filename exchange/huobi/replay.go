@@ -0,0 +1,111 @@
+package huobi
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/huobirdcenter/huobi_golang/logging/applogger"
+	"github.com/huobirdcenter/huobi_golang/pkg/client/websocketclientbase"
+	"github.com/huobirdcenter/huobi_golang/pkg/model/account"
+	"github.com/huobirdcenter/huobi_golang/pkg/model/market"
+	"github.com/huobirdcenter/huobi_golang/pkg/model/order"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
+	"time"
+)
+
+// SetRecorder 记录之后创建的订阅（K线、订单、账户、交易明细、深度）收到的消息，nil停止记录。
+// SDK在调用解析函数之前已经解压并处理了ping，所以记录的是解压后的文本消息，不含ping。
+func (c *Client) SetRecorder(recorder *base.Recorder) {
+	c.recorder = recorder
+}
+
+// recording 替换SDK的解析函数，先记录再解析
+func (c *Client) recording(parse websocketclientbase.MessageHandler) websocketclientbase.MessageHandler {
+	recorder := c.recorder
+	if recorder == nil {
+		return parse
+	}
+	return func(message string) (interface{}, error) {
+		if err := recorder.Record(websocket.TextMessage, []byte(message)); err != nil {
+			applogger.Error("Record error: %s", err)
+		}
+		return parse(message)
+	}
+}
+
+// 以下解析函数和SDK中各client的handleMessage相同
+
+func parseCandlestick(message string) (interface{}, error) {
+	result := market.SubscribeCandlestickResponse{}
+	err := json.Unmarshal([]byte(message), &result)
+	return result, err
+}
+
+func parseTrade(message string) (interface{}, error) {
+	result := market.SubscribeTradeResponse{}
+	err := json.Unmarshal([]byte(message), &result)
+	return result, err
+}
+
+func parseMarketByPrice(message string) (interface{}, error) {
+	result := market.SubscribeMarketByPriceResponse{}
+	err := json.Unmarshal([]byte(message), &result)
+	return result, err
+}
+
+func parseOrderV2(message string) (interface{}, error) {
+	result := order.SubscribeOrderV2Response{}
+	err := json.Unmarshal([]byte(message), &result)
+	return result, err
+}
+
+func parseAccountV2(message string) (interface{}, error) {
+	result := account.SubscribeAccountV2Response{}
+	err := json.Unmarshal([]byte(message), &result)
+	return result, err
+}
+
+// replay 把记录的消息交给解析函数和订阅时同样的处理函数，speed见base.Replay
+func replay(ctx context.Context, frames []base.Frame, speed float64,
+	parse websocketclientbase.MessageHandler, handler websocketclientbase.ResponseHandler) error {
+	return base.Replay(ctx, frames, speed, func(_ int, payload []byte) {
+		result, err := parse(string(payload))
+		if err != nil {
+			applogger.Error("Handle message error: %s", err)
+			return
+		}
+		handler(result)
+	})
+}
+
+// ReplayTrade 回放SubscribeTrade记录的消息
+func ReplayTrade(ctx context.Context, frames []base.Frame, speed float64, handler exchange.TradeHandler) error {
+	return replay(ctx, frames, speed, parseTrade, tradeHandler(handler))
+}
+
+// ReplayDepth 回放SubscribeDepth和SubscribeFullDepth记录的消息，req的应答中没有交易对，由参数指定
+func ReplayDepth(ctx context.Context, frames []base.Frame, speed float64, symbol string, handler exchange.DepthHandler) error {
+	return replay(ctx, frames, speed, parseMarketByPrice, depthHandler(symbol, handler))
+}
+
+// ReplayCandlestick 回放SubscribeCandlestick记录的消息，handler收到的是SDK的结构
+func ReplayCandlestick(ctx context.Context, frames []base.Frame, speed float64, handler exchange.ResponseHandler) error {
+	return replay(ctx, frames, speed, parseCandlestick, websocketclientbase.ResponseHandler(handler))
+}
+
+// ReplayCandleUpdate 回放SubscribeCandleUpdate记录的消息，推送中没有交易对和周期，由参数指定
+func ReplayCandleUpdate(ctx context.Context, frames []base.Frame, speed float64,
+	symbol string, period time.Duration, handler exchange.CandleHandler) error {
+	return replay(ctx, frames, speed, parseCandlestick, candleUpdateHandler(symbol, period, handler))
+}
+
+// ReplayOrderUpdate 回放SubscribeOrder和SubscribeOrderUpdate记录的消息
+func ReplayOrderUpdate(ctx context.Context, frames []base.Frame, speed float64, handler exchange.OrderHandler) error {
+	return replay(ctx, frames, speed, parseOrderV2, orderUpdateHandler(handler))
+}
+
+// ReplayBalanceUpdate 回放SubscribeAccountUpdate和SubscribeBalanceUpdate记录的消息
+func ReplayBalanceUpdate(ctx context.Context, frames []base.Frame, speed float64, handler exchange.BalanceHandler) error {
+	return replay(ctx, frames, speed, parseAccountV2, balanceUpdateHandler(handler))
+}
@@ -0,0 +1,53 @@
+package huobi
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
+	"testing"
+)
+
+func TestReplayTrade(t *testing.T) {
+	var buf bytes.Buffer
+	client := &Client{}
+	client.SetRecorder(base.NewRecorder(&buf))
+	parse := client.recording(parseTrade)
+	_, err := parse(`{"ch":"market.btcusdt.trade.detail","ts":1630994963175,"tick":{"id":137005445109,"ts":1630994963173,"data":[{"id":1,"ts":1630994963173,"tradeId":102523573486,"amount":0.006754,"price":52648.62,"direction":"buy"},{"id":2,"ts":1630994963170,"tradeId":102523573485,"amount":0.1,"price":52648.6,"direction":"sell"}]}}`)
+	require.NoError(t, err)
+	// not recorded after SetRecorder(nil)
+	client.SetRecorder(nil)
+	_, err = client.recording(parseTrade)(`{"ch":"market.btcusdt.trade.detail","tick":{"data":[]}}`)
+	require.NoError(t, err)
+
+	frames, err := base.ReadFrames(&buf)
+	require.NoError(t, err)
+	require.Len(t, frames, 1)
+
+	var details []exchange.TradeDetail
+	require.NoError(t, ReplayTrade(context.Background(), frames, 0, func(d []exchange.TradeDetail) {
+		details = append(details, d...)
+	}))
+	require.Len(t, details, 2)
+	// old first
+	require.Equal(t, int64(102523573485), details[0].Id)
+	require.Equal(t, exchange.TradeDirectionBuy, details[1].Direction)
+}
+
+func TestReplayOrderUpdate(t *testing.T) {
+	var buf bytes.Buffer
+	r := base.NewRecorder(&buf)
+	require.NoError(t, r.Record(1, []byte(`{"action":"push","ch":"orders#btcusdt","data":{"orderSize":"2","orderCreateTime":1583853365586,"orderPrice":"77","type":"sell-limit","orderId":27163533,"clientOrderId":"abc","orderStatus":"submitted","symbol":"btcusdt","eventType":"creation"}}`)))
+	frames, err := base.ReadFrames(&buf)
+	require.NoError(t, err)
+
+	var orders []exchange.Order
+	require.NoError(t, ReplayOrderUpdate(context.Background(), frames, 0, func(o exchange.Order) {
+		orders = append(orders, o)
+	}))
+	require.Len(t, orders, 1)
+	require.Equal(t, uint64(27163533), orders[0].Id)
+	require.Equal(t, "submitted", orders[0].Status)
+	require.Equal(t, "2", orders[0].Amount.String())
+}
@@ -0,0 +1,152 @@
+package huobi
+
+import (
+	"github.com/huobirdcenter/huobi_golang/logging/applogger"
+	"github.com/huobirdcenter/huobi_golang/pkg/client/websocketclientbase"
+	"github.com/huobirdcenter/huobi_golang/pkg/model/account"
+	"github.com/huobirdcenter/huobi_golang/pkg/model/market"
+	"github.com/huobirdcenter/huobi_golang/pkg/model/order"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"time"
+)
+
+var _ exchange.StreamAPIExchange = (*Client)(nil)
+
+// SubscribeOrderUpdate 订阅订单推送，和SubscribeOrder相同，只是把SDK的结构转换成exchange.Order
+func (c *Client) SubscribeOrderUpdate(symbol, clientId string, handler exchange.OrderHandler) *exchange.Subscription {
+	return c.subscribeOrder(symbol, clientId, orderUpdateHandler(handler))
+}
+
+// SubscribeCandleUpdate 订阅K线推送，和SubscribeCandlestick相同，只是把SDK的结构转换成exchange.CandleUpdate
+func (c *Client) SubscribeCandleUpdate(symbol, clientId string, period time.Duration, handler exchange.CandleHandler) *exchange.Subscription {
+	return c.subscribeCandlestick(symbol, clientId, period, candleUpdateHandler(symbol, period, handler))
+}
+
+// SubscribeBalanceUpdate 订阅现货账户的余额推送（可用和总余额都推送），用UnsubscribeAccountUpdate退订
+func (c *Client) SubscribeBalanceUpdate(clientId string, handler exchange.BalanceHandler) *exchange.Subscription {
+	return c.SubscribeAccountUpdate(clientId, balanceUpdateHandler(handler))
+}
+
+func orderUpdateHandler(handler exchange.OrderHandler) websocketclientbase.ResponseHandler {
+	return func(response interface{}) {
+		r, ok := response.(order.SubscribeOrderV2Response)
+		if !ok {
+			applogger.Warn("Unknown response: %v", response)
+			return
+		}
+		if r.Data == nil {
+			// subscribe response, ping etc.
+			return
+		}
+		handler(convertOrderUpdate(r))
+	}
+}
+
+// convertOrderUpdate 转换orders#${symbol}推送，不同的eventType带的字段不一样：
+// creation有订单价格和数量，trade有成交和剩余数量，cancellation只有剩余数量
+func convertOrderUpdate(r order.SubscribeOrderV2Response) exchange.Order {
+	d := r.Data
+	o := exchange.Order{
+		Id:            uint64(d.OrderId),
+		ClientOrderId: d.ClientOrderId,
+		Type:          d.Type,
+		Side:          exchange.ParseSide(d.Type),
+		Symbol:        d.Symbol,
+		Price:         toDecimal(d.OrderPrice),
+		Amount:        toDecimal(d.OrderSize),
+		Status:        d.OrderStatus,
+	}
+	switch {
+	case d.OrderCreateTime != 0:
+		o.Time = time.Unix(0, d.OrderCreateTime*int64(time.Millisecond))
+	case d.TradeTime != 0:
+		o.Time = time.Unix(0, d.TradeTime*int64(time.Millisecond))
+	case d.LastActTime != 0:
+		o.Time = time.Unix(0, d.LastActTime*int64(time.Millisecond))
+	}
+	if d.OrderSize != "" && d.RemainAmt != "" {
+		o.FilledAmount = o.Amount.Sub(toDecimal(d.RemainAmt))
+	}
+	o.State = orderState.Normalize(o.Status, o.FilledAmount)
+	if d.EventType == "trade" {
+		o.FilledPrice = toDecimal(d.TradePrice)
+		role := "maker"
+		if d.Aggressor {
+			role = "taker"
+		}
+		o.Trades = append(o.Trades, exchange.Trade{
+			Id:      uint64(d.TradeId),
+			OrderId: o.Id,
+			Symbol:  d.Symbol,
+			Type:    d.Type,
+			Side:    d.OrderSide,
+			Role:    role,
+			Price:   o.FilledPrice,
+			Amount:  toDecimal(d.TradeVolume),
+			Time:    o.Time,
+		})
+	}
+	return o
+}
+
+func candleUpdateHandler(symbol string, period time.Duration, handler exchange.CandleHandler) websocketclientbase.ResponseHandler {
+	return func(response interface{}) {
+		r, ok := response.(market.SubscribeCandlestickResponse)
+		if !ok {
+			applogger.Warn("Unknown response: %v", response)
+			return
+		}
+		if r.Tick == nil {
+			return
+		}
+		handler(exchange.CandleUpdate{Symbol: symbol, Period: period, Ticker: convertTick(r.Tick)})
+	}
+}
+
+func convertTick(tick *market.Tick) hs.Ticker {
+	ticker := hs.Ticker{Timestamp: tick.Id}
+	ticker.Open, _ = tick.Open.Float64()
+	ticker.High, _ = tick.High.Float64()
+	ticker.Low, _ = tick.Low.Float64()
+	ticker.Close, _ = tick.Close.Float64()
+	ticker.Volume, _ = tick.Vol.Float64()
+	return ticker
+}
+
+func balanceUpdateHandler(handler exchange.BalanceHandler) websocketclientbase.ResponseHandler {
+	return func(response interface{}) {
+		r, ok := response.(account.SubscribeAccountV2Response)
+		if !ok {
+			applogger.Warn("Unknown response: %v", response)
+			return
+		}
+		if r.Data == nil {
+			return
+		}
+		handler(convertAccountUpdate(r))
+	}
+}
+
+// convertAccountUpdate 转换accounts.update#1推送，Locked是总余额减去可用余额，缺少其中一个时为0
+func convertAccountUpdate(r account.SubscribeAccountV2Response) exchange.BalanceUpdate {
+	d := r.Data
+	u := exchange.BalanceUpdate{
+		Balance: exchange.Balance{Currency: d.Currency, Available: toDecimal(d.Available)},
+		Time:    time.Unix(0, d.ChangeTime*int64(time.Millisecond)),
+	}
+	if d.Balance != "" && d.Available != "" {
+		u.Locked = toDecimal(d.Balance).Sub(u.Available)
+	}
+	return u
+}
+
+// toDecimal 推送中没有的字段是空字符串，转换为0
+func toDecimal(s string) decimal.Decimal {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
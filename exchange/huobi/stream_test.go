@@ -0,0 +1,90 @@
+package huobi
+
+import (
+	"encoding/json"
+	"github.com/huobirdcenter/huobi_golang/pkg/model/account"
+	"github.com/huobirdcenter/huobi_golang/pkg/model/order"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs/exchange"
+	"testing"
+	"time"
+)
+
+func TestConvertOrderUpdate(t *testing.T) {
+	raw := `{"action":"push","ch":"orders#btcusdt","data":{"tradePrice":"76.000000000000000000","tradeVolume":"1.013157894736842100","tradeId":301,"tradeTime":1583854188883,"aggressor":true,"remainAmt":"0.000000000000000400000000000000000000","orderId":2,"type":"buy-limit","clientOrderId":"abc","orderStatus":"partial-filled","symbol":"btcusdt","orderPrice":"76.000000000000000000","orderSize":"2.013157894736842500","orderSide":"buy","eventType":"trade"}}`
+	var r order.SubscribeOrderV2Response
+	require.NoError(t, json.Unmarshal([]byte(raw), &r))
+	o := convertOrderUpdate(r)
+	require.Equal(t, uint64(2), o.Id)
+	require.Equal(t, "abc", o.ClientOrderId)
+	require.Equal(t, "partial-filled", o.Status)
+	require.Equal(t, exchange.Filled, o.State)
+	require.Equal(t, exchange.Buy, o.Side)
+	require.Equal(t, "76", o.Price.String())
+	require.Equal(t, "2.0131578947368421", o.FilledAmount.String())
+	require.Equal(t, int64(1583854188883), o.Time.UnixNano()/1e6)
+	require.Len(t, o.Trades, 1)
+	require.Equal(t, "taker", o.Trades[0].Role)
+	require.Equal(t, "1.0131578947368421", o.Trades[0].Amount.String())
+
+	raw = `{"action":"push","ch":"orders#btcusdt","data":{"lastActTime":1583853475406,"remainAmt":"2.000000000000000000","orderId":2,"type":"buy-limit","clientOrderId":"abc","orderStatus":"canceled","symbol":"btcusdt","eventType":"cancellation"}}`
+	r = order.SubscribeOrderV2Response{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &r))
+	o = convertOrderUpdate(r)
+	require.Equal(t, "canceled", o.Status)
+	require.Equal(t, exchange.Cancelled, o.State)
+	require.True(t, o.FilledAmount.IsZero())
+	require.Empty(t, o.Trades)
+}
+
+func TestConvertAccountUpdate(t *testing.T) {
+	raw := `{"action":"push","ch":"accounts.update#1","data":{"currency":"btc","accountId":123456,"balance":"2030","available":"2028.5","changeType":"transfer","accountType":"trade","changeTime":1568601800000}}`
+	var r account.SubscribeAccountV2Response
+	require.NoError(t, json.Unmarshal([]byte(raw), &r))
+	u := convertAccountUpdate(r)
+	require.Equal(t, "btc", u.Currency)
+	require.Equal(t, "2028.5", u.Available.String())
+	require.Equal(t, "1.5", u.Locked.String())
+
+	raw = `{"action":"push","ch":"accounts.update#1","data":{"currency":"btc","accountId":123456,"available":"1","changeType":"order.place","accountType":"trade","changeTime":1568601800000}}`
+	r = account.SubscribeAccountV2Response{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &r))
+	u = convertAccountUpdate(r)
+	require.True(t, u.Locked.IsZero())
+}
+
+type fakeCloser struct{}
+
+func (fakeCloser) Close() {}
+
+func TestClient_subscribe(t *testing.T) {
+	client := &Client{subs: exchange.NewSubscriptions()}
+	guard := new(handlerGuard)
+	calls := make(chan struct{}, 1)
+	release := make(chan struct{})
+	handler := guard.wrap(func(response interface{}) {
+		calls <- struct{}{}
+		<-release
+	})
+	sub := client.subscribe("test", fakeCloser{}, guard, func() {})
+
+	// Unsubscribe waits for the running handler
+	go handler(nil)
+	<-calls
+	done := make(chan struct{})
+	go func() {
+		sub.Unsubscribe()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("unsubscribed before the handler returned")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-done
+
+	// the handler is not called after unsubscribed
+	handler(nil)
+	require.Empty(t, calls)
+}
@@ -8,22 +8,26 @@ import (
 	"github.com/xyths/hs/exchange"
 )
 
-func (c *Client) SubscribeTrade(symbol, clientId string, responseHandler exchange.TradeHandler) {
+func (c *Client) SubscribeTrade(symbol, clientId string, responseHandler exchange.TradeHandler) *exchange.Subscription {
 	hb := new(marketwebsocketclient.TradeWebSocketClient).Init(c.Host)
-	hb.SetHandler(
+	guard := new(handlerGuard)
+	hb.WebSocketClientBase.SetHandler(
 		// Connected handler
 		func() {
 			hb.Subscribe(symbol, clientId)
 		},
-		tradeHandler(responseHandler),
+		c.recording(parseTrade),
+		guard.wrap(tradeHandler(responseHandler)),
 	)
 
 	hb.Connect(true)
+	return c.subscribe(subKey("trade", symbol, clientId), hb, guard, func() {
+		hb.UnSubscribe(symbol, clientId)
+	})
 }
 
 func (c *Client) UnsubscribeTrade(symbol, clientId string) {
-	hb := new(marketwebsocketclient.TradeWebSocketClient).Init(c.Host)
-	hb.UnSubscribe(symbol, clientId)
+	c.subs.Unsubscribe(subKey("trade", symbol, clientId))
 }
 
 func tradeHandler(responseHandler exchange.TradeHandler) websocketclientbase.ResponseHandler {
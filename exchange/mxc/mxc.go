@@ -0,0 +1,361 @@
+// Package mxc is the spot client of mexc.com (formerly mxc), by v3 RESTful and websocket api.
+package mxc
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/convert"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
+	"go.uber.org/zap"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHost   = "api.mexc.com"
+	DefaultWsHost = "wbs.mexc.com"
+
+	// max klines of one request
+	maxCandleLength = 1000
+
+	// order id prefix of v3 api, the rest is a decimal number
+	orderIdPrefix = "C02__"
+	// HashIdFlag marks the order id hashed from the mxc order id not in the form of C02__<number>
+	HashIdFlag = uint64(1) << 63
+)
+
+// Client is the mxc spot client.
+// The order id of mxc is string like C02__<number>, but exchange.RestAPIExchange use uint64,
+// so the number is used as order id, and the id can be recovered after restart.
+// The id in other form is hashed with HashIdFlag, and the client remembers the pairs it has seen,
+// GetOrderById and CancelOrder only work for such orders placed, queried or pushed by this client,
+// and the pair is forgotten once the order is queried as finished.
+type Client struct {
+	Key    string
+	Secret string
+	// Host is the RESTful api host, with optional scheme, https by default
+	Host string
+	// WsHost is the websocket host, with optional scheme, wss by default
+	WsHost string
+	Logger *zap.SugaredLogger
+
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	orderIds map[uint64]string
+	subs     map[string]*subscription
+}
+
+var (
+	_ exchange.RestAPIExchange = (*Client)(nil)
+	_ exchange.WsAPIExchange   = (*Client)(nil)
+)
+
+func New(key, secret, host string, logger *zap.SugaredLogger) *Client {
+	if host == "" {
+		host = DefaultHost
+	}
+	return &Client{
+		Key:        key,
+		Secret:     secret,
+		Host:       host,
+		WsHost:     DefaultWsHost,
+		Logger:     logger,
+		httpClient: &http.Client{Timeout: time.Second * 10},
+		orderIds:   make(map[uint64]string),
+		subs:       make(map[string]*subscription),
+	}
+}
+
+func init() {
+	exchange.Register(hs.MXC, func(conf hs.ExchangeConf, logger *zap.SugaredLogger) (exchange.Exchange, error) {
+		return New(conf.Key, conf.Secret, conf.Host, logger), nil
+	})
+}
+
+func (c *Client) Name() string {
+	return hs.MXC
+}
+
+// FormatSymbol returns symbol like BTCUSDT
+func (c *Client) FormatSymbol(base, quote string) string {
+	return strings.ToUpper(base + quote)
+}
+
+func (c *Client) exchangeInfo(ctx context.Context, params url.Values) ([]rawSymbol, error) {
+	var info struct {
+		Symbols []rawSymbol `json:"symbols"`
+	}
+	err := c.rest().Get(ctx, "/api/v3/exchangeInfo", params, false, &info)
+	return info.Symbols, err
+}
+
+func (c *Client) AllSymbols(ctx context.Context) (s []exchange.Symbol, err error) {
+	symbols, err := c.exchangeInfo(ctx, nil)
+	if err != nil {
+		return
+	}
+	for _, r := range symbols {
+		s = append(s, convertSymbol(r))
+	}
+	return
+}
+
+func (c *Client) GetSymbol(ctx context.Context, symbol string) (exchange.Symbol, error) {
+	symbols, err := c.exchangeInfo(ctx, url.Values{"symbol": {symbol}})
+	if err != nil {
+		return exchange.Symbol{}, err
+	}
+	for _, r := range symbols {
+		if r.Symbol == symbol {
+			return convertSymbol(r), nil
+		}
+	}
+	return exchange.Symbol{}, ErrSymbolNotFound
+}
+
+// GetFee returns the commission in symbol info
+func (c *Client) GetFee(symbol string) (fee exchange.Fee, err error) {
+	symbols, err := c.exchangeInfo(context.Background(), url.Values{"symbol": {symbol}})
+	if err != nil {
+		return
+	}
+	if len(symbols) == 0 {
+		return fee, ErrSymbolNotFound
+	}
+	fee.Symbol = symbol
+	fee.BaseMaker = convert.StrToDecimal(symbols[0].MakerCommission)
+	fee.BaseTaker = convert.StrToDecimal(symbols[0].TakerCommission)
+	fee.ActualMaker = fee.BaseMaker
+	fee.ActualTaker = fee.BaseTaker
+	return
+}
+
+func (c *Client) SpotBalance() (map[string]decimal.Decimal, error) {
+	total, _, err := c.rest().Balances(strings.ToLower)
+	return total, err
+}
+
+func (c *Client) SpotAvailableBalance() (map[string]decimal.Decimal, error) {
+	_, available, err := c.rest().Balances(strings.ToLower)
+	return available, err
+}
+
+func (c *Client) LastPrice(symbol string) (decimal.Decimal, error) {
+	var ticker struct {
+		Price string `json:"price"`
+	}
+	if err := c.rest().Get(context.Background(), "/api/v3/ticker/price", url.Values{"symbol": {symbol}}, false, &ticker); err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromString(ticker.Price)
+}
+
+// Last24hVolume returns the volume of base currency in 24 hours
+func (c *Client) Last24hVolume(symbol string) (decimal.Decimal, error) {
+	var ticker struct {
+		Volume string `json:"volume"`
+	}
+	if err := c.rest().Get(context.Background(), "/api/v3/ticker/24hr", url.Values{"symbol": {symbol}}, false, &ticker); err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromString(ticker.Volume)
+}
+
+func (c *Client) CandleBySize(symbol string, period time.Duration, size int) (hs.Candle, error) {
+	return c.rest().CandleBySize(symbol, getInterval(period), size, maxCandleLength)
+}
+
+func (c *Client) CandleFrom(symbol, clientId string, period time.Duration, from, to time.Time) (hs.Candle, error) {
+	return c.rest().CandleFrom(symbol, getInterval(period), period, from, to, maxCandleLength)
+}
+
+func (c *Client) BuyLimit(symbol, clientOrderId string, price, amount decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(symbol, clientOrderId, SideBuy, OrderTypeLimit, price, amount, decimal.Zero)
+}
+
+func (c *Client) SellLimit(symbol, clientOrderId string, price, amount decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(symbol, clientOrderId, SideSell, OrderTypeLimit, price, amount, decimal.Zero)
+}
+
+// BuyMarket spends total quote currency
+func (c *Client) BuyMarket(symbol exchange.Symbol, clientOrderId string, total decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(symbol.Symbol, clientOrderId, SideBuy, OrderTypeMarket, decimal.Zero, decimal.Zero, total)
+}
+
+func (c *Client) SellMarket(symbol exchange.Symbol, clientOrderId string, amount decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(symbol.Symbol, clientOrderId, SideSell, OrderTypeMarket, decimal.Zero, amount, decimal.Zero)
+}
+
+// orderOptions lists the spot order options, the time in force is the order type of mxc
+var orderOptions = exchange.OptionSupport{
+	Name:        "mxc",
+	TimeInForce: []exchange.TimeInForce{exchange.GTC, exchange.IOC, exchange.FOK, exchange.PostOnly},
+}
+
+// PlaceOrder places limit order, mxc has no iceberg or margin order
+func (c *Client) PlaceOrder(symbol, clientOrderId string, side exchange.OrderType, price, amount decimal.Decimal, options exchange.OrderOptions) (orderId uint64, err error) {
+	if err = orderOptions.Check(options, amount); err != nil {
+		return
+	}
+	s := SideBuy
+	switch side {
+	case exchange.Buy:
+	case exchange.Sell:
+		s = SideSell
+	default:
+		return 0, exchange.ErrBadSide
+	}
+	orderType := OrderTypeLimit
+	switch options.TIF() {
+	case exchange.IOC:
+		orderType = OrderTypeIOC
+	case exchange.FOK:
+		orderType = OrderTypeFOK
+	case exchange.PostOnly:
+		orderType = OrderTypeLimitMaker
+	}
+	return c.placeOrder(symbol, clientOrderId, s, orderType, price, amount, decimal.Zero)
+}
+
+// BuyStopLimit is not supported by mxc spot api
+func (c *Client) BuyStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (orderId uint64, err error) {
+	return 0, ErrNotSupported
+}
+
+// SellStopLimit is not supported by mxc spot api
+func (c *Client) SellStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (orderId uint64, err error) {
+	return 0, ErrNotSupported
+}
+
+func (c *Client) placeOrder(symbol, clientOrderId, side, orderType string, price, amount, total decimal.Decimal) (uint64, error) {
+	params := url.Values{
+		"symbol": {symbol},
+		"side":   {side},
+		"type":   {orderType},
+	}
+	if clientOrderId != "" {
+		params.Set("newClientOrderId", clientOrderId)
+	}
+	if orderType != OrderTypeMarket {
+		params.Set("price", price.String())
+	}
+	if !amount.IsZero() {
+		params.Set("quantity", amount.String())
+	}
+	if !total.IsZero() {
+		params.Set("quoteOrderQty", total.String())
+	}
+	var r struct {
+		OrderId string `json:"orderId"`
+	}
+	if err := c.rest().Do(context.Background(), http.MethodPost, "/api/v3/order", params, true, &r); err != nil {
+		return 0, err
+	}
+	return c.orderId(r.OrderId), nil
+}
+
+func (c *Client) GetOrderById(orderId uint64, symbol string) (exchange.Order, error) {
+	id, err := c.rawOrderId(orderId)
+	if err != nil {
+		return exchange.Order{}, err
+	}
+	return c.GetOrder(symbol, id)
+}
+
+// GetOrder queries order by mxc string order id
+func (c *Client) GetOrder(symbol, orderId string) (exchange.Order, error) {
+	var r rawOrder
+	params := url.Values{"symbol": {symbol}, "orderId": {orderId}}
+	if err := c.rest().Get(context.Background(), "/api/v3/order", params, true, &r); err != nil {
+		return exchange.Order{}, err
+	}
+	o := convertOrder(c.orderId(r.OrderId), r)
+	if o.Finished() {
+		c.forget(o.Id)
+	}
+	return o, nil
+}
+
+func (c *Client) CancelOrder(symbol string, orderId uint64) error {
+	id, err := c.rawOrderId(orderId)
+	if err != nil {
+		return err
+	}
+	params := url.Values{"symbol": {symbol}, "orderId": {id}}
+	return c.rest().Do(context.Background(), http.MethodDelete, "/api/v3/order", params, true, nil)
+}
+
+func (c *Client) IsFullFilled(symbol string, orderId uint64) (exchange.Order, bool, error) {
+	o, err := c.GetOrderById(orderId, symbol)
+	if err != nil {
+		return o, false, err
+	}
+	return o, o.FullFilled(), nil
+}
+
+// orderId converts mxc order id to uint64, the id not in the form of C02__<number> is hashed and remembered
+func (c *Client) orderId(id string) uint64 {
+	if strings.HasPrefix(id, orderIdPrefix) {
+		number := id[len(orderIdPrefix):]
+		if n, err := strconv.ParseUint(number, 10, 63); err == nil && strconv.FormatUint(n, 10) == number {
+			return n
+		}
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(id))
+	n := h.Sum64() | HashIdFlag
+	c.mu.Lock()
+	c.orderIds[n] = id
+	c.mu.Unlock()
+	return n
+}
+
+// forget removes the hashed id of finished order
+func (c *Client) forget(id uint64) {
+	c.mu.Lock()
+	delete(c.orderIds, id)
+	c.mu.Unlock()
+}
+
+func (c *Client) rawOrderId(id uint64) (string, error) {
+	if id&HashIdFlag == 0 {
+		return orderIdPrefix + strconv.FormatUint(id, 10), nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.orderIds[id]; ok {
+		return s, nil
+	}
+	return "", ErrUnknownOrderId
+}
+
+// ApiError is the error returned by mxc api
+type ApiError = base.ApiError
+
+var (
+	ErrSymbolNotFound = errors.New("symbol not found")
+	ErrNotSupported   = errors.New("not supported by mxc")
+	ErrUnknownOrderId = errors.New("unknown order id, not placed or queried by this client")
+)
+
+// rest returns the RESTful client of current Host and keys
+func (c *Client) rest() *base.QueryClient {
+	return &base.QueryClient{
+		Exchange:  hs.MXC,
+		Host:      c.Host,
+		Key:       c.Key,
+		Secret:    c.Secret,
+		KeyHeader: "X-MEXC-APIKEY",
+		Header:    http.Header{"Content-Type": {"application/json"}},
+		HTTP:      c.httpClient,
+	}
+}
@@ -0,0 +1,174 @@
+package mxc
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base/basetest"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+)
+
+// newFixtureClient returns client to the server, which responses testdata/<last part of path>.json for rest api,
+// and sends testdata/ws-<channel>.json after subscription for websocket.
+func newFixtureClient(t *testing.T) *Client {
+	upgrader := websocket.Upgrader{}
+	server := basetest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		basetest.CheckQuerySign(t, r, "X-MEXC-APIKEY", "key", "secret")
+		name := path.Base(r.URL.Path)
+		switch {
+		case r.URL.Path == "/ws":
+			serveWs(t, upgrader, w, r)
+			return
+		case name == "userDataStream":
+			_, _ = w.Write([]byte(`{"listenKey":"pqia91ma19a5s61cv6a81va65sdf19v8a65a1a5s61cv6a81va65sdf19v8a65a1"}`))
+			return
+		case name == "order" && r.Method == http.MethodPost:
+			name = "place-order"
+		case name == "order" && r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":-2011,"msg":"Unknown order id"}`))
+			return
+		}
+		basetest.WriteFixture(t, w, name)
+	}))
+	c := New("key", "secret", server.URL, zap.NewNop().Sugar())
+	c.WsHost = basetest.WsURL(server.URL)
+	return c
+}
+
+func serveWs(t *testing.T, upgrader websocket.Upgrader, w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	var req struct {
+		Method string   `json:"method"`
+		Params []string `json:"params"`
+	}
+	require.NoError(t, conn.ReadJSON(&req))
+	require.Equal(t, "SUBSCRIPTION", req.Method)
+	require.Len(t, req.Params, 1)
+	name := "ws-kline"
+	if req.Params[0] == ChannelOrders {
+		require.NotEmpty(t, r.URL.Query().Get("listenKey"))
+		name = "ws-orders"
+	}
+	data, err := ioutil.ReadFile("testdata/" + name + ".json")
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, data))
+	_, _, _ = conn.ReadMessage()
+}
+
+func TestClient_Market(t *testing.T) {
+	c := newFixtureClient(t)
+	symbol := c.FormatSymbol("btc", "usdt")
+	s, err := c.GetSymbol(context.Background(), symbol)
+	require.NoError(t, err)
+	require.False(t, s.Disabled)
+	require.Equal(t, "btc", s.BaseCurrency)
+	require.Equal(t, int32(2), s.PricePrecision)
+	require.Equal(t, int32(6), s.AmountPrecision)
+	require.Equal(t, "5", s.MinTotal.String())
+
+	fee, err := c.GetFee(symbol)
+	require.NoError(t, err)
+	require.Equal(t, "0.0005", fee.BaseTaker.String())
+
+	price, err := c.LastPrice(symbol)
+	require.NoError(t, err)
+	require.Equal(t, "26995.12", price.String())
+	volume, err := c.Last24hVolume(symbol)
+	require.NoError(t, err)
+	require.Equal(t, "3510.401385", volume.String())
+
+	candle, err := c.CandleBySize(symbol, time.Minute*5, 2)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1695717900, 1695718200}, candle.Timestamp)
+	require.Equal(t, 27041.3, candle.Close[1])
+}
+
+func TestClient_Order(t *testing.T) {
+	c := newFixtureClient(t)
+	balances, err := c.SpotBalance()
+	require.NoError(t, err)
+	require.Len(t, balances, 2)
+	require.Equal(t, "120.5", balances["usdt"].String())
+
+	_, err = c.GetOrderById(HashIdFlag|1, "BTCUSDT")
+	require.Equal(t, ErrUnknownOrderId, err)
+	_, err = c.BuyStopLimit("BTCUSDT", "", decimal.NewFromInt(1), decimal.NewFromInt(1), decimal.NewFromInt(1))
+	require.Equal(t, ErrNotSupported, err)
+
+	id, err := c.BuyLimit("BTCUSDT", "b15", decimal.NewFromInt(27001), decimal.RequireFromString("0.001"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(312269865356374016), id)
+	o, filled, err := c.IsFullFilled("BTCUSDT", id)
+	require.NoError(t, err)
+	require.True(t, filled)
+	require.Equal(t, id, o.Id)
+	require.Equal(t, "buy-limit", o.Type)
+	require.Equal(t, "27000.5", o.FilledPrice.String())
+
+	err = c.CancelOrder("BTCUSDT", id)
+	apiErr, ok := err.(ApiError)
+	require.True(t, ok)
+	require.Equal(t, -2011, apiErr.Code)
+}
+
+func TestClient_OrderId(t *testing.T) {
+	c := New("key", "secret", "", zap.NewNop().Sugar())
+	for _, raw := range []string{"C02__312269865356374016", "C02__0312", "C02__312269865356374016123", "abc"} {
+		id := c.orderId(raw)
+		s, err := c.rawOrderId(id)
+		require.NoError(t, err, raw)
+		require.Equal(t, raw, s)
+	}
+	require.Equal(t, uint64(312269865356374016), c.orderId("C02__312269865356374016"))
+	require.Len(t, c.orderIds, 3)
+
+	// the hashed id is forgotten after finished
+	id := c.orderId("abc")
+	c.forget(id)
+	_, err := c.rawOrderId(id)
+	require.Equal(t, ErrUnknownOrderId, err)
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	c := newFixtureClient(t)
+
+	orders := make(chan exchange.Order, 1)
+	c.SubscribeOrder("BTCUSDT", "test", func(response interface{}) {
+		orders <- response.(exchange.Order)
+	})
+	defer c.UnsubscribeOrder("BTCUSDT", "test")
+	select {
+	case o := <-orders:
+		require.Equal(t, "b15", o.ClientOrderId)
+		require.Equal(t, OrderStatusFilled, o.Status)
+		require.Equal(t, "27000.5", o.FilledPrice.String())
+		id, err := c.rawOrderId(o.Id)
+		require.NoError(t, err)
+		require.Equal(t, "C02__312269865356374016", id)
+	case <-time.After(time.Second * 5):
+		t.Fatal("no order update")
+	}
+
+	tickers := make(chan hs.Ticker, 1)
+	c.SubscribeCandlestick("BTCUSDT", "test", time.Minute, func(response interface{}) {
+		tickers <- response.(hs.Ticker)
+	})
+	defer c.UnsubscribeCandlestick("BTCUSDT", "test", time.Minute)
+	select {
+	case ticker := <-tickers:
+		require.Equal(t, hs.Ticker{Timestamp: 1695718200, Open: 27034.1, High: 27045.4, Low: 27025.4, Close: 27041.3, Volume: 1.0741}, ticker)
+	case <-time.After(time.Second * 5):
+		t.Fatal("no candlestick update")
+	}
+}
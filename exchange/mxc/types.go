@@ -0,0 +1,100 @@
+package mxc
+
+import "encoding/json"
+
+const (
+	SideBuy  = "BUY"
+	SideSell = "SELL"
+
+	OrderTypeLimit      = "LIMIT"
+	OrderTypeMarket     = "MARKET"
+	OrderTypeLimitMaker = "LIMIT_MAKER" // post-only
+	OrderTypeIOC        = "IMMEDIATE_OR_CANCEL"
+	OrderTypeFOK        = "FILL_OR_KILL"
+
+	OrderStatusNew               = "NEW"
+	OrderStatusFilled            = "FILLED"
+	OrderStatusPartiallyFilled   = "PARTIALLY_FILLED"
+	OrderStatusCanceled          = "CANCELED"
+	OrderStatusPartiallyCanceled = "PARTIALLY_CANCELED"
+)
+
+type rawSymbol struct {
+	Symbol string `json:"symbol"`
+	// "1" or "ENABLED" is online
+	Status               string `json:"status"`
+	BaseAsset            string `json:"baseAsset"`
+	BaseAssetPrecision   int32  `json:"baseAssetPrecision"`
+	QuoteAsset           string `json:"quoteAsset"`
+	QuotePrecision       int32  `json:"quotePrecision"`
+	QuoteAmountPrecision string `json:"quoteAmountPrecision"` // min total
+	BaseSizePrecision    string `json:"baseSizePrecision"`    // min amount
+	IsSpotTradingAllowed bool   `json:"isSpotTradingAllowed"`
+	MakerCommission      string `json:"makerCommission"`
+	TakerCommission      string `json:"takerCommission"`
+}
+
+type rawOrder struct {
+	Symbol              string `json:"symbol"`
+	OrderId             string `json:"orderId"`
+	ClientOrderId       string `json:"clientOrderId"`
+	Price               string `json:"price"`
+	OrigQty             string `json:"origQty"`
+	ExecutedQty         string `json:"executedQty"`
+	CummulativeQuoteQty string `json:"cummulativeQuoteQty"`
+	Status              string `json:"status"`
+	Type                string `json:"type"`
+	Side                string `json:"side"`
+	Time                int64  `json:"time"`
+}
+
+// wsMessage is the push of websocket, c is the channel and d is the data
+type wsMessage struct {
+	Channel string          `json:"c"`
+	Data    json.RawMessage `json:"d"`
+	Symbol  string          `json:"s"`
+	Time    int64           `json:"t"`
+	// response of subscription and ping
+	Id   int64  `json:"id"`
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// rawKline is the data of spot@public.kline.v3.api channel, times are in seconds.
+// Fields differ only in case ("t" and "T") are both declared, because json matches keys case-insensitively.
+type rawKline struct {
+	Kline struct {
+		StartTime int64       `json:"t"`
+		EndTime   int64       `json:"T"`
+		Interval  string      `json:"i"`
+		Open      json.Number `json:"o"`
+		High      json.Number `json:"h"`
+		Low       json.Number `json:"l"`
+		Close     json.Number `json:"c"`
+		Volume    json.Number `json:"v"`
+		Amount    json.Number `json:"a"`
+	} `json:"k"`
+	EventType string `json:"e"`
+}
+
+// rawOrderUpdate is the data of spot@private.orders.v3.api channel
+type rawOrderUpdate struct {
+	OrderId            string      `json:"i"`
+	ClientOrderId      string      `json:"c"`
+	Price              json.Number `json:"p"`
+	Quantity           json.Number `json:"v"`
+	RemainQuantity     json.Number `json:"V"`
+	Amount             json.Number `json:"a"`
+	RemainAmount       json.Number `json:"A"`
+	AvgPrice           json.Number `json:"ap"`
+	CumulativeQuantity json.Number `json:"cv"`
+	CumulativeAmount   json.Number `json:"ca"`
+	// 1 buy, 2 sell
+	TradeType int `json:"S"`
+	// 1 limit, 2 post only, 3 ioc, 4 fok, 5 market
+	OrderType int `json:"o"`
+	// 1 new, 2 filled, 3 partially filled, 4 canceled, 5 partially canceled
+	Status     int   `json:"s"`
+	IsMaker    int   `json:"m"`
+	CreateTime int64 `json:"O"`
+}
@@ -0,0 +1,155 @@
+package mxc
+
+import (
+	"encoding/json"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/convert"
+	"github.com/xyths/hs/exchange"
+	"strings"
+	"time"
+)
+
+// getInterval convert duration to mxc kline interval, used in rest api
+func getInterval(period time.Duration) string {
+	switch period {
+	case time.Minute:
+		return "1m"
+	case time.Minute * 5:
+		return "5m"
+	case time.Minute * 15:
+		return "15m"
+	case time.Minute * 30:
+		return "30m"
+	case time.Hour:
+		return "60m"
+	case time.Hour * 4:
+		return "4h"
+	case time.Hour * 24:
+		return "1d"
+	case time.Hour * 24 * 7:
+		return "1W"
+	default:
+		return "1d"
+	}
+}
+
+// getWsInterval convert duration to mxc kline interval, used in websocket
+func getWsInterval(period time.Duration) string {
+	switch period {
+	case time.Minute:
+		return "Min1"
+	case time.Minute * 5:
+		return "Min5"
+	case time.Minute * 15:
+		return "Min15"
+	case time.Minute * 30:
+		return "Min30"
+	case time.Hour:
+		return "Min60"
+	case time.Hour * 4:
+		return "Hour4"
+	case time.Hour * 8:
+		return "Hour8"
+	case time.Hour * 24:
+		return "Day1"
+	case time.Hour * 24 * 7:
+		return "Week1"
+	default:
+		return "Day1"
+	}
+}
+
+func convertSymbol(r rawSymbol) exchange.Symbol {
+	return exchange.Symbol{
+		Symbol:              r.Symbol,
+		Disabled:            !(r.Status == "1" || r.Status == "ENABLED") || !r.IsSpotTradingAllowed,
+		BaseCurrency:        strings.ToLower(r.BaseAsset),
+		QuoteCurrency:       strings.ToLower(r.QuoteAsset),
+		PricePrecision:      r.QuotePrecision,
+		AmountPrecision:     r.BaseAssetPrecision,
+		LimitOrderMinAmount: convert.StrToDecimal(r.BaseSizePrecision),
+		MinTotal:            convert.StrToDecimal(r.QuoteAmountPrecision),
+	}
+}
+
+// orderType returns the type like buy-limit, sell-market
+func orderType(side, typ string) string {
+	return strings.ToLower(side) + "-" + strings.ToLower(typ)
+}
+
+var orderStatus = exchange.StatusTable{
+	OrderStatusNew:               exchange.Open,
+	OrderStatusFilled:            exchange.Closed,
+	OrderStatusPartiallyFilled:   exchange.Filled,
+	OrderStatusCanceled:          exchange.Cancelled,
+	OrderStatusPartiallyCanceled: exchange.PartialCancelled,
+}
+
+func convertOrder(id uint64, r rawOrder) exchange.Order {
+	o := exchange.Order{
+		Id:            id,
+		ClientOrderId: r.ClientOrderId,
+		Type:          orderType(r.Side, r.Type),
+		Side:          exchange.ParseSide(r.Side),
+		Symbol:        r.Symbol,
+		Price:         convert.StrToDecimal(r.Price),
+		Amount:        convert.StrToDecimal(r.OrigQty),
+		Time:          time.Unix(0, r.Time*int64(time.Millisecond)),
+		Status:        r.Status,
+		FilledAmount:  convert.StrToDecimal(r.ExecutedQty),
+	}
+	o.State = orderStatus.Normalize(o.Status, o.FilledAmount)
+	if !o.FilledAmount.IsZero() {
+		o.FilledPrice = convert.StrToDecimal(r.CummulativeQuoteQty).Div(o.FilledAmount)
+	}
+	return o
+}
+
+func numberToDecimal(n json.Number) decimal.Decimal {
+	return convert.StrToDecimal(n.String())
+}
+
+var wsOrderStatus = map[int]string{
+	1: OrderStatusNew,
+	2: OrderStatusFilled,
+	3: OrderStatusPartiallyFilled,
+	4: OrderStatusCanceled,
+	5: OrderStatusPartiallyCanceled,
+}
+
+func convertOrderUpdate(id uint64, symbol string, r rawOrderUpdate) exchange.Order {
+	side, typ := SideBuy, OrderTypeLimit
+	if r.TradeType == 2 {
+		side = SideSell
+	}
+	if r.OrderType == 5 {
+		typ = OrderTypeMarket
+	}
+	o := exchange.Order{
+		Id:            id,
+		ClientOrderId: r.ClientOrderId,
+		Type:          orderType(side, typ),
+		Side:          exchange.ParseSide(side),
+		Symbol:        symbol,
+		Price:         numberToDecimal(r.Price),
+		Amount:        numberToDecimal(r.Quantity),
+		Time:          time.Unix(0, r.CreateTime*int64(time.Millisecond)),
+		Status:        wsOrderStatus[r.Status],
+		FilledPrice:   numberToDecimal(r.AvgPrice),
+		FilledAmount:  numberToDecimal(r.CumulativeQuantity),
+	}
+	o.State = orderStatus.Normalize(o.Status, o.FilledAmount)
+	return o
+}
+
+func convertKline(r rawKline) hs.Ticker {
+	return hs.Ticker{
+		Timestamp: r.Kline.StartTime,
+		Open:      convert.StrToFloat64(r.Kline.Open.String()),
+		High:      convert.StrToFloat64(r.Kline.High.String()),
+		Low:       convert.StrToFloat64(r.Kline.Low.String()),
+		Close:     convert.StrToFloat64(r.Kline.Close.String()),
+		Volume:    convert.StrToFloat64(r.Kline.Volume.String()),
+	}
+}
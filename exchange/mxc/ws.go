@@ -0,0 +1,209 @@
+package mxc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	ChannelKline  = "spot@public.kline.v3.api"
+	ChannelOrders = "spot@private.orders.v3.api"
+
+	// CandlestickReqMaxLength is the history length sent before the candlestick updates
+	CandlestickReqMaxLength = 300
+
+	// the connection will break if no data in 60 seconds
+	pingInterval = time.Second * 20
+	// listen key expires after 60 minutes, keep it alive every 30 minutes
+	listenKeyKeepAlive = time.Minute * 30
+)
+
+// subscription is one websocket connection
+type subscription struct {
+	ws        *base.WebsocketBase
+	listenKey string
+	stop      chan struct{}
+}
+
+// SubscribeOrder sends exchange.Order to responseHandler, symbol is empty for all
+func (c *Client) SubscribeOrder(symbol, clientId string, responseHandler exchange.ResponseHandler) {
+	listenKey, err := c.newListenKey()
+	if err != nil {
+		c.Logger.Errorf("create listen key error: %s", err)
+		return
+	}
+	sub := c.newSubscription("/ws?listenKey=" + url.QueryEscape(listenKey))
+	sub.listenKey = listenKey
+	sub.ws.SetHandler(
+		func() {
+			sub.ws.Send(request("SUBSCRIPTION", ChannelOrders))
+		},
+		func(messageType int, payload []byte) {
+			var msg wsMessage
+			if err := json.Unmarshal(payload, &msg); err != nil {
+				c.Logger.Errorf("unmarshal message error: %s", err)
+				return
+			}
+			if msg.Channel != ChannelOrders || (symbol != "" && msg.Symbol != symbol) {
+				return
+			}
+			var r rawOrderUpdate
+			if err := json.Unmarshal(msg.Data, &r); err != nil {
+				c.Logger.Errorf("unmarshal order error: %s", err)
+				return
+			}
+			responseHandler(convertOrderUpdate(c.orderId(r.OrderId), msg.Symbol, r))
+		})
+	c.start(orderKey(symbol, clientId), sub)
+	go c.keepAlive(sub)
+}
+
+func (c *Client) UnsubscribeOrder(symbol, clientId string) {
+	sub := c.stop(orderKey(symbol, clientId), request("UNSUBSCRIPTION", ChannelOrders))
+	if sub == nil {
+		return
+	}
+	if err := c.rest().Do(context.Background(), http.MethodDelete, "/api/v3/userDataStream", url.Values{"listenKey": {sub.listenKey}}, true, nil); err != nil {
+		c.Logger.Errorf("delete listen key error: %s", err)
+	}
+}
+
+// SubscribeCandlestick sends hs.Ticker to responseHandler
+func (c *Client) SubscribeCandlestick(symbol, clientId string, period time.Duration, responseHandler exchange.ResponseHandler) {
+	channel := klineChannel(symbol, period)
+	sub := c.newSubscription("/ws")
+	sub.ws.SetHandler(
+		func() {
+			sub.ws.Send(request("SUBSCRIPTION", channel))
+		},
+		func(messageType int, payload []byte) {
+			var msg wsMessage
+			if err := json.Unmarshal(payload, &msg); err != nil {
+				c.Logger.Errorf("unmarshal message error: %s", err)
+				return
+			}
+			if msg.Channel != channel {
+				return
+			}
+			var r rawKline
+			if err := json.Unmarshal(msg.Data, &r); err != nil {
+				c.Logger.Errorf("unmarshal kline error: %s", err)
+				return
+			}
+			responseHandler(convertKline(r))
+		})
+	c.start(candleKey(symbol, clientId, period), sub)
+}
+
+func (c *Client) UnsubscribeCandlestick(symbol, clientId string, period time.Duration) {
+	c.stop(candleKey(symbol, clientId, period), request("UNSUBSCRIPTION", klineChannel(symbol, period)))
+}
+
+// SubscribeCandlestickWithReq sends the latest history as hs.Candle first, then hs.Ticker as SubscribeCandlestick
+func (c *Client) SubscribeCandlestickWithReq(symbol, clientId string, period time.Duration, responseHandler exchange.ResponseHandler) {
+	candle, err := c.CandleBySize(symbol, period, CandlestickReqMaxLength)
+	if err != nil {
+		c.Logger.Errorf("get candle error: %s", err)
+	} else {
+		responseHandler(candle)
+	}
+	c.SubscribeCandlestick(symbol, clientId, period, responseHandler)
+}
+
+func (c *Client) UnsubscribeCandlestickWithReq(symbol, clientId string, period time.Duration) {
+	c.UnsubscribeCandlestick(symbol, clientId, period)
+}
+
+func (c *Client) newListenKey() (string, error) {
+	var r struct {
+		ListenKey string `json:"listenKey"`
+	}
+	err := c.rest().Do(context.Background(), http.MethodPost, "/api/v3/userDataStream", nil, true, &r)
+	return r.ListenKey, err
+}
+
+func (c *Client) keepAlive(sub *subscription) {
+	ticker := time.NewTicker(listenKeyKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sub.stop:
+			return
+		case <-ticker.C:
+			if err := c.rest().Do(context.Background(), http.MethodPut, "/api/v3/userDataStream", url.Values{"listenKey": {sub.listenKey}}, true, nil); err != nil {
+				c.Logger.Errorf("keep alive listen key error: %s", err)
+			}
+		}
+	}
+}
+
+func (c *Client) newSubscription(path string) *subscription {
+	return &subscription{
+		ws:   new(base.WebsocketBase).Init(base.WithScheme(c.WsHost, "wss"), path, c.Logger, 10, 60, false),
+		stop: make(chan struct{}),
+	}
+}
+
+// start connects and keeps the connection alive by ping
+func (c *Client) start(key string, sub *subscription) {
+	c.mu.Lock()
+	old := c.subs[key]
+	c.subs[key] = sub
+	c.mu.Unlock()
+	if old != nil {
+		close(old.stop)
+		old.ws.Close()
+	}
+
+	sub.ws.Connect(true)
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sub.stop:
+				return
+			case <-ticker.C:
+				sub.ws.Send(`{"method":"PING"}`)
+			}
+		}
+	}()
+}
+
+func (c *Client) stop(key, unsubscribe string) *subscription {
+	c.mu.Lock()
+	sub := c.subs[key]
+	delete(c.subs, key)
+	c.mu.Unlock()
+	if sub == nil {
+		return nil
+	}
+	sub.ws.Send(unsubscribe)
+	close(sub.stop)
+	sub.ws.Close()
+	return sub
+}
+
+func request(method string, params ...string) string {
+	data, _ := json.Marshal(map[string]interface{}{"method": method, "params": params})
+	return string(data)
+}
+
+func klineChannel(symbol string, period time.Duration) string {
+	return fmt.Sprintf("%s@%s@%s", ChannelKline, strings.ToUpper(symbol), getWsInterval(period))
+}
+
+func orderKey(symbol, clientId string) string {
+	return fmt.Sprintf("orders#%s#%s", symbol, clientId)
+}
+
+func candleKey(symbol, clientId string, period time.Duration) string {
+	return fmt.Sprintf("kline#%s#%s#%s", symbol, getWsInterval(period), clientId)
+}
@@ -0,0 +1,533 @@
+// Package okex is the spot client of okx.com (formerly okex), by v5 RESTful and websocket api.
+package okex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/convert"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHost   = "www.okx.com"
+	DefaultWsHost = "ws.okx.com:8443"
+
+	// max candles of one request
+	maxCandleLength = 300
+)
+
+type Client struct {
+	Key        string
+	Secret     string
+	Passphrase string
+	// Host is the RESTful api host, with optional scheme, https by default
+	Host string
+	// WsHost is the websocket host, with optional scheme, wss by default
+	WsHost string
+	Logger *zap.SugaredLogger
+
+	httpClient *http.Client
+
+	mu   sync.Mutex
+	subs map[string]*subscription
+}
+
+var (
+	_ exchange.RestAPIExchange = (*Client)(nil)
+	_ exchange.WsAPIExchange   = (*Client)(nil)
+)
+
+func New(key, secret, passphrase, host string, logger *zap.SugaredLogger) *Client {
+	if host == "" {
+		host = DefaultHost
+	}
+	return &Client{
+		Key:        key,
+		Secret:     secret,
+		Passphrase: passphrase,
+		Host:       host,
+		WsHost:     DefaultWsHost,
+		Logger:     logger,
+		httpClient: &http.Client{Timeout: time.Second * 10},
+		subs:       make(map[string]*subscription),
+	}
+}
+
+func init() {
+	exchange.Register(hs.OKEx, func(conf hs.ExchangeConf, logger *zap.SugaredLogger) (exchange.Exchange, error) {
+		return New(conf.Key, conf.Secret, conf.Passphrase, conf.Host, logger), nil
+	})
+}
+
+func (c *Client) Name() string {
+	return hs.OKEx
+}
+
+// FormatSymbol returns instrument id like BTC-USDT
+func (c *Client) FormatSymbol(base, quote string) string {
+	return strings.ToUpper(base + "-" + quote)
+}
+
+func (c *Client) AllSymbols(ctx context.Context) (s []exchange.Symbol, err error) {
+	var instruments []rawInstrument
+	if err = c.get(ctx, "/api/v5/public/instruments", url.Values{"instType": {"SPOT"}}, false, &instruments); err != nil {
+		return
+	}
+	for _, r := range instruments {
+		s = append(s, convertSymbol(r))
+	}
+	return
+}
+
+func (c *Client) GetSymbol(ctx context.Context, symbol string) (exchange.Symbol, error) {
+	var instruments []rawInstrument
+	params := url.Values{"instType": {"SPOT"}, "instId": {symbol}}
+	if err := c.get(ctx, "/api/v5/public/instruments", params, false, &instruments); err != nil {
+		return exchange.Symbol{}, err
+	}
+	if len(instruments) == 0 {
+		return exchange.Symbol{}, ErrSymbolNotFound
+	}
+	return convertSymbol(instruments[0]), nil
+}
+
+// GetFee returns positive fee rate, okex use negative number for commission
+func (c *Client) GetFee(symbol string) (fee exchange.Fee, err error) {
+	var fees []struct {
+		Maker string `json:"maker"`
+		Taker string `json:"taker"`
+	}
+	params := url.Values{"instType": {"SPOT"}, "instId": {symbol}}
+	if err = c.get(context.Background(), "/api/v5/account/trade-fee", params, true, &fees); err != nil {
+		return
+	}
+	if len(fees) == 0 {
+		return fee, ErrSymbolNotFound
+	}
+	fee.Symbol = symbol
+	fee.BaseMaker = convert.StrToDecimal(fees[0].Maker).Neg()
+	fee.BaseTaker = convert.StrToDecimal(fees[0].Taker).Neg()
+	fee.ActualMaker = fee.BaseMaker
+	fee.ActualTaker = fee.BaseTaker
+	return
+}
+
+func (c *Client) balances() ([]rawBalance, error) {
+	var accounts []struct {
+		Details []rawBalance `json:"details"`
+	}
+	if err := c.get(context.Background(), "/api/v5/account/balance", nil, true, &accounts); err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, nil
+	}
+	return accounts[0].Details, nil
+}
+
+func (c *Client) SpotBalance() (map[string]decimal.Decimal, error) {
+	balances, err := c.balances()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]decimal.Decimal)
+	for _, b := range balances {
+		if total := convert.StrToDecimal(b.CashBal); !total.IsZero() {
+			result[strings.ToLower(b.Ccy)] = total
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) SpotAvailableBalance() (map[string]decimal.Decimal, error) {
+	balances, err := c.balances()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]decimal.Decimal)
+	for _, b := range balances {
+		if available := convert.StrToDecimal(b.AvailBal); !available.IsZero() {
+			result[strings.ToLower(b.Ccy)] = available
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) ticker(symbol string) (t rawTicker, err error) {
+	var tickers []rawTicker
+	if err = c.get(context.Background(), "/api/v5/market/ticker", url.Values{"instId": {symbol}}, false, &tickers); err != nil {
+		return
+	}
+	if len(tickers) == 0 {
+		return t, ErrSymbolNotFound
+	}
+	return tickers[0], nil
+}
+
+func (c *Client) LastPrice(symbol string) (decimal.Decimal, error) {
+	t, err := c.ticker(symbol)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromString(t.Last)
+}
+
+// Last24hVolume returns the volume of base currency in 24 hours
+func (c *Client) Last24hVolume(symbol string) (decimal.Decimal, error) {
+	t, err := c.ticker(symbol)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromString(t.Vol24h)
+}
+
+// CandleBySize get the latest candles, the first page is from /market/candles, and older from /market/history-candles
+func (c *Client) CandleBySize(symbol string, period time.Duration, size int) (hs.Candle, error) {
+	candle := hs.NewCandle(size)
+	path := "/api/v5/market/candles"
+	for after := int64(0); candle.Length() < size; path = "/api/v5/market/history-candles" {
+		limit := size - candle.Length()
+		if limit > maxCandleLength {
+			limit = maxCandleLength
+		}
+		params := url.Values{"instId": {symbol}, "bar": {getBar(period)}, "limit": {strconv.Itoa(limit)}}
+		if after > 0 {
+			params.Set("after", strconv.FormatInt(after, 10))
+		}
+		batch, err := c.candles(path, params)
+		if err != nil {
+			return candle, err
+		}
+		n := batch.Length()
+		if n == 0 {
+			break
+		}
+		// batch is before candle
+		batch.Capacity = size
+		batch.Add(candle)
+		candle = batch
+		after = candle.Timestamp[0] * 1000
+		if n < limit {
+			break
+		}
+	}
+	return candle, nil
+}
+
+// CandleFrom get candles in [from, to], page backward from 'to' by history-candles
+func (c *Client) CandleFrom(symbol, clientId string, period time.Duration, from, to time.Time) (hs.Candle, error) {
+	if !from.Before(to) {
+		return hs.Candle{}, errors.New("'from' need before 'to'")
+	}
+	candle := hs.NewCandle(int(to.Sub(from)/period) + 1)
+	for after := to.Unix()*1000 + 1; ; {
+		params := url.Values{
+			"instId": {symbol},
+			"bar":    {getBar(period)},
+			"limit":  {strconv.Itoa(maxCandleLength)},
+			"after":  {strconv.FormatInt(after, 10)},
+			"before": {strconv.FormatInt(from.Unix()*1000-1, 10)},
+		}
+		batch, err := c.candles("/api/v5/market/history-candles", params)
+		if err != nil {
+			return candle, err
+		}
+		n := batch.Length()
+		if n == 0 {
+			break
+		}
+		batch.Capacity = candle.Capacity
+		batch.Add(candle)
+		candle = batch
+		if n < maxCandleLength {
+			break
+		}
+		after = candle.Timestamp[0] * 1000
+	}
+	return candle, nil
+}
+
+// candles returns candles in ascending order, okex returns the latest first
+func (c *Client) candles(path string, params url.Values) (hs.Candle, error) {
+	var raw [][]string
+	if err := c.get(context.Background(), path, params, false, &raw); err != nil {
+		return hs.Candle{}, err
+	}
+	candle := hs.NewCandle(len(raw))
+	for i := len(raw) - 1; i >= 0; i-- {
+		if t, ok := parseCandle(raw[i]); ok {
+			candle.Append(t)
+		}
+	}
+	return candle, nil
+}
+
+// orderOptions lists the spot order options, the iceberg is an algo order and not supported here,
+// reduce-only is for margin mode, but the spot orders are placed in cash mode.
+var orderOptions = exchange.OptionSupport{
+	Name:        "okex",
+	TimeInForce: []exchange.TimeInForce{exchange.GTC, exchange.IOC, exchange.FOK, exchange.PostOnly},
+}
+
+// PlaceOrder places limit order, the time in force is the order type of okex
+func (c *Client) PlaceOrder(symbol, clientOrderId string, side exchange.OrderType, price, amount decimal.Decimal, options exchange.OrderOptions) (orderId uint64, err error) {
+	if err = orderOptions.Check(options, amount); err != nil {
+		return
+	}
+	s := SideBuy
+	switch side {
+	case exchange.Buy:
+	case exchange.Sell:
+		s = SideSell
+	default:
+		return 0, exchange.ErrBadSide
+	}
+	orderType := OrderTypeLimit
+	switch options.TIF() {
+	case exchange.IOC:
+		orderType = OrderTypeIOC
+	case exchange.FOK:
+		orderType = OrderTypeFOK
+	case exchange.PostOnly:
+		orderType = OrderTypePostOnly
+	}
+	return c.placeOrder(rawOrderRequest{InstId: symbol, ClOrdId: clientOrderId, Side: s, OrdType: orderType, Px: price.String(), Sz: amount.String()})
+}
+
+func (c *Client) BuyLimit(symbol, clientOrderId string, price, amount decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(rawOrderRequest{InstId: symbol, ClOrdId: clientOrderId, Side: SideBuy, OrdType: OrderTypeLimit, Px: price.String(), Sz: amount.String()})
+}
+
+func (c *Client) SellLimit(symbol, clientOrderId string, price, amount decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(rawOrderRequest{InstId: symbol, ClOrdId: clientOrderId, Side: SideSell, OrdType: OrderTypeLimit, Px: price.String(), Sz: amount.String()})
+}
+
+// BuyMarket spends total quote currency
+func (c *Client) BuyMarket(symbol exchange.Symbol, clientOrderId string, total decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(rawOrderRequest{InstId: symbol.Symbol, ClOrdId: clientOrderId, Side: SideBuy, OrdType: OrderTypeMarket, Sz: total.String(), TgtCcy: "quote_ccy"})
+}
+
+func (c *Client) SellMarket(symbol exchange.Symbol, clientOrderId string, amount decimal.Decimal) (orderId uint64, err error) {
+	return c.placeOrder(rawOrderRequest{InstId: symbol.Symbol, ClOrdId: clientOrderId, Side: SideSell, OrdType: OrderTypeMarket, Sz: amount.String(), TgtCcy: "base_ccy"})
+}
+
+// BuyStopLimit places a trigger algo order, returns the algo id with AlgoIdFlag,
+// GetOrderById and CancelOrder route it to the algo order api.
+func (c *Client) BuyStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (orderId uint64, err error) {
+	return c.placeAlgoOrder(rawAlgoOrderRequest{InstId: symbol, AlgoClOrdId: clientOrderId, Side: SideBuy, Sz: amount.String(), TriggerPx: stopPrice.String(), OrderPx: price.String()})
+}
+
+// SellStopLimit places a trigger algo order, returns the algo id with AlgoIdFlag, see BuyStopLimit
+func (c *Client) SellStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (orderId uint64, err error) {
+	return c.placeAlgoOrder(rawAlgoOrderRequest{InstId: symbol, AlgoClOrdId: clientOrderId, Side: SideSell, Sz: amount.String(), TriggerPx: stopPrice.String(), OrderPx: price.String()})
+}
+
+func (c *Client) placeOrder(req rawOrderRequest) (uint64, error) {
+	req.TdMode = "cash"
+	var results []rawOrderResult
+	if err := c.post(context.Background(), "/api/v5/trade/order", req, &results); err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, errors.New("no order result")
+	}
+	if results[0].SCode != "0" {
+		return 0, ApiError{Code: results[0].SCode, Msg: results[0].SMsg}
+	}
+	return convert.StrToUint64(results[0].OrdId), nil
+}
+
+func (c *Client) placeAlgoOrder(req rawAlgoOrderRequest) (uint64, error) {
+	req.TdMode = "cash"
+	req.OrdType = "trigger"
+	var results []rawOrderResult
+	if err := c.post(context.Background(), "/api/v5/trade/order-algo", req, &results); err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, errors.New("no order result")
+	}
+	if results[0].SCode != "0" {
+		return 0, ApiError{Code: results[0].SCode, Msg: results[0].SMsg}
+	}
+	return AlgoIdFlag | convert.StrToUint64(results[0].AlgoId), nil
+}
+
+// IsAlgoId returns true if the order id is returned by BuyStopLimit or SellStopLimit
+func IsAlgoId(orderId uint64) bool {
+	return orderId&AlgoIdFlag != 0
+}
+
+// algoOrder queries the pending algo orders first, then the history
+func (c *Client) algoOrder(orderId uint64) (rawAlgoOrder, error) {
+	algoId := strconv.FormatUint(orderId&^AlgoIdFlag, 10)
+	params := url.Values{"ordType": {"trigger"}, "algoId": {algoId}}
+	for _, path := range []string{"/api/v5/trade/orders-algo-pending", "/api/v5/trade/orders-algo-history"} {
+		var orders []rawAlgoOrder
+		if err := c.get(context.Background(), path, params, true, &orders); err != nil {
+			return rawAlgoOrder{}, err
+		}
+		if len(orders) > 0 {
+			return orders[0], nil
+		}
+	}
+	return rawAlgoOrder{}, ErrOrderNotFound
+}
+
+// getAlgoOrder returns the triggered order if any, with the algo id
+func (c *Client) getAlgoOrder(orderId uint64, symbol string) (exchange.Order, error) {
+	r, err := c.algoOrder(orderId)
+	if err != nil {
+		return exchange.Order{}, err
+	}
+	if r.State == AlgoStateEffective && r.OrdId != "" {
+		o, err := c.GetOrderById(convert.StrToUint64(r.OrdId), symbol)
+		o.Id = orderId
+		return o, err
+	}
+	return convertAlgoOrder(orderId, r), nil
+}
+
+func (c *Client) GetOrderById(orderId uint64, symbol string) (exchange.Order, error) {
+	if IsAlgoId(orderId) {
+		return c.getAlgoOrder(orderId, symbol)
+	}
+	var orders []rawOrder
+	params := url.Values{"instId": {symbol}, "ordId": {strconv.FormatUint(orderId, 10)}}
+	if err := c.get(context.Background(), "/api/v5/trade/order", params, true, &orders); err != nil {
+		return exchange.Order{}, err
+	}
+	if len(orders) == 0 {
+		return exchange.Order{}, ErrOrderNotFound
+	}
+	return convertOrder(orders[0]), nil
+}
+
+func (c *Client) CancelOrder(symbol string, orderId uint64) error {
+	if IsAlgoId(orderId) {
+		return c.cancelAlgoOrder(symbol, orderId)
+	}
+	req := map[string]string{"instId": symbol, "ordId": strconv.FormatUint(orderId, 10)}
+	var results []rawOrderResult
+	if err := c.post(context.Background(), "/api/v5/trade/cancel-order", req, &results); err != nil {
+		return err
+	}
+	if len(results) > 0 && results[0].SCode != "0" {
+		return ApiError{Code: results[0].SCode, Msg: results[0].SMsg}
+	}
+	return nil
+}
+
+// cancelAlgoOrder cancels the triggered order if the algo order is effective
+func (c *Client) cancelAlgoOrder(symbol string, orderId uint64) error {
+	r, err := c.algoOrder(orderId)
+	if err != nil {
+		return err
+	}
+	if r.State == AlgoStateEffective && r.OrdId != "" {
+		return c.CancelOrder(symbol, convert.StrToUint64(r.OrdId))
+	}
+	req := []map[string]string{{"instId": symbol, "algoId": r.AlgoId}}
+	var results []rawOrderResult
+	if err := c.post(context.Background(), "/api/v5/trade/cancel-algos", req, &results); err != nil {
+		return err
+	}
+	if len(results) > 0 && results[0].SCode != "0" {
+		return ApiError{Code: results[0].SCode, Msg: results[0].SMsg}
+	}
+	return nil
+}
+
+func (c *Client) IsFullFilled(symbol string, orderId uint64) (exchange.Order, bool, error) {
+	o, err := c.GetOrderById(orderId, symbol)
+	if err != nil {
+		return o, false, err
+	}
+	return o, o.FullFilled(), nil
+}
+
+// ApiError is the error code and message returned by okex api
+type ApiError struct {
+	Code string
+	Msg  string
+}
+
+func (e ApiError) Error() string {
+	return fmt.Sprintf("okex api error %s: %s", e.Code, e.Msg)
+}
+
+var (
+	ErrSymbolNotFound = errors.New("symbol not found")
+	ErrOrderNotFound  = errors.New("order not found")
+)
+
+func (c *Client) get(ctx context.Context, path string, params url.Values, signed bool, result interface{}) error {
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	return c.do(ctx, http.MethodGet, path, nil, signed, result)
+}
+
+// post sends signed request with json body
+func (c *Client) post(ctx context.Context, path string, body interface{}, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, path, data, true, result)
+}
+
+// do sends the request, and unmarshal the data field of response to result.
+// path is the request path with query string, which is a part of signature payload.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, signed bool, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, base.WithScheme(c.Host, "https")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if signed {
+		timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+		req.Header.Set("OK-ACCESS-KEY", c.Key)
+		req.Header.Set("OK-ACCESS-SIGN", base.SignBase64(c.Secret, timestamp+method+path+string(body)))
+		req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+		req.Header.Set("OK-ACCESS-PASSPHRASE", c.Passphrase)
+	}
+	status, data, err := base.Send(c.httpClient, req)
+	if err != nil {
+		return err
+	}
+	var r struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err = json.Unmarshal(data, &r); err != nil {
+		return fmt.Errorf("bad response, status %d: %w", status, err)
+	}
+	if r.Code != "0" {
+		apiErr := ApiError{Code: r.Code, Msg: r.Msg}
+		// the detail is in data for order api
+		var results []rawOrderResult
+		if json.Unmarshal(r.Data, &results) == nil && len(results) > 0 && results[0].SCode != "" {
+			apiErr.Code, apiErr.Msg = results[0].SCode, results[0].SMsg
+		}
+		return apiErr
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Data, result)
+}
@@ -0,0 +1,213 @@
+package okex
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
+	"github.com/xyths/hs/exchange/base/basetest"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+)
+
+// newFixtureClient returns client to the server, which responses testdata/<last part of path>.json
+func newFixtureClient(t *testing.T, fixtures map[string]string) *Client {
+	server := basetest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		if ts := r.Header.Get("OK-ACCESS-TIMESTAMP"); ts != "" {
+			require.Equal(t, "key", r.Header.Get("OK-ACCESS-KEY"))
+			require.Equal(t, "passphrase", r.Header.Get("OK-ACCESS-PASSPHRASE"))
+			require.Equal(t, base.SignBase64("secret", ts+r.Method+r.URL.RequestURI()+string(body)), r.Header.Get("OK-ACCESS-SIGN"))
+		}
+		name, ok := fixtures[r.URL.Path]
+		if !ok {
+			name = path.Base(r.URL.Path)
+		}
+		basetest.WriteFixture(t, w, name)
+	}))
+	return New("key", "secret", "passphrase", server.URL, zap.NewNop().Sugar())
+}
+
+func TestClient_Market(t *testing.T) {
+	c := newFixtureClient(t, nil)
+	symbol := c.FormatSymbol("btc", "usdt")
+	require.Equal(t, "BTC-USDT", symbol)
+
+	s, err := c.GetSymbol(context.Background(), symbol)
+	require.NoError(t, err)
+	require.Equal(t, "btc", s.BaseCurrency)
+	require.Equal(t, int32(1), s.PricePrecision)
+	require.Equal(t, int32(8), s.AmountPrecision)
+	require.True(t, decimal.RequireFromString("0.00001").Equal(s.LimitOrderMinAmount))
+
+	price, err := c.LastPrice(symbol)
+	require.NoError(t, err)
+	require.Equal(t, "27041.3", price.String())
+	volume, err := c.Last24hVolume(symbol)
+	require.NoError(t, err)
+	require.Equal(t, "5352.39052316", volume.String())
+
+	// okex returns the latest first
+	candle, err := c.CandleBySize(symbol, time.Minute*5, 2)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1695717900, 1695718200}, candle.Timestamp)
+	require.Equal(t, 27041.3, candle.Close[1])
+
+	candle, err = c.CandleFrom(symbol, "", time.Minute*5, time.Unix(1695717600, 0), time.Unix(1695718200, 0))
+	require.NoError(t, err)
+	require.Equal(t, []int64{1695717600}, candle.Timestamp)
+}
+
+func TestClient_Account(t *testing.T) {
+	c := newFixtureClient(t, nil)
+	balances, err := c.SpotBalance()
+	require.NoError(t, err)
+	require.Len(t, balances, 2)
+	require.Equal(t, "120.5", balances["usdt"].String())
+	available, err := c.SpotAvailableBalance()
+	require.NoError(t, err)
+	require.Equal(t, "100.5", available["usdt"].String())
+
+	fee, err := c.GetFee("BTC-USDT")
+	require.NoError(t, err)
+	require.Equal(t, "0.0008", fee.BaseMaker.String())
+	require.Equal(t, "0.001", fee.ActualTaker.String())
+}
+
+func TestClient_Order(t *testing.T) {
+	c := newFixtureClient(t, map[string]string{
+		"/api/v5/trade/order":        "order",
+		"/api/v5/trade/cancel-order": "cancel-order-error",
+	})
+	o, filled, err := c.IsFullFilled("BTC-USDT", 312269865356374016)
+	require.NoError(t, err)
+	require.True(t, filled)
+	require.Equal(t, "buy-limit", o.Type)
+	require.Equal(t, "b15", o.ClientOrderId)
+	require.Equal(t, "27000.5", o.FilledPrice.String())
+	require.Equal(t, int64(1695718434), o.Time.Unix())
+
+	err = c.CancelOrder("BTC-USDT", 312269865356374016)
+	require.Error(t, err)
+	apiErr, ok := err.(ApiError)
+	require.True(t, ok)
+	require.Equal(t, "51400", apiErr.Code)
+}
+
+func TestClient_AlgoOrder(t *testing.T) {
+	algoId := AlgoIdFlag | 681096944655273984
+	// triggered, the order is queried and cancelled by the order id
+	c := newFixtureClient(t, map[string]string{
+		"/api/v5/trade/orders-algo-pending": "orders-algo-pending-empty",
+		"/api/v5/trade/order":               "order",
+		"/api/v5/trade/cancel-order":        "cancel-order-error",
+	})
+	o, err := c.GetOrderById(algoId, "BTC-USDT")
+	require.NoError(t, err)
+	require.Equal(t, algoId, o.Id)
+	require.Equal(t, exchange.Closed, o.State)
+	require.Equal(t, "27000.5", o.FilledPrice.String())
+	err = c.CancelOrder("BTC-USDT", algoId)
+	require.Error(t, err)
+	require.Equal(t, "51400", err.(ApiError).Code)
+
+	// not triggered yet
+	c = newFixtureClient(t, nil)
+	o, err = c.GetOrderById(algoId, "BTC-USDT")
+	require.NoError(t, err)
+	require.Equal(t, algoId, o.Id)
+	require.Equal(t, exchange.Open, o.State)
+	require.Equal(t, exchange.Sell, o.Side)
+	require.Equal(t, "s1", o.ClientOrderId)
+	require.Equal(t, "26000", o.Price.String())
+	require.NoError(t, c.CancelOrder("BTC-USDT", algoId))
+}
+
+func TestClient_PlaceOrder(t *testing.T) {
+	server := basetest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/api/v5/trade/order", r.URL.Path)
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "cash", req["tdMode"])
+		require.Equal(t, "market", req["ordType"])
+		require.Equal(t, "quote_ccy", req["tgtCcy"])
+		require.Equal(t, "100", req["sz"])
+		basetest.WriteFixture(t, w, "place-order")
+	}))
+
+	c := New("key", "secret", "passphrase", server.URL, zap.NewNop().Sugar())
+	id, err := c.BuyMarket(exchange.Symbol{Symbol: "BTC-USDT"}, "b15", decimal.NewFromInt(100))
+	require.NoError(t, err)
+	require.Equal(t, uint64(312269865356374016), id)
+}
+
+func TestClient_SubscribeOrder(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := basetest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, WsPathPrivate, r.URL.Path)
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var login struct {
+			Op   string              `json:"op"`
+			Args []map[string]string `json:"args"`
+		}
+		require.NoError(t, conn.ReadJSON(&login))
+		require.Equal(t, "login", login.Op)
+		require.Equal(t, base.SignBase64("secret", login.Args[0]["timestamp"]+"GET/users/self/verify"), login.Args[0]["sign"])
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"login","code":"0","msg":""}`)))
+
+		var subscribe struct {
+			Op   string  `json:"op"`
+			Args []wsArg `json:"args"`
+		}
+		require.NoError(t, conn.ReadJSON(&subscribe))
+		require.Equal(t, "subscribe", subscribe.Op)
+		require.Equal(t, wsArg{Channel: "orders", InstType: "SPOT", InstId: "BTC-USDT"}, subscribe.Args[0])
+
+		data, err := ioutil.ReadFile("testdata/ws-orders.json")
+		require.NoError(t, err)
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, data))
+		_, _, _ = conn.ReadMessage()
+	}))
+
+	c := New("key", "secret", "passphrase", server.URL, zap.NewNop().Sugar())
+	c.WsHost = basetest.WsURL(server.URL)
+	orders := make(chan exchange.Order, 1)
+	c.SubscribeOrder("BTC-USDT", "test", func(response interface{}) {
+		orders <- response.(exchange.Order)
+	})
+	defer c.UnsubscribeOrder("BTC-USDT", "test")
+
+	select {
+	case o := <-orders:
+		require.Equal(t, uint64(312269865356374016), o.Id)
+		require.Equal(t, OrderStateFilled, o.Status)
+		require.True(t, o.FullFilled())
+		require.Len(t, o.Trades, 1)
+		require.Equal(t, "maker", o.Trades[0].Role)
+		require.Equal(t, "0.000001", o.Trades[0].FeeAmount.String())
+		require.Equal(t, "btc", o.Trades[0].FeeCurrency)
+	case <-time.After(time.Second * 5):
+		t.Fatal("no order update")
+	}
+}
+
+func TestParseCandle(t *testing.T) {
+	ticker, ok := parseCandle([]string{"1695718200000", "27034.1", "27045.4", "27025.4", "27041.3", "17.94372453", "485145.69", "485145.69", "0"})
+	require.True(t, ok)
+	require.Equal(t, hs.Ticker{Timestamp: 1695718200, Open: 27034.1, High: 27045.4, Low: 27025.4, Close: 27041.3, Volume: 17.94372453}, ticker)
+	_, ok = parseCandle([]string{"1695718200000"})
+	require.False(t, ok)
+}
@@ -0,0 +1,121 @@
+package okex
+
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+
+	OrderTypeLimit    = "limit"
+	OrderTypeMarket   = "market"
+	OrderTypePostOnly = "post_only"
+	OrderTypeFOK      = "fok"
+	OrderTypeIOC      = "ioc"
+
+	OrderStateLive            = "live"
+	OrderStatePartiallyFilled = "partially_filled"
+	OrderStateFilled          = "filled"
+	OrderStateCanceled        = "canceled"
+
+	AlgoStateLive        = "live"
+	AlgoStatePause       = "pause"
+	AlgoStateEffective   = "effective" // triggered, the order is placed
+	AlgoStateCanceled    = "canceled"
+	AlgoStateOrderFailed = "order_failed"
+
+	// AlgoIdFlag marks the algo id in the order id space, the algo id and order id of okex are both less than 2^63
+	AlgoIdFlag = uint64(1) << 63
+)
+
+type rawInstrument struct {
+	InstId   string `json:"instId"`
+	BaseCcy  string `json:"baseCcy"`
+	QuoteCcy string `json:"quoteCcy"`
+	TickSz   string `json:"tickSz"`
+	LotSz    string `json:"lotSz"`
+	MinSz    string `json:"minSz"`
+	State    string `json:"state"`
+}
+
+type rawBalance struct {
+	Ccy       string `json:"ccy"`
+	CashBal   string `json:"cashBal"`
+	AvailBal  string `json:"availBal"`
+	FrozenBal string `json:"frozenBal"`
+}
+
+type rawTicker struct {
+	InstId string `json:"instId"`
+	Last   string `json:"last"`
+	Vol24h string `json:"vol24h"`
+	Ts     string `json:"ts"`
+}
+
+type rawOrderRequest struct {
+	InstId  string `json:"instId"`
+	TdMode  string `json:"tdMode"`
+	ClOrdId string `json:"clOrdId,omitempty"`
+	Side    string `json:"side"`
+	OrdType string `json:"ordType"`
+	Px      string `json:"px,omitempty"`
+	Sz      string `json:"sz"`
+	// base_ccy or quote_ccy, the unit of sz for market order
+	TgtCcy string `json:"tgtCcy,omitempty"`
+}
+
+type rawAlgoOrderRequest struct {
+	InstId      string `json:"instId"`
+	TdMode      string `json:"tdMode"`
+	AlgoClOrdId string `json:"algoClOrdId,omitempty"`
+	Side        string `json:"side"`
+	OrdType     string `json:"ordType"`
+	Sz          string `json:"sz"`
+	TriggerPx   string `json:"triggerPx"`
+	OrderPx     string `json:"orderPx"`
+}
+
+type rawOrderResult struct {
+	OrdId   string `json:"ordId"`
+	AlgoId  string `json:"algoId"`
+	ClOrdId string `json:"clOrdId"`
+	SCode   string `json:"sCode"`
+	SMsg    string `json:"sMsg"`
+}
+
+// rawAlgoOrder is the algo order in orders-algo-pending and orders-algo-history
+type rawAlgoOrder struct {
+	InstId      string `json:"instId"`
+	AlgoId      string `json:"algoId"`
+	AlgoClOrdId string `json:"algoClOrdId"`
+	Side        string `json:"side"`
+	OrdType     string `json:"ordType"`
+	Sz          string `json:"sz"`
+	TriggerPx   string `json:"triggerPx"`
+	OrdPx       string `json:"ordPx"`
+	State       string `json:"state"`
+	// the triggered order id, only if effective
+	OrdId string `json:"ordId"`
+	CTime string `json:"cTime"`
+}
+
+// rawOrder is the order in rest api and orders channel
+type rawOrder struct {
+	InstId    string `json:"instId"`
+	OrdId     string `json:"ordId"`
+	ClOrdId   string `json:"clOrdId"`
+	Px        string `json:"px"`
+	Sz        string `json:"sz"`
+	OrdType   string `json:"ordType"`
+	Side      string `json:"side"`
+	AccFillSz string `json:"accFillSz"`
+	AvgPx     string `json:"avgPx"`
+	State     string `json:"state"`
+	CTime     string `json:"cTime"`
+
+	// the latest trade, only in orders channel
+	TradeId  string `json:"tradeId"`
+	FillPx   string `json:"fillPx"`
+	FillSz   string `json:"fillSz"`
+	FillTime string `json:"fillTime"`
+	FillFee  string `json:"fillFee"`
+	FeeCcy   string `json:"fillFeeCcy"`
+	ExecType string `json:"execType"`
+}
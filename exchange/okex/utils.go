@@ -0,0 +1,152 @@
+package okex
+
+import (
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/convert"
+	"github.com/xyths/hs/exchange"
+	"strings"
+	"time"
+)
+
+// getBar convert duration to okex candle bar
+func getBar(period time.Duration) string {
+	switch period {
+	case time.Minute:
+		return "1m"
+	case time.Minute * 3:
+		return "3m"
+	case time.Minute * 5:
+		return "5m"
+	case time.Minute * 15:
+		return "15m"
+	case time.Minute * 30:
+		return "30m"
+	case time.Hour:
+		return "1H"
+	case time.Hour * 2:
+		return "2H"
+	case time.Hour * 4:
+		return "4H"
+	case time.Hour * 6:
+		return "6H"
+	case time.Hour * 12:
+		return "12H"
+	case time.Hour * 24:
+		return "1D"
+	case time.Hour * 24 * 7:
+		return "1W"
+	default:
+		return "1D"
+	}
+}
+
+// precision returns the decimal places of size, eg. "0.0001" is 4, "1" is 0
+func precision(size string) int32 {
+	pos := strings.Index(size, ".")
+	if pos < 0 {
+		return 0
+	}
+	size = strings.TrimRight(size, "0")
+	return int32(len(size) - pos - 1)
+}
+
+func convertSymbol(r rawInstrument) exchange.Symbol {
+	return exchange.Symbol{
+		Symbol:              r.InstId,
+		Disabled:            r.State != "live",
+		BaseCurrency:        strings.ToLower(r.BaseCcy),
+		QuoteCurrency:       strings.ToLower(r.QuoteCcy),
+		PricePrecision:      precision(r.TickSz),
+		AmountPrecision:     precision(r.LotSz),
+		LimitOrderMinAmount: convert.StrToDecimal(r.MinSz),
+	}
+}
+
+// parseCandle parse [ts,o,h,l,c,vol,...], ts is in milliseconds
+func parseCandle(raw []string) (hs.Ticker, bool) {
+	if len(raw) < 6 {
+		return hs.Ticker{}, false
+	}
+	return hs.Ticker{
+		Timestamp: convert.StrToInt64(raw[0]) / 1000,
+		Open:      convert.StrToFloat64(raw[1]),
+		High:      convert.StrToFloat64(raw[2]),
+		Low:       convert.StrToFloat64(raw[3]),
+		Close:     convert.StrToFloat64(raw[4]),
+		Volume:    convert.StrToFloat64(raw[5]),
+	}, true
+}
+
+func msToTime(ms string) time.Time {
+	return time.Unix(0, convert.StrToInt64(ms)*int64(time.Millisecond))
+}
+
+var orderState = exchange.StatusTable{
+	OrderStateLive:            exchange.Open,
+	OrderStatePartiallyFilled: exchange.Filled,
+	OrderStateFilled:          exchange.Closed,
+	OrderStateCanceled:        exchange.Cancelled,
+}
+
+func convertOrder(r rawOrder) exchange.Order {
+	o := exchange.Order{
+		Id:            convert.StrToUint64(r.OrdId),
+		ClientOrderId: r.ClOrdId,
+		Type:          r.Side + "-" + r.OrdType,
+		Side:          exchange.ParseSide(r.Side),
+		Symbol:        r.InstId,
+		Price:         convert.StrToDecimal(r.Px),
+		Amount:        convert.StrToDecimal(r.Sz),
+		Time:          msToTime(r.CTime),
+		Status:        r.State,
+		FilledPrice:   convert.StrToDecimal(r.AvgPx),
+		FilledAmount:  convert.StrToDecimal(r.AccFillSz),
+	}
+	o.State = orderState.Normalize(o.Status, o.FilledAmount)
+	if r.TradeId != "" {
+		t := exchange.Trade{
+			Id:          convert.StrToUint64(r.TradeId),
+			OrderId:     o.Id,
+			Symbol:      r.InstId,
+			Type:        o.Type,
+			Side:        r.Side,
+			Role:        "taker",
+			Price:       convert.StrToDecimal(r.FillPx),
+			Amount:      convert.StrToDecimal(r.FillSz),
+			FeeCurrency: strings.ToLower(r.FeeCcy),
+			// okex use negative number for commission
+			FeeAmount: convert.StrToDecimal(r.FillFee).Neg(),
+			Time:      msToTime(r.FillTime),
+		}
+		if r.ExecType == "M" {
+			t.Role = "maker"
+		}
+		o.Trades = append(o.Trades, t)
+	}
+	return o
+}
+
+var algoState = exchange.StatusTable{
+	AlgoStateLive:        exchange.Open,
+	AlgoStatePause:       exchange.Open,
+	AlgoStateEffective:   exchange.Closed,
+	AlgoStateCanceled:    exchange.Cancelled,
+	AlgoStateOrderFailed: exchange.Rejected,
+}
+
+// convertAlgoOrder converts the algo order not triggered yet
+func convertAlgoOrder(orderId uint64, r rawAlgoOrder) exchange.Order {
+	o := exchange.Order{
+		Id:            orderId,
+		ClientOrderId: r.AlgoClOrdId,
+		Type:          r.Side + "-" + r.OrdType,
+		Side:          exchange.ParseSide(r.Side),
+		Symbol:        r.InstId,
+		Price:         convert.StrToDecimal(r.OrdPx),
+		Amount:        convert.StrToDecimal(r.Sz),
+		Time:          msToTime(r.CTime),
+		Status:        r.State,
+	}
+	o.State = algoState.Normalize(o.Status, o.FilledAmount)
+	return o
+}
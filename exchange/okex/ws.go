@@ -0,0 +1,212 @@
+package okex
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/base"
+	"strconv"
+	"time"
+)
+
+const (
+	WsPathPublic   = "/ws/v5/public"
+	WsPathPrivate  = "/ws/v5/private"
+	WsPathBusiness = "/ws/v5/business"
+
+	// CandlestickReqMaxLength is the history length sent before the candlestick updates
+	CandlestickReqMaxLength = 300
+
+	// the connection will break if no data in 30 seconds
+	pingInterval = time.Second * 20
+)
+
+// subscription is one websocket connection
+type subscription struct {
+	ws   *base.WebsocketBase
+	stop chan struct{}
+}
+
+type wsArg struct {
+	Channel  string `json:"channel"`
+	InstType string `json:"instType,omitempty"`
+	InstId   string `json:"instId,omitempty"`
+}
+
+type wsMessage struct {
+	Event string          `json:"event"`
+	Code  string          `json:"code"`
+	Msg   string          `json:"msg"`
+	Arg   wsArg           `json:"arg"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// SubscribeOrder sends exchange.Order to responseHandler, symbol is instrument id, empty for all
+func (c *Client) SubscribeOrder(symbol, clientId string, responseHandler exchange.ResponseHandler) {
+	arg := wsArg{Channel: "orders", InstType: "SPOT", InstId: symbol}
+	sub := c.newSubscription(WsPathPrivate)
+	sub.ws.SetHandler(
+		func() {
+			sub.ws.Send(c.loginRequest())
+		},
+		func(messageType int, payload []byte) {
+			msg, ok := c.parseMessage(payload)
+			if !ok {
+				return
+			}
+			switch {
+			case msg.Event == "login":
+				sub.ws.Send(request("subscribe", arg))
+			case msg.Arg.Channel == arg.Channel && len(msg.Data) > 0:
+				var orders []rawOrder
+				if err := json.Unmarshal(msg.Data, &orders); err != nil {
+					c.Logger.Errorf("unmarshal orders error: %s", err)
+					return
+				}
+				for _, o := range orders {
+					responseHandler(convertOrder(o))
+				}
+			}
+		})
+	c.start(orderKey(symbol, clientId), sub)
+}
+
+func (c *Client) UnsubscribeOrder(symbol, clientId string) {
+	c.stop(orderKey(symbol, clientId), request("unsubscribe", wsArg{Channel: "orders", InstType: "SPOT", InstId: symbol}))
+}
+
+// SubscribeCandlestick sends hs.Ticker to responseHandler
+func (c *Client) SubscribeCandlestick(symbol, clientId string, period time.Duration, responseHandler exchange.ResponseHandler) {
+	arg := wsArg{Channel: "candle" + getBar(period), InstId: symbol}
+	sub := c.newSubscription(WsPathBusiness)
+	sub.ws.SetHandler(
+		func() {
+			sub.ws.Send(request("subscribe", arg))
+		},
+		func(messageType int, payload []byte) {
+			msg, ok := c.parseMessage(payload)
+			if !ok || msg.Arg.Channel != arg.Channel || len(msg.Data) == 0 {
+				return
+			}
+			var candles [][]string
+			if err := json.Unmarshal(msg.Data, &candles); err != nil {
+				c.Logger.Errorf("unmarshal candle error: %s", err)
+				return
+			}
+			for _, raw := range candles {
+				if t, ok := parseCandle(raw); ok {
+					responseHandler(t)
+				}
+			}
+		})
+	c.start(candleKey(symbol, clientId, period), sub)
+}
+
+func (c *Client) UnsubscribeCandlestick(symbol, clientId string, period time.Duration) {
+	c.stop(candleKey(symbol, clientId, period), request("unsubscribe", wsArg{Channel: "candle" + getBar(period), InstId: symbol}))
+}
+
+// SubscribeCandlestickWithReq sends the latest history as hs.Candle first, then hs.Ticker as SubscribeCandlestick
+func (c *Client) SubscribeCandlestickWithReq(symbol, clientId string, period time.Duration, responseHandler exchange.ResponseHandler) {
+	candle, err := c.CandleBySize(symbol, period, CandlestickReqMaxLength)
+	if err != nil {
+		c.Logger.Errorf("get candle error: %s", err)
+	} else {
+		responseHandler(candle)
+	}
+	c.SubscribeCandlestick(symbol, clientId, period, responseHandler)
+}
+
+func (c *Client) UnsubscribeCandlestickWithReq(symbol, clientId string, period time.Duration) {
+	c.UnsubscribeCandlestick(symbol, clientId, period)
+}
+
+func (c *Client) newSubscription(path string) *subscription {
+	return &subscription{
+		ws:   new(base.WebsocketBase).Init(base.WithScheme(c.WsHost, "wss"), path, c.Logger, 10, 60, false),
+		stop: make(chan struct{}),
+	}
+}
+
+// start connects and keeps the connection alive by ping
+func (c *Client) start(key string, sub *subscription) {
+	c.mu.Lock()
+	old := c.subs[key]
+	c.subs[key] = sub
+	c.mu.Unlock()
+	if old != nil {
+		close(old.stop)
+		old.ws.Close()
+	}
+
+	sub.ws.Connect(true)
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sub.stop:
+				return
+			case <-ticker.C:
+				sub.ws.Send("ping")
+			}
+		}
+	}()
+}
+
+func (c *Client) stop(key, unsubscribe string) {
+	c.mu.Lock()
+	sub := c.subs[key]
+	delete(c.subs, key)
+	c.mu.Unlock()
+	if sub == nil {
+		return
+	}
+	sub.ws.Send(unsubscribe)
+	close(sub.stop)
+	sub.ws.Close()
+}
+
+// parseMessage returns false for pong and error
+func (c *Client) parseMessage(payload []byte) (msg wsMessage, ok bool) {
+	if string(payload) == "pong" {
+		return
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		c.Logger.Errorf("unmarshal message error: %s", err)
+		return
+	}
+	if msg.Event == "error" || (msg.Event == "login" && msg.Code != "0") {
+		c.Logger.Errorf("websocket error %s: %s", msg.Code, msg.Msg)
+		return
+	}
+	return msg, true
+}
+
+// loginRequest signs timestamp + GET + /users/self/verify, timestamp is in seconds
+func (c *Client) loginRequest() string {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	data, _ := json.Marshal(map[string]interface{}{
+		"op": "login",
+		"args": []map[string]string{{
+			"apiKey":     c.Key,
+			"passphrase": c.Passphrase,
+			"timestamp":  timestamp,
+			"sign":       base.SignBase64(c.Secret, timestamp+"GET/users/self/verify"),
+		}},
+	})
+	return string(data)
+}
+
+func request(op string, args ...wsArg) string {
+	data, _ := json.Marshal(map[string]interface{}{"op": op, "args": args})
+	return string(data)
+}
+
+func orderKey(symbol, clientId string) string {
+	return fmt.Sprintf("orders#%s#%s", symbol, clientId)
+}
+
+func candleKey(symbol, clientId string, period time.Duration) string {
+	return fmt.Sprintf("candle%s#%s#%s", getBar(period), symbol, clientId)
+}
@@ -0,0 +1,71 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrUnsupportedOption = errors.New("unsupported order option")
+	ErrBadSide           = errors.New("side should be buy or sell")
+	ErrBadIceberg        = errors.New("iceberg should be positive and less than amount")
+)
+
+// TimeInForce is how long the limit order stays in the order book
+type TimeInForce = string
+
+const (
+	GTC      TimeInForce = "gtc"       // good till cancelled, the default
+	IOC      TimeInForce = "ioc"       // immediate or cancel, the unfilled part is cancelled
+	FOK      TimeInForce = "fok"       // fill or kill, cancelled if can't be filled fully at once
+	PostOnly TimeInForce = "post-only" // maker only, cancelled if it would take
+)
+
+// OrderOptions are the extra options of limit order, the zero value is a plain GTC limit order.
+type OrderOptions struct {
+	TimeInForce TimeInForce `json:"timeInForce,omitempty"`
+	// Iceberg is the amount shown in the order book, zero means showing all
+	Iceberg decimal.Decimal `json:"iceberg"`
+	// ReduceOnly only reduces the margin position, never opens or increases it
+	ReduceOnly bool `json:"reduceOnly,omitempty"`
+}
+
+// TIF returns the time in force, GTC if not set
+func (o OrderOptions) TIF() TimeInForce {
+	if o.TimeInForce == "" {
+		return GTC
+	}
+	return o.TimeInForce
+}
+
+// OptionSupport lists the order options supported by an exchange
+type OptionSupport struct {
+	Name        string
+	TimeInForce []TimeInForce
+	Iceberg     bool
+	ReduceOnly  bool
+}
+
+// Check returns ErrUnsupportedOption with the exchange name and the option if any option is not supported,
+// or ErrBadIceberg if the iceberg is not in (0, amount).
+func (s OptionSupport) Check(o OrderOptions, amount decimal.Decimal) error {
+	supported := false
+	for _, tif := range s.TimeInForce {
+		if tif == o.TIF() {
+			supported = true
+			break
+		}
+	}
+	switch {
+	case !supported:
+		return fmt.Errorf("%w: %s doesn't support time in force %q", ErrUnsupportedOption, s.Name, o.TIF())
+	case !o.Iceberg.IsZero() && !s.Iceberg:
+		return fmt.Errorf("%w: %s doesn't support iceberg order", ErrUnsupportedOption, s.Name)
+	case o.ReduceOnly && !s.ReduceOnly:
+		return fmt.Errorf("%w: %s doesn't support reduce-only order", ErrUnsupportedOption, s.Name)
+	case !o.Iceberg.IsZero() && (!o.Iceberg.IsPositive() || o.Iceberg.GreaterThanOrEqual(amount)):
+		return fmt.Errorf("%w: iceberg %s, amount %s", ErrBadIceberg, o.Iceberg, amount)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package exchange
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestOptionSupport_Check(t *testing.T) {
+	amount := decimal.NewFromInt(10)
+	s := OptionSupport{Name: "test", TimeInForce: []TimeInForce{GTC, IOC}}
+	require.NoError(t, s.Check(OrderOptions{}, amount))
+	require.NoError(t, s.Check(OrderOptions{TimeInForce: IOC}, amount))
+	tests := []OrderOptions{
+		{TimeInForce: PostOnly},
+		{Iceberg: decimal.NewFromInt(1)},
+		{ReduceOnly: true},
+	}
+	for _, o := range tests {
+		err := s.Check(o, amount)
+		require.True(t, errors.Is(err, ErrUnsupportedOption), "%v", o)
+	}
+	require.Contains(t, s.Check(OrderOptions{TimeInForce: FOK}, amount).Error(), `test doesn't support time in force "fok"`)
+
+	s.Iceberg = true
+	require.NoError(t, s.Check(OrderOptions{Iceberg: decimal.NewFromInt(1)}, amount))
+	for _, iceberg := range []int64{-1, 10, 11} {
+		err := s.Check(OrderOptions{Iceberg: decimal.NewFromInt(iceberg)}, amount)
+		require.True(t, errors.Is(err, ErrBadIceberg), "iceberg %d", iceberg)
+	}
+}
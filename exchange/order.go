@@ -2,9 +2,11 @@ package exchange
 
 import (
 	"github.com/shopspring/decimal"
+	"strings"
 	"time"
 )
 
+// OrderType is the normalized order side, 0 is unknown
 type OrderType = int
 
 const (
@@ -12,15 +14,50 @@ const (
 	Sell           = -1
 )
 
+// OrderStatus is the normalized order status, 0 is unknown
 type OrderStatus = int
 
 const (
-	Open      OrderStatus = 1 // open but not filled
-	Closed                = 2 // full filled
-	Filled                = 3 // part filled
-	Cancelled             = 4
+	Open             OrderStatus = 1 // open but not filled
+	Closed                       = 2 // full filled
+	Filled                       = 3 // part filled
+	Cancelled                    = 4 // cancelled, not filled
+	PartialCancelled             = 5 // cancelled after part filled
+	Rejected                     = 6 // rejected by exchange, not filled
 )
 
+// StatusTable maps the raw status of exchange to OrderStatus, every adapter has one
+type StatusTable map[string]OrderStatus
+
+// Normalize returns the normalized status of raw status, unknown status is 0.
+// Some exchanges don't tell part filled in status, so it's refined by filled amount:
+// Open with filled amount is Filled, Cancelled with filled amount is PartialCancelled.
+func (t StatusTable) Normalize(raw string, filled decimal.Decimal) OrderStatus {
+	s := t[raw]
+	if filled.IsPositive() {
+		switch s {
+		case Open:
+			return Filled
+		case Cancelled:
+			return PartialCancelled
+		}
+	}
+	return s
+}
+
+// ParseSide parses the side or type of exchange, like buy, SELL, buy-limit, sell-stop-limit
+func ParseSide(s string) OrderType {
+	s = strings.ToLower(s)
+	switch {
+	case strings.HasPrefix(s, "buy"):
+		return Buy
+	case strings.HasPrefix(s, "sell"):
+		return Sell
+	default:
+		return 0
+	}
+}
+
 // Order is common order type between all exchanges, use for exchange interface
 type Order struct {
 	Id            uint64 `json:"id"` // Id should be uint64
@@ -34,13 +71,32 @@ type Order struct {
 	Amount decimal.Decimal `json:"amount"`
 	Time   time.Time       `json:"time"`
 
-	Status string `json:"status"`
+	// Side is Buy or Sell
+	Side OrderType `json:"side"`
+	// Status is the raw status of exchange, State is the normalized one
+	Status string      `json:"status"`
+	State  OrderStatus `json:"state"`
 
 	FilledPrice  decimal.Decimal `json:"filledPrice"`
 	FilledAmount decimal.Decimal `json:"filledAmount"`
 	Trades       []Trade         `json:"trades,omitempty"`
 }
 
+// FullFilled returns true if the order is full filled
+func (o Order) FullFilled() bool {
+	return o.State == Closed
+}
+
+// Finished returns true if the order will not change any more
+func (o Order) Finished() bool {
+	switch o.State {
+	case Closed, Cancelled, PartialCancelled, Rejected:
+		return true
+	default:
+		return false
+	}
+}
+
 type Trade struct {
 	Id      uint64 `json:"id"` // Id should be uint64
 	OrderId uint64 `json:"orderId"`
@@ -0,0 +1,42 @@
+package exchange
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestStatusTable_Normalize(t *testing.T) {
+	table := StatusTable{"open": Open, "closed": Closed, "cancelled": Cancelled}
+	one := decimal.NewFromInt(1)
+	tests := []struct {
+		raw    string
+		filled decimal.Decimal
+		want   OrderStatus
+	}{
+		{"open", decimal.Zero, Open},
+		{"open", one, Filled},
+		{"closed", one, Closed},
+		{"cancelled", decimal.Zero, Cancelled},
+		{"cancelled", one, PartialCancelled},
+		{"unknown", one, 0},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, table.Normalize(tt.raw, tt.filled), "%s %s", tt.raw, tt.filled)
+	}
+}
+
+func TestParseSide(t *testing.T) {
+	require.Equal(t, Buy, ParseSide("buy"))
+	require.Equal(t, Buy, ParseSide("BUY"))
+	require.Equal(t, Sell, ParseSide("sell-stop-limit"))
+	require.Equal(t, 0, ParseSide(""))
+}
+
+func TestOrder_Finished(t *testing.T) {
+	require.True(t, Order{State: Closed}.FullFilled())
+	require.False(t, Order{State: Filled}.FullFilled())
+	require.False(t, Order{State: Filled}.Finished())
+	require.True(t, Order{State: PartialCancelled}.Finished())
+	require.False(t, Order{}.Finished())
+}
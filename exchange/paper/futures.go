@@ -0,0 +1,547 @@
+package paper
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"sync"
+	"time"
+)
+
+const (
+	FuturesOrderStatusOpen     = "open"
+	FuturesOrderStatusFinished = "finished"
+
+	FinishAsFilled     = "filled"
+	FinishAsCancelled  = "cancelled"
+	FinishAsIOC        = "ioc"
+	FinishAsReduceOnly = "reduce_only"
+
+	TifGTC = "gtc"
+	TifIOC = "ioc"
+	TifPOC = "poc"
+)
+
+var (
+	ErrUnknownContract    = errors.New("unknown contract")
+	ErrInsufficientMargin = errors.New("insufficient margin")
+	ErrNotSupported       = errors.New("not supported by paper exchange")
+	ErrBadSize            = errors.New("bad order size")
+	ErrMarketNotIOC       = errors.New("market order (price 0) needs tif ioc")
+)
+
+type futuresPosition struct {
+	size     int64
+	entry    decimal.Decimal
+	leverage int
+	margin   decimal.Decimal // margin added by AdjustMargin
+	realised decimal.Decimal
+}
+
+// Futures is an in-memory linear perpetual exchange, implements exchange.FuturesExchange.
+// Only single position mode is supported, and there is no liquidation or funding.
+type Futures struct {
+	mu sync.Mutex
+
+	currency   string
+	total      decimal.Decimal // deposit + realised pnl - fees
+	contracts  map[string]exchange.Contract
+	positions  map[string]*futuresPosition
+	prices     map[string]decimal.Decimal // mark price
+	orderbooks map[string]exchange.FuturesOrderbook
+	trades     map[string][]exchange.FuturesTrade
+	history    map[string]*hs.Candle
+	clock      time.Time
+
+	nextId uint64
+	orders map[uint64]*exchange.FuturesOrder
+	opens  []uint64
+}
+
+var _ exchange.FuturesExchange = (*Futures)(nil)
+
+// NewFutures creates a paper futures exchange settled in currency,
+// the Contract.QuoteMultiplier is the size of one contract, 1 if empty.
+func NewFutures(contracts []exchange.Contract, currency string) *Futures {
+	f := &Futures{
+		currency:   currency,
+		contracts:  make(map[string]exchange.Contract),
+		positions:  make(map[string]*futuresPosition),
+		prices:     make(map[string]decimal.Decimal),
+		orderbooks: make(map[string]exchange.FuturesOrderbook),
+		trades:     make(map[string][]exchange.FuturesTrade),
+		history:    make(map[string]*hs.Candle),
+		orders:     make(map[uint64]*exchange.FuturesOrder),
+	}
+	for _, c := range contracts {
+		f.contracts[c.Name] = c
+	}
+	return f
+}
+
+// Deposit adds amount to the account
+func (f *Futures) Deposit(amount decimal.Decimal) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.total = f.total.Add(amount)
+}
+
+// UpdateTicker feeds a bar of contract, close is the mark price,
+// and open orders are matched against its high and low.
+func (f *Futures) UpdateTicker(contract string, ticker hs.Ticker) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.history[contract]
+	if !ok {
+		candle := hs.NewCandle(historyCapacity)
+		c = &candle
+		f.history[contract] = c
+	}
+	c.Append(ticker)
+	f.clock = time.Unix(ticker.Timestamp, 0)
+	f.prices[contract] = decimal.NewFromFloat(ticker.Close)
+	f.match(contract, decimal.NewFromFloat(ticker.Low), decimal.NewFromFloat(ticker.High))
+}
+
+// UpdateTrades feeds public trades in time order, the price of last one is the mark price
+func (f *Futures) UpdateTrades(contract string, trades []exchange.FuturesTrade) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range trades {
+		f.clock = t.CreateTime
+		f.prices[contract] = t.Price
+		f.match(contract, t.Price, t.Price)
+	}
+	f.trades[contract] = append(f.trades[contract], trades...)
+	if n := len(f.trades[contract]); n > historyCapacity {
+		f.trades[contract] = f.trades[contract][n-historyCapacity:]
+	}
+}
+
+// UpdateOrderbook sets the orderbook returned by FuturesDepth, it's not used in matching
+func (f *Futures) UpdateOrderbook(contract string, orderbook exchange.FuturesOrderbook) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.orderbooks[contract] = orderbook
+}
+
+func (f *Futures) Contracts(ctx context.Context) ([]exchange.Contract, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var contracts []exchange.Contract
+	for _, c := range f.contracts {
+		contracts = append(contracts, c)
+	}
+	return contracts, nil
+}
+
+func (f *Futures) Contract(ctx context.Context, contract string) (exchange.Contract, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.contracts[contract]
+	if !ok {
+		return c, ErrUnknownContract
+	}
+	return c, nil
+}
+
+func (f *Futures) FuturesDepth(ctx context.Context, contract string, limit int) (exchange.FuturesOrderbook, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ob := f.orderbooks[contract]
+	if limit > 0 && len(ob.Asks) > limit {
+		ob.Asks = ob.Asks[:limit]
+	}
+	if limit > 0 && len(ob.Bids) > limit {
+		ob.Bids = ob.Bids[:limit]
+	}
+	return ob, nil
+}
+
+func (f *Futures) FuturesTrades(ctx context.Context, contract string, limit int) ([]exchange.FuturesTrade, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	trades := f.trades[contract]
+	if limit > 0 && len(trades) > limit {
+		trades = trades[len(trades)-limit:]
+	}
+	return append([]exchange.FuturesTrade(nil), trades...), nil
+}
+
+// FuturesCandles returns the latest size bars of the fed history, period is ignored.
+func (f *Futures) FuturesCandles(ctx context.Context, contract string, period time.Duration, size int) (hs.Candle, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	candle := hs.NewCandle(size)
+	if c, ok := f.history[contract]; ok {
+		candle.Add(*c)
+	}
+	return candle, nil
+}
+
+func (f *Futures) FuturesAccount(ctx context.Context) (exchange.FuturesBalance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.account(), nil
+}
+
+func (f *Futures) Positions(ctx context.Context) ([]exchange.Position, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var positions []exchange.Position
+	for name, p := range f.positions {
+		if p.size != 0 {
+			positions = append(positions, f.position(name))
+		}
+	}
+	return positions, nil
+}
+
+func (f *Futures) Position(ctx context.Context, contract string) (exchange.Position, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.contracts[contract]; !ok {
+		return exchange.Position{}, ErrUnknownContract
+	}
+	return f.position(contract), nil
+}
+
+// SetLeverage sets the leverage of contract, cross margin (0) is treated as 1.
+func (f *Futures) SetLeverage(ctx context.Context, contract string, leverage int) (exchange.Position, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.contracts[contract]; !ok {
+		return exchange.Position{}, ErrUnknownContract
+	}
+	p := f.pos(contract)
+	old := p.leverage
+	p.leverage = leverage
+	if f.available().IsNegative() {
+		p.leverage = old
+		return f.position(contract), ErrInsufficientMargin
+	}
+	return f.position(contract), nil
+}
+
+func (f *Futures) AdjustMargin(ctx context.Context, contract string, change decimal.Decimal) (exchange.Position, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.contracts[contract]; !ok {
+		return exchange.Position{}, ErrUnknownContract
+	}
+	p := f.pos(contract)
+	if p.size == 0 || p.margin.Add(change).IsNegative() || change.GreaterThan(f.available()) {
+		return f.position(contract), ErrInsufficientMargin
+	}
+	p.margin = p.margin.Add(change)
+	return f.position(contract), nil
+}
+
+// SwitchDualMode only supports single mode
+func (f *Futures) SwitchDualMode(ctx context.Context, dual bool) (exchange.FuturesBalance, error) {
+	if dual {
+		return exchange.FuturesBalance{}, ErrNotSupported
+	}
+	return f.FuturesAccount(ctx)
+}
+
+// PlaceFuturesOrder places order, iceberg is ignored.
+func (f *Futures) PlaceFuturesOrder(ctx context.Context, order exchange.FuturesOrder) (exchange.FuturesOrder, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.contracts[order.Contract]; !ok {
+		return exchange.FuturesOrder{}, ErrUnknownContract
+	}
+	mark, hasPrice := f.prices[order.Contract]
+	p := f.pos(order.Contract)
+	if order.Close {
+		order.Size = -p.size
+		order.ReduceOnly = true
+	}
+	if order.Size == 0 {
+		return exchange.FuturesOrder{}, ErrBadSize
+	}
+	order.Left = order.Size
+	if order.Tif == "" {
+		order.Tif = TifGTC
+	}
+	market := order.Price.IsZero()
+	if market && order.Tif != TifIOC {
+		return exchange.FuturesOrder{}, ErrMarketNotIOC
+	}
+	if market && !hasPrice {
+		return exchange.FuturesOrder{}, ErrNoPrice
+	}
+	if order.ReduceOnly && (p.size == 0 || sign(p.size) == sign(order.Size)) {
+		return exchange.FuturesOrder{}, ErrBadSize
+	}
+	if !order.ReduceOnly && f.orderMargin(order).GreaterThan(f.available()) {
+		return exchange.FuturesOrder{}, ErrInsufficientMargin
+	}
+
+	f.nextId++
+	o := order
+	o.Id = f.nextId
+	o.CreateTime = f.now()
+	o.Status = FuturesOrderStatusOpen
+	o.IsReduceOnly = o.ReduceOnly
+	o.IsClose = o.Close
+	c := f.contracts[o.Contract]
+	o.TakerFee = convertRate(c.TakerFeeRate)
+	o.MakerFee = convertRate(c.MakerFeeRate)
+	f.orders[o.Id] = &o
+	f.opens = append(f.opens, o.Id)
+
+	crossed := hasPrice && (market || o.Size > 0 && mark.LessThanOrEqual(o.Price) || o.Size < 0 && mark.GreaterThanOrEqual(o.Price))
+	switch {
+	case crossed && o.Tif == TifPOC:
+		f.finish(&o, FinishAsCancelled)
+	case crossed:
+		f.fill(&o, mark, true)
+	case o.Tif == TifIOC:
+		f.finish(&o, FinishAsIOC)
+	}
+	return o, nil
+}
+
+func (f *Futures) CancelFuturesOrder(ctx context.Context, contract string, orderId uint64) (exchange.FuturesOrder, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.orders[orderId]
+	if !ok {
+		return exchange.FuturesOrder{}, ErrOrderNotFound
+	}
+	if o.Status != FuturesOrderStatusOpen {
+		return *o, ErrOrderFinished
+	}
+	f.finish(o, FinishAsCancelled)
+	return *o, nil
+}
+
+func (f *Futures) CancelFuturesOrders(ctx context.Context, contract string) ([]exchange.FuturesOrder, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var orders []exchange.FuturesOrder
+	for _, id := range append([]uint64(nil), f.opens...) {
+		if o := f.orders[id]; o.Contract == contract {
+			f.finish(o, FinishAsCancelled)
+			orders = append(orders, *o)
+		}
+	}
+	return orders, nil
+}
+
+func (f *Futures) GetFuturesOrder(ctx context.Context, contract string, orderId uint64) (exchange.FuturesOrder, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.orders[orderId]
+	if !ok {
+		return exchange.FuturesOrder{}, ErrOrderNotFound
+	}
+	return *o, nil
+}
+
+func (f *Futures) OpenFuturesOrders(ctx context.Context, contract string) ([]exchange.FuturesOrder, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var orders []exchange.FuturesOrder
+	for _, id := range f.opens {
+		if o := f.orders[id]; o.Contract == contract {
+			orders = append(orders, *o)
+		}
+	}
+	return orders, nil
+}
+
+func (f *Futures) now() time.Time {
+	if f.clock.IsZero() {
+		return time.Now()
+	}
+	return f.clock
+}
+
+func (f *Futures) pos(contract string) *futuresPosition {
+	p, ok := f.positions[contract]
+	if !ok {
+		p = &futuresPosition{leverage: 1}
+		f.positions[contract] = p
+	}
+	return p
+}
+
+func (f *Futures) multiplier(contract string) decimal.Decimal {
+	m, err := decimal.NewFromString(f.contracts[contract].QuoteMultiplier)
+	if err != nil || !m.IsPositive() {
+		return decimal.NewFromInt(1)
+	}
+	return m
+}
+
+func leverage(p *futuresPosition) decimal.Decimal {
+	if p.leverage <= 0 {
+		return decimal.NewFromInt(1)
+	}
+	return decimal.NewFromInt(int64(p.leverage))
+}
+
+func sign(n int64) int64 {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+func abs(n int64) int64 {
+	return n * sign(n)
+}
+
+func convertRate(rate string) float64 {
+	r, _ := decimal.NewFromString(rate)
+	f, _ := r.Float64()
+	return f
+}
+
+// notional is the value of size contracts at price
+func (f *Futures) notional(contract string, size int64, price decimal.Decimal) decimal.Decimal {
+	return decimal.NewFromInt(abs(size)).Mul(f.multiplier(contract)).Mul(price)
+}
+
+func (f *Futures) orderMargin(o exchange.FuturesOrder) decimal.Decimal {
+	if o.IsReduceOnly || o.ReduceOnly {
+		return decimal.Zero
+	}
+	price := o.Price
+	if price.IsZero() {
+		price = f.prices[o.Contract]
+	}
+	return f.notional(o.Contract, o.Left, price).Div(leverage(f.pos(o.Contract)))
+}
+
+func (f *Futures) positionMargin(contract string) decimal.Decimal {
+	p := f.pos(contract)
+	return f.notional(contract, p.size, p.entry).Div(leverage(p)).Add(p.margin)
+}
+
+func (f *Futures) unrealised(contract string) decimal.Decimal {
+	p := f.pos(contract)
+	mark, ok := f.prices[contract]
+	if !ok || p.size == 0 {
+		return decimal.Zero
+	}
+	return decimal.NewFromInt(p.size).Mul(f.multiplier(contract)).Mul(mark.Sub(p.entry))
+}
+
+func (f *Futures) account() exchange.FuturesBalance {
+	b := exchange.FuturesBalance{Total: f.total, Currency: f.currency}
+	for name := range f.positions {
+		b.PositionMargin = b.PositionMargin.Add(f.positionMargin(name))
+		b.UnrealisedPnl = b.UnrealisedPnl.Add(f.unrealised(name))
+	}
+	for _, id := range f.opens {
+		b.OrderMargin = b.OrderMargin.Add(f.orderMargin(*f.orders[id]))
+	}
+	b.Available = b.Total.Add(b.UnrealisedPnl).Sub(b.PositionMargin).Sub(b.OrderMargin)
+	return b
+}
+
+func (f *Futures) available() decimal.Decimal {
+	return f.account().Available
+}
+
+func (f *Futures) position(contract string) exchange.Position {
+	p := f.pos(contract)
+	return exchange.Position{
+		Contract:      contract,
+		Size:          p.size,
+		Leverage:      p.leverage,
+		Value:         f.notional(contract, p.size, f.prices[contract]),
+		Margin:        f.positionMargin(contract),
+		EntryPrice:    p.entry,
+		MarkPrice:     f.prices[contract],
+		UnrealisedPnl: f.unrealised(contract),
+		RealisedPnl:   p.realised,
+		Mode:          "single",
+	}
+}
+
+// match open orders of contract with price range [low, high]
+func (f *Futures) match(contract string, low, high decimal.Decimal) {
+	for _, id := range append([]uint64(nil), f.opens...) {
+		o := f.orders[id]
+		if o.Contract != contract {
+			continue
+		}
+		if o.Size > 0 && low.LessThanOrEqual(o.Price) || o.Size < 0 && high.GreaterThanOrEqual(o.Price) {
+			f.fill(o, o.Price, false)
+		}
+	}
+}
+
+// fill the left size of order at price, reduce-only order is clipped to the position size
+func (f *Futures) fill(o *exchange.FuturesOrder, price decimal.Decimal, taker bool) {
+	p := f.pos(o.Contract)
+	size := o.Left
+	if o.ReduceOnly {
+		if p.size == 0 || sign(p.size) == sign(size) {
+			f.finish(o, FinishAsReduceOnly)
+			return
+		}
+		if abs(size) > abs(p.size) {
+			size = -p.size
+		}
+	}
+	rate := decimal.NewFromFloat(o.MakerFee)
+	if taker {
+		rate = decimal.NewFromFloat(o.TakerFee)
+	}
+	fee := f.notional(o.Contract, size, price).Mul(rate)
+	f.total = f.total.Sub(fee)
+
+	mult := f.multiplier(o.Contract)
+	if p.size == 0 || sign(p.size) == sign(size) {
+		// open or add
+		oldValue := decimal.NewFromInt(abs(p.size)).Mul(p.entry)
+		newValue := decimal.NewFromInt(abs(size)).Mul(price)
+		p.entry = oldValue.Add(newValue).Div(decimal.NewFromInt(abs(p.size) + abs(size)))
+		p.size += size
+	} else {
+		closed := abs(size)
+		if closed > abs(p.size) {
+			closed = abs(p.size)
+		}
+		pnl := decimal.NewFromInt(closed * sign(p.size)).Mul(mult).Mul(price.Sub(p.entry))
+		p.realised = p.realised.Add(pnl)
+		f.total = f.total.Add(pnl)
+		p.size += size
+		switch {
+		case p.size == 0:
+			p.entry = decimal.Zero
+			p.margin = decimal.Zero
+		case sign(p.size) == sign(size):
+			// reversed
+			p.entry = price
+		}
+	}
+	p.realised = p.realised.Sub(fee)
+
+	o.FillPrice = price
+	o.Left -= size
+	f.finish(o, FinishAsFilled)
+}
+
+func (f *Futures) finish(o *exchange.FuturesOrder, finishAs string) {
+	o.Status = FuturesOrderStatusFinished
+	o.FinishAs = finishAs
+	o.FinishTime = f.now()
+	for i, id := range f.opens {
+		if id == o.Id {
+			f.opens = append(f.opens[:i], f.opens[i+1:]...)
+			break
+		}
+	}
+}
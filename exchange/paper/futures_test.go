@@ -0,0 +1,102 @@
+package paper
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"testing"
+)
+
+func newFutures(t *testing.T) *Futures {
+	f := NewFutures([]exchange.Contract{
+		{Name: "BTC_USDT", QuoteMultiplier: "0.01", MakerFeeRate: "-0.00025", TakerFeeRate: "0.00075"},
+	}, "usdt")
+	f.Deposit(decimal.NewFromInt(1000))
+	f.UpdateTicker("BTC_USDT", hs.Ticker{Timestamp: 60, Open: 10000, High: 10000, Low: 10000, Close: 10000})
+	return f
+}
+
+func TestFutures_MarketOrder(t *testing.T) {
+	ctx := context.Background()
+	f := newFutures(t)
+	_, err := f.SetLeverage(ctx, "BTC_USDT", 10)
+	require.NoError(t, err)
+
+	o, err := f.PlaceFuturesOrder(ctx, exchange.FuturesOrder{Contract: "BTC_USDT", Size: 5, Tif: TifIOC})
+	require.NoError(t, err)
+	require.Equal(t, FuturesOrderStatusFinished, o.Status)
+	require.Equal(t, FinishAsFilled, o.FinishAs)
+	require.Equal(t, int64(0), o.Left)
+
+	p, err := f.Position(ctx, "BTC_USDT")
+	require.NoError(t, err)
+	require.Equal(t, int64(5), p.Size)
+	require.True(t, decimal.NewFromInt(10000).Equal(p.EntryPrice))
+	// margin of 500 usdt at leverage 10
+	require.True(t, decimal.NewFromInt(50).Equal(p.Margin), p.Margin.String())
+
+	f.UpdateTicker("BTC_USDT", hs.Ticker{Timestamp: 120, Open: 10000, High: 11000, Low: 10000, Close: 11000})
+	b, err := f.FuturesAccount(ctx)
+	require.NoError(t, err)
+	require.True(t, decimal.NewFromInt(50).Equal(b.UnrealisedPnl))
+
+	// close position
+	o, err = f.PlaceFuturesOrder(ctx, exchange.FuturesOrder{Contract: "BTC_USDT", Close: true, Tif: TifIOC})
+	require.NoError(t, err)
+	require.Equal(t, int64(-5), o.Size)
+	p, _ = f.Position(ctx, "BTC_USDT")
+	require.Equal(t, int64(0), p.Size)
+	b, _ = f.FuturesAccount(ctx)
+	// 1000 + 50 - fee 0.375 - fee 0.4125
+	require.True(t, decimal.RequireFromString("1049.2125").Equal(b.Total), b.Total.String())
+	require.True(t, b.Total.Equal(b.Available))
+}
+
+func TestFutures_LimitOrder(t *testing.T) {
+	ctx := context.Background()
+	f := newFutures(t)
+
+	o, err := f.PlaceFuturesOrder(ctx, exchange.FuturesOrder{Contract: "BTC_USDT", Size: -2, Price: decimal.NewFromInt(10500)})
+	require.NoError(t, err)
+	require.Equal(t, FuturesOrderStatusOpen, o.Status)
+	opens, _ := f.OpenFuturesOrders(ctx, "BTC_USDT")
+	require.Len(t, opens, 1)
+	b, _ := f.FuturesAccount(ctx)
+	require.True(t, decimal.NewFromInt(210).Equal(b.OrderMargin), b.OrderMargin.String())
+
+	f.UpdateTicker("BTC_USDT", hs.Ticker{Timestamp: 120, Open: 10000, High: 10600, Low: 10000, Close: 10400})
+	o, err = f.GetFuturesOrder(ctx, "BTC_USDT", o.Id)
+	require.NoError(t, err)
+	require.Equal(t, FinishAsFilled, o.FinishAs)
+	require.True(t, decimal.NewFromInt(10500).Equal(o.FillPrice))
+	p, _ := f.Position(ctx, "BTC_USDT")
+	require.Equal(t, int64(-2), p.Size)
+	// maker rebate
+	require.True(t, decimal.RequireFromString("0.0525").Equal(p.RealisedPnl), p.RealisedPnl.String())
+
+	// reduce only can not increase position
+	_, err = f.PlaceFuturesOrder(ctx, exchange.FuturesOrder{Contract: "BTC_USDT", Size: -1, Price: decimal.NewFromInt(11000), ReduceOnly: true})
+	require.Equal(t, ErrBadSize, err)
+
+	// poc crossing is cancelled
+	o, err = f.PlaceFuturesOrder(ctx, exchange.FuturesOrder{Contract: "BTC_USDT", Size: 1, Price: decimal.NewFromInt(10500), Tif: TifPOC})
+	require.NoError(t, err)
+	require.Equal(t, FinishAsCancelled, o.FinishAs)
+
+	o, err = f.PlaceFuturesOrder(ctx, exchange.FuturesOrder{Contract: "BTC_USDT", Size: 1, Price: decimal.NewFromInt(9000)})
+	require.NoError(t, err)
+	cancelled, err := f.CancelFuturesOrders(ctx, "BTC_USDT")
+	require.NoError(t, err)
+	require.Len(t, cancelled, 1)
+	_, err = f.CancelFuturesOrder(ctx, "BTC_USDT", o.Id)
+	require.Equal(t, ErrOrderFinished, err)
+
+	_, err = f.PlaceFuturesOrder(ctx, exchange.FuturesOrder{Contract: "BTC_USDT", Size: 100, Price: decimal.NewFromInt(10000)})
+	require.Equal(t, ErrInsufficientMargin, err)
+	_, err = f.PlaceFuturesOrder(ctx, exchange.FuturesOrder{Contract: "BTC_USDT", Size: 1})
+	require.Equal(t, ErrMarketNotIOC, err)
+	_, err = f.SwitchDualMode(ctx, true)
+	require.Equal(t, ErrNotSupported, err)
+}
@@ -0,0 +1,767 @@
+// Package paper is an in-memory exchange for paper trading and unit tests.
+// Orders are matched against the price feed given by UpdateTicker, UpdateCandle or UpdateTrades,
+// no network is needed.
+package paper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	Name = "paper"
+
+	OrderStatusOpen      = "open"
+	OrderStatusClosed    = "closed" // full filled
+	OrderStatusCancelled = "cancelled"
+
+	OrderTypeBuyLimit      = "buy-limit"
+	OrderTypeSellLimit     = "sell-limit"
+	OrderTypeBuyMarket     = "buy-market"
+	OrderTypeSellMarket    = "sell-market"
+	OrderTypeBuyStopLimit  = "buy-stop-limit"
+	OrderTypeSellStopLimit = "sell-stop-limit"
+
+	// historyCapacity is the max length of candle kept for each symbol
+	historyCapacity = 10000
+)
+
+var orderStatus = exchange.StatusTable{
+	OrderStatusOpen:      exchange.Open,
+	OrderStatusClosed:    exchange.Closed,
+	OrderStatusCancelled: exchange.Cancelled,
+}
+
+var (
+	ErrUnknownSymbol       = errors.New("unknown symbol")
+	ErrNoPrice             = errors.New("no price for symbol")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrOrderFinished       = errors.New("order is finished")
+	ErrBadAmount           = errors.New("amount less than minimum")
+	ErrPostOnly            = errors.New("post-only order would take")
+)
+
+// orderOptions lists the order options, iceberg is accepted and has no effect, for paper has no order book
+var orderOptions = exchange.OptionSupport{
+	Name:        Name,
+	TimeInForce: []exchange.TimeInForce{exchange.GTC, exchange.IOC, exchange.FOK, exchange.PostOnly},
+	Iceberg:     true,
+}
+
+type balance struct {
+	Available decimal.Decimal
+	Locked    decimal.Decimal
+}
+
+// order is the internal state of an order
+type order struct {
+	exchange.Order
+	side      exchange.OrderType
+	market    bool
+	stopPrice decimal.Decimal // zero if not stop order
+	triggered bool
+	total     decimal.Decimal // quote amount for market buy
+	locked    decimal.Decimal // locked amount left, quote for buy, base for sell
+	turnover  decimal.Decimal // filled quote amount, used for average price
+	tif       exchange.TimeInForce
+}
+
+func (o *order) open() bool {
+	return o.Status == OrderStatusOpen
+}
+
+type subscription struct {
+	symbol  string
+	handler exchange.ResponseHandler
+}
+
+// Exchange implements exchange.RestAPIExchange and exchange.WsAPIExchange in process.
+type Exchange struct {
+	mu sync.Mutex
+
+	symbols  map[string]exchange.Symbol
+	fees     map[string]exchange.Fee
+	fee      exchange.Fee // default fee
+	balances map[string]*balance
+	prices   map[string]decimal.Decimal
+	history  map[string]*hs.Candle
+	clock    time.Time // time of last price update
+
+	nextId uint64
+	orders map[uint64]*order
+	opens  []uint64 // open orders in placing sequence
+
+	orderSubs  map[string]subscription // clientId -> subscription
+	candleSubs map[string]subscription
+}
+
+var (
+	_ exchange.RestAPIExchange = (*Exchange)(nil)
+	_ exchange.WsAPIExchange   = (*Exchange)(nil)
+)
+
+// New creates a paper exchange trading the symbols, fee is the default fee of all symbols.
+func New(symbols []exchange.Symbol, fee exchange.Fee) *Exchange {
+	e := &Exchange{
+		symbols:    make(map[string]exchange.Symbol),
+		fees:       make(map[string]exchange.Fee),
+		fee:        fee,
+		balances:   make(map[string]*balance),
+		prices:     make(map[string]decimal.Decimal),
+		history:    make(map[string]*hs.Candle),
+		orders:     make(map[uint64]*order),
+		orderSubs:  make(map[string]subscription),
+		candleSubs: make(map[string]subscription),
+	}
+	for _, s := range symbols {
+		e.symbols[s.Symbol] = s
+	}
+	return e
+}
+
+// init registers the paper exchange, the symbols in config are like btc_usdt,
+// with 8 decimal places and zero fee.
+func init() {
+	exchange.Register(Name, func(conf hs.ExchangeConf, logger *zap.SugaredLogger) (exchange.Exchange, error) {
+		var symbols []exchange.Symbol
+		for _, s := range conf.Symbols {
+			parts := strings.Split(s, "_")
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("bad paper symbol %q, should be like btc_usdt", s)
+			}
+			symbols = append(symbols, exchange.Symbol{
+				Symbol:          s,
+				BaseCurrency:    parts[0],
+				QuoteCurrency:   parts[1],
+				PricePrecision:  8,
+				AmountPrecision: 8,
+			})
+		}
+		return New(symbols, exchange.Fee{}), nil
+	})
+}
+
+func (e *Exchange) Name() string {
+	return Name
+}
+
+// Deposit add amount to available balance of currency
+func (e *Exchange) Deposit(currency string, amount decimal.Decimal) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.balance(currency).Available = e.balance(currency).Available.Add(amount)
+}
+
+// SetFee set the fee of symbol, overwrite the default fee
+func (e *Exchange) SetFee(symbol string, fee exchange.Fee) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fee.Symbol = symbol
+	e.fees[symbol] = fee
+}
+
+// Balances returns all balances, include zero balances
+func (e *Exchange) Balances() []exchange.Balance {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var result []exchange.Balance
+	for c, b := range e.balances {
+		result = append(result, exchange.Balance{Currency: c, Available: b.Available, Locked: b.Locked})
+	}
+	return result
+}
+
+// OpenOrders returns all open orders of symbol, in placing sequence
+func (e *Exchange) OpenOrders(symbol string) []exchange.Order {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var result []exchange.Order
+	for _, id := range e.opens {
+		if o := e.orders[id]; o.Symbol == symbol {
+			result = append(result, e.snapshot(o))
+		}
+	}
+	return result
+}
+
+func (e *Exchange) FormatSymbol(base, quote string) string {
+	return fmt.Sprintf("%s_%s", strings.ToLower(base), strings.ToLower(quote))
+}
+
+func (e *Exchange) AllSymbols(ctx context.Context) (symbols []exchange.Symbol, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range e.symbols {
+		symbols = append(symbols, s)
+	}
+	return
+}
+
+func (e *Exchange) GetSymbol(ctx context.Context, symbol string) (exchange.Symbol, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s, ok := e.symbols[symbol]
+	if !ok {
+		return s, ErrUnknownSymbol
+	}
+	return s, nil
+}
+
+func (e *Exchange) GetFee(symbol string) (exchange.Fee, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.feeOf(symbol), nil
+}
+
+func (e *Exchange) SpotBalance() (map[string]decimal.Decimal, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	result := make(map[string]decimal.Decimal)
+	for c, b := range e.balances {
+		if total := b.Available.Add(b.Locked); !total.IsZero() {
+			result[c] = total
+		}
+	}
+	return result, nil
+}
+
+func (e *Exchange) SpotAvailableBalance() (map[string]decimal.Decimal, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	result := make(map[string]decimal.Decimal)
+	for c, b := range e.balances {
+		if !b.Available.IsZero() {
+			result[c] = b.Available
+		}
+	}
+	return result, nil
+}
+
+func (e *Exchange) LastPrice(symbol string) (decimal.Decimal, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	p, ok := e.prices[symbol]
+	if !ok {
+		return decimal.Zero, ErrNoPrice
+	}
+	return p, nil
+}
+
+// Last24hVolume sum the volume of candle history in the last 24 hours
+func (e *Exchange) Last24hVolume(symbol string) (decimal.Decimal, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, ok := e.history[symbol]
+	if !ok || c.Length() == 0 {
+		return decimal.Zero, nil
+	}
+	since := c.Timestamp[c.Length()-1] - int64(exchange.DAY1/time.Second)
+	volume := 0.0
+	for i := c.Length() - 1; i >= 0 && c.Timestamp[i] > since; i-- {
+		volume += c.Volume[i]
+	}
+	return decimal.NewFromFloat(volume), nil
+}
+
+// CandleBySize returns the latest size bars of the fed history, period is ignored.
+func (e *Exchange) CandleBySize(symbol string, period time.Duration, size int) (hs.Candle, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	candle := hs.NewCandle(size)
+	if c, ok := e.history[symbol]; ok {
+		candle.Add(*c)
+	}
+	return candle, nil
+}
+
+// CandleFrom returns the fed history between from and to, period is ignored.
+func (e *Exchange) CandleFrom(symbol, clientId string, period time.Duration, from, to time.Time) (hs.Candle, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, ok := e.history[symbol]
+	if !ok {
+		return hs.NewCandle(0), nil
+	}
+	candle := hs.NewCandle(c.Length())
+	for i := 0; i < c.Length(); i++ {
+		if c.Timestamp[i] < from.Unix() || c.Timestamp[i] > to.Unix() {
+			continue
+		}
+		candle.Append(hs.Ticker{
+			Timestamp: c.Timestamp[i],
+			Open:      c.Open[i],
+			High:      c.High[i],
+			Low:       c.Low[i],
+			Close:     c.Close[i],
+			Volume:    c.Volume[i],
+		})
+	}
+	return candle, nil
+}
+
+// PlaceOrder places limit order, the taking order is filled all at last price,
+// so IOC and FOK are same, which is cancelled if can't be filled at once.
+func (e *Exchange) PlaceOrder(symbol, clientOrderId string, side exchange.OrderType, price, amount decimal.Decimal, options exchange.OrderOptions) (uint64, error) {
+	if err := orderOptions.Check(options, amount); err != nil {
+		return 0, err
+	}
+	o := &order{
+		Order: exchange.Order{ClientOrderId: clientOrderId, Type: OrderTypeBuyLimit, Symbol: symbol, Price: price, Amount: amount},
+		side:  side,
+		tif:   options.TIF(),
+	}
+	switch side {
+	case exchange.Buy:
+	case exchange.Sell:
+		o.Type = OrderTypeSellLimit
+	default:
+		return 0, exchange.ErrBadSide
+	}
+	return e.place(o)
+}
+
+func (e *Exchange) BuyLimit(symbol, clientOrderId string, price, amount decimal.Decimal) (uint64, error) {
+	return e.place(&order{
+		Order: exchange.Order{ClientOrderId: clientOrderId, Type: OrderTypeBuyLimit, Symbol: symbol, Price: price, Amount: amount},
+		side:  exchange.Buy,
+	})
+}
+
+func (e *Exchange) SellLimit(symbol, clientOrderId string, price, amount decimal.Decimal) (uint64, error) {
+	return e.place(&order{
+		Order: exchange.Order{ClientOrderId: clientOrderId, Type: OrderTypeSellLimit, Symbol: symbol, Price: price, Amount: amount},
+		side:  exchange.Sell,
+	})
+}
+
+// BuyMarket spend total quote currency at last price
+func (e *Exchange) BuyMarket(symbol exchange.Symbol, clientOrderId string, total decimal.Decimal) (uint64, error) {
+	return e.place(&order{
+		Order:  exchange.Order{ClientOrderId: clientOrderId, Type: OrderTypeBuyMarket, Symbol: symbol.Symbol},
+		side:   exchange.Buy,
+		market: true,
+		total:  total,
+	})
+}
+
+// SellMarket sell amount base currency at last price
+func (e *Exchange) SellMarket(symbol exchange.Symbol, clientOrderId string, amount decimal.Decimal) (uint64, error) {
+	return e.place(&order{
+		Order:  exchange.Order{ClientOrderId: clientOrderId, Type: OrderTypeSellMarket, Symbol: symbol.Symbol, Amount: amount},
+		side:   exchange.Sell,
+		market: true,
+	})
+}
+
+// BuyStopLimit place a limit order when price >= stopPrice
+func (e *Exchange) BuyStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (uint64, error) {
+	return e.place(&order{
+		Order:     exchange.Order{ClientOrderId: clientOrderId, Type: OrderTypeBuyStopLimit, Symbol: symbol, Price: price, Amount: amount},
+		side:      exchange.Buy,
+		stopPrice: stopPrice,
+	})
+}
+
+// SellStopLimit place a limit order when price <= stopPrice
+func (e *Exchange) SellStopLimit(symbol, clientOrderId string, price, amount, stopPrice decimal.Decimal) (uint64, error) {
+	return e.place(&order{
+		Order:     exchange.Order{ClientOrderId: clientOrderId, Type: OrderTypeSellStopLimit, Symbol: symbol, Price: price, Amount: amount},
+		side:      exchange.Sell,
+		stopPrice: stopPrice,
+	})
+}
+
+func (e *Exchange) GetOrderById(orderId uint64, symbol string) (exchange.Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	o, ok := e.orders[orderId]
+	if !ok {
+		return exchange.Order{}, ErrOrderNotFound
+	}
+	return e.snapshot(o), nil
+}
+
+func (e *Exchange) CancelOrder(symbol string, orderId uint64) error {
+	e.mu.Lock()
+	o, ok := e.orders[orderId]
+	if !ok {
+		e.mu.Unlock()
+		return ErrOrderNotFound
+	}
+	if !o.open() {
+		e.mu.Unlock()
+		return ErrOrderFinished
+	}
+	e.finish(o, OrderStatusCancelled)
+	events := []exchange.Order{e.snapshot(o)}
+	e.mu.Unlock()
+
+	e.notifyOrders(events)
+	return nil
+}
+
+func (e *Exchange) IsFullFilled(symbol string, orderId uint64) (exchange.Order, bool, error) {
+	o, err := e.GetOrderById(orderId, symbol)
+	if err != nil {
+		return o, false, err
+	}
+	return o, o.FullFilled(), nil
+}
+
+// SubscribeOrder calls responseHandler with an exchange.Order on every order update of symbol
+func (e *Exchange) SubscribeOrder(symbol, clientId string, responseHandler exchange.ResponseHandler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.orderSubs[clientId] = subscription{symbol: symbol, handler: responseHandler}
+}
+
+func (e *Exchange) UnsubscribeOrder(symbol, clientId string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.orderSubs, clientId)
+}
+
+// SubscribeCandlestick calls responseHandler with a hs.Ticker on every price update of symbol
+func (e *Exchange) SubscribeCandlestick(symbol, clientId string, period time.Duration, responseHandler exchange.ResponseHandler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.candleSubs[clientId] = subscription{symbol: symbol, handler: responseHandler}
+}
+
+func (e *Exchange) UnsubscribeCandlestick(symbol, clientId string, period time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.candleSubs, clientId)
+}
+
+// SubscribeCandlestickWithReq calls responseHandler with the history in a hs.Candle first,
+// then with a hs.Ticker on every price update.
+func (e *Exchange) SubscribeCandlestickWithReq(symbol, clientId string, period time.Duration, responseHandler exchange.ResponseHandler) {
+	candle, _ := e.CandleBySize(symbol, period, historyCapacity)
+	responseHandler(candle)
+	e.SubscribeCandlestick(symbol, clientId, period, responseHandler)
+}
+
+func (e *Exchange) UnsubscribeCandlestickWithReq(symbol, clientId string, period time.Duration) {
+	e.UnsubscribeCandlestick(symbol, clientId, period)
+}
+
+// UpdateTicker feeds a bar of symbol, the bar is appended to history,
+// and open orders are matched against its high and low.
+func (e *Exchange) UpdateTicker(symbol string, ticker hs.Ticker) {
+	e.mu.Lock()
+	c, ok := e.history[symbol]
+	if !ok {
+		candle := hs.NewCandle(historyCapacity)
+		c = &candle
+		e.history[symbol] = c
+	}
+	c.Append(ticker)
+	e.clock = time.Unix(ticker.Timestamp, 0)
+	e.prices[symbol] = decimal.NewFromFloat(ticker.Close)
+	events := e.match(symbol, decimal.NewFromFloat(ticker.Low), decimal.NewFromFloat(ticker.High), decimal.Zero)
+	var handlers []exchange.ResponseHandler
+	for _, s := range e.candleSubs {
+		if s.symbol == symbol {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	e.mu.Unlock()
+
+	e.notifyOrders(events)
+	for _, h := range handlers {
+		h(ticker)
+	}
+}
+
+// UpdateCandle feeds all bars in candle one by one
+func (e *Exchange) UpdateCandle(symbol string, candle hs.Candle) {
+	for i := 0; i < candle.Length(); i++ {
+		e.UpdateTicker(symbol, hs.Ticker{
+			Timestamp: candle.Timestamp[i],
+			Open:      candle.Open[i],
+			High:      candle.High[i],
+			Low:       candle.Low[i],
+			Close:     candle.Close[i],
+			Volume:    candle.Volume[i],
+		})
+	}
+}
+
+// UpdateTrades feeds trade details in time order,
+// the amount of each trade is shared by the open orders in the order of placing, so partial fill is possible.
+func (e *Exchange) UpdateTrades(symbol string, trades []exchange.TradeDetail) {
+	var events []exchange.Order
+	e.mu.Lock()
+	for _, t := range trades {
+		e.clock = time.Unix(t.Timestamp/1000, t.Timestamp%1000*int64(time.Millisecond))
+		e.prices[symbol] = t.Price
+		events = append(events, e.match(symbol, t.Price, t.Price, t.Amount)...)
+	}
+	e.mu.Unlock()
+
+	e.notifyOrders(events)
+}
+
+func (e *Exchange) balance(currency string) *balance {
+	b, ok := e.balances[currency]
+	if !ok {
+		b = &balance{}
+		e.balances[currency] = b
+	}
+	return b
+}
+
+func (e *Exchange) feeOf(symbol string) exchange.Fee {
+	if f, ok := e.fees[symbol]; ok {
+		return f
+	}
+	f := e.fee
+	f.Symbol = symbol
+	return f
+}
+
+func (e *Exchange) now() time.Time {
+	if e.clock.IsZero() {
+		return time.Now()
+	}
+	return e.clock
+}
+
+// place validates and locks the balance, then try to fill the order at last price
+func (e *Exchange) place(o *order) (uint64, error) {
+	e.mu.Lock()
+	s, ok := e.symbols[o.Symbol]
+	if !ok {
+		e.mu.Unlock()
+		return 0, ErrUnknownSymbol
+	}
+	last, hasPrice := e.prices[o.Symbol]
+	if o.market {
+		if !hasPrice {
+			e.mu.Unlock()
+			return 0, ErrNoPrice
+		}
+		if o.side == exchange.Buy {
+			o.Amount = o.total.DivRound(last, s.AmountPrecision+1).Truncate(s.AmountPrecision)
+		}
+		o.Price = last
+	}
+	o.Price = o.Price.Round(s.PricePrecision)
+	o.Amount = o.Amount.Truncate(s.AmountPrecision)
+	if !o.Amount.IsPositive() || o.Amount.LessThan(s.LimitOrderMinAmount) {
+		e.mu.Unlock()
+		return 0, ErrBadAmount
+	}
+	if o.tif == exchange.PostOnly && hasPrice && (o.side == exchange.Buy && last.LessThanOrEqual(o.Price) ||
+		o.side == exchange.Sell && last.GreaterThanOrEqual(o.Price)) {
+		e.mu.Unlock()
+		return 0, ErrPostOnly
+	}
+	if o.side == exchange.Buy {
+		o.locked = o.Price.Mul(o.Amount)
+		if o.market {
+			o.locked = o.total
+		}
+		if o.locked.LessThan(s.MinTotal) {
+			e.mu.Unlock()
+			return 0, ErrBadAmount
+		}
+		if err := e.lock(s.QuoteCurrency, o.locked); err != nil {
+			e.mu.Unlock()
+			return 0, err
+		}
+	} else {
+		o.locked = o.Amount
+		if err := e.lock(s.BaseCurrency, o.locked); err != nil {
+			e.mu.Unlock()
+			return 0, err
+		}
+	}
+
+	e.nextId++
+	o.Id = e.nextId
+	o.Time = e.now()
+	o.Status = OrderStatusOpen
+	e.orders[o.Id] = o
+	e.opens = append(e.opens, o.Id)
+	events := []exchange.Order{e.snapshot(o)}
+
+	if hasPrice {
+		switch {
+		case o.market:
+			e.fill(o, last, o.Amount, true)
+		case !o.stopPrice.IsZero():
+			// wait for next price update
+		case o.side == exchange.Buy && last.LessThanOrEqual(o.Price),
+			o.side == exchange.Sell && last.GreaterThanOrEqual(o.Price):
+			e.fill(o, last, o.Amount, true)
+		}
+		if o.FilledAmount.IsPositive() {
+			events = append(events, e.snapshot(o))
+		}
+	}
+	if (o.tif == exchange.IOC || o.tif == exchange.FOK) && o.open() {
+		e.finish(o, OrderStatusCancelled)
+		events = append(events, e.snapshot(o))
+	}
+	e.mu.Unlock()
+
+	e.notifyOrders(events)
+	return o.Id, nil
+}
+
+func (e *Exchange) lock(currency string, amount decimal.Decimal) error {
+	b := e.balance(currency)
+	if b.Available.LessThan(amount) {
+		return ErrInsufficientBalance
+	}
+	b.Available = b.Available.Sub(amount)
+	b.Locked = b.Locked.Add(amount)
+	return nil
+}
+
+// match open orders of symbol with price range [low, high] in the order of placing,
+// volume is the max amount can be filled of all orders, zero means no limit.
+func (e *Exchange) match(symbol string, low, high, volume decimal.Decimal) (events []exchange.Order) {
+	limited := volume.IsPositive()
+	for _, id := range append([]uint64(nil), e.opens...) {
+		if limited && !volume.IsPositive() {
+			break
+		}
+		o := e.orders[id]
+		if o.Symbol != symbol || !o.open() {
+			continue
+		}
+		if !o.stopPrice.IsZero() && !o.triggered {
+			if o.side == exchange.Buy && high.GreaterThanOrEqual(o.stopPrice) ||
+				o.side == exchange.Sell && low.LessThanOrEqual(o.stopPrice) {
+				o.triggered = true
+			} else {
+				continue
+			}
+		}
+		left := o.Amount.Sub(o.FilledAmount)
+		if limited && volume.LessThan(left) {
+			left = volume
+		}
+		if o.side == exchange.Buy && low.LessThanOrEqual(o.Price) ||
+			o.side == exchange.Sell && high.GreaterThanOrEqual(o.Price) {
+			e.fill(o, o.Price, left, false)
+			events = append(events, e.snapshot(o))
+			if limited {
+				volume = volume.Sub(left)
+			}
+		}
+	}
+	return
+}
+
+// fill the order with amount at price, charge the fee from the received currency
+func (e *Exchange) fill(o *order, price, amount decimal.Decimal, taker bool) {
+	s := e.symbols[o.Symbol]
+	fee := e.feeOf(o.Symbol)
+	rate := fee.ActualMaker
+	role := "maker"
+	if taker {
+		rate = fee.ActualTaker
+		role = "taker"
+	}
+	turnover := price.Mul(amount)
+	trade := exchange.Trade{
+		Id:      uint64(len(o.Trades) + 1),
+		OrderId: o.Id,
+		Symbol:  o.Symbol,
+		Type:    o.Type,
+		Role:    role,
+		Price:   price,
+		Amount:  amount,
+		Time:    e.now(),
+	}
+	if o.side == exchange.Buy {
+		trade.Side = exchange.TradeDirectionBuy
+		trade.FeeCurrency = s.BaseCurrency
+		trade.FeeAmount = amount.Mul(rate)
+		quote := e.balance(s.QuoteCurrency)
+		cost := turnover
+		if cost.GreaterThan(o.locked) {
+			cost = o.locked
+		}
+		quote.Locked = quote.Locked.Sub(cost)
+		o.locked = o.locked.Sub(cost)
+		base := e.balance(s.BaseCurrency)
+		base.Available = base.Available.Add(amount.Sub(trade.FeeAmount))
+	} else {
+		trade.Side = exchange.TradeDirectionSell
+		trade.FeeCurrency = s.QuoteCurrency
+		trade.FeeAmount = turnover.Mul(rate)
+		base := e.balance(s.BaseCurrency)
+		base.Locked = base.Locked.Sub(amount)
+		o.locked = o.locked.Sub(amount)
+		quote := e.balance(s.QuoteCurrency)
+		quote.Available = quote.Available.Add(turnover.Sub(trade.FeeAmount))
+	}
+	o.Trades = append(o.Trades, trade)
+	o.FilledAmount = o.FilledAmount.Add(amount)
+	o.turnover = o.turnover.Add(turnover)
+	o.FilledPrice = o.turnover.Div(o.FilledAmount).Round(s.PricePrecision)
+	if o.FilledAmount.GreaterThanOrEqual(o.Amount) {
+		e.finish(o, OrderStatusClosed)
+	}
+}
+
+// finish the order, and unlock the balance left
+func (e *Exchange) finish(o *order, status string) {
+	s := e.symbols[o.Symbol]
+	currency := s.BaseCurrency
+	if o.side == exchange.Buy {
+		currency = s.QuoteCurrency
+	}
+	b := e.balance(currency)
+	b.Locked = b.Locked.Sub(o.locked)
+	b.Available = b.Available.Add(o.locked)
+	o.locked = decimal.Zero
+	o.Status = status
+	for i, id := range e.opens {
+		if id == o.Id {
+			e.opens = append(e.opens[:i], e.opens[i+1:]...)
+			break
+		}
+	}
+}
+
+func (e *Exchange) snapshot(o *order) exchange.Order {
+	s := o.Order
+	s.Side = o.side
+	s.State = orderStatus.Normalize(o.Status, o.FilledAmount)
+	s.Trades = append([]exchange.Trade(nil), o.Trades...)
+	return s
+}
+
+func (e *Exchange) notifyOrders(orders []exchange.Order) {
+	if len(orders) == 0 {
+		return
+	}
+	e.mu.Lock()
+	var subs []subscription
+	for _, s := range e.orderSubs {
+		subs = append(subs, s)
+	}
+	e.mu.Unlock()
+	for _, o := range orders {
+		for _, s := range subs {
+			if s.symbol == o.Symbol {
+				s.handler(o)
+			}
+		}
+	}
+}
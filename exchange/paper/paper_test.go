@@ -0,0 +1,248 @@
+package paper
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"testing"
+)
+
+var btcUsdt = exchange.Symbol{
+	Symbol:          "btc_usdt",
+	BaseCurrency:    "btc",
+	QuoteCurrency:   "usdt",
+	PricePrecision:  2,
+	AmountPrecision: 4,
+}
+
+func newExchange(t *testing.T) *Exchange {
+	fee := exchange.Fee{ActualMaker: decimal.RequireFromString("0.001"), ActualTaker: decimal.RequireFromString("0.002")}
+	e := New([]exchange.Symbol{btcUsdt}, fee)
+	e.Deposit("usdt", decimal.NewFromInt(10000))
+	e.Deposit("btc", decimal.NewFromInt(1))
+	e.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 1600000000, Open: 100, High: 100, Low: 100, Close: 100, Volume: 1})
+	return e
+}
+
+func requireBalance(t *testing.T, e *Exchange, currency, available, locked string) {
+	for _, b := range e.Balances() {
+		if b.Currency == currency {
+			require.True(t, decimal.RequireFromString(available).Equal(b.Available), "%s available %s", currency, b.Available)
+			require.True(t, decimal.RequireFromString(locked).Equal(b.Locked), "%s locked %s", currency, b.Locked)
+			return
+		}
+	}
+	t.Fatalf("no balance of %s", currency)
+}
+
+func TestExchange_BuyLimit(t *testing.T) {
+	e := newExchange(t)
+	var updates []exchange.Order
+	e.SubscribeOrder("btc_usdt", "test", func(r interface{}) {
+		updates = append(updates, r.(exchange.Order))
+	})
+
+	id, err := e.BuyLimit("btc_usdt", "c1", decimal.NewFromInt(90), decimal.NewFromInt(10))
+	require.NoError(t, err)
+	requireBalance(t, e, "usdt", "9100", "900")
+	_, filled, err := e.IsFullFilled("btc_usdt", id)
+	require.NoError(t, err)
+	require.False(t, filled)
+
+	e.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 1600000060, Open: 100, High: 101, Low: 95, Close: 96})
+	_, filled, _ = e.IsFullFilled("btc_usdt", id)
+	require.False(t, filled)
+
+	e.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 1600000120, Open: 96, High: 97, Low: 89, Close: 92})
+	o, filled, err := e.IsFullFilled("btc_usdt", id)
+	require.NoError(t, err)
+	require.True(t, filled)
+	require.Equal(t, OrderStatusClosed, o.Status)
+	require.Equal(t, "maker", o.Trades[0].Role)
+	// maker fee 0.1% charged in btc
+	requireBalance(t, e, "btc", "10.99", "0")
+	requireBalance(t, e, "usdt", "9100", "0")
+
+	require.Len(t, updates, 2)
+	require.Equal(t, OrderStatusOpen, updates[0].Status)
+	require.Equal(t, exchange.Buy, updates[0].Side)
+	require.Equal(t, OrderStatusClosed, updates[1].Status)
+}
+
+func TestExchange_SellLimitImmediate(t *testing.T) {
+	e := newExchange(t)
+	id, err := e.SellLimit("btc_usdt", "c1", decimal.NewFromInt(99), decimal.RequireFromString("0.5"))
+	require.NoError(t, err)
+	o, err := e.GetOrderById(id, "btc_usdt")
+	require.NoError(t, err)
+	require.Equal(t, OrderStatusClosed, o.Status)
+	require.Equal(t, "taker", o.Trades[0].Role)
+	require.True(t, decimal.NewFromInt(100).Equal(o.FilledPrice))
+	// 50 usdt minus 0.2% taker fee
+	requireBalance(t, e, "usdt", "10049.9", "0")
+	requireBalance(t, e, "btc", "0.5", "0")
+}
+
+func TestExchange_PlaceOrder(t *testing.T) {
+	e := newExchange(t)
+	_, err := e.PlaceOrder("btc_usdt", "c1", exchange.Buy, decimal.NewFromInt(101), decimal.RequireFromString("0.1"), exchange.OrderOptions{TimeInForce: exchange.PostOnly})
+	require.Equal(t, ErrPostOnly, err)
+	_, err = e.PlaceOrder("btc_usdt", "c1", exchange.Buy, decimal.NewFromInt(101), decimal.RequireFromString("0.1"), exchange.OrderOptions{ReduceOnly: true})
+	require.True(t, errors.Is(err, exchange.ErrUnsupportedOption))
+	_, err = e.PlaceOrder("btc_usdt", "c1", 0, decimal.NewFromInt(101), decimal.RequireFromString("0.1"), exchange.OrderOptions{})
+	require.Equal(t, exchange.ErrBadSide, err)
+
+	id, err := e.PlaceOrder("btc_usdt", "c2", exchange.Buy, decimal.NewFromInt(99), decimal.RequireFromString("0.1"), exchange.OrderOptions{TimeInForce: exchange.PostOnly})
+	require.NoError(t, err)
+	require.Len(t, e.OpenOrders("btc_usdt"), 1)
+	require.NoError(t, e.CancelOrder("btc_usdt", id))
+
+	// IOC is cancelled if not filled at once
+	id, err = e.PlaceOrder("btc_usdt", "c3", exchange.Sell, decimal.NewFromInt(101), decimal.RequireFromString("0.1"), exchange.OrderOptions{TimeInForce: exchange.IOC})
+	require.NoError(t, err)
+	o, err := e.GetOrderById(id, "btc_usdt")
+	require.NoError(t, err)
+	require.Equal(t, exchange.Cancelled, o.State)
+	require.Equal(t, OrderTypeSellLimit, o.Type)
+	requireBalance(t, e, "btc", "1", "0")
+
+	id, err = e.PlaceOrder("btc_usdt", "c4", exchange.Sell, decimal.NewFromInt(99), decimal.RequireFromString("0.1"), exchange.OrderOptions{TimeInForce: exchange.FOK})
+	require.NoError(t, err)
+	o, err = e.GetOrderById(id, "btc_usdt")
+	require.NoError(t, err)
+	require.Equal(t, exchange.Closed, o.State)
+	require.Empty(t, e.OpenOrders("btc_usdt"))
+}
+
+func TestExchange_Market(t *testing.T) {
+	e := newExchange(t)
+	id, err := e.BuyMarket(btcUsdt, "c1", decimal.NewFromInt(1000))
+	require.NoError(t, err)
+	o, filled, err := e.IsFullFilled("btc_usdt", id)
+	require.NoError(t, err)
+	require.True(t, filled)
+	require.True(t, decimal.NewFromInt(10).Equal(o.FilledAmount))
+	requireBalance(t, e, "usdt", "9000", "0")
+	requireBalance(t, e, "btc", "10.98", "0")
+
+	_, err = e.SellMarket(btcUsdt, "c2", decimal.NewFromInt(100))
+	require.Equal(t, ErrInsufficientBalance, err)
+}
+
+func TestExchange_CancelOrder(t *testing.T) {
+	e := newExchange(t)
+	id, err := e.SellLimit("btc_usdt", "c1", decimal.NewFromInt(120), decimal.RequireFromString("0.3"))
+	require.NoError(t, err)
+	requireBalance(t, e, "btc", "0.7", "0.3")
+	require.Len(t, e.OpenOrders("btc_usdt"), 1)
+
+	require.NoError(t, e.CancelOrder("btc_usdt", id))
+	requireBalance(t, e, "btc", "1", "0")
+	require.Empty(t, e.OpenOrders("btc_usdt"))
+	require.Equal(t, ErrOrderFinished, e.CancelOrder("btc_usdt", id))
+	require.Equal(t, ErrOrderNotFound, e.CancelOrder("btc_usdt", 100))
+}
+
+func TestExchange_UpdateTrades(t *testing.T) {
+	e := newExchange(t)
+	id, err := e.SellLimit("btc_usdt", "c1", decimal.NewFromInt(110), decimal.NewFromInt(1))
+	require.NoError(t, err)
+
+	e.UpdateTrades("btc_usdt", []exchange.TradeDetail{
+		{Id: 1, Price: decimal.NewFromInt(105), Amount: decimal.NewFromInt(5), Timestamp: 1600000100000},
+		{Id: 2, Price: decimal.NewFromInt(110), Amount: decimal.RequireFromString("0.4"), Timestamp: 1600000101000},
+	})
+	o, filled, err := e.IsFullFilled("btc_usdt", id)
+	require.NoError(t, err)
+	require.False(t, filled)
+	require.Equal(t, OrderStatusOpen, o.Status)
+	require.True(t, decimal.RequireFromString("0.4").Equal(o.FilledAmount))
+	requireBalance(t, e, "btc", "0", "0.6")
+
+	e.UpdateTrades("btc_usdt", []exchange.TradeDetail{
+		{Id: 3, Price: decimal.NewFromInt(111), Amount: decimal.NewFromInt(2), Timestamp: 1600000102000},
+	})
+	o, filled, _ = e.IsFullFilled("btc_usdt", id)
+	require.True(t, filled)
+	require.Len(t, o.Trades, 2)
+	require.True(t, decimal.NewFromInt(110).Equal(o.FilledPrice))
+}
+
+func TestExchange_UpdateTradesSharedVolume(t *testing.T) {
+	e := newExchange(t)
+	id1, err := e.SellLimit("btc_usdt", "c1", decimal.NewFromInt(110), decimal.RequireFromString("0.5"))
+	require.NoError(t, err)
+	id2, err := e.SellLimit("btc_usdt", "c2", decimal.NewFromInt(110), decimal.RequireFromString("0.5"))
+	require.NoError(t, err)
+
+	// the trade volume is consumed by the earlier order first
+	e.UpdateTrades("btc_usdt", []exchange.TradeDetail{
+		{Id: 1, Price: decimal.NewFromInt(110), Amount: decimal.RequireFromString("0.7"), Timestamp: 1600000100000},
+	})
+	o1, filled, err := e.IsFullFilled("btc_usdt", id1)
+	require.NoError(t, err)
+	require.True(t, filled)
+	o2, filled, err := e.IsFullFilled("btc_usdt", id2)
+	require.NoError(t, err)
+	require.False(t, filled)
+	require.True(t, decimal.RequireFromString("0.2").Equal(o2.FilledAmount), "%s", o2.FilledAmount)
+	require.True(t, decimal.RequireFromString("0.5").Equal(o1.FilledAmount))
+	requireBalance(t, e, "btc", "0", "0.3")
+}
+
+func TestExchange_StopLimit(t *testing.T) {
+	e := newExchange(t)
+	id, err := e.SellStopLimit("btc_usdt", "c1", decimal.NewFromInt(89), decimal.NewFromInt(1), decimal.NewFromInt(90))
+	require.NoError(t, err)
+
+	e.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 1600000060, Open: 100, High: 100, Low: 91, Close: 92})
+	_, filled, _ := e.IsFullFilled("btc_usdt", id)
+	require.False(t, filled)
+
+	e.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 1600000120, Open: 92, High: 92, Low: 85, Close: 86})
+	o, filled, _ := e.IsFullFilled("btc_usdt", id)
+	require.True(t, filled)
+	require.True(t, decimal.NewFromInt(89).Equal(o.FilledPrice))
+}
+
+func TestExchange_Candle(t *testing.T) {
+	e := newExchange(t)
+	var tickers []hs.Ticker
+	e.SubscribeCandlestick("btc_usdt", "test", exchange.MIN1, func(r interface{}) {
+		tickers = append(tickers, r.(hs.Ticker))
+	})
+	e.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 1600000060, Open: 100, High: 101, Low: 99, Close: 100, Volume: 2})
+	e.UnsubscribeCandlestick("btc_usdt", "test", exchange.MIN1)
+	e.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 1600000120, Open: 100, High: 101, Low: 99, Close: 101, Volume: 3})
+	require.Len(t, tickers, 1)
+
+	c, err := e.CandleBySize("btc_usdt", exchange.MIN1, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, c.Length())
+	require.Equal(t, int64(1600000120), c.Timestamp[1])
+
+	v, err := e.Last24hVolume("btc_usdt")
+	require.NoError(t, err)
+	require.True(t, decimal.NewFromInt(6).Equal(v))
+
+	price, err := e.LastPrice("btc_usdt")
+	require.NoError(t, err)
+	require.True(t, decimal.NewFromInt(101).Equal(price))
+
+	_, err = e.GetSymbol(context.Background(), "eth_usdt")
+	require.Equal(t, ErrUnknownSymbol, err)
+}
+
+func TestRegister(t *testing.T) {
+	ex, err := exchange.New(hs.ExchangeConf{Name: Name, Symbols: []string{"btc_usdt"}}, nil)
+	require.NoError(t, err)
+	s, err := ex.GetSymbol(context.Background(), "btc_usdt")
+	require.NoError(t, err)
+	require.Equal(t, "usdt", s.QuoteCurrency)
+
+	_, err = exchange.New(hs.ExchangeConf{Name: Name, Symbols: []string{"btcusdt"}}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xyths/hs"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory creates exchange from config
+type Factory func(conf hs.ExchangeConf, logger *zap.SugaredLogger) (Exchange, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+var ErrUnknownExchange = errors.New("unknown exchange")
+
+// Register makes the exchange available by name in New.
+// The adapters register themselves in init(), so import the adapter package (or exchange/all) before New,
+// like database/sql drivers. Third-party adapters can register by their own name.
+// It panics if called twice with the same name or factory is nil.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if factory == nil {
+		panic("exchange: Register factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("exchange: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// Registered returns the sorted names of registered exchanges
+func Registered() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	var names []string
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the exchange by conf.Name, see hs.GateIO, hs.Huobi etc.
+func New(conf hs.ExchangeConf, logger *zap.SugaredLogger) (Exchange, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[conf.Name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q, registered: [%s], forgot to import the adapter package?",
+			ErrUnknownExchange, conf.Name, strings.Join(Registered(), ", "))
+	}
+	return factory(conf, logger)
+}
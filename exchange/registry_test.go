@@ -0,0 +1,31 @@
+package exchange
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	var got hs.ExchangeConf
+	Register("test-registry", func(conf hs.ExchangeConf, logger *zap.SugaredLogger) (Exchange, error) {
+		got = conf
+		return nil, errors.New("test error")
+	})
+	require.Contains(t, Registered(), "test-registry")
+	require.Panics(t, func() {
+		Register("test-registry", func(conf hs.ExchangeConf, logger *zap.SugaredLogger) (Exchange, error) {
+			return nil, nil
+		})
+	})
+	require.Panics(t, func() { Register("test-nil", nil) })
+
+	_, err := New(hs.ExchangeConf{Name: "test-registry", Key: "key"}, nil)
+	require.EqualError(t, err, "test error")
+	require.Equal(t, "key", got.Key)
+
+	_, err = New(hs.ExchangeConf{Name: "no-such-exchange"}, nil)
+	require.True(t, errors.Is(err, ErrUnknownExchange))
+}
@@ -0,0 +1,145 @@
+package exchange
+
+import (
+	"github.com/xyths/hs"
+	"sync"
+	"time"
+)
+
+// CandleUpdate 是K线推送，每次推送最新的一根，同一个Timestamp在收盘前会推送多次
+type CandleUpdate struct {
+	Symbol string
+	Period time.Duration
+	hs.Ticker
+}
+
+// BalanceUpdate 是余额推送，每次一个币种
+type BalanceUpdate struct {
+	Balance
+	Time time.Time
+}
+
+// OrderHandler 是订单推送的处理函数，Status是交易所的原始状态，State是统一的状态
+type OrderHandler func(order Order)
+
+// CandleHandler 是K线推送的处理函数
+type CandleHandler func(update CandleUpdate)
+
+// BalanceHandler 是余额推送的处理函数
+type BalanceHandler func(update BalanceUpdate)
+
+// StreamAPIExchange is the typed version of WsAPIExchange, all exchanges push the same structs.
+// The subscription is stopped by the returned handle, or the old style Unsubscribe* with the same clientId.
+type StreamAPIExchange interface {
+	SubscribeOrderUpdate(symbol, clientId string, handler OrderHandler) *Subscription
+	SubscribeCandleUpdate(symbol, clientId string, period time.Duration, handler CandleHandler) *Subscription
+	SubscribeTrade(symbol, clientId string, handler TradeHandler) *Subscription
+	SubscribeBalanceUpdate(clientId string, handler BalanceHandler) *Subscription
+}
+
+// OrderUpdates delivers the order updates over channel, size is the buffer size.
+// The channel is closed after the returned subscription is unsubscribed.
+func OrderUpdates(ex StreamAPIExchange, symbol, clientId string, size int) (<-chan Order, *Subscription) {
+	ch := make(chan Order, size)
+	sub := pipe(func(send func(func(quit <-chan struct{}))) *Subscription {
+		return ex.SubscribeOrderUpdate(symbol, clientId, func(order Order) {
+			send(func(quit <-chan struct{}) {
+				select {
+				case ch <- order:
+				case <-quit:
+				}
+			})
+		})
+	}, func() { close(ch) })
+	return ch, sub
+}
+
+// CandleUpdates delivers the candle updates over channel, see OrderUpdates
+func CandleUpdates(ex StreamAPIExchange, symbol, clientId string, period time.Duration, size int) (<-chan CandleUpdate, *Subscription) {
+	ch := make(chan CandleUpdate, size)
+	sub := pipe(func(send func(func(quit <-chan struct{}))) *Subscription {
+		return ex.SubscribeCandleUpdate(symbol, clientId, period, func(update CandleUpdate) {
+			send(func(quit <-chan struct{}) {
+				select {
+				case ch <- update:
+				case <-quit:
+				}
+			})
+		})
+	}, func() { close(ch) })
+	return ch, sub
+}
+
+// TradeUpdates delivers the trade details one by one over channel, in time order, see OrderUpdates
+func TradeUpdates(ex StreamAPIExchange, symbol, clientId string, size int) (<-chan TradeDetail, *Subscription) {
+	ch := make(chan TradeDetail, size)
+	sub := pipe(func(send func(func(quit <-chan struct{}))) *Subscription {
+		return ex.SubscribeTrade(symbol, clientId, func(details []TradeDetail) {
+			send(func(quit <-chan struct{}) {
+				for _, d := range details {
+					select {
+					case ch <- d:
+					case <-quit:
+						return
+					}
+				}
+			})
+		})
+	}, func() { close(ch) })
+	return ch, sub
+}
+
+// BalanceUpdates delivers the balance updates over channel, see OrderUpdates
+func BalanceUpdates(ex StreamAPIExchange, clientId string, size int) (<-chan BalanceUpdate, *Subscription) {
+	ch := make(chan BalanceUpdate, size)
+	sub := pipe(func(send func(func(quit <-chan struct{}))) *Subscription {
+		return ex.SubscribeBalanceUpdate(clientId, func(update BalanceUpdate) {
+			send(func(quit <-chan struct{}) {
+				select {
+				case ch <- update:
+				case <-quit:
+				}
+			})
+		})
+	}, func() { close(ch) })
+	return ch, sub
+}
+
+// pipe wraps the subscription which sends to channel, the handler sends by send.
+// The sender blocks when the channel is full, so quit is closed first to release it,
+// otherwise unsubscribe would wait for the blocked handler forever.
+// send holds the read lock and skips after stopped, so the channel is closed under the write lock
+// after the running senders returned, even if the exchange calls the handler after its subscription stopped.
+func pipe(subscribe func(send func(func(quit <-chan struct{}))) *Subscription, closeChannel func()) *Subscription {
+	quit := make(chan struct{})
+	var (
+		mu      sync.RWMutex
+		stopped bool
+		once    sync.Once
+	)
+	send := func(f func(quit <-chan struct{})) {
+		mu.RLock()
+		defer mu.RUnlock()
+		if !stopped {
+			f(quit)
+		}
+	}
+	stop := func() {
+		once.Do(func() {
+			close(quit)
+			mu.Lock()
+			stopped = true
+			closeChannel()
+			mu.Unlock()
+		})
+	}
+	inner := subscribe(send)
+	go func() {
+		<-inner.Done()
+		stop()
+	}()
+	return NewSubscription(func() {
+		stop()
+		inner.Unsubscribe()
+	})
+}
@@ -0,0 +1,122 @@
+package exchange
+
+import (
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStream pushes from another goroutine like websocket, stop waits for it
+type fakeStream struct {
+	orders []Order
+	trades [][]TradeDetail
+}
+
+func (f *fakeStream) push(n int, send func(i int)) *Subscription {
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			select {
+			case <-quit:
+				return
+			default:
+				send(i)
+			}
+		}
+		<-quit
+	}()
+	return NewSubscription(func() {
+		close(quit)
+		wg.Wait()
+	})
+}
+
+func (f *fakeStream) SubscribeOrderUpdate(symbol, clientId string, handler OrderHandler) *Subscription {
+	return f.push(len(f.orders), func(i int) { handler(f.orders[i]) })
+}
+
+func (f *fakeStream) SubscribeCandleUpdate(symbol, clientId string, period time.Duration, handler CandleHandler) *Subscription {
+	return f.push(0, nil)
+}
+
+func (f *fakeStream) SubscribeTrade(symbol, clientId string, handler TradeHandler) *Subscription {
+	return f.push(len(f.trades), func(i int) { handler(f.trades[i]) })
+}
+
+func (f *fakeStream) SubscribeBalanceUpdate(clientId string, handler BalanceHandler) *Subscription {
+	return f.push(0, nil)
+}
+
+func TestOrderUpdates(t *testing.T) {
+	f := &fakeStream{orders: []Order{{Id: 1}, {Id: 2}, {Id: 3}}}
+	ch, sub := OrderUpdates(f, "btc_usdt", "test", 0)
+	require.Equal(t, uint64(1), (<-ch).Id)
+	require.Equal(t, uint64(2), (<-ch).Id)
+
+	// the sender is blocked by the unbuffered channel
+	done := make(chan struct{})
+	go func() {
+		sub.Unsubscribe()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("unsubscribe is blocked by the sender")
+	}
+	for range ch {
+		// drain, it's closed after unsubscribed
+	}
+}
+
+func TestTradeUpdates(t *testing.T) {
+	f := &fakeStream{trades: [][]TradeDetail{{{Id: 1}, {Id: 2}}, {{Id: 3}}}}
+	ch, sub := TradeUpdates(f, "btc_usdt", "test", 10)
+	for i := int64(1); i <= 3; i++ {
+		require.Equal(t, i, (<-ch).Id)
+	}
+	sub.Unsubscribe()
+	_, ok := <-ch
+	require.False(t, ok)
+}
+
+// lateStream calls the handler after its subscription stopped, like the huobi sdk
+type lateStream struct {
+	fakeStream
+	handler OrderHandler
+	sub     *Subscription
+}
+
+func (f *lateStream) SubscribeOrderUpdate(symbol, clientId string, handler OrderHandler) *Subscription {
+	f.handler = handler
+	f.sub = NewSubscription(nil)
+	return f.sub
+}
+
+func TestOrderUpdates_Late(t *testing.T) {
+	f := &lateStream{}
+	ch, sub := OrderUpdates(f, "btc_usdt", "test", 0)
+
+	// the inner subscription stopped by the exchange, the sender blocked is released
+	sent := make(chan struct{})
+	go func() {
+		f.handler(Order{Id: 1})
+		close(sent)
+	}()
+	f.sub.Unsubscribe()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("the sender is not released")
+	}
+	_, ok := <-ch
+	require.False(t, ok)
+
+	// the late update is dropped, not sent on the closed channel
+	f.handler(Order{Id: 2})
+	sub.Unsubscribe()
+}
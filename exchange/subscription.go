@@ -0,0 +1,113 @@
+package exchange
+
+import (
+	"context"
+	"sync"
+)
+
+// Subscription is the handle of a websocket subscription.
+// Unsubscribe sends the unsubscribe request and closes the connection, the handler is not called after it returns.
+// Most exchanges also wait for the websocket goroutines to exit, but huobi SDK doesn't expose them,
+// so they may exit a little later.
+type Subscription struct {
+	once sync.Once
+	stop func()
+	done chan struct{}
+}
+
+// NewSubscription creates a handle, stop is called only once by Unsubscribe
+func NewSubscription(stop func()) *Subscription {
+	return &Subscription{stop: stop, done: make(chan struct{})}
+}
+
+// Unsubscribe is safe to call many times and concurrently, it returns after the subscription stopped.
+// Don't call it in the handler of the same subscription, it will wait for itself.
+func (s *Subscription) Unsubscribe() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		if s.stop != nil {
+			s.stop()
+		}
+		close(s.done)
+	})
+}
+
+// Done is closed after unsubscribed
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// WithContext unsubscribes when ctx is done
+func (s *Subscription) WithContext(ctx context.Context) *Subscription {
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Unsubscribe()
+		case <-s.done:
+		}
+	}()
+	return s
+}
+
+// Subscriptions keeps the subscriptions by key (eg. channel, symbol and client id),
+// so the old style Unsubscribe(symbol, clientId) can stop the right one.
+type Subscriptions struct {
+	mu   sync.Mutex
+	subs map[string]*Subscription
+}
+
+func NewSubscriptions() *Subscriptions {
+	return &Subscriptions{subs: make(map[string]*Subscription)}
+}
+
+// Add keeps sub with key, the old one with the same key is unsubscribed
+func (s *Subscriptions) Add(key string, sub *Subscription) {
+	s.mu.Lock()
+	old := s.subs[key]
+	s.subs[key] = sub
+	s.mu.Unlock()
+	if old != nil && old != sub {
+		old.Unsubscribe()
+	}
+	// forget it when unsubscribed by the handle
+	go func() {
+		<-sub.Done()
+		s.mu.Lock()
+		if s.subs[key] == sub {
+			delete(s.subs, key)
+		}
+		s.mu.Unlock()
+	}()
+}
+
+// Unsubscribe stops the subscription of key, returns false if not found
+func (s *Subscriptions) Unsubscribe(key string) bool {
+	s.mu.Lock()
+	sub, ok := s.subs[key]
+	delete(s.subs, key)
+	s.mu.Unlock()
+	if ok {
+		sub.Unsubscribe()
+	}
+	return ok
+}
+
+// Close stops all the subscriptions
+func (s *Subscriptions) Close() {
+	s.mu.Lock()
+	all := s.subs
+	s.subs = make(map[string]*Subscription)
+	s.mu.Unlock()
+	for _, sub := range all {
+		sub.Unsubscribe()
+	}
+}
+
+// Len returns the count of active subscriptions
+func (s *Subscriptions) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs)
+}
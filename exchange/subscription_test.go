@@ -0,0 +1,69 @@
+package exchange
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscription_Unsubscribe(t *testing.T) {
+	var stopped int32
+	sub := NewSubscription(func() {
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&stopped, 1)
+	})
+	for i := 0; i < 3; i++ {
+		go sub.Unsubscribe()
+	}
+	sub.Unsubscribe()
+	// returns after stopped
+	require.Equal(t, int32(1), atomic.LoadInt32(&stopped))
+	<-sub.Done()
+
+	var nilSub *Subscription
+	nilSub.Unsubscribe()
+}
+
+func TestSubscription_WithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := NewSubscription(nil).WithContext(ctx)
+	select {
+	case <-sub.Done():
+		t.Fatal("unsubscribed before cancel")
+	default:
+	}
+	cancel()
+	select {
+	case <-sub.Done():
+	case <-time.After(time.Second):
+		t.Fatal("not unsubscribed after cancel")
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	subs := NewSubscriptions()
+	a := NewSubscription(nil)
+	b := NewSubscription(nil)
+	c := NewSubscription(nil)
+	subs.Add("ticker:btc_usdt:1", a)
+	subs.Add("ticker:btc_usdt:1", b)
+	<-a.Done()
+	require.Equal(t, 1, subs.Len())
+
+	subs.Add("kline:btc_usdt:1", c)
+	require.True(t, subs.Unsubscribe("kline:btc_usdt:1"))
+	require.False(t, subs.Unsubscribe("kline:btc_usdt:1"))
+	<-c.Done()
+
+	// unsubscribed by the handle
+	b.Unsubscribe()
+	require.Eventually(t, func() bool { return subs.Len() == 0 }, time.Second, time.Millisecond)
+
+	d := NewSubscription(nil)
+	subs.Add("order", d)
+	subs.Close()
+	<-d.Done()
+	require.Equal(t, 0, subs.Len())
+}
@@ -0,0 +1,314 @@
+package grid
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+type State struct {
+	Grids []hs.Grid `json:"grids"`
+	// Base is the grid index near current price, no order on it.
+	// The engine keeps a buy order on Base-1 and a sell order on Base+1.
+	Base int `json:"base"`
+	// Residual is the base currency bought (positive) or sold (negative) by the cancelled orders,
+	// which are part filled before cancelled, or filled before the cancel. It's not traded back by the grid.
+	Residual decimal.Decimal `json:"residual"`
+}
+
+// Engine runs a grid, it moves the base one level up or down when an order full filled.
+type Engine struct {
+	ex     exchange.RestAPIExchange
+	symbol exchange.Symbol
+	grids  []hs.Grid
+	store  hs.KeyStore
+	key    string
+	Sugar  *zap.SugaredLogger
+
+	mu    sync.Mutex
+	state State
+
+	// the filled orders are handled one by one in time order
+	queueMu  sync.Mutex
+	queue    []exchange.Order
+	draining bool
+}
+
+// New creates an engine, grids is used only if no state found by key in store.
+func New(ex exchange.RestAPIExchange, symbol exchange.Symbol, grids []hs.Grid, store hs.KeyStore, key string, logger *zap.SugaredLogger) *Engine {
+	return &Engine{
+		ex:     ex,
+		symbol: symbol,
+		grids:  grids,
+		store:  store,
+		key:    key,
+		Sugar:  logger,
+	}
+}
+
+// State returns a copy of current state
+func (e *Engine) State() State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := e.state
+	s.Grids = append([]hs.Grid(nil), e.state.Grids...)
+	return s
+}
+
+// Start resumes the saved state, or begins a new grid from the last price.
+// If rebalance is true, a new grid buys or sells the base currency at market,
+// to hold the amount for the sell orders above base.
+func (e *Engine) Start(ctx context.Context, rebalance bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var state State
+	if err := e.store.Load(ctx, e.key, &state); err != nil {
+		return err
+	}
+	if len(state.Grids) > 0 {
+		e.state = state
+		e.Sugar.Infof("grid %s resumed, base is %d", e.key, state.Base)
+		return e.ensure(ctx)
+	}
+	if len(e.grids) < 2 {
+		return ErrBadConf
+	}
+	price, err := e.ex.LastPrice(e.symbol.Symbol)
+	if err != nil {
+		return err
+	}
+	e.state = State{Grids: append([]hs.Grid(nil), e.grids...), Base: nearest(e.grids, price)}
+	e.Sugar.Infof("grid %s started at price %s, base is %d", e.key, price, e.state.Base)
+	if rebalance {
+		if err := e.rebalance(price); err != nil {
+			return err
+		}
+	}
+	return e.ensure(ctx)
+}
+
+// Stop cancels the orders, state is saved, so Start will place them again.
+// ErrNotCanceled is returned if any order is not confirmed cancelled, the order is kept and call Stop again.
+func (e *Engine) Stop(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var err error
+	for i := range e.state.Grids {
+		if !e.cancel(i) {
+			err = ErrNotCanceled
+		}
+	}
+	if saveErr := e.store.Save(ctx, e.key, e.state); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// OnOrderUpdate is a handler for exchange.WsAPIExchange.SubscribeOrder, the response should be an exchange.Order.
+// The updates are queued and handled in another goroutine one by one,
+// for the handler may be called inside BuyLimit or SellLimit.
+func (e *Engine) OnOrderUpdate(response interface{}) {
+	o, ok := response.(exchange.Order)
+	if !ok || o.Amount.IsZero() || o.FilledAmount.LessThan(o.Amount) {
+		return
+	}
+	e.queueMu.Lock()
+	e.queue = append(e.queue, o)
+	if e.draining {
+		e.queueMu.Unlock()
+		return
+	}
+	e.draining = true
+	e.queueMu.Unlock()
+	go e.drain()
+}
+
+// drain handles the queued updates until empty
+func (e *Engine) drain() {
+	for {
+		e.queueMu.Lock()
+		if len(e.queue) == 0 {
+			e.draining = false
+			e.queueMu.Unlock()
+			return
+		}
+		o := e.queue[0]
+		e.queue = e.queue[1:]
+		e.queueMu.Unlock()
+		e.onFilled(o)
+	}
+}
+
+func (e *Engine) onFilled(o exchange.Order) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, i := range []int{e.state.Base - 1, e.state.Base + 1} {
+		if e.valid(i) && e.state.Grids[i].Order == o.Id {
+			e.shift(i)
+			if err := e.ensure(context.Background()); err != nil {
+				e.Sugar.Errorf("grid %s ensure orders error: %s", e.key, err)
+			}
+			return
+		}
+	}
+}
+
+// Poll checks the orders by IsFullFilled, and moves the base if filled.
+func (e *Engine) Poll(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// the price may cross several levels between two polls
+	for n := 0; n < len(e.state.Grids); n++ {
+		shifted := false
+		for _, i := range []int{e.state.Base - 1, e.state.Base + 1} {
+			if !e.valid(i) || e.state.Grids[i].Order == 0 {
+				continue
+			}
+			_, filled, err := e.ex.IsFullFilled(e.symbol.Symbol, e.state.Grids[i].Order)
+			if err != nil {
+				return err
+			}
+			if filled {
+				e.shift(i)
+				shifted = true
+				break
+			}
+		}
+		if err := e.ensure(ctx); err != nil || !shifted {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run polls every interval until ctx done
+func (e *Engine) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := e.Poll(ctx); err != nil {
+				e.Sugar.Errorf("grid %s poll error: %s", e.key, err)
+			}
+		}
+	}
+}
+
+func (e *Engine) valid(i int) bool {
+	return i >= 0 && i < len(e.state.Grids)
+}
+
+// shift the base to the filled grid i, and cancel the order on the other side
+func (e *Engine) shift(i int) {
+	e.Sugar.Infof("grid %s order %d at %s filled", e.key, e.state.Grids[i].Order, e.state.Grids[i].Price)
+	e.state.Grids[i].Order = 0
+	e.cancel(2*e.state.Base - i)
+	e.state.Base = i
+}
+
+// cancel cancels the order on grid i, and adds the filled amount to Residual.
+// The order is kept if it's not confirmed finished by GetOrderById, and cancelled again by ensure or Stop.
+// It returns true if no order left on grid i.
+func (e *Engine) cancel(i int) bool {
+	if !e.valid(i) || e.state.Grids[i].Order == 0 {
+		return true
+	}
+	id := e.state.Grids[i].Order
+	if err := e.ex.CancelOrder(e.symbol.Symbol, id); err != nil {
+		// maybe filled just now
+		e.Sugar.Infof("grid %s cancel order %d error: %s", e.key, id, err)
+	}
+	o, err := e.ex.GetOrderById(id, e.symbol.Symbol)
+	if err != nil {
+		e.Sugar.Errorf("grid %s get order %d error: %s", e.key, id, err)
+		return false
+	}
+	if !o.Finished() {
+		e.Sugar.Infof("grid %s order %d is not cancelled yet, state %d", e.key, id, o.State)
+		return false
+	}
+	if o.FilledAmount.IsPositive() {
+		filled := o.FilledAmount
+		if o.Side == exchange.Sell {
+			filled = filled.Neg()
+		}
+		e.state.Residual = e.state.Residual.Add(filled)
+		e.Sugar.Infof("grid %s order %d filled %s before cancelled, residual is %s", e.key, id, o.FilledAmount, e.state.Residual)
+	}
+	e.state.Grids[i].Order = 0
+	return true
+}
+
+// ensure cancels the orders left away from base, places the missing orders around base, and saves state
+func (e *Engine) ensure(ctx context.Context) error {
+	for i := range e.state.Grids {
+		if i != e.state.Base-1 && i != e.state.Base+1 {
+			e.cancel(i)
+		}
+	}
+	var err error
+	if i := e.state.Base - 1; e.valid(i) && e.state.Grids[i].Order == 0 {
+		g := &e.state.Grids[i]
+		if g.Order, err = e.ex.BuyLimit(e.symbol.Symbol, "", g.Price, g.AmountBuy); err != nil {
+			e.Sugar.Errorf("grid %s buy %s at %s error: %s", e.key, g.AmountBuy, g.Price, err)
+		}
+	}
+	if i := e.state.Base + 1; e.valid(i) && e.state.Grids[i].Order == 0 {
+		g := &e.state.Grids[i]
+		var sellErr error
+		if g.Order, sellErr = e.ex.SellLimit(e.symbol.Symbol, "", g.Price, g.AmountSell); sellErr != nil {
+			e.Sugar.Errorf("grid %s sell %s at %s error: %s", e.key, g.AmountSell, g.Price, sellErr)
+			if err == nil {
+				err = sellErr
+			}
+		}
+	}
+	if saveErr := e.store.Save(ctx, e.key, e.state); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// rebalance buys or sells the base currency at market,
+// to hold the amount for the sell orders above base.
+func (e *Engine) rebalance(price decimal.Decimal) error {
+	need := decimal.Zero
+	for i := e.state.Base + 1; i < len(e.state.Grids); i++ {
+		need = need.Add(e.state.Grids[i].AmountSell)
+	}
+	balances, err := e.ex.SpotAvailableBalance()
+	if err != nil {
+		return err
+	}
+	diff := need.Sub(balances[e.symbol.BaseCurrency])
+	amount := diff.Abs().Truncate(e.symbol.AmountPrecision)
+	if amount.IsZero() || amount.LessThan(e.symbol.LimitOrderMinAmount) || amount.Mul(price).LessThan(e.symbol.MinTotal) {
+		return nil
+	}
+	if diff.IsPositive() {
+		e.Sugar.Infof("grid %s rebalance, buy %s %s", e.key, amount, e.symbol.BaseCurrency)
+		_, err = e.ex.BuyMarket(e.symbol, "", amount.Mul(price))
+	} else {
+		e.Sugar.Infof("grid %s rebalance, sell %s %s", e.key, amount, e.symbol.BaseCurrency)
+		_, err = e.ex.SellMarket(e.symbol, "", amount)
+	}
+	return err
+}
+
+// nearest returns the index of the grid which price is nearest to price
+func nearest(grids []hs.Grid, price decimal.Decimal) int {
+	index := 0
+	for i, g := range grids {
+		if g.Price.Sub(price).Abs().LessThan(grids[index].Price.Sub(price).Abs()) {
+			index = i
+		}
+	}
+	return index
+}
@@ -0,0 +1,154 @@
+package grid
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/paper"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+var btcUsdt = exchange.Symbol{
+	Symbol:              "btc_usdt",
+	BaseCurrency:        "btc",
+	QuoteCurrency:       "usdt",
+	PricePrecision:      2,
+	AmountPrecision:     4,
+	LimitOrderMinAmount: decimal.RequireFromString("0.0001"),
+	MinTotal:            decimal.NewFromInt(1),
+}
+
+func TestArithmetic(t *testing.T) {
+	grids, err := Arithmetic(hs.GridStrategyConf{MaxPrice: 120, MinPrice: 80, Number: 4, Total: 400}, btcUsdt)
+	require.NoError(t, err)
+	require.Len(t, grids, 5)
+	prices := []string{"80", "90", "100", "110", "120"}
+	buys := []string{"1.25", "1.1111", "1", "0.909", "0"}
+	for i, g := range grids {
+		require.Equal(t, i, g.Id)
+		require.True(t, decimal.RequireFromString(prices[i]).Equal(g.Price), "price %d: %s", i, g.Price)
+		require.True(t, decimal.RequireFromString(buys[i]).Equal(g.AmountBuy), "buy %d: %s", i, g.AmountBuy)
+		if i > 0 {
+			require.True(t, grids[i-1].AmountBuy.Equal(g.AmountSell))
+		}
+	}
+
+	_, err = Arithmetic(hs.GridStrategyConf{MaxPrice: 80, MinPrice: 120, Number: 4, Total: 400}, btcUsdt)
+	require.Equal(t, ErrBadConf, err)
+	_, err = Arithmetic(hs.GridStrategyConf{MaxPrice: 120, MinPrice: 80, Number: 4, Total: 2}, btcUsdt)
+	require.Equal(t, ErrTooSmallBuy, err)
+}
+
+func TestGeometric(t *testing.T) {
+	grids, err := Geometric(hs.GridStrategyConf{MaxPrice: 160, MinPrice: 10, Number: 4, Total: 400}, btcUsdt)
+	require.NoError(t, err)
+	prices := []string{"10", "20", "40", "80", "160"}
+	for i, g := range grids {
+		require.True(t, decimal.RequireFromString(prices[i]).Equal(g.Price), "price %d: %s", i, g.Price)
+	}
+}
+
+func newPaper(price float64) *paper.Exchange {
+	ex := paper.New([]exchange.Symbol{btcUsdt}, exchange.Fee{})
+	ex.Deposit("usdt", decimal.NewFromInt(1000))
+	ex.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 60, Open: price, High: price, Low: price, Close: price})
+	return ex
+}
+
+func TestEngine(t *testing.T) {
+	ctx := context.Background()
+	grids, err := Arithmetic(hs.GridStrategyConf{MaxPrice: 120, MinPrice: 80, Number: 4, Total: 400}, btcUsdt)
+	require.NoError(t, err)
+	ex := newPaper(101)
+	store := hs.NewMemoryKeyStore()
+	logger := zap.NewNop().Sugar()
+	e := New(ex, btcUsdt, grids, store, "test", logger)
+	require.NoError(t, e.Start(ctx, true))
+
+	state := e.State()
+	require.Equal(t, 2, state.Base)
+	// rebalance bought the amount for sell orders of grid 3 and 4
+	balances, _ := ex.SpotBalance()
+	require.True(t, decimal.RequireFromString("1.909").Equal(balances["btc"]), balances["btc"].String())
+	require.Len(t, ex.OpenOrders("btc_usdt"), 2)
+
+	// price down to 90, buy order of grid 1 filled
+	ex.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 120, Open: 101, High: 101, Low: 89, Close: 91})
+	require.NoError(t, e.Poll(ctx))
+	state = e.State()
+	require.Equal(t, 1, state.Base)
+	orders := ex.OpenOrders("btc_usdt")
+	require.Len(t, orders, 2)
+	require.True(t, decimal.NewFromInt(80).Equal(orders[0].Price))
+	require.True(t, decimal.NewFromInt(100).Equal(orders[1].Price))
+	require.True(t, grids[2].AmountSell.Equal(orders[1].Amount))
+
+	// restart resumes from store
+	e2 := New(ex, btcUsdt, grids, store, "test", logger)
+	require.NoError(t, e2.Start(ctx, true))
+	require.Equal(t, 1, e2.State().Base)
+	require.Len(t, ex.OpenOrders("btc_usdt"), 2)
+
+	// order update from websocket
+	ex.SubscribeOrder("btc_usdt", "grid", e2.OnOrderUpdate)
+	ex.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 180, Open: 91, High: 100, Low: 91, Close: 99})
+	require.Eventually(t, func() bool {
+		return e2.State().Base == 2 && len(ex.OpenOrders("btc_usdt")) == 2
+	}, time.Second, time.Millisecond*10)
+
+	require.NoError(t, e2.Stop(ctx))
+	require.Empty(t, ex.OpenOrders("btc_usdt"))
+}
+
+// failCancel fails to cancel the orders when down
+type failCancel struct {
+	*paper.Exchange
+	down bool
+}
+
+func (f *failCancel) CancelOrder(symbol string, orderId uint64) error {
+	if f.down {
+		return errors.New("exchange is down")
+	}
+	return f.Exchange.CancelOrder(symbol, orderId)
+}
+
+func TestEngine_Cancel(t *testing.T) {
+	ctx := context.Background()
+	grids, err := Arithmetic(hs.GridStrategyConf{MaxPrice: 120, MinPrice: 80, Number: 4, Total: 400}, btcUsdt)
+	require.NoError(t, err)
+	ex := &failCancel{Exchange: newPaper(101)}
+	e := New(ex, btcUsdt, grids, hs.NewMemoryKeyStore(), "test", zap.NewNop().Sugar())
+	require.NoError(t, e.Start(ctx, true))
+
+	// the sell order of grid 3 is kept if not cancelled
+	ex.down = true
+	ex.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 120, Open: 101, High: 101, Low: 89, Close: 91})
+	require.NoError(t, e.Poll(ctx))
+	state := e.State()
+	require.Equal(t, 1, state.Base)
+	require.NotZero(t, state.Grids[3].Order)
+	require.Len(t, ex.OpenOrders("btc_usdt"), 3)
+	require.Equal(t, ErrNotCanceled, e.Stop(ctx))
+
+	// cancelled again by the next poll
+	ex.down = false
+	require.NoError(t, e.Poll(ctx))
+	require.Zero(t, e.State().Grids[3].Order)
+	require.Len(t, ex.OpenOrders("btc_usdt"), 2)
+
+	// the sell order of grid 2 is part filled before cancelled
+	ex.UpdateTrades("btc_usdt", []exchange.TradeDetail{{Id: 1, Price: decimal.NewFromInt(100), Amount: decimal.RequireFromString("0.1"), Timestamp: 180000}})
+	ex.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 240, Open: 91, High: 91, Low: 79, Close: 81})
+	require.NoError(t, e.Poll(ctx))
+	state = e.State()
+	require.Equal(t, 0, state.Base)
+	require.Equal(t, "-0.1", state.Residual.String())
+	require.NoError(t, e.Stop(ctx))
+	require.Empty(t, ex.OpenOrders("btc_usdt"))
+}
@@ -0,0 +1,72 @@
+// Package grid lays out and runs grid trading strategies.
+package grid
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"math"
+)
+
+var (
+	ErrBadConf     = errors.New("bad grid config")
+	ErrTooSmallBuy = errors.New("grid amount or total is less than symbol minimum")
+	ErrNotCanceled = errors.New("grid order is not cancelled yet")
+)
+
+// Arithmetic lays out Number+1 levels from MinPrice to MaxPrice with the same price difference.
+func Arithmetic(conf hs.GridStrategyConf, symbol exchange.Symbol) ([]hs.Grid, error) {
+	if err := check(conf); err != nil {
+		return nil, err
+	}
+	step := (conf.MaxPrice - conf.MinPrice) / float64(conf.Number)
+	prices := make([]decimal.Decimal, conf.Number+1)
+	for i := range prices {
+		prices[i] = decimal.NewFromFloat(conf.MinPrice + step*float64(i))
+	}
+	return layout(prices, conf, symbol)
+}
+
+// Geometric lays out Number+1 levels from MinPrice to MaxPrice with the same price ratio.
+func Geometric(conf hs.GridStrategyConf, symbol exchange.Symbol) ([]hs.Grid, error) {
+	if err := check(conf); err != nil {
+		return nil, err
+	}
+	ratio := math.Pow(conf.MaxPrice/conf.MinPrice, 1/float64(conf.Number))
+	prices := make([]decimal.Decimal, conf.Number+1)
+	for i := range prices {
+		prices[i] = decimal.NewFromFloat(conf.MinPrice * math.Pow(ratio, float64(i)))
+	}
+	return layout(prices, conf, symbol)
+}
+
+func check(conf hs.GridStrategyConf) error {
+	if conf.Number < 1 || conf.MinPrice <= 0 || conf.MaxPrice <= conf.MinPrice || conf.Total <= 0 {
+		return ErrBadConf
+	}
+	return nil
+}
+
+// layout the grids in ascending price, each grid buys Total/Number quote currency at its price,
+// and sells the amount bought by the grid below it.
+func layout(prices []decimal.Decimal, conf hs.GridStrategyConf, symbol exchange.Symbol) ([]hs.Grid, error) {
+	quote := decimal.NewFromFloat(conf.Total).Div(decimal.NewFromInt(int64(conf.Number)))
+	grids := make([]hs.Grid, len(prices))
+	for i, p := range prices {
+		grids[i].Id = i
+		grids[i].Price = p.Round(symbol.PricePrecision)
+		if i < len(prices)-1 {
+			grids[i].AmountBuy = quote.Div(grids[i].Price).Truncate(symbol.AmountPrecision)
+			grids[i].TotalBuy = grids[i].AmountBuy.Mul(grids[i].Price)
+			if grids[i].AmountBuy.IsZero() || grids[i].AmountBuy.LessThan(symbol.LimitOrderMinAmount) ||
+				grids[i].TotalBuy.LessThan(symbol.MinTotal) {
+				return nil, ErrTooSmallBuy
+			}
+		}
+		if i > 0 {
+			grids[i].AmountSell = grids[i-1].AmountBuy
+		}
+	}
+	return grids, nil
+}
@@ -0,0 +1,73 @@
+package indicator
+
+import (
+	"github.com/xyths/hs"
+	"math"
+)
+
+// ATR is the average true range of n periods, with Wilder's smoothing.
+// The true range of the first bar is high - low.
+func ATR(high, low, close []float64, n int) []float64 {
+	result := nans(len(close))
+	s := NewATRStream(n)
+	for i := range close {
+		result[i] = s.Update(int64(i), high[i], low[i], close[i])
+	}
+	return result
+}
+
+// CandleATR is the ATR of candle
+func CandleATR(candle hs.Candle, n int) []float64 {
+	return ATR(candle.High, candle.Low, candle.Close, n)
+}
+
+// TrueRange is the max of high - low, |high - prevClose| and |low - prevClose|
+func TrueRange(high, low, prevClose float64) float64 {
+	return math.Max(high-low, math.Max(math.Abs(high-prevClose), math.Abs(low-prevClose)))
+}
+
+type atrState struct {
+	count int
+	last  float64 // last close
+	value float64 // sum of true range before ready
+}
+
+type ATRStream struct {
+	stamp
+	n     int
+	state atrState
+	prev  atrState
+}
+
+func NewATRStream(n int) *ATRStream {
+	return &ATRStream{n: n}
+}
+
+func (s *ATRStream) Update(timestamp int64, high, low, close float64) float64 {
+	if s.replace(timestamp) {
+		s.state = s.prev
+	} else {
+		s.prev = s.state
+	}
+	st := &s.state
+	tr := high - low
+	if st.count > 0 {
+		tr = TrueRange(high, low, st.last)
+	}
+	st.count++
+	n := float64(s.n)
+	if st.count <= s.n {
+		st.value += tr / n
+	} else {
+		st.value = (st.value*(n-1) + tr) / n
+	}
+	st.last = close
+	return s.Value()
+}
+
+func (s *ATRStream) Value() float64 {
+	if s.n <= 0 || s.state.count < s.n {
+		return math.NaN()
+	}
+	return s.state.value
+}
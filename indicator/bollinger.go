@@ -0,0 +1,102 @@
+package indicator
+
+import "math"
+
+// Bollinger returns the bollinger bands of n periods, upper and lower are k standard deviations from the middle SMA
+func Bollinger(values []float64, n int, k float64) (upper, middle, lower []float64) {
+	upper, middle, lower = nans(len(values)), nans(len(values)), nans(len(values))
+	if n <= 0 {
+		return
+	}
+	for i := n - 1; i < len(values); i++ {
+		mean, std := meanStd(values[i-n+1 : i+1])
+		upper[i], middle[i], lower[i] = mean+k*std, mean, mean-k*std
+	}
+	return
+}
+
+// meanStd returns the mean and population standard deviation
+func meanStd(values []float64) (mean, std float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		std += (v - mean) * (v - mean)
+	}
+	std = math.Sqrt(std / float64(len(values)))
+	return
+}
+
+// BollingerStream updates the running sum and sum of squares in O(1).
+// The sums are of the values minus shift, which is reset to the mean every n updates,
+// so the precision doesn't drift at high price.
+type BollingerStream struct {
+	stamp
+	k      float64
+	window window
+	shift  float64
+	sum    float64
+	sumSq  float64
+	pushes int // updates since the last rebase
+}
+
+func NewBollingerStream(n int, k float64) *BollingerStream {
+	return &BollingerStream{k: k, window: newWindow(n)}
+}
+
+func (s *BollingerStream) Update(timestamp int64, v float64) (upper, middle, lower float64) {
+	w := &s.window
+	n := len(w.values)
+	if n == 0 {
+		return s.Value()
+	}
+	if s.replace(timestamp) && w.count > 0 {
+		s.remove(w.values[(w.pos-1+n)%n])
+		w.replace(v)
+	} else {
+		if w.count == 0 {
+			s.shift = v
+		}
+		if w.full() {
+			s.remove(w.values[w.pos])
+		}
+		w.push(v)
+	}
+	d := v - s.shift
+	s.sum += d
+	s.sumSq += d * d
+	if s.pushes++; s.pushes >= n {
+		s.rebase()
+	}
+	return s.Value()
+}
+
+func (s *BollingerStream) remove(v float64) {
+	d := v - s.shift
+	s.sum -= d
+	s.sumSq -= d * d
+}
+
+// rebase recomputes the sums around the current mean
+func (s *BollingerStream) rebase() {
+	w := &s.window
+	s.shift += s.sum / float64(w.count)
+	s.sum, s.sumSq, s.pushes = 0, 0, 0
+	for i := 0; i < w.count; i++ {
+		d := w.values[i] - s.shift
+		s.sum += d
+		s.sumSq += d * d
+	}
+}
+
+func (s *BollingerStream) Value() (upper, middle, lower float64) {
+	if !s.window.full() {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+	n := float64(len(s.window.values))
+	mean := s.sum / n
+	std := math.Sqrt(math.Max(s.sumSq/n-mean*mean, 0))
+	middle = s.shift + mean
+	return middle + s.k*std, middle, middle - s.k*std
+}
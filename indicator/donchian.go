@@ -0,0 +1,86 @@
+package indicator
+
+import "math"
+
+// Donchian returns the highest high and lowest low of last n periods, current bar included
+func Donchian(high, low []float64, n int) (upper, lower []float64) {
+	upper, lower = nans(len(high)), nans(len(low))
+	s := NewDonchianStream(n)
+	for i := range high {
+		upper[i], lower[i] = s.Update(int64(i), high[i], low[i])
+	}
+	return
+}
+
+// DonchianStream keeps monotonic queues of the indexes before the last bar in window, and the last bar apart,
+// so the update is amortized O(1), and the revision of the last bar is O(1).
+type DonchianStream struct {
+	stamp
+	n     int
+	index int // index of last bar, from 0
+	highs []float64
+	lows  []float64
+	maxQ  []int // indexes with decreasing highs, the last bar excluded
+	minQ  []int // indexes with increasing lows, the last bar excluded
+}
+
+// NewDonchianStream creates the stream, n <= 0 always gives NaN
+func NewDonchianStream(n int) *DonchianStream {
+	if n < 0 {
+		n = 0
+	}
+	return &DonchianStream{
+		n:     n,
+		index: -1,
+		highs: make([]float64, n),
+		lows:  make([]float64, n),
+	}
+}
+
+func (s *DonchianStream) Update(timestamp int64, high, low float64) (upper, lower float64) {
+	if s.n <= 0 {
+		return math.NaN(), math.NaN()
+	}
+	if s.replace(timestamp) && s.index >= 0 {
+		s.highs[s.index%s.n], s.lows[s.index%s.n] = high, low
+		return s.Value()
+	}
+	// the last bar is final now
+	if s.index >= 0 {
+		s.push(s.index)
+	}
+	s.index++
+	s.highs[s.index%s.n], s.lows[s.index%s.n] = high, low
+	for len(s.maxQ) > 0 && s.maxQ[0] <= s.index-s.n {
+		s.maxQ = s.maxQ[1:]
+	}
+	for len(s.minQ) > 0 && s.minQ[0] <= s.index-s.n {
+		s.minQ = s.minQ[1:]
+	}
+	return s.Value()
+}
+
+func (s *DonchianStream) push(i int) {
+	for len(s.maxQ) > 0 && s.highs[s.maxQ[len(s.maxQ)-1]%s.n] <= s.highs[i%s.n] {
+		s.maxQ = s.maxQ[:len(s.maxQ)-1]
+	}
+	s.maxQ = append(s.maxQ, i)
+	for len(s.minQ) > 0 && s.lows[s.minQ[len(s.minQ)-1]%s.n] >= s.lows[i%s.n] {
+		s.minQ = s.minQ[:len(s.minQ)-1]
+	}
+	s.minQ = append(s.minQ, i)
+}
+
+func (s *DonchianStream) Value() (upper, lower float64) {
+	if s.n <= 0 || s.index < s.n-1 {
+		return math.NaN(), math.NaN()
+	}
+	upper, lower = s.highs[s.index%s.n], s.lows[s.index%s.n]
+	if len(s.maxQ) > 0 {
+		upper = math.Max(upper, s.highs[s.maxQ[0]%s.n])
+	}
+	if len(s.minQ) > 0 {
+		lower = math.Min(lower, s.lows[s.minQ[0]%s.n])
+	}
+	return
+}
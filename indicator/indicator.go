@@ -0,0 +1,83 @@
+// Package indicator computes technical indicators over the slices of hs.Candle.
+//
+// Batch functions return a slice as long as the input, values before the indicator is ready are NaN.
+// Stream types update in O(1) on each bar, except the standard deviation of BollingerStream.
+// Like hs.Candle.Append, an update with the same timestamp as the last one replaces the last bar instead of adding a new one.
+package indicator
+
+import "math"
+
+// stamp remembers the timestamp of last update
+type stamp struct {
+	timestamp int64
+	set       bool
+}
+
+// replace reports whether timestamp is the same bar of last update, and records it
+func (s *stamp) replace(timestamp int64) bool {
+	r := s.set && s.timestamp == timestamp
+	s.timestamp, s.set = timestamp, true
+	return r
+}
+
+func nans(n int) []float64 {
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = math.NaN()
+	}
+	return result
+}
+
+// Last returns the last value of values, NaN if empty
+func Last(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	return values[len(values)-1]
+}
+
+// window is a ring buffer of the last n values, with running sum.
+// The window of n <= 0 is never full, so the indicator is always NaN.
+type window struct {
+	values []float64
+	pos    int
+	count  int
+	sum    float64
+}
+
+func newWindow(n int) window {
+	if n < 0 {
+		n = 0
+	}
+	return window{values: make([]float64, n)}
+}
+
+func (w *window) push(v float64) {
+	n := len(w.values)
+	if n == 0 {
+		return
+	}
+	if w.count == n {
+		w.sum -= w.values[w.pos]
+	} else {
+		w.count++
+	}
+	w.values[w.pos] = v
+	w.sum += v
+	w.pos = (w.pos + 1) % n
+}
+
+// replace the newest value
+func (w *window) replace(v float64) {
+	if w.count == 0 {
+		w.push(v)
+		return
+	}
+	i := (w.pos - 1 + len(w.values)) % len(w.values)
+	w.sum += v - w.values[i]
+	w.values[i] = v
+}
+
+func (w *window) full() bool {
+	return len(w.values) > 0 && w.count == len(w.values)
+}
@@ -0,0 +1,170 @@
+package indicator
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const epsilon = 1e-4
+
+func equal(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) < epsilon
+}
+
+func check(t *testing.T, name string, expect, got []float64) {
+	t.Helper()
+	if len(expect) != len(got) {
+		t.Fatalf("%s length expect %d, got %d", name, len(expect), len(got))
+	}
+	for i := range expect {
+		if !equal(expect[i], got[i]) {
+			t.Errorf("%s[%d] expect %f, got %f", name, i, expect[i], got[i])
+		}
+	}
+}
+
+var nan = math.NaN()
+
+func TestSMA(t *testing.T) {
+	check(t, "SMA", []float64{nan, nan, 2, 3, 4}, SMA([]float64{1, 2, 3, 4, 5}, 3))
+}
+
+func TestEMA(t *testing.T) {
+	// example from stockcharts.com
+	prices := []float64{22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23, 22.43, 22.24, 22.29, 22.15, 22.39, 22.38, 22.61}
+	got := EMA(prices, 10)
+	check(t, "EMA", []float64{22.221, 22.2081, 22.2412, 22.2664, 22.3289}, got[9:])
+	for i := 0; i < 9; i++ {
+		if !math.IsNaN(got[i]) {
+			t.Errorf("EMA[%d] expect NaN, got %f", i, got[i])
+		}
+	}
+}
+
+func TestRSI(t *testing.T) {
+	// example from stockcharts.com
+	closes := []float64{44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08, 45.89, 46.03, 45.61, 46.28,
+		46.28, 46.00, 46.03, 46.41, 46.22, 45.64}
+	got := RSI(closes, 14)
+	check(t, "RSI", []float64{70.4641, 66.2496, 66.4809, 69.3469, 66.2947, 57.915}, got[14:])
+	check(t, "RSI up", []float64{nan, nan, 100, 100}, RSI([]float64{1, 2, 3, 4}, 2))
+}
+
+func TestATR(t *testing.T) {
+	high := []float64{10, 11, 12, 11, 13}
+	low := []float64{8, 9, 10, 9, 10}
+	closes := []float64{9, 10, 11, 10, 12}
+	check(t, "ATR", []float64{nan, nan, 2, 2, 2.3333}, ATR(high, low, closes, 3))
+}
+
+func TestBollinger(t *testing.T) {
+	upper, middle, lower := Bollinger([]float64{1, 2, 3, 4, 5}, 3, 2)
+	check(t, "middle", []float64{nan, nan, 2, 3, 4}, middle)
+	check(t, "upper", []float64{nan, nan, 2 + 2*0.8165, 3 + 2*0.8165, 4 + 2*0.8165}, upper)
+	check(t, "lower", []float64{nan, nan, 2 - 2*0.8165, 3 - 2*0.8165, 4 - 2*0.8165}, lower)
+}
+
+func TestDonchian(t *testing.T) {
+	upper, lower := Donchian([]float64{3, 5, 4, 2, 1, 6}, []float64{2, 4, 1, 1, 0, 5}, 3)
+	check(t, "upper", []float64{nan, nan, 5, 5, 4, 6}, upper)
+	check(t, "lower", []float64{nan, nan, 1, 1, 0, 0}, lower)
+}
+
+func TestMACD(t *testing.T) {
+	values := make([]float64, 50)
+	for i := range values {
+		values[i] = float64(i)
+	}
+	macd, signal, hist := MACD(values, 12, 26, 9)
+	if !math.IsNaN(macd[24]) || math.IsNaN(macd[25]) || !math.IsNaN(signal[32]) || math.IsNaN(signal[33]) {
+		t.Errorf("MACD warm up is wrong")
+	}
+	// for a linear series, EMA lags (n-1)/2, so macd is (26-12)/2
+	check(t, "macd", []float64{7, 7}, macd[48:])
+	check(t, "hist", []float64{0, 0}, hist[48:])
+}
+
+// streams must give the same values as batch functions, even with revisions of the last bar
+func TestStream(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var closes, highs, lows []float64
+	sma, ema, rsi, atr := NewSMAStream(5), NewEMAStream(5), NewRSIStream(5), NewATRStream(5)
+	macd, bb, dc := NewMACDStream(3, 6, 4), NewBollingerStream(5, 2), NewDonchianStream(5)
+	for i := 0; i < 100; i++ {
+		var c, h, l float64
+		// revise each bar several times
+		for j := 0; j < 3; j++ {
+			c = 100 + r.Float64()*10
+			h, l = c+r.Float64(), c-r.Float64()
+			ts := int64(i * 60)
+			sma.Update(ts, c)
+			ema.Update(ts, c)
+			rsi.Update(ts, c)
+			atr.Update(ts, h, l, c)
+			macd.Update(ts, c)
+			bb.Update(ts, c)
+			dc.Update(ts, h, l)
+		}
+		closes, highs, lows = append(closes, c), append(highs, h), append(lows, l)
+		check(t, "SMA stream", []float64{Last(SMA(closes, 5))}, []float64{sma.Value()})
+		check(t, "EMA stream", []float64{Last(EMA(closes, 5))}, []float64{ema.Value()})
+		check(t, "RSI stream", []float64{Last(RSI(closes, 5))}, []float64{rsi.Value()})
+		check(t, "ATR stream", []float64{Last(ATR(highs, lows, closes, 5))}, []float64{atr.Value()})
+		m, s, _ := MACD(closes, 3, 6, 4)
+		gm, gs, _ := macd.Value()
+		if i >= 8 {
+			check(t, "MACD stream", []float64{Last(m), Last(s)}, []float64{gm, gs})
+		}
+		u, mid, lo := Bollinger(closes, 5, 2)
+		gu, gmid, glo := bb.Value()
+		check(t, "Bollinger stream", []float64{Last(u), Last(mid), Last(lo)}, []float64{gu, gmid, glo})
+		du, dl := Donchian(highs, lows, 5)
+		gdu, gdl := dc.Value()
+		check(t, "Donchian stream", []float64{Last(du), Last(dl)}, []float64{gdu, gdl})
+	}
+}
+
+func TestBadPeriod(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		sma, bb, dc := NewSMAStream(n), NewBollingerStream(n, 2), NewDonchianStream(n)
+		for i := int64(0); i < 3; i++ {
+			check(t, "SMA stream", []float64{nan}, []float64{sma.Update(i, 1)})
+			u, m, l := bb.Update(i, 1)
+			check(t, "Bollinger stream", []float64{nan, nan, nan}, []float64{u, m, l})
+			du, dl := dc.Update(i, 2, 1)
+			check(t, "Donchian stream", []float64{nan, nan}, []float64{du, dl})
+		}
+		u, _, _ := Bollinger([]float64{1, 2, 3}, n, 2)
+		check(t, "Bollinger", []float64{nan, nan, nan}, u)
+	}
+}
+
+// the variance of high price with small changes, the plain running sum of squares without shift gives 0 or garbage
+func TestBollingerStream_Precision(t *testing.T) {
+	values := []float64{1e9 + 0.1, 1e9 + 0.2, 1e9 + 0.3, 1e9 + 0.4, 1e9 + 0.5, 1e9 + 0.6}
+	bb := NewBollingerStream(5, 2)
+	for i, v := range values {
+		bb.Update(int64(i), v)
+	}
+	u, m, l := Bollinger(values, 5, 2)
+	gu, gm, gl := bb.Value()
+	check(t, "Bollinger stream", []float64{Last(u), Last(m), Last(l)}, []float64{gu, gm, gl})
+	check(t, "std", []float64{0.1414}, []float64{(gu - gm) / 2})
+
+	// the running sums don't drift in a long stream with revisions
+	values = values[:0]
+	bb = NewBollingerStream(5, 2)
+	for i := 0; i < 10000; i++ {
+		v := 1e9 + float64(i%7)*0.1 + float64(i)
+		bb.Update(int64(i), v+0.05)
+		bb.Update(int64(i), v)
+		values = append(values, v)
+	}
+	u, m, l = Bollinger(values, 5, 2)
+	gu, gm, gl = bb.Value()
+	check(t, "long Bollinger stream", []float64{Last(u), Last(m), Last(l)}, []float64{gu, gm, gl})
+}
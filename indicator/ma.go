@@ -0,0 +1,102 @@
+package indicator
+
+import "math"
+
+// SMA is the simple moving average of n periods
+func SMA(values []float64, n int) []float64 {
+	result := nans(len(values))
+	if n <= 0 {
+		return result
+	}
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if i >= n {
+			sum -= values[i-n]
+		}
+		if i >= n-1 {
+			result[i] = sum / float64(n)
+		}
+	}
+	return result
+}
+
+// EMA is the exponential moving average of n periods, seeded with the SMA of first n values
+func EMA(values []float64, n int) []float64 {
+	result := nans(len(values))
+	s := NewEMAStream(n)
+	for i, v := range values {
+		result[i] = s.Update(int64(i), v)
+	}
+	return result
+}
+
+type SMAStream struct {
+	stamp
+	window window
+}
+
+func NewSMAStream(n int) *SMAStream {
+	return &SMAStream{window: newWindow(n)}
+}
+
+func (s *SMAStream) Update(timestamp int64, v float64) float64 {
+	if s.replace(timestamp) {
+		s.window.replace(v)
+	} else {
+		s.window.push(v)
+	}
+	return s.Value()
+}
+
+func (s *SMAStream) Value() float64 {
+	if !s.window.full() {
+		return math.NaN()
+	}
+	return s.window.sum / float64(len(s.window.values))
+}
+
+type emaState struct {
+	count int
+	sum   float64 // sum of first n values, for seed
+	value float64
+}
+
+type EMAStream struct {
+	stamp
+	n     int
+	alpha float64
+	state emaState
+	prev  emaState // state before last update
+}
+
+func NewEMAStream(n int) *EMAStream {
+	return &EMAStream{n: n, alpha: 2 / float64(n+1)}
+}
+
+func (s *EMAStream) Update(timestamp int64, v float64) float64 {
+	if s.replace(timestamp) {
+		s.state = s.prev
+	} else {
+		s.prev = s.state
+	}
+	st := &s.state
+	st.count++
+	switch {
+	case st.count < s.n:
+		st.sum += v
+	case st.count == s.n:
+		st.sum += v
+		st.value = st.sum / float64(s.n)
+	default:
+		st.value += s.alpha * (v - st.value)
+	}
+	return s.Value()
+}
+
+func (s *EMAStream) Value() float64 {
+	if s.n <= 0 || s.state.count < s.n {
+		return math.NaN()
+	}
+	return s.state.value
+}
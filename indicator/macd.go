@@ -0,0 +1,42 @@
+package indicator
+
+import "math"
+
+// MACD returns the macd line (EMA fast - EMA slow), its EMA signal line, and the histogram (macd - signal)
+func MACD(values []float64, fast, slow, signal int) (macd, sig, hist []float64) {
+	macd, sig, hist = nans(len(values)), nans(len(values)), nans(len(values))
+	s := NewMACDStream(fast, slow, signal)
+	for i, v := range values {
+		macd[i], sig[i], hist[i] = s.Update(int64(i), v)
+	}
+	return
+}
+
+type MACDStream struct {
+	fast   *EMAStream
+	slow   *EMAStream
+	signal *EMAStream
+}
+
+func NewMACDStream(fast, slow, signal int) *MACDStream {
+	return &MACDStream{
+		fast:   NewEMAStream(fast),
+		slow:   NewEMAStream(slow),
+		signal: NewEMAStream(signal),
+	}
+}
+
+func (s *MACDStream) Update(timestamp int64, v float64) (macd, signal, hist float64) {
+	macd = s.fast.Update(timestamp, v) - s.slow.Update(timestamp, v)
+	if math.IsNaN(macd) {
+		return macd, math.NaN(), math.NaN()
+	}
+	signal = s.signal.Update(timestamp, macd)
+	return macd, signal, macd - signal
+}
+
+func (s *MACDStream) Value() (macd, signal, hist float64) {
+	macd = s.fast.Value() - s.slow.Value()
+	signal = s.signal.Value()
+	return macd, signal, macd - signal
+}
@@ -0,0 +1,66 @@
+package indicator
+
+import "math"
+
+// RSI is the relative strength index of n periods, with Wilder's smoothing
+func RSI(values []float64, n int) []float64 {
+	result := nans(len(values))
+	s := NewRSIStream(n)
+	for i, v := range values {
+		result[i] = s.Update(int64(i), v)
+	}
+	return result
+}
+
+type rsiState struct {
+	count   int
+	last    float64 // last close
+	avgGain float64
+	avgLoss float64
+}
+
+type RSIStream struct {
+	stamp
+	n     int
+	state rsiState
+	prev  rsiState
+}
+
+func NewRSIStream(n int) *RSIStream {
+	return &RSIStream{n: n}
+}
+
+func (s *RSIStream) Update(timestamp int64, v float64) float64 {
+	if s.replace(timestamp) {
+		s.state = s.prev
+	} else {
+		s.prev = s.state
+	}
+	st := &s.state
+	st.count++
+	if st.count > 1 {
+		change := v - st.last
+		gain, loss := math.Max(change, 0), math.Max(-change, 0)
+		n := float64(s.n)
+		if st.count <= s.n+1 {
+			// the first average is simple mean
+			st.avgGain += gain / n
+			st.avgLoss += loss / n
+		} else {
+			st.avgGain = (st.avgGain*(n-1) + gain) / n
+			st.avgLoss = (st.avgLoss*(n-1) + loss) / n
+		}
+	}
+	st.last = v
+	return s.Value()
+}
+
+func (s *RSIStream) Value() float64 {
+	if s.n <= 0 || s.state.count <= s.n {
+		return math.NaN()
+	}
+	if s.state.avgLoss == 0 {
+		return 100
+	}
+	return 100 - 100/(1+s.state.avgGain/s.state.avgLoss)
+}
@@ -0,0 +1,453 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBadGroup       = errors.New("group needs id, side, amount, limit price and stop price")
+	ErrDuplicateGroup = errors.New("group id exists")
+	ErrGroupNotFound  = errors.New("group not found")
+)
+
+const (
+	GroupOpen      = "open"
+	GroupFilled    = "filled"    // one leg full filled, the other is cancelled
+	GroupCancelled = "cancelled" // cancelled by Cancel, or any leg is cancelled outside
+)
+
+// Leg is one order of OCO group, it's replaced by a new order when resized.
+type Leg struct {
+	OrderId   uint64          `json:"orderId"`
+	Price     decimal.Decimal `json:"price"`
+	StopPrice decimal.Decimal `json:"stopPrice"` // stop leg only
+	// Amount and Filled are of the current order
+	Amount decimal.Decimal      `json:"amount"`
+	Filled decimal.Decimal      `json:"filled"`
+	State  exchange.OrderStatus `json:"state"`
+	// Done is the filled amount of the replaced orders
+	Done decimal.Decimal `json:"done"`
+}
+
+func (l Leg) finished() bool {
+	switch l.State {
+	case exchange.Closed, exchange.Cancelled, exchange.PartialCancelled, exchange.Rejected:
+		return true
+	default:
+		return false
+	}
+}
+
+// Group is an OCO (one-cancels-other) group, a limit order and a stop-limit order of the same side and amount.
+// For sell, the limit is the take-profit above the price, and the stop-limit is the stop-loss below.
+// For buy, the limit is below the price, and the stop-limit buys the breakout above.
+type Group struct {
+	Id     string             `json:"id"`
+	Symbol exchange.Symbol    `json:"symbol"`
+	Side   exchange.OrderType `json:"side"`
+	Amount decimal.Decimal    `json:"amount"`
+	Limit  Leg                `json:"limit"`
+	Stop   Leg                `json:"stop"`
+	Status string             `json:"status"`
+	// Closing is the status after the legs are cancelled, the group is kept open until the cancel is confirmed
+	Closing string    `json:"closing,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Filled returns the filled amount of both legs
+func (g Group) Filled() decimal.Decimal {
+	return g.Limit.Done.Add(g.Limit.Filled).Add(g.Stop.Done).Add(g.Stop.Filled)
+}
+
+// GroupHandler is called on every change of group, include the fill, resize and finish
+type GroupHandler func(group Group)
+
+// OCO places the OCO groups on any exchange, by BuyLimit/SellLimit and BuyStopLimit/SellStopLimit,
+// so huobi uses the native stop-limit order. The stop-limit order id should be queryable by GetOrderById.
+//
+// When one leg is full filled, the other is cancelled. When one leg is part filled,
+// the other is replaced by a new order of the open amount, so the groups never sells more than Amount.
+// The fills are watched by OnOrderUpdate or Poll, the finished group is removed after the handler called.
+// If the other leg can't be cancelled or queried, the group is kept open with Closing, and retried by Poll.
+type OCO struct {
+	ex      exchange.RestAPIExchange
+	store   hs.KeyStore
+	key     string
+	handler GroupHandler
+	Sugar   *zap.SugaredLogger
+
+	mu    sync.Mutex
+	state State
+
+	// the order updates are handled one by one in time order
+	queueMu  sync.Mutex
+	queue    []exchange.Order
+	draining bool
+}
+
+// NewOCO creates the OCO manager, call Load to resume the saved groups.
+// The key should be different from the stop manager if they share the same store.
+func NewOCO(ex exchange.RestAPIExchange, store hs.KeyStore, key string, handler GroupHandler, logger *zap.SugaredLogger) *OCO {
+	return &OCO{
+		ex:      ex,
+		store:   store,
+		key:     key,
+		handler: handler,
+		Sugar:   logger,
+	}
+}
+
+// Load resumes the saved groups, call Poll after that to catch up the fills during restart.
+func (m *OCO) Load(ctx context.Context) error {
+	var state State
+	if err := m.store.Load(ctx, m.key, &state); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = state
+	return nil
+}
+
+// Groups returns a copy of the open groups
+func (m *OCO) Groups() []Group {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Group(nil), m.state.Groups...)
+}
+
+// Group returns the open group by id
+func (m *OCO) Group(id string) (Group, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i := m.find(id); i >= 0 {
+		return m.state.Groups[i], true
+	}
+	return Group{}, false
+}
+
+// Place places both legs of group, Id, Symbol, Side, Amount, Limit.Price, Stop.Price and Stop.StopPrice are required.
+// If the stop leg failed, the limit leg is cancelled, and the group is kept to retry the cancel if failed.
+// The placed group is tracked even if the save failed, which is only logged.
+func (m *OCO) Place(ctx context.Context, group Group) (Group, error) {
+	if group.Id == "" || group.Side != exchange.Buy && group.Side != exchange.Sell || !group.Amount.IsPositive() ||
+		!group.Limit.Price.IsPositive() || !group.Stop.Price.IsPositive() || !group.Stop.StopPrice.IsPositive() {
+		return group, ErrBadGroup
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.find(group.Id) >= 0 {
+		return group, ErrDuplicateGroup
+	}
+	g := group
+	g.Amount = g.Amount.Truncate(g.Symbol.AmountPrecision)
+	g.Status = GroupOpen
+	g.Time = time.Now()
+	g.Limit = Leg{Price: group.Limit.Price}
+	g.Stop = Leg{Price: group.Stop.Price, StopPrice: group.Stop.StopPrice}
+	if err := m.place(&g, &g.Limit, g.Amount); err != nil {
+		return g, err
+	}
+	if err := m.place(&g, &g.Stop, g.Amount); err != nil {
+		if !m.close(&g, GroupCancelled) {
+			m.state.Groups = append(m.state.Groups, g)
+			m.flush(ctx, []int{len(m.state.Groups) - 1})
+		}
+		return g, err
+	}
+	// the legs are placed, keep tracking them even if not saved
+	m.state.Groups = append(m.state.Groups, g)
+	if err := m.store.Save(ctx, m.key, m.state); err != nil {
+		m.Sugar.Errorf("save oco groups error: %s", err)
+	}
+	m.Sugar.Infof("oco %s placed, limit order %d at %s, stop order %d at %s", g.Id, g.Limit.OrderId, g.Limit.Price, g.Stop.OrderId, g.Stop.StopPrice)
+	return g, nil
+}
+
+// Cancel cancels both legs of the open group, the group is kept with Closing if any leg is not cancelled yet.
+func (m *OCO) Cancel(ctx context.Context, id string) (Group, error) {
+	m.mu.Lock()
+	i := m.find(id)
+	if i < 0 {
+		m.mu.Unlock()
+		return Group{}, ErrGroupNotFound
+	}
+	g := &m.state.Groups[i]
+	m.close(g, GroupCancelled)
+	changed := m.flush(ctx, []int{i})
+	m.mu.Unlock()
+
+	m.notify(changed)
+	return changed[0], nil
+}
+
+// OnOrderUpdate is a handler for exchange.WsAPIExchange.SubscribeOrder, the response should be an exchange.Order.
+// The updates are queued and handled in another goroutine one by one,
+// for the handler may be called inside the order placement.
+func (m *OCO) OnOrderUpdate(response interface{}) {
+	o, ok := response.(exchange.Order)
+	if !ok {
+		return
+	}
+	m.queueMu.Lock()
+	m.queue = append(m.queue, o)
+	if m.draining {
+		m.queueMu.Unlock()
+		return
+	}
+	m.draining = true
+	m.queueMu.Unlock()
+	go m.drain()
+}
+
+// drain handles the queued updates until empty
+func (m *OCO) drain() {
+	for {
+		m.queueMu.Lock()
+		if len(m.queue) == 0 {
+			m.draining = false
+			m.queueMu.Unlock()
+			return
+		}
+		o := m.queue[0]
+		m.queue = m.queue[1:]
+		m.queueMu.Unlock()
+		m.onOrder(o)
+	}
+}
+
+func (m *OCO) onOrder(o exchange.Order) {
+	m.mu.Lock()
+	var updated []int
+	for i := range m.state.Groups {
+		g := &m.state.Groups[i]
+		if m.apply(g, &g.Limit, &g.Stop, o) || m.apply(g, &g.Stop, &g.Limit, o) {
+			updated = append(updated, i)
+		}
+	}
+	changed := m.flush(context.Background(), updated)
+	m.mu.Unlock()
+
+	m.notify(changed)
+}
+
+// Poll checks the legs of all open groups by GetOrderById, and retries the cancel of closing groups
+func (m *OCO) Poll(ctx context.Context) error {
+	m.mu.Lock()
+	var updated []int
+	var err error
+	for i := range m.state.Groups {
+		g := &m.state.Groups[i]
+		if g.Closing != "" {
+			if m.close(g, g.Closing) {
+				updated = append(updated, i)
+			}
+			continue
+		}
+		changed := false
+		for _, legs := range [][2]*Leg{{&g.Limit, &g.Stop}, {&g.Stop, &g.Limit}} {
+			leg := legs[0]
+			if g.Status != GroupOpen || leg.OrderId == 0 || leg.finished() {
+				continue
+			}
+			o, e := m.ex.GetOrderById(leg.OrderId, g.Symbol.Symbol)
+			if e != nil {
+				err = e
+				continue
+			}
+			if m.apply(g, leg, legs[1], o) {
+				changed = true
+			}
+		}
+		if changed {
+			updated = append(updated, i)
+		}
+	}
+	changed := m.flush(ctx, updated)
+	m.mu.Unlock()
+
+	m.notify(changed)
+	return err
+}
+
+// Run polls every interval until ctx done
+func (m *OCO) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := m.Poll(ctx); err != nil {
+				m.Sugar.Errorf("oco poll error: %s", err)
+			}
+		}
+	}
+}
+
+// apply updates the leg by its order, and cancels or resizes the other leg
+func (m *OCO) apply(g *Group, leg, other *Leg, o exchange.Order) bool {
+	if g.Status != GroupOpen || leg.OrderId == 0 || o.Id != leg.OrderId {
+		return false
+	}
+	newFill := o.FilledAmount.GreaterThan(leg.Filled)
+	if !newFill && o.State == leg.State {
+		return false
+	}
+	if newFill {
+		leg.Filled = o.FilledAmount
+	}
+	leg.State = o.State
+	switch {
+	case g.Closing != "":
+		m.close(g, g.Closing)
+	case o.FullFilled() || g.Filled().GreaterThanOrEqual(g.Amount):
+		m.close(g, GroupFilled)
+	case o.Finished():
+		m.close(g, GroupCancelled)
+	case newFill:
+		m.resize(g, leg, other)
+	}
+	return true
+}
+
+// close cancels the open legs, the group is finished with status if both legs are finished,
+// otherwise it's kept open with Closing. It returns true if finished.
+func (m *OCO) close(g *Group, status string) bool {
+	g.Closing = status
+	limit := m.cancel(g, &g.Limit)
+	stop := m.cancel(g, &g.Stop)
+	if !limit || !stop {
+		m.Sugar.Infof("oco %s: the legs are not cancelled yet, closing as %s", g.Id, status)
+		return false
+	}
+	if g.Filled().GreaterThanOrEqual(g.Amount) {
+		status = GroupFilled
+	}
+	g.Status = status
+	g.Closing = ""
+	return true
+}
+
+// resize replaces the other leg by the open amount of the part filled leg.
+// The other leg is kept if it's not cancelled yet, and resized at the next fill.
+func (m *OCO) resize(g *Group, leg, other *Leg) {
+	if !m.cancel(g, other) {
+		return
+	}
+	if g.Filled().GreaterThanOrEqual(g.Amount) {
+		// the other leg is filled before cancelled
+		m.close(g, GroupFilled)
+		return
+	}
+	amount := leg.Amount.Sub(leg.Filled)
+	if left := g.Amount.Sub(g.Filled()); left.LessThan(amount) {
+		amount = left
+	}
+	amount = amount.Truncate(g.Symbol.AmountPrecision)
+	other.Done = other.Done.Add(other.Filled)
+	other.OrderId = 0
+	other.Amount = decimal.Zero
+	other.Filled = decimal.Zero
+	other.State = exchange.Cancelled
+	if amount.LessThan(g.Symbol.LimitOrderMinAmount) || !amount.IsPositive() {
+		m.Sugar.Infof("oco %s: the open amount %s is too small, leg not replaced", g.Id, amount)
+		return
+	}
+	if err := m.place(g, other, amount); err != nil {
+		m.Sugar.Errorf("oco %s: replace leg with amount %s error: %s", g.Id, amount, err)
+		return
+	}
+	m.Sugar.Infof("oco %s: leg replaced by order %d with amount %s", g.Id, other.OrderId, amount)
+}
+
+func (m *OCO) place(g *Group, leg *Leg, amount decimal.Decimal) (err error) {
+	symbol := g.Symbol.Symbol
+	switch {
+	case g.Side == exchange.Buy && leg.StopPrice.IsPositive():
+		leg.OrderId, err = m.ex.BuyStopLimit(symbol, "", leg.Price, amount, leg.StopPrice)
+	case g.Side == exchange.Buy:
+		leg.OrderId, err = m.ex.BuyLimit(symbol, "", leg.Price, amount)
+	case leg.StopPrice.IsPositive():
+		leg.OrderId, err = m.ex.SellStopLimit(symbol, "", leg.Price, amount, leg.StopPrice)
+	default:
+		leg.OrderId, err = m.ex.SellLimit(symbol, "", leg.Price, amount)
+	}
+	if err != nil {
+		leg.OrderId = 0
+		return err
+	}
+	leg.Amount = amount
+	leg.Filled = decimal.Zero
+	leg.State = exchange.Open
+	return nil
+}
+
+// cancel cancels the open leg, and gets the amount filled before cancelled.
+// It returns true if the leg is finished.
+func (m *OCO) cancel(g *Group, leg *Leg) bool {
+	if leg.OrderId == 0 || leg.finished() {
+		return true
+	}
+	if err := m.ex.CancelOrder(g.Symbol.Symbol, leg.OrderId); err != nil {
+		m.Sugar.Errorf("oco %s: cancel order %d error: %s", g.Id, leg.OrderId, err)
+	}
+	o, err := m.ex.GetOrderById(leg.OrderId, g.Symbol.Symbol)
+	if err != nil {
+		m.Sugar.Errorf("oco %s: get order %d error: %s", g.Id, leg.OrderId, err)
+		return false
+	}
+	leg.Filled = o.FilledAmount
+	leg.State = o.State
+	return leg.finished()
+}
+
+// flush saves the state, returns the copy of updated groups, the finished groups are removed.
+func (m *OCO) flush(ctx context.Context, updated []int) []Group {
+	if len(updated) == 0 {
+		return nil
+	}
+	var changed []Group
+	for _, i := range updated {
+		changed = append(changed, m.state.Groups[i])
+	}
+	var open []Group
+	for _, g := range m.state.Groups {
+		if g.Status == GroupOpen {
+			open = append(open, g)
+		} else {
+			m.Sugar.Infof("oco %s %s, filled %s", g.Id, g.Status, g.Filled())
+		}
+	}
+	m.state.Groups = open
+	if err := m.store.Save(ctx, m.key, m.state); err != nil {
+		m.Sugar.Errorf("save oco groups error: %s", err)
+	}
+	return changed
+}
+
+func (m *OCO) notify(groups []Group) {
+	if m.handler == nil {
+		return
+	}
+	for _, g := range groups {
+		m.handler(g)
+	}
+}
+
+func (m *OCO) find(id string) int {
+	for i, g := range m.state.Groups {
+		if g.Id == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,192 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/paper"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func sellGroup(id string) Group {
+	return Group{
+		Id:     id,
+		Symbol: btcUsdt,
+		Side:   exchange.Sell,
+		Amount: dec(1),
+		Limit:  Leg{Price: dec(110)},
+		Stop:   Leg{Price: dec(89), StopPrice: dec(90)},
+	}
+}
+
+func TestOCO_Filled(t *testing.T) {
+	ctx := context.Background()
+	ex := newPaper(100)
+	var groups []Group
+	m := NewOCO(ex, hs.NewMemoryKeyStore(), "oco", func(g Group) { groups = append(groups, g) }, zap.NewNop().Sugar())
+	_, err := m.Place(ctx, Group{Id: "a", Symbol: btcUsdt, Amount: dec(1)})
+	require.Equal(t, ErrBadGroup, err)
+	g, err := m.Place(ctx, sellGroup("a"))
+	require.NoError(t, err)
+	require.Equal(t, GroupOpen, g.Status)
+	require.Len(t, ex.OpenOrders("btc_usdt"), 2)
+	_, err = m.Place(ctx, sellGroup("a"))
+	require.Equal(t, ErrDuplicateGroup, err)
+
+	ex.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 120, Open: 100, High: 111, Low: 100, Close: 108})
+	require.NoError(t, m.Poll(ctx))
+	require.Len(t, groups, 1)
+	require.Equal(t, GroupFilled, groups[0].Status)
+	require.Equal(t, exchange.Closed, groups[0].Limit.State)
+	require.Equal(t, exchange.Cancelled, groups[0].Stop.State)
+	require.True(t, dec(1).Equal(groups[0].Filled()))
+	require.Empty(t, ex.OpenOrders("btc_usdt"))
+	require.Empty(t, m.Groups())
+}
+
+func TestOCO_PartialFilled(t *testing.T) {
+	ctx := context.Background()
+	ex := newPaper(100)
+	store := hs.NewMemoryKeyStore()
+	var groups []Group
+	handler := func(g Group) { groups = append(groups, g) }
+	m := NewOCO(ex, store, "oco", handler, zap.NewNop().Sugar())
+	_, err := m.Place(ctx, sellGroup("a"))
+	require.NoError(t, err)
+
+	// 0.4 of take-profit filled, the stop-loss is resized to 0.6
+	ex.UpdateTrades("btc_usdt", []exchange.TradeDetail{{Id: 1, Price: dec(110), Amount: dec(0.4), Timestamp: 120000}})
+	m = NewOCO(ex, store, "oco", handler, zap.NewNop().Sugar())
+	require.NoError(t, m.Load(ctx))
+	require.NoError(t, m.Poll(ctx))
+	g, ok := m.Group("a")
+	require.True(t, ok)
+	require.Equal(t, GroupOpen, g.Status)
+	require.Equal(t, exchange.Filled, g.Limit.State)
+	require.True(t, dec(0.6).Equal(g.Stop.Amount))
+	stop, err := ex.GetOrderById(g.Stop.OrderId, "btc_usdt")
+	require.NoError(t, err)
+	require.True(t, dec(0.6).Equal(stop.Amount))
+	require.Len(t, ex.OpenOrders("btc_usdt"), 2)
+
+	// the stop-loss is triggered and filled, the rest of take-profit is cancelled
+	ex.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 180, Open: 100, High: 100, Low: 85, Close: 86})
+	require.NoError(t, m.Poll(ctx))
+	g = groups[len(groups)-1]
+	require.Equal(t, GroupFilled, g.Status)
+	require.Equal(t, exchange.PartialCancelled, g.Limit.State)
+	require.True(t, dec(1).Equal(g.Filled()))
+	require.Empty(t, ex.OpenOrders("btc_usdt"))
+
+	var state State
+	require.NoError(t, store.Load(ctx, "oco", &state))
+	require.Empty(t, state.Groups)
+}
+
+func TestOCO_Cancel(t *testing.T) {
+	ctx := context.Background()
+	ex := newPaper(100)
+	m := NewOCO(ex, hs.NewMemoryKeyStore(), "oco", nil, zap.NewNop().Sugar())
+	_, err := m.Place(ctx, sellGroup("a"))
+	require.NoError(t, err)
+	g, err := m.Cancel(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, GroupCancelled, g.Status)
+	require.Empty(t, ex.OpenOrders("btc_usdt"))
+	_, err = m.Cancel(ctx, "a")
+	require.Equal(t, ErrGroupNotFound, err)
+	requireBtc(t, ex, "10")
+}
+
+// flaky fails to cancel and query the orders when down
+type flaky struct {
+	*paper.Exchange
+	down bool
+}
+
+var errDown = errors.New("exchange is down")
+
+func (f *flaky) CancelOrder(symbol string, orderId uint64) error {
+	if f.down {
+		return errDown
+	}
+	return f.Exchange.CancelOrder(symbol, orderId)
+}
+
+func (f *flaky) GetOrderById(orderId uint64, symbol string) (exchange.Order, error) {
+	if f.down {
+		return exchange.Order{}, errDown
+	}
+	return f.Exchange.GetOrderById(orderId, symbol)
+}
+
+func TestOCO_CancelFailed(t *testing.T) {
+	ctx := context.Background()
+	ex := &flaky{Exchange: newPaper(100)}
+	store := hs.NewMemoryKeyStore()
+	m := NewOCO(ex, store, "oco", nil, zap.NewNop().Sugar())
+	g, err := m.Place(ctx, sellGroup("a"))
+	require.NoError(t, err)
+
+	// the take-profit is filled, but the stop-loss can't be cancelled
+	ex.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 120, Open: 100, High: 111, Low: 100, Close: 108})
+	o, err := ex.Exchange.GetOrderById(g.Limit.OrderId, "btc_usdt")
+	require.NoError(t, err)
+	ex.down = true
+	m.OnOrderUpdate(o)
+	require.Eventually(t, func() bool {
+		g, _ = m.Group("a")
+		return g.Closing == GroupFilled
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, GroupOpen, g.Status)
+	require.Len(t, ex.OpenOrders("btc_usdt"), 1)
+	var state State
+	require.NoError(t, store.Load(ctx, "oco", &state))
+	require.Len(t, state.Groups, 1)
+
+	// retried by poll
+	require.NoError(t, m.Poll(ctx))
+	require.Len(t, m.Groups(), 1)
+	ex.down = false
+	require.NoError(t, m.Poll(ctx))
+	require.Empty(t, m.Groups())
+	require.Empty(t, ex.OpenOrders("btc_usdt"))
+}
+
+// brokenStore fails to save
+type brokenStore struct {
+	hs.KeyStore
+}
+
+func (s brokenStore) Save(ctx context.Context, key string, value interface{}) error {
+	return errDown
+}
+
+func TestOCO_SaveFailed(t *testing.T) {
+	ctx := context.Background()
+	ex := newPaper(100)
+	var groups []Group
+	m := NewOCO(ex, brokenStore{hs.NewMemoryKeyStore()}, "oco", func(g Group) { groups = append(groups, g) }, zap.NewNop().Sugar())
+	g, err := m.Place(ctx, sellGroup("a"))
+	require.NoError(t, err)
+	require.Equal(t, GroupOpen, g.Status)
+	require.Len(t, m.Groups(), 1)
+
+	// the legs are still tracked
+	ex.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 120, Open: 100, High: 111, Low: 100, Close: 108})
+	require.NoError(t, m.Poll(ctx))
+	require.Len(t, groups, 1)
+	require.Equal(t, GroupFilled, groups[0].Status)
+	require.Empty(t, ex.OpenOrders("btc_usdt"))
+}
+
+func requireBtc(t *testing.T, ex *paper.Exchange, available string) {
+	balance, err := ex.SpotAvailableBalance()
+	require.NoError(t, err)
+	require.True(t, decimal.RequireFromString(available).Equal(balance["btc"]), balance["btc"].String())
+}
@@ -0,0 +1,389 @@
+// Package orders manages the client-side orders which exchanges don't support natively.
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/indicator"
+	"go.uber.org/zap"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBadStop       = errors.New("stop needs id, amount, and trailing distance or take-profit target")
+	ErrDuplicateStop = errors.New("stop id exists")
+	ErrStopNotFound  = errors.New("stop not found")
+)
+
+const (
+	ReasonTrailingStop = "trailing-stop"
+	ReasonTakeProfit   = "take-profit"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// Stop sells Amount of Symbol when the price falls the trailing distance from the high-water mark,
+// or rises to the take-profit Target, whichever comes first.
+// The trailing distance is Percent of High if Percent is set, otherwise ATRMultiple times ATR.
+type Stop struct {
+	Id            string          `json:"id"`
+	ClientOrderId string          `json:"clientOrderId"`
+	Symbol        exchange.Symbol `json:"symbol"`
+	Amount        decimal.Decimal `json:"amount"`
+
+	// Percent is the trailing distance in percent, 5 means 5% below the high
+	Percent decimal.Decimal `json:"percent"`
+	// ATRMultiple of the ATRLength periods ATR of ATRPeriod candle
+	ATRMultiple decimal.Decimal `json:"atrMultiple"`
+	ATRPeriod   time.Duration   `json:"atrPeriod"`
+	ATRLength   int             `json:"atrLength"`
+
+	// Target is the take-profit price, zero means no take-profit
+	Target decimal.Decimal `json:"target"`
+
+	// Slippage is the percent below the trigger price for limit order, zero means market order
+	Slippage decimal.Decimal `json:"slippage"`
+
+	// High is the high-water mark, the last price when added if not set
+	High decimal.Decimal `json:"high"`
+	// ATR is the last ATR value
+	ATR  decimal.Decimal `json:"atr"`
+	Time time.Time       `json:"time"`
+}
+
+func (s Stop) trailing() bool {
+	return s.Percent.IsPositive() || s.ATRMultiple.IsPositive()
+}
+
+func (s Stop) atr() bool {
+	return !s.Percent.IsPositive() && s.ATRMultiple.IsPositive()
+}
+
+// StopPrice returns the trailing stop price, zero if no trailing stop or ATR is not ready
+func (s Stop) StopPrice() decimal.Decimal {
+	var distance decimal.Decimal
+	switch {
+	case s.Percent.IsPositive():
+		distance = s.High.Mul(s.Percent).Div(hundred)
+	case s.ATRMultiple.IsPositive() && s.ATR.IsPositive():
+		distance = s.ATR.Mul(s.ATRMultiple)
+	default:
+		return decimal.Zero
+	}
+	return s.High.Sub(distance)
+}
+
+// Event is sent to handler after the stop fired, OrderId is 0 if Err is not nil
+type Event struct {
+	Stop    Stop
+	Reason  string // ReasonTrailingStop or ReasonTakeProfit
+	Price   decimal.Decimal
+	OrderId uint64
+	Err     error
+}
+
+type Handler func(event Event)
+
+// State is the persisted state of Manager and OCO
+type State struct {
+	Stops  []Stop  `json:"stops,omitempty"`
+	Groups []Group `json:"groups,omitempty"`
+}
+
+// Manager tracks the high-water mark by trade or candle updates, and sells when the stop is triggered.
+// The fired stop is removed even if the order failed, the handler gets the error.
+type Manager struct {
+	ex      exchange.RestAPIExchange
+	store   hs.KeyStore
+	key     string
+	handler Handler
+	Sugar   *zap.SugaredLogger
+
+	mu    sync.Mutex
+	state State
+	atrs  map[string]*indicator.ATRStream
+	bars  map[string]exchange.CandleUpdate // the last candle update by symbol and period
+}
+
+// New creates a manager, which saves the stops to store by key, call Load to resume the saved stops.
+func New(ex exchange.RestAPIExchange, store hs.KeyStore, key string, handler Handler, logger *zap.SugaredLogger) *Manager {
+	return &Manager{
+		ex:      ex,
+		store:   store,
+		key:     key,
+		handler: handler,
+		Sugar:   logger,
+		atrs:    make(map[string]*indicator.ATRStream),
+		bars:    make(map[string]exchange.CandleUpdate),
+	}
+}
+
+// Load resumes the saved stops, and loads candles for the ATR stops
+func (m *Manager) Load(ctx context.Context) error {
+	var state State
+	if err := m.store.Load(ctx, m.key, &state); err != nil {
+		return err
+	}
+	candles, err := m.loadCandles(state.Stops)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = state
+	for i := range m.state.Stops {
+		m.prime(&m.state.Stops[i], candles)
+	}
+	return nil
+}
+
+// Stops returns a copy of the active stops
+func (m *Manager) Stops() []Stop {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Stop(nil), m.state.Stops...)
+}
+
+// Add registers a stop, the id should be unique
+func (m *Manager) Add(ctx context.Context, stop Stop) error {
+	if stop.Id == "" || !stop.Amount.IsPositive() || !stop.trailing() && !stop.Target.IsPositive() ||
+		stop.atr() && (stop.ATRPeriod <= 0 || stop.ATRLength <= 0) {
+		return ErrBadStop
+	}
+	if stop.High.IsZero() {
+		price, err := m.ex.LastPrice(stop.Symbol.Symbol)
+		if err != nil {
+			return err
+		}
+		stop.High = price
+	}
+	if stop.Time.IsZero() {
+		stop.Time = time.Now()
+	}
+	candles, err := m.loadCandles([]Stop{stop})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.state.Stops {
+		if s.Id == stop.Id {
+			return ErrDuplicateStop
+		}
+	}
+	m.prime(&stop, candles)
+	state := State{Stops: append(append([]Stop(nil), m.state.Stops...), stop)}
+	if err := m.store.Save(ctx, m.key, state); err != nil {
+		return err
+	}
+	m.state = state
+	m.Sugar.Infof("stop %s of %s added, high %s, stop price %s, target %s", stop.Id, stop.Symbol.Symbol, stop.High, stop.StopPrice(), stop.Target)
+	return nil
+}
+
+// Remove unregisters the stop
+func (m *Manager) Remove(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var left []Stop
+	for _, s := range m.state.Stops {
+		if s.Id != id {
+			left = append(left, s)
+		}
+	}
+	if len(left) == len(m.state.Stops) {
+		return ErrStopNotFound
+	}
+	state := State{Stops: left}
+	if err := m.store.Save(ctx, m.key, state); err != nil {
+		return err
+	}
+	m.state = state
+	return nil
+}
+
+// OnPrice updates the stops of symbol with the price range since last update
+func (m *Manager) OnPrice(symbol string, high, low decimal.Decimal) {
+	m.update(symbol, func(Stop) (decimal.Decimal, decimal.Decimal) { return high, low })
+}
+
+// update updates the stops of symbol with the price range of every stop
+func (m *Manager) update(symbol string, priceRange func(s Stop) (high, low decimal.Decimal)) {
+	m.mu.Lock()
+	var events []Event
+	var left []Stop
+	changed := false
+	for _, s := range m.state.Stops {
+		if s.Symbol.Symbol != symbol {
+			left = append(left, s)
+			continue
+		}
+		high, low := priceRange(s)
+		// check the stop before raising the high, the low may be earlier than the high
+		if stop := s.StopPrice(); stop.IsPositive() && low.LessThanOrEqual(stop) {
+			events = append(events, Event{Stop: s, Reason: ReasonTrailingStop, Price: stop})
+			continue
+		}
+		if s.Target.IsPositive() && high.GreaterThanOrEqual(s.Target) {
+			events = append(events, Event{Stop: s, Reason: ReasonTakeProfit, Price: s.Target})
+			continue
+		}
+		if high.GreaterThan(s.High) {
+			s.High = high
+			changed = true
+		}
+		left = append(left, s)
+	}
+	if len(events) > 0 || changed {
+		m.state.Stops = left
+		// save without the fired stops before selling: a restart in between skips the sell instead of repeating it
+		if err := m.store.Save(context.Background(), m.key, m.state); err != nil {
+			m.Sugar.Errorf("save stops error: %s", err)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, e := range events {
+		m.fire(e)
+	}
+}
+
+// OnCandle updates the ATR and the stops with candle update, for exchange.StreamAPIExchange.SubscribeCandleUpdate
+func (m *Manager) OnCandle(update exchange.CandleUpdate) {
+	m.mu.Lock()
+	// the stream is shared, update once
+	values := make(map[string]float64)
+	for i := range m.state.Stops {
+		s := &m.state.Stops[i]
+		if s.Symbol.Symbol != update.Symbol || !s.atr() || s.ATRPeriod != update.Period {
+			continue
+		}
+		key := atrKey(*s)
+		v, ok := values[key]
+		if !ok {
+			stream, found := m.atrs[key]
+			if !found {
+				continue
+			}
+			v = stream.Update(update.Timestamp, update.High, update.Low, update.Close)
+			values[key] = v
+		}
+		if !math.IsNaN(v) {
+			s.ATR = decimal.NewFromFloat(v)
+		}
+	}
+	// the updates of the same bar are cumulative, use only the price change since the previous one
+	barKey := fmt.Sprintf("%s-%s", update.Symbol, update.Period)
+	prev, seen := m.bars[barKey]
+	seen = seen && prev.Timestamp == update.Timestamp
+	m.bars[barKey] = update
+	m.mu.Unlock()
+
+	high, low := update.High, update.Low
+	if seen {
+		if high <= prev.High {
+			high = update.Close
+		}
+		if low >= prev.Low {
+			low = update.Close
+		}
+	}
+	closePrice := decimal.NewFromFloat(update.Close)
+	highPrice, lowPrice := decimal.NewFromFloat(high), decimal.NewFromFloat(low)
+	start := time.Unix(update.Timestamp, 0)
+	m.update(update.Symbol, func(s Stop) (decimal.Decimal, decimal.Decimal) {
+		// the stop added in the bar doesn't know the earlier high and low
+		if !seen && start.Before(s.Time) {
+			return closePrice, closePrice
+		}
+		return highPrice, lowPrice
+	})
+}
+
+// Watch subscribes the trades of symbol, and updates the stops with every trade price
+func (m *Manager) Watch(ex exchange.StreamAPIExchange, symbol, clientId string) *exchange.Subscription {
+	return ex.SubscribeTrade(symbol, clientId, func(details []exchange.TradeDetail) {
+		for _, d := range details {
+			m.OnPrice(symbol, d.Price, d.Price)
+		}
+	})
+}
+
+// WatchCandle subscribes the candles of symbol, the ATR stops need the candle of ATRPeriod to update ATR.
+func (m *Manager) WatchCandle(ex exchange.StreamAPIExchange, symbol, clientId string, period time.Duration) *exchange.Subscription {
+	return ex.SubscribeCandleUpdate(symbol, clientId, period, m.OnCandle)
+}
+
+func (m *Manager) fire(e Event) {
+	s := e.Stop
+	amount := s.Amount.Truncate(s.Symbol.AmountPrecision)
+	if s.Slippage.IsPositive() {
+		price := e.Price.Mul(hundred.Sub(s.Slippage)).Div(hundred).Round(s.Symbol.PricePrecision)
+		e.OrderId, e.Err = m.ex.SellLimit(s.Symbol.Symbol, s.ClientOrderId, price, amount)
+	} else {
+		e.OrderId, e.Err = m.ex.SellMarket(s.Symbol, s.ClientOrderId, amount)
+	}
+	if e.Err != nil {
+		m.Sugar.Errorf("%s %s of %s fired at %s, sell error: %s", e.Reason, s.Id, s.Symbol.Symbol, e.Price, e.Err)
+	} else {
+		m.Sugar.Infof("%s %s of %s fired at %s, order %d placed", e.Reason, s.Id, s.Symbol.Symbol, e.Price, e.OrderId)
+	}
+	if m.handler != nil {
+		m.handler(e)
+	}
+}
+
+// loadCandles loads the candles to calculate ATR for the stops without ATR stream, it's called without m.mu
+func (m *Manager) loadCandles(stops []Stop) (map[string]hs.Candle, error) {
+	candles := make(map[string]hs.Candle)
+	for _, s := range stops {
+		if !s.atr() {
+			continue
+		}
+		key := atrKey(s)
+		m.mu.Lock()
+		_, ok := m.atrs[key]
+		m.mu.Unlock()
+		if _, loaded := candles[key]; ok || loaded {
+			continue
+		}
+		candle, err := m.ex.CandleBySize(s.Symbol.Symbol, s.ATRPeriod, s.ATRLength*4)
+		if err != nil {
+			return nil, err
+		}
+		candles[key] = candle
+	}
+	return candles, nil
+}
+
+// prime sets the ATR of stop, the stream is shared by the stops with same symbol and period,
+// and created from the candles by loadCandles if not exists
+func (m *Manager) prime(s *Stop, candles map[string]hs.Candle) {
+	if !s.atr() {
+		return
+	}
+	key := atrKey(*s)
+	stream, ok := m.atrs[key]
+	if !ok {
+		candle := candles[key]
+		stream = indicator.NewATRStream(s.ATRLength)
+		for i := 0; i < candle.Length(); i++ {
+			stream.Update(candle.Timestamp[i], candle.High[i], candle.Low[i], candle.Close[i])
+		}
+		m.atrs[key] = stream
+	}
+	if v := stream.Value(); !math.IsNaN(v) {
+		s.ATR = decimal.NewFromFloat(v)
+	}
+}
+
+func atrKey(s Stop) string {
+	return fmt.Sprintf("%s-%s-%d", s.Symbol.Symbol, s.ATRPeriod, s.ATRLength)
+}
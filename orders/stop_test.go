@@ -0,0 +1,147 @@
+package orders
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/exchange"
+	"github.com/xyths/hs/exchange/paper"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+var btcUsdt = exchange.Symbol{
+	Symbol:              "btc_usdt",
+	BaseCurrency:        "btc",
+	QuoteCurrency:       "usdt",
+	PricePrecision:      2,
+	AmountPrecision:     4,
+	LimitOrderMinAmount: decimal.RequireFromString("0.0001"),
+	MinTotal:            decimal.NewFromInt(1),
+}
+
+func newPaper(price float64) *paper.Exchange {
+	ex := paper.New([]exchange.Symbol{btcUsdt}, exchange.Fee{})
+	ex.Deposit("btc", decimal.NewFromInt(10))
+	ex.UpdateTicker("btc_usdt", hs.Ticker{Timestamp: 60, Open: price, High: price, Low: price, Close: price})
+	return ex
+}
+
+func dec(f float64) decimal.Decimal {
+	return decimal.NewFromFloat(f)
+}
+
+func TestManager_TrailingPercent(t *testing.T) {
+	ctx := context.Background()
+	ex := newPaper(100)
+	store := hs.NewMemoryKeyStore()
+	var events []Event
+	m := New(ex, store, "stops", func(e Event) { events = append(events, e) }, zap.NewNop().Sugar())
+	require.Equal(t, ErrBadStop, m.Add(ctx, Stop{Id: "a", Symbol: btcUsdt, Amount: dec(1)}))
+	require.NoError(t, m.Add(ctx, Stop{Id: "a", Symbol: btcUsdt, Amount: dec(1), Percent: dec(5)}))
+	require.Equal(t, ErrDuplicateStop, m.Add(ctx, Stop{Id: "a", Symbol: btcUsdt, Amount: dec(1), Percent: dec(5)}))
+	require.True(t, dec(95).Equal(m.Stops()[0].StopPrice()))
+
+	m.OnPrice("btc_usdt", dec(110), dec(105))
+	require.True(t, dec(104.5).Equal(m.Stops()[0].StopPrice()))
+
+	// the high-water mark survives restart
+	m = New(ex, store, "stops", func(e Event) { events = append(events, e) }, zap.NewNop().Sugar())
+	require.NoError(t, m.Load(ctx))
+	require.True(t, dec(110).Equal(m.Stops()[0].High))
+
+	m.OnPrice("eth_usdt", dec(50), dec(50))
+	m.OnPrice("btc_usdt", dec(106), dec(104.6))
+	require.Empty(t, events)
+	m.OnPrice("btc_usdt", dec(104.5), dec(104.5))
+	require.Len(t, events, 1)
+	require.Equal(t, ReasonTrailingStop, events[0].Reason)
+	require.NoError(t, events[0].Err)
+	require.Empty(t, m.Stops())
+	o, err := ex.GetOrderById(events[0].OrderId, "btc_usdt")
+	require.NoError(t, err)
+	require.Equal(t, exchange.Sell, o.Side)
+	require.True(t, dec(1).Equal(o.Amount))
+
+	var state State
+	require.NoError(t, store.Load(ctx, "stops", &state))
+	require.Empty(t, state.Stops)
+}
+
+func TestManager_TakeProfit(t *testing.T) {
+	ctx := context.Background()
+	ex := newPaper(100)
+	var events []Event
+	m := New(ex, hs.NewMemoryKeyStore(), "stops", func(e Event) { events = append(events, e) }, zap.NewNop().Sugar())
+	require.NoError(t, m.Add(ctx, Stop{Id: "tp", Symbol: btcUsdt, Amount: dec(0.5), Percent: dec(10), Target: dec(120), Slippage: dec(1)}))
+	m.OnPrice("btc_usdt", dec(121), dec(115))
+	require.Len(t, events, 1)
+	require.Equal(t, ReasonTakeProfit, events[0].Reason)
+	o, err := ex.GetOrderById(events[0].OrderId, "btc_usdt")
+	require.NoError(t, err)
+	require.True(t, dec(118.8).Equal(o.Price), o.Price.String())
+	require.Equal(t, ErrStopNotFound, m.Remove(ctx, "tp"))
+}
+
+func TestManager_TrailingATR(t *testing.T) {
+	ctx := context.Background()
+	ex := paper.New([]exchange.Symbol{btcUsdt}, exchange.Fee{})
+	ex.Deposit("btc", decimal.NewFromInt(10))
+	// true range is 2 for every bar
+	candle := hs.NewCandle(100)
+	for i := 0; i < 20; i++ {
+		candle.Append(hs.Ticker{Timestamp: int64(60 + i*60), Open: 100, High: 101, Low: 99, Close: 100})
+	}
+	ex.UpdateCandle("btc_usdt", candle)
+	store := hs.NewMemoryKeyStore()
+	var events []Event
+	m := New(ex, store, "stops", func(e Event) { events = append(events, e) }, zap.NewNop().Sugar())
+	require.Equal(t, ErrBadStop, m.Add(ctx, Stop{Id: "atr", Symbol: btcUsdt, Amount: dec(1), ATRMultiple: dec(3)}))
+	require.NoError(t, m.Add(ctx, Stop{Id: "atr", Symbol: btcUsdt, Amount: dec(1), ATRMultiple: dec(3), ATRPeriod: time.Minute, ATRLength: 14,
+		Time: time.Unix(1260, 0)}))
+	atr, _ := m.Stops()[0].ATR.Float64()
+	require.InDelta(t, 2, atr, 1e-9)
+	stop, _ := m.Stops()[0].StopPrice().Float64()
+	require.InDelta(t, 94, stop, 1e-9)
+
+	m = New(ex, store, "stops", func(e Event) { events = append(events, e) }, zap.NewNop().Sugar())
+	require.NoError(t, m.Load(ctx))
+	// a wider bar raises the high and the ATR
+	m.OnCandle(exchange.CandleUpdate{Symbol: "btc_usdt", Period: time.Minute,
+		Ticker: hs.Ticker{Timestamp: 1320, Open: 100, High: 116, Low: 100, Close: 110}})
+	s := m.Stops()[0]
+	require.True(t, dec(116).Equal(s.High))
+	require.True(t, s.ATR.GreaterThan(dec(2)))
+	require.Empty(t, events)
+	m.OnPrice("btc_usdt", s.StopPrice(), s.StopPrice())
+	require.Len(t, events, 1)
+	require.Equal(t, ReasonTrailingStop, events[0].Reason)
+}
+
+func TestManager_OnCandleMidBar(t *testing.T) {
+	ctx := context.Background()
+	ex := newPaper(100)
+	var events []Event
+	m := New(ex, hs.NewMemoryKeyStore(), "stops", func(e Event) { events = append(events, e) }, zap.NewNop().Sugar())
+	// added in the bar started at 120, after its low 90
+	require.NoError(t, m.Add(ctx, Stop{Id: "a", Symbol: btcUsdt, Amount: dec(1), Percent: dec(5), Time: time.Unix(150, 0)}))
+	m.OnCandle(exchange.CandleUpdate{Symbol: "btc_usdt", Period: time.Minute,
+		Ticker: hs.Ticker{Timestamp: 120, Open: 100, High: 100, Low: 90, Close: 100}})
+	require.Empty(t, events)
+	require.True(t, dec(100).Equal(m.Stops()[0].High))
+
+	// the later updates of the bar use the change since the previous one, the earlier low 90 is not repeated
+	m.OnCandle(exchange.CandleUpdate{Symbol: "btc_usdt", Period: time.Minute,
+		Ticker: hs.Ticker{Timestamp: 120, Open: 100, High: 110, Low: 90, Close: 108}})
+	require.Empty(t, events)
+	require.True(t, dec(110).Equal(m.Stops()[0].High))
+	m.OnCandle(exchange.CandleUpdate{Symbol: "btc_usdt", Period: time.Minute,
+		Ticker: hs.Ticker{Timestamp: 120, Open: 100, High: 110, Low: 90, Close: 105}})
+	require.Empty(t, events)
+	m.OnCandle(exchange.CandleUpdate{Symbol: "btc_usdt", Period: time.Minute,
+		Ticker: hs.Ticker{Timestamp: 120, Open: 100, High: 110, Low: 90, Close: 104}})
+	require.Len(t, events, 1)
+	require.Equal(t, ReasonTrailingStop, events[0].Reason)
+}
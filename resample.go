@@ -0,0 +1,131 @@
+package hs
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var ErrBadPeriod = errors.New("resample period should be whole seconds and positive")
+
+// Resample aggregates c into bars of period, aligned to UTC boundaries.
+// See ResampleWithOffset.
+func (c Candle) Resample(period time.Duration) (Candle, error) {
+	return c.ResampleWithOffset(period, 0)
+}
+
+// ResampleWithOffset aggregates c into bars of period, the bar boundaries are shifted by offset,
+// eg. offset -8h makes 1d bars begin at 00:00 of UTC+8.
+// The timestamp of a result bar is its boundary, not the first source bar in it.
+// The first and last bars may be partial, and there is no bar for gaps.
+// c must be in time order, and its period must be a divisor of period.
+// The result keeps the capacity of c, or the length of c if the capacity is less.
+func (c Candle) ResampleWithOffset(period, offset time.Duration) (Candle, error) {
+	capacity := c.Capacity
+	if capacity < c.Length() {
+		capacity = c.Length()
+	}
+	r, err := NewResampler(period, offset, capacity)
+	if err != nil {
+		return Candle{}, err
+	}
+	for i := 0; i < c.Length(); i++ {
+		r.Update(Ticker{
+			Timestamp: c.Timestamp[i],
+			Open:      c.Open[i],
+			High:      c.High[i],
+			Low:       c.Low[i],
+			Close:     c.Close[i],
+			Volume:    c.Volume[i],
+		})
+	}
+	return r.Candle(), nil
+}
+
+// Resampler aggregates streaming tickers into bars of a longer period.
+type Resampler struct {
+	period int64 // in seconds
+	offset int64
+	candle Candle
+	begin  int64    // the begin of the last bucket, kept here for candle may have no capacity
+	bars   []Ticker // source bars in the last bucket
+}
+
+// NewResampler returns ErrBadPeriod if period is not positive whole seconds,
+// offset is truncated to seconds, and can be negative or longer than period.
+func NewResampler(period, offset time.Duration, capacity int) (*Resampler, error) {
+	if period < time.Second || period%time.Second != 0 {
+		return nil, ErrBadPeriod
+	}
+	p := int64(period / time.Second)
+	o := int64(offset/time.Second) % p
+	if o < 0 {
+		o += p
+	}
+	return &Resampler{
+		period: p,
+		offset: o,
+		candle: NewCandle(capacity),
+	}, nil
+}
+
+// Align returns the begin of the bar which timestamp in
+func (r *Resampler) Align(timestamp int64) int64 {
+	t := timestamp - r.offset
+	q := t / r.period
+	if t%r.period < 0 {
+		q--
+	}
+	return q*r.period + r.offset
+}
+
+// Update adds ticker into the resampler, a ticker with the same timestamp of a previous one replaces it.
+// It returns the updated bar, and false if ticker is older than the last bar, and is dropped.
+func (r *Resampler) Update(ticker Ticker) (Ticker, bool) {
+	begin := r.Align(ticker.Timestamp)
+	if len(r.bars) > 0 {
+		if begin < r.begin {
+			return Ticker{}, false
+		}
+		if begin > r.begin {
+			r.bars = r.bars[:0]
+		}
+	}
+	r.begin = begin
+	r.insert(ticker)
+
+	bar := Ticker{
+		Timestamp: begin,
+		Open:      r.bars[0].Open,
+		High:      -math.MaxFloat64,
+		Low:       math.MaxFloat64,
+		Close:     r.bars[len(r.bars)-1].Close,
+	}
+	for _, b := range r.bars {
+		bar.High = math.Max(bar.High, b.High)
+		bar.Low = math.Min(bar.Low, b.Low)
+		bar.Volume += b.Volume
+	}
+	r.candle.Append(bar)
+	return bar, true
+}
+
+// Candle returns the resampled bars, the last one may be partial
+func (r *Resampler) Candle() Candle {
+	return r.candle
+}
+
+// insert ticker into bars in time order
+func (r *Resampler) insert(ticker Ticker) {
+	i := len(r.bars)
+	for i > 0 && r.bars[i-1].Timestamp >= ticker.Timestamp {
+		i--
+	}
+	if i < len(r.bars) && r.bars[i].Timestamp == ticker.Timestamp {
+		r.bars[i] = ticker
+		return
+	}
+	r.bars = append(r.bars, Ticker{})
+	copy(r.bars[i+1:], r.bars[i:])
+	r.bars[i] = ticker
+}
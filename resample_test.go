@@ -0,0 +1,131 @@
+package hs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCandle_Resample(t *testing.T) {
+	c := NewCandle(100)
+	// 1m bars from 00:03 to 00:11, 00:07 is missing
+	tickers := []Ticker{
+		{180, 1, 2, 1, 2, 1},
+		{240, 2, 3, 2, 3, 1},
+		{300, 3, 5, 3, 4, 1},
+		{360, 4, 4, 1, 2, 2},
+		{480, 2, 6, 2, 6, 3},
+		{540, 6, 7, 5, 5, 1},
+		{600, 5, 5, 5, 5, 1},
+		{660, 5, 9, 5, 8, 1},
+	}
+	for _, ticker := range tickers {
+		c.Append(ticker)
+	}
+	r, err := c.Resample(time.Minute * 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Ticker{
+		{0, 1, 3, 1, 3, 2},
+		{300, 3, 7, 1, 5, 7},
+		{600, 5, 9, 5, 8, 2},
+	}
+	if r.Length() != len(expect) {
+		t.Fatalf("length expect %d, got %d", len(expect), r.Length())
+	}
+	for i, e := range expect {
+		got := Ticker{r.Timestamp[i], r.Open[i], r.High[i], r.Low[i], r.Close[i], r.Volume[i]}
+		if got != e {
+			t.Errorf("[%d] expect %v, got %v", i, e, got)
+		}
+	}
+
+	// 10m bars begin at xx:05
+	r, _ = c.ResampleWithOffset(time.Minute*10, time.Minute*5)
+	if r.Length() != 2 || r.Timestamp[0] != -300 || r.Timestamp[1] != 300 {
+		t.Errorf("offset resample got %v", r.Timestamp)
+	}
+
+	// the candle built without capacity
+	c.Capacity = 0
+	if r, _ = c.Resample(time.Minute * 5); r.Length() != len(expect) {
+		t.Errorf("zero capacity resample got %v", r.Timestamp)
+	}
+	for _, period := range []time.Duration{0, -time.Minute, time.Millisecond * 500, time.Millisecond * 1500} {
+		if _, err = c.Resample(period); err != ErrBadPeriod {
+			t.Errorf("period %s expect ErrBadPeriod, got %v", period, err)
+		}
+	}
+}
+
+func TestResampler_Align(t *testing.T) {
+	tests := []struct {
+		Period    time.Duration
+		Offset    time.Duration
+		Timestamp int64
+		Expect    int64
+	}{
+		{time.Hour, 0, 3599, 0},
+		{time.Hour, 0, 3600, 3600},
+		{time.Hour, 0, -1, -3600},
+		// 1d bar of UTC+8, 2021-01-01T00:00:00+08:00
+		{time.Hour * 24, -time.Hour * 8, 1609430400 + 3600, 1609430400},
+		{time.Hour * 24, -time.Hour * 8, 1609430400 - 1, 1609430400 - 86400},
+		{time.Hour * 24, time.Hour * 16, 1609430400 + 3600, 1609430400},
+		{time.Hour * 24, -time.Hour * 32, 1609430400 + 3600, 1609430400},
+	}
+	for i, tt := range tests {
+		r, err := NewResampler(tt.Period, tt.Offset, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Align(tt.Timestamp); got != tt.Expect {
+			t.Errorf("[%d] expect %d, got %d", i, tt.Expect, got)
+		}
+	}
+}
+
+func TestResampler_Update(t *testing.T) {
+	r, _ := NewResampler(time.Minute*5, 0, 10)
+	r.Update(Ticker{0, 1, 2, 1, 2, 1})
+	r.Update(Ticker{60, 2, 3, 2, 3, 1})
+	// revision of the 00:01 bar
+	bar, ok := r.Update(Ticker{60, 2, 4, 2, 4, 2})
+	if !ok || bar != (Ticker{0, 1, 4, 1, 4, 3}) {
+		t.Errorf("revision got %v", bar)
+	}
+	// next bucket
+	bar, ok = r.Update(Ticker{300, 4, 5, 3, 3, 1})
+	if !ok || bar != (Ticker{300, 4, 5, 3, 3, 1}) {
+		t.Errorf("new bar got %v", bar)
+	}
+	// too old
+	if _, ok = r.Update(Ticker{240, 1, 1, 1, 1, 1}); ok {
+		t.Errorf("old ticker should be dropped")
+	}
+	c := r.Candle()
+	if c.Length() != 2 || c.Close[0] != 4 || c.Volume[0] != 3 {
+		t.Errorf("candle got %v", c)
+	}
+}
+
+func TestResampler_UpdateNoCapacity(t *testing.T) {
+	// the streaming use keeps no candle
+	r, _ := NewResampler(time.Minute*5, 0, 0)
+	r.Update(Ticker{0, 1, 2, 1, 2, 1})
+	r.Update(Ticker{60, 2, 3, 2, 3, 1})
+	bar, ok := r.Update(Ticker{300, 4, 5, 3, 3, 1})
+	if !ok || bar != (Ticker{300, 4, 5, 3, 3, 1}) {
+		t.Errorf("new bar got %v", bar)
+	}
+	bar, ok = r.Update(Ticker{360, 3, 6, 3, 6, 2})
+	if !ok || bar != (Ticker{300, 4, 6, 3, 6, 3}) {
+		t.Errorf("second source bar got %v", bar)
+	}
+	if _, ok = r.Update(Ticker{240, 1, 1, 1, 1, 1}); ok {
+		t.Errorf("old ticker should be dropped")
+	}
+	if c := r.Candle(); c.Length() != 0 {
+		t.Errorf("candle got %v", c)
+	}
+}
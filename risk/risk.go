@@ -1,6 +1,11 @@
 package risk
 
-import "github.com/shopspring/decimal"
+import (
+	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
+	"github.com/xyths/hs/indicator"
+	"math"
+)
 
 func SpotRisk(buy, stop, amount decimal.Decimal) decimal.Decimal {
 	return amount.Mul(buy.Sub(stop))
@@ -22,7 +27,20 @@ func (s Spot) Quota(buy, stop, total decimal.Decimal) decimal.Decimal {
 	return total.DivRound(buy.Sub(stop), s.AmountPrecision)
 }
 
-// QuotaAtr is Quota by atr
+// QuotaAtr is Quota by atr, zero if atr is zero after rounding to PricePrecision
 func (s Spot) QuotaAtr(atr float64, total decimal.Decimal) decimal.Decimal {
-	return total.DivRound(decimal.NewFromFloat(atr).Round(s.PricePrecision), s.AmountPrecision)
+	d := decimal.NewFromFloat(atr).Round(s.PricePrecision)
+	if d.Sign() <= 0 {
+		return decimal.Zero
+	}
+	return total.DivRound(d, s.AmountPrecision)
+}
+
+// QuotaCandle is QuotaAtr by the n periods ATR of candle, zero if candle is too short
+func (s Spot) QuotaCandle(candle hs.Candle, n int, total decimal.Decimal) decimal.Decimal {
+	atr := indicator.Last(indicator.CandleATR(candle, n))
+	if math.IsNaN(atr) || atr <= 0 {
+		return decimal.Zero
+	}
+	return s.QuotaAtr(atr, total)
 }
@@ -2,6 +2,7 @@ package risk
 
 import (
 	"github.com/shopspring/decimal"
+	"github.com/xyths/hs"
 	"testing"
 )
 
@@ -111,3 +112,30 @@ func TestSpot_QuotaAtr(t *testing.T) {
 		}
 	})
 }
+
+func TestSpot_QuotaCandle(t *testing.T) {
+	candle := hs.NewCandle(10)
+	for i, p := range []float64{10, 11, 12, 11, 13} {
+		candle.Append(hs.Ticker{Timestamp: int64(i), High: p, Low: p - 2, Close: p - 1})
+	}
+	spot := Spot{PricePrecision: 0, AmountPrecision: 0}
+	// ATR(3) is 2.33, rounded to 2
+	if quota := spot.QuotaCandle(candle, 3, decimal.NewFromInt(1000)); !quota.Equal(decimal.NewFromInt(500)) {
+		t.Errorf("expect 500, got %s", quota)
+	}
+	if quota := spot.QuotaCandle(candle, 10, decimal.NewFromInt(1000)); !quota.IsZero() {
+		t.Errorf("expect 0, got %s", quota)
+	}
+
+	// ATR below half a tick is rounded to zero
+	flat := hs.NewCandle(10)
+	for i := 0; i < 5; i++ {
+		flat.Append(hs.Ticker{Timestamp: int64(i), High: 10.2, Low: 10, Close: 10.1})
+	}
+	if quota := spot.QuotaCandle(flat, 3, decimal.NewFromInt(1000)); !quota.IsZero() {
+		t.Errorf("expect 0, got %s", quota)
+	}
+	if quota := spot.QuotaAtr(0.4, decimal.NewFromInt(1000)); !quota.IsZero() {
+		t.Errorf("expect 0, got %s", quota)
+	}
+}
@@ -0,0 +1,70 @@
+package hs
+
+import (
+	"context"
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sync"
+)
+
+// KeyStore saves any json value by key, for the states which need to survive restarts.
+type KeyStore interface {
+	// Load unmarshal the value of key to value, and leaves value untouched if not found
+	Load(ctx context.Context, key string, value interface{}) error
+	Save(ctx context.Context, key string, value interface{}) error
+}
+
+// MongoKeyStore saves value as json string by SaveKey, for decimal has no bson codec.
+type MongoKeyStore struct {
+	coll *mongo.Collection
+}
+
+func NewMongoKeyStore(coll *mongo.Collection) *MongoKeyStore {
+	return &MongoKeyStore{coll: coll}
+}
+
+func (s *MongoKeyStore) Load(ctx context.Context, key string, value interface{}) error {
+	var raw string
+	if err := LoadKey(ctx, s.coll, key, &raw); err != nil || raw == "" {
+		return err
+	}
+	return json.Unmarshal([]byte(raw), value)
+}
+
+func (s *MongoKeyStore) Save(ctx context.Context, key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return SaveKey(ctx, s.coll, key, string(raw))
+}
+
+// MemoryKeyStore keeps value as json in memory, for test and paper trading
+type MemoryKeyStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{values: make(map[string][]byte)}
+}
+
+func (s *MemoryKeyStore) Load(ctx context.Context, key string, value interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if raw, ok := s.values[key]; ok {
+		return json.Unmarshal(raw, value)
+	}
+	return nil
+}
+
+func (s *MemoryKeyStore) Save(ctx context.Context, key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = raw
+	return nil
+}
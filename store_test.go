@@ -0,0 +1,23 @@
+package hs
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMemoryKeyStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryKeyStore()
+	value := struct {
+		Name string `json:"name"`
+	}{Name: "default"}
+	require.NoError(t, s.Load(ctx, "key", &value))
+	require.Equal(t, "default", value.Name)
+
+	value.Name = "saved"
+	require.NoError(t, s.Save(ctx, "key", value))
+	value.Name = ""
+	require.NoError(t, s.Load(ctx, "key", &value))
+	require.Equal(t, "saved", value.Name)
+}